@@ -2,21 +2,35 @@ package main
 
 import (
 	"context"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
 
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
 	"github.com/srgjo27/e-learning/internal/entity"
+	"github.com/srgjo27/e-learning/internal/infrastructure/breach"
+	"github.com/srgjo27/e-learning/internal/infrastructure/migration"
+	"github.com/srgjo27/e-learning/internal/infrastructure/oidc"
 	"github.com/srgjo27/e-learning/internal/infrastructure/repository"
+	"github.com/srgjo27/e-learning/internal/infrastructure/storage"
 	"github.com/srgjo27/e-learning/internal/interface/rest"
+	"github.com/srgjo27/e-learning/internal/notification"
+	"github.com/srgjo27/e-learning/internal/scheduler"
 	"github.com/srgjo27/e-learning/internal/usecase"
 	"github.com/srgjo27/e-learning/internal/utils"
 )
@@ -27,9 +41,9 @@ func main() {
 		mongoURI = uri
 	}
 
-	jwtSecret := "supersecretkey"
-	if secret := os.Getenv("JWT_SECRET"); secret != "" {
-		jwtSecret = secret
+	jwtSecret, err := loadJWTSecret()
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	clientOptions := options.Client().ApplyURI(mongoURI)
@@ -52,6 +66,45 @@ func main() {
 	assignmentCollection := client.Database("e-learning").Collection("assignments")
 	assessmentCollection := client.Database("e-learning").Collection("assessments")
 	messageCollection := client.Database("e-learning").Collection("messages")
+	submissionCollection := client.Database("e-learning").Collection("submissions")
+	notificationCollection := client.Database("e-learning").Collection("notifications")
+	reminderCollection := client.Database("e-learning").Collection("reminder_sends")
+	lockCollection := client.Database("e-learning").Collection("locks")
+	moduleCollection := client.Database("e-learning").Collection("modules")
+	lessonCollection := client.Database("e-learning").Collection("lessons")
+	progressCollection := client.Database("e-learning").Collection("progress")
+	sessionCollection := client.Database("e-learning").Collection("class_sessions")
+	attendanceCollection := client.Database("e-learning").Collection("attendance")
+	termCollection := client.Database("e-learning").Collection("terms")
+	auditCollection := client.Database("e-learning").Collection("audit_log")
+	attemptCollection := client.Database("e-learning").Collection("login_attempts")
+	settingsCollection := client.Database("e-learning").Collection("settings")
+	oidcFlowCollection := client.Database("e-learning").Collection("oidc_flows")
+	verificationCollection := client.Database("e-learning").Collection("email_verifications")
+	signingKeyCollection := client.Database("e-learning").Collection("signing_keys")
+	apiTokenCollection := client.Database("e-learning").Collection("api_tokens")
+	roleCollection := client.Database("e-learning").Collection("roles")
+	guardianCollection := client.Database("e-learning").Collection("guardian_links")
+	impersonationCollection := client.Database("e-learning").Collection("impersonation_sessions")
+	passwordResetCollection := client.Database("e-learning").Collection("password_resets")
+
+	migrationLock := scheduler.NewMongoLock(lockCollection, "schema-migrations", instanceID(), 10*time.Minute)
+	migrations := migration.NewRunner(client.Database("e-learning"), migrationLock, migration.All)
+
+	// "migrate [up|status]" manages the schema and exits without serving.
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err := runMigrateCommand(migrations, os.Args[2:])
+		client.Disconnect(context.Background())
+		if err != nil {
+			log.Fatalf("Migration error: %v", err)
+		}
+		return
+	}
+	if os.Getenv("AUTO_MIGRATE") != "false" {
+		if err := applyMigrations(migrations); err != nil {
+			log.Fatalf("Migration error: %v", err)
+		}
+	}
 
 	userRepo := repository.NewMongoUserRepository(userCollection)
 	courseRepo := repository.NewMongoCourseRepository(courseCollection)
@@ -60,70 +113,401 @@ func main() {
 	assignmentRepo := repository.NewMongoAssignmentRepository(assignmentCollection)
 	assessmentRepo := repository.NewMongoAssessmentRepository(assessmentCollection)
 	messageRepo := repository.NewMongoMessageRepository(messageCollection)
+	submissionRepo := repository.NewMongoSubmissionRepository(submissionCollection)
+	reminderRepo := repository.NewMongoReminderRepository(reminderCollection)
+	moduleRepo := repository.NewMongoModuleRepository(moduleCollection)
+	lessonRepo := repository.NewMongoLessonRepository(lessonCollection)
+	progressRepo := repository.NewMongoProgressRepository(progressCollection)
+	sessionRepo := repository.NewMongoSessionRepository(sessionCollection)
+	attendanceRepo := repository.NewMongoAttendanceRepository(attendanceCollection)
+	termRepo := repository.NewMongoTermRepository(termCollection)
+	searchRepo := repository.NewMongoSearchRepository(courseCollection, lessonCollection, assignmentCollection, announcementCollection, userCollection)
+	dependencyRepo := repository.NewMongoDependencyRepository(client.Database("e-learning"))
+	trashRepo := repository.NewMongoTrashRepository(client.Database("e-learning"))
+	auditRepo := repository.NewMongoAuditRepository(auditCollection)
+	attemptRepo := repository.NewMongoAttemptRepository(attemptCollection)
+	settingsRepo := repository.NewMongoSettingsRepository(settingsCollection)
+	oidcFlowRepo := repository.NewMongoOIDCFlowRepository(oidcFlowCollection)
+	verificationRepo := repository.NewMongoEmailVerificationRepository(verificationCollection)
+	apiTokenRepo := repository.NewMongoAPITokenRepository(apiTokenCollection)
+	passwordResetRepo := repository.NewMongoPasswordResetRepository(passwordResetCollection)
+	roleRepo := repository.NewMongoRoleRepository(roleCollection)
+	guardianRepo := repository.NewMongoGuardianRepository(guardianCollection)
+	impersonationRepo := repository.NewMongoImpersonationRepository(impersonationCollection)
 
-	authUseCase := usecase.NewAuthUseCase(userRepo, []byte(jwtSecret))
-	adminUseCase := usecase.NewAdminUseCase(courseRepo, classRepo, announcementRepo)
-	teacherUseCase := usecase.NewTeacherUseCase(courseRepo, classRepo, userRepo)
-	teacherAdvancedUseCase := usecase.NewTeacherAdvancedUseCase(assignmentRepo, assessmentRepo, messageRepo)
+	sealKey, err := hkdf.Key(sha256.New, []byte(jwtSecret), nil, "e-learning signing keys", 32)
+	if err != nil {
+		log.Fatalf("Signing key error: %v", err)
+	}
+	signingKeyRepo, err := repository.NewMongoSigningKeyRepository(signingKeyCollection, sealKey)
+	if err != nil {
+		log.Fatalf("Signing key error: %v", err)
+	}
 
-	authHandler := rest.NewAuthHandler(authUseCase)
-	profileHandler := rest.NewProfileHandler(authUseCase)
-	adminTasksHandler := rest.NewAdminTasksHandler(adminUseCase)
-	adminHandler := rest.NewAdminHandler(authUseCase)
+	transactor, err := repository.NewMongoTransactor(ctx, client)
+	if err != nil {
+		log.Fatalf("MongoDB topology error: %v", err)
+	}
+	// Deletes, purges and the changes that queue notifications rely on
+	// transactions to stay consistent, so a standalone server is refused
+	// unless MONGO_ALLOW_STANDALONE opts in, as for local development.
+	if !transactor.Supported() {
+		if os.Getenv("MONGO_ALLOW_STANDALONE") != "true" {
+			log.Fatal("MongoDB is not a replica set and does not support transactions; run a replica set or set MONGO_ALLOW_STANDALONE=true")
+		}
+		log.Println("WARNING: MongoDB is not a replica set; deletes, purges and notifications run WITHOUT transactions and can leave partial changes behind")
+	}
+
+	blobDir := "blobs"
+	if dir := os.Getenv("BLOB_DIR"); dir != "" {
+		blobDir = dir
+	}
+	blobStore, err := storage.NewLocalBlobStore(blobDir)
+	if err != nil {
+		log.Fatalf("Blob store error: %v", err)
+	}
+
+	mailRenderer, err := notification.NewRenderer()
+	if err != nil {
+		log.Fatalf("Mail template error: %v", err)
+	}
+	outboxKey, err := hkdf.Key(sha256.New, []byte(jwtSecret), nil, "e-learning notification bodies", 32)
+	if err != nil {
+		log.Fatalf("Outbox key error: %v", err)
+	}
+	outbox, err := notification.NewOutbox(notificationCollection, mailRenderer, outboxKey)
+	if err != nil {
+		log.Fatalf("Outbox key error: %v", err)
+	}
+
+	mailSender, err := newMailSender()
+	if err != nil {
+		log.Fatalf("Mail sender error: %v", err)
+	}
+
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	go notification.NewWorker(outbox, mailSender, userRepo).Run(workerCtx)
+
+	accountPolicy, ipPolicy, err := loginThrottlePolicies()
+	if err != nil {
+		log.Fatalf("Invalid login throttle setting: %v", err)
+	}
+	attemptThrottle := usecase.NewAttemptThrottle(attemptRepo, accountPolicy, ipPolicy)
+
+	passwordService, err := newPasswordService()
+	if err != nil {
+		log.Fatalf("Invalid password setting: %v", err)
+	}
+
+	keyPolicy, err := keyRotationPolicy()
+	if err != nil {
+		log.Fatalf("Invalid signing key setting: %v", err)
+	}
+	keyRing, err := usecase.NewKeyRing(signingKeyRepo, keyPolicy)
+	if err != nil {
+		log.Fatalf("Invalid signing key setting: %v", err)
+	}
+	keyCtx, cancelKeys := context.WithTimeout(context.Background(), 30*time.Second)
+	err = keyRing.Maintain(keyCtx, time.Now())
+	cancelKeys()
+	if err != nil {
+		log.Fatalf("Signing key error: %v", err)
+	}
+	keyLock := scheduler.NewMongoLock(lockCollection, "signing-key-rotation", instanceID(), 10*time.Minute)
+	go scheduler.New("signing-key-rotation", keyLock, time.Hour, keyRing.Maintain).Run(workerCtx)
+
+	mfaIssuer := "E-Learning"
+	if v := os.Getenv("MFA_ISSUER"); v != "" {
+		mfaIssuer = v
+	}
+
+	verifyRoles, err := parseRoles("EMAIL_VERIFICATION_REQUIRED_ROLES", "admin,teacher,student,guardian")
+	if err != nil {
+		log.Fatalf("Invalid email verification setting: %v", err)
+	}
+
+	roleUseCase := usecase.NewRoleUseCase(roleRepo, userRepo)
+	authUseCase := usecase.NewAuthUseCase(userRepo, keyRing, outbox, attemptThrottle, passwordService, settingsRepo, mfaIssuer, verificationRepo, verifyRoles, apiTokenRepo, roleUseCase, impersonationRepo, passwordResetRepo)
+	adminUseCase := usecase.NewAdminUseCase(courseRepo, classRepo, announcementRepo, userRepo, outbox, termRepo, roleUseCase, transactor)
+	teacherUseCase := usecase.NewTeacherUseCase(courseRepo, classRepo, userRepo, termRepo)
+	teacherAdvancedUseCase := usecase.NewTeacherAdvancedUseCase(assignmentRepo, assessmentRepo, messageRepo, submissionRepo, courseRepo, classRepo, userRepo, outbox, dependencyRepo, transactor)
+	notificationUseCase := usecase.NewNotificationUseCase(userRepo)
+	calendarUseCase := usecase.NewCalendarUseCase(userRepo, courseRepo, classRepo, assignmentRepo, assessmentRepo, progressRepo, lessonRepo, roleUseCase)
+	contentUseCase := usecase.NewContentUseCase(moduleRepo, lessonRepo, courseRepo, classRepo, progressRepo, assessmentRepo, blobStore)
+	progressUseCase := usecase.NewProgressUseCase(progressRepo, courseRepo, classRepo, userRepo, lessonRepo, assignmentRepo, assessmentRepo)
+	studentUseCase := usecase.NewStudentUseCase(courseRepo, classRepo, assignmentRepo, assessmentRepo, messageRepo, submissionRepo, userRepo, progressRepo, lessonRepo, announcementRepo)
+	guardianUseCase := usecase.NewGuardianUseCase(guardianRepo, userRepo, roleUseCase, outbox, studentUseCase, progressUseCase)
+	releaseUseCase := usecase.NewReleaseUseCase(courseRepo, lessonRepo, assignmentRepo, assessmentRepo)
+	// Classmates often share an address, so check-in codes are only
+	// throttled per student and session.
+	checkInThrottle := usecase.NewAttemptThrottle(attemptRepo, entity.ThrottlePolicy{
+		FreeAttempts: 3,
+		BaseDelay:    5 * time.Second,
+		MaxDelay:     time.Minute,
+		LockoutAfter: 10,
+		LockoutFor:   2 * time.Hour,
+		Window:       2 * time.Hour,
+	}, entity.ThrottlePolicy{})
+	attendanceUseCase := usecase.NewAttendanceUseCase(sessionRepo, attendanceRepo, classRepo, courseRepo, userRepo, checkInThrottle)
+	termUseCase := usecase.NewTermUseCase(termRepo, courseRepo, moduleRepo, lessonRepo, assignmentRepo, assessmentRepo, blobStore, dependencyRepo, transactor)
+	searchUseCase := usecase.NewSearchUseCase(searchRepo, courseRepo, classRepo, progressRepo, lessonRepo, assessmentRepo, roleUseCase)
+
+	reminderOffsets, err := parseReminderOffsets()
+	if err != nil {
+		log.Fatalf("Invalid REMINDER_OFFSETS: %v", err)
+	}
+	reminderUseCase := usecase.NewReminderUseCase(assignmentRepo, assessmentRepo, submissionRepo, courseRepo, classRepo, userRepo, reminderRepo, progressRepo, lessonRepo, outbox, reminderOffsets)
+	reminderLock := scheduler.NewMongoLock(lockCollection, "due-reminders", instanceID(), 3*time.Minute)
+	go scheduler.New("due-reminders", reminderLock, time.Minute, reminderUseCase.SendDueReminders).Run(workerCtx)
+
+	trashRetention := 30 * 24 * time.Hour
+	if v := os.Getenv("TRASH_RETENTION"); v != "" {
+		trashRetention, err = time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("Invalid TRASH_RETENTION: %v", err)
+		}
+	}
+	trashUseCase := usecase.NewTrashUseCase(trashRepo, dependencyRepo, transactor, blobStore, trashRetention)
+	trashLock := scheduler.NewMongoLock(lockCollection, "trash-purge", instanceID(), 30*time.Minute)
+	go scheduler.New("trash-purge", trashLock, time.Hour, trashUseCase.PurgeExpired).Run(workerCtx)
+
+	oidcProvider, groupRoles, err := newOIDCProvider()
+	if err != nil {
+		log.Fatalf("Invalid single sign-on setting: %v", err)
+	}
+	oidcUseCase := usecase.NewOIDCUseCase(oidcProvider, oidcFlowRepo, userRepo, authUseCase, groupRoles)
+
+	auditUseCase := usecase.NewAuditUseCase(auditRepo)
+	auditedAuthUseCase := usecase.NewAuditedAuthUseCase(authUseCase, auditUseCase)
+	profileUseCase := usecase.NewAuditedProfileUseCase(usecase.NewProfileUseCase(userRepo, blobStore), auditUseCase)
+
+	authHandler := rest.NewAuthHandler(auditedAuthUseCase)
+	profileHandler := rest.NewProfileHandler(authUseCase, profileUseCase)
+	mfaHandler := rest.NewMFAHandler(auditedAuthUseCase)
+	oidcHandler := rest.NewOIDCHandler(oidcUseCase, os.Getenv("OIDC_POST_LOGIN_URL"))
+	adminTasksHandler := rest.NewAdminTasksHandler(usecase.NewAuditedAdminUseCase(adminUseCase, auditUseCase))
+	adminHandler := rest.NewAdminHandler(auditedAuthUseCase)
 	teacherHandler := rest.NewTeacherHandler(teacherUseCase)
-	teacherAdvancedHandler := rest.NewTeacherAdvancedHandler(teacherAdvancedUseCase)
+	teacherAdvancedHandler := rest.NewTeacherAdvancedHandler(usecase.NewAuditedTeacherAdvancedUseCase(teacherAdvancedUseCase, auditUseCase))
+	notificationHandler := rest.NewNotificationHandler(notificationUseCase)
+	calendarHandler := rest.NewCalendarHandler(calendarUseCase, os.Getenv("PUBLIC_BASE_URL"))
+	contentHandler := rest.NewContentHandler(contentUseCase)
+	progressHandler := rest.NewProgressHandler(usecase.NewAuditedProgressUseCase(progressUseCase, auditUseCase))
+	studentHandler := rest.NewStudentHandler(studentUseCase)
+	releaseHandler := rest.NewReleaseHandler(releaseUseCase)
+	attendanceHandler := rest.NewAttendanceHandler(attendanceUseCase)
+	termHandler := rest.NewTermHandler(usecase.NewAuditedTermUseCase(termUseCase, auditUseCase))
+	searchHandler := rest.NewSearchHandler(searchUseCase)
+	auditHandler := rest.NewAuditHandler(auditUseCase)
+	trashHandler := rest.NewTrashHandler(usecase.NewAuditedTrashUseCase(trashUseCase, auditUseCase))
+	apiTokenHandler := rest.NewAPITokenHandler(auditedAuthUseCase)
+	impersonationHandler := rest.NewImpersonationHandler(auditedAuthUseCase)
+	signingKeyHandler := rest.NewSigningKeyHandler(usecase.NewAuditedKeyRing(keyRing, auditUseCase))
+	roleHandler := rest.NewRoleHandler(usecase.NewAuditedRoleUseCase(roleUseCase, auditUseCase))
+	guardianHandler := rest.NewGuardianHandler(usecase.NewAuditedGuardianUseCase(guardianUseCase, auditUseCase))
+
+	// can guards a route with a permission of the signed-in user's role.
+	can := func(perm entity.Permission, h http.HandlerFunc) http.Handler {
+		return utils.RequirePermission(roleUseCase, perm)(h)
+	}
 
 	router := mux.NewRouter()
+	router.Use(utils.RequestInfoMiddleware)
+
+	router.HandleFunc("/.well-known/jwks.json", signingKeyHandler.JWKS).Methods(http.MethodGet)
 
 	router.HandleFunc("/v1/auth/register", authHandler.HandleRegister)
 	router.HandleFunc("/v1/auth/login", authHandler.HandleLogin)
 	router.HandleFunc("/v1/auth/password-reset/request", authHandler.HandlePasswordResetRequest)
 	router.HandleFunc("/v1/auth/password-reset/reset", authHandler.HandlePasswordReset)
+	router.HandleFunc("/v1/auth/verify-email", authHandler.HandleVerifyEmail).Methods(http.MethodPost)
+	router.HandleFunc("/v1/auth/verify-email/resend", authHandler.HandleResendVerification).Methods(http.MethodPost)
+	router.HandleFunc("/v1/auth/login/mfa", mfaHandler.CompleteLogin).Methods(http.MethodPost)
+	router.HandleFunc("/v1/auth/oidc/login", oidcHandler.Login).Methods(http.MethodGet)
+	router.HandleFunc("/v1/auth/oidc/callback", oidcHandler.Callback).Methods(http.MethodGet)
+	router.HandleFunc("/v1/auth/login/mfa/enroll", mfaHandler.BeginLoginEnrollment).Methods(http.MethodPost)
+	router.HandleFunc("/v1/auth/login/mfa/enroll/verify", mfaHandler.ConfirmLoginEnrollment).Methods(http.MethodPost)
+
+	router.Handle("/v1/profile", utils.JWTMiddleware(authUseCase, profileHandler))
+	router.Handle("/v1/profile/avatar", utils.JWTMiddleware(authUseCase, http.HandlerFunc(profileHandler.UploadAvatar))).Methods(http.MethodPut)
+	router.Handle("/v1/profile/avatar", utils.JWTMiddleware(authUseCase, http.HandlerFunc(profileHandler.DeleteAvatar))).Methods(http.MethodDelete)
+	router.Handle("/v1/users/{id}/avatar", utils.JWTMiddleware(authUseCase, http.HandlerFunc(profileHandler.GetAvatar))).Methods(http.MethodGet)
+	router.Handle("/v1/profile/notifications", utils.JWTMiddleware(authUseCase, http.HandlerFunc(notificationHandler.GetPreferences))).Methods(http.MethodGet)
+	router.Handle("/v1/profile/notifications", utils.JWTMiddleware(authUseCase, http.HandlerFunc(notificationHandler.UpdatePreferences))).Methods(http.MethodPut)
+	router.Handle("/v1/profile/calendar", utils.JWTMiddleware(authUseCase, http.HandlerFunc(calendarHandler.GetFeedURL))).Methods(http.MethodGet)
+	router.Handle("/v1/profile/calendar/regenerate", utils.JWTMiddleware(authUseCase, http.HandlerFunc(calendarHandler.RegenerateFeedURL))).Methods(http.MethodPost)
+	router.Handle("/v1/profile/pending-email", utils.JWTMiddleware(authUseCase, http.HandlerFunc(profileHandler.CancelEmailChange))).Methods(http.MethodDelete)
+	router.Handle("/v1/profile/mfa/enroll", utils.JWTMiddleware(authUseCase, http.HandlerFunc(mfaHandler.BeginEnrollment))).Methods(http.MethodPost)
+	router.Handle("/v1/profile/mfa/verify", utils.JWTMiddleware(authUseCase, http.HandlerFunc(mfaHandler.ConfirmEnrollment))).Methods(http.MethodPost)
+	router.Handle("/v1/profile/mfa/recovery-codes", utils.JWTMiddleware(authUseCase, http.HandlerFunc(mfaHandler.RegenerateRecoveryCodes))).Methods(http.MethodPost)
+	router.Handle("/v1/profile/mfa", utils.JWTMiddleware(authUseCase, http.HandlerFunc(mfaHandler.Disable))).Methods(http.MethodDelete)
+	router.Handle("/v1/profile/api-tokens", utils.JWTMiddleware(authUseCase, http.HandlerFunc(apiTokenHandler.ListTokens))).Methods(http.MethodGet)
+	router.Handle("/v1/profile/api-tokens", utils.JWTMiddleware(authUseCase, http.HandlerFunc(apiTokenHandler.CreateToken))).Methods(http.MethodPost)
+	router.Handle("/v1/profile/api-tokens/{id}", utils.JWTMiddleware(authUseCase, http.HandlerFunc(apiTokenHandler.RevokeToken))).Methods(http.MethodDelete)
+	router.Handle("/v1/profile/permissions", utils.JWTMiddleware(authUseCase, http.HandlerFunc(roleHandler.MyPermissions))).Methods(http.MethodGet)
+	router.Handle("/v1/impersonation", utils.JWTMiddleware(authUseCase, http.HandlerFunc(impersonationHandler.CurrentSession))).Methods(http.MethodGet)
+	router.Handle("/v1/impersonation/end", utils.JWTMiddleware(authUseCase, http.HandlerFunc(impersonationHandler.EndCurrentSession))).Methods(http.MethodPost)
 
-	router.Handle("v1/profile", utils.JWTMiddleware(authUseCase, profileHandler))
+	router.Handle("/v1/terms/current", utils.JWTMiddleware(authUseCase, http.HandlerFunc(termHandler.GetCurrentTerm))).Methods(http.MethodGet)
+	router.Handle("/v1/search", utils.JWTMiddleware(authUseCase, http.HandlerFunc(searchHandler.Search))).Methods(http.MethodGet)
+
+	router.HandleFunc("/v1/calendar/{token:[0-9a-f]+}.ics", calendarHandler.ServeFeed).Methods(http.MethodGet)
 
 	adminSubrouter := router.PathPrefix("/v1/admin").Subrouter()
 	adminSubrouter.Use(func(next http.Handler) http.Handler {
-		return utils.JWTMiddleware(authUseCase, utils.RBACMiddleware(entity.RoleAdmin)(next))
+		return utils.JWTMiddleware(authUseCase, next)
 	})
-	adminSubrouter.HandleFunc("/users", adminHandler.ListUsers).Methods(http.MethodGet)
-	adminSubrouter.HandleFunc("/users/{id}/role", adminHandler.UpdateUserRole).Methods(http.MethodPut)
-	adminSubrouter.HandleFunc("/users/{id}", adminHandler.DeleteUser).Methods(http.MethodDelete)
+	adminSubrouter.Handle("/users", can(entity.PermUsersRead, adminHandler.ListUsers)).Methods(http.MethodGet)
+	adminSubrouter.Handle("/users/{id}/role", can(entity.PermUsersManage, adminHandler.UpdateUserRole)).Methods(http.MethodPut)
+	adminSubrouter.Handle("/users/{id}/profile", can(entity.PermUsersManage, profileHandler.UpdateUserProfile)).Methods(http.MethodPut)
+	adminSubrouter.Handle("/users/{id}", can(entity.PermUsersManage, adminHandler.DeleteUser)).Methods(http.MethodDelete)
+	adminSubrouter.Handle("/users/{id}/mfa", can(entity.PermUsersManage, mfaHandler.ResetUserMFA)).Methods(http.MethodDelete)
+	adminSubrouter.Handle("/users/{id}/sso-link", can(entity.PermUsersManage, oidcHandler.AllowLink)).Methods(http.MethodPut)
+	adminSubrouter.Handle("/mfa-policy", can(entity.PermSettingsManage, mfaHandler.GetPolicy)).Methods(http.MethodGet)
+	adminSubrouter.Handle("/mfa-policy", can(entity.PermSettingsManage, mfaHandler.UpdatePolicy)).Methods(http.MethodPut)
+	adminSubrouter.Handle("/service-accounts", can(entity.PermUsersManage, apiTokenHandler.CreateServiceAccount)).Methods(http.MethodPost)
+	adminSubrouter.Handle("/service-accounts/{id}/api-tokens", can(entity.PermUsersManage, apiTokenHandler.CreateServiceAccountToken)).Methods(http.MethodPost)
+	adminSubrouter.Handle("/users/{id}/api-tokens", can(entity.PermUsersManage, apiTokenHandler.ListUserTokens)).Methods(http.MethodGet)
+	adminSubrouter.Handle("/users/{id}/api-tokens/{tokenId}", can(entity.PermUsersManage, apiTokenHandler.RevokeUserToken)).Methods(http.MethodDelete)
+	adminSubrouter.Handle("/users/{id}/guardians", can(entity.PermUsersRead, guardianHandler.ListUserGuardians)).Methods(http.MethodGet)
+	adminSubrouter.Handle("/guardian-links", can(entity.PermUsersManage, guardianHandler.LinkGuardian)).Methods(http.MethodPost)
+	adminSubrouter.Handle("/guardian-links/{id}", can(entity.PermUsersManage, guardianHandler.RemoveLink)).Methods(http.MethodDelete)
+	adminSubrouter.Handle("/signing-keys", can(entity.PermSettingsManage, signingKeyHandler.ListKeys)).Methods(http.MethodGet)
+	adminSubrouter.Handle("/signing-keys/rotate", can(entity.PermSettingsManage, signingKeyHandler.Rotate)).Methods(http.MethodPost)
+	adminSubrouter.Handle("/signing-keys/{kid}", can(entity.PermSettingsManage, signingKeyHandler.RevokeKey)).Methods(http.MethodDelete)
+
+	adminSubrouter.Handle("/courses", can(entity.PermCoursesRead, adminTasksHandler.ListCourses)).Methods(http.MethodGet)
+	adminSubrouter.Handle("/courses", can(entity.PermCoursesWrite, adminTasksHandler.CreateCourse)).Methods(http.MethodPost)
+	adminSubrouter.Handle("/courses/{id}", can(entity.PermCoursesRead, adminTasksHandler.GetCourse)).Methods(http.MethodGet)
+	adminSubrouter.Handle("/courses/{id}", can(entity.PermCoursesWrite, adminTasksHandler.UpdateCourse)).Methods(http.MethodPut)
+	adminSubrouter.Handle("/courses/{id}", can(entity.PermCoursesDelete, adminTasksHandler.DeleteCourse)).Methods(http.MethodDelete)
 
-	adminSubrouter.HandleFunc("/courses", adminTasksHandler.ListCourses).Methods(http.MethodGet)
-	adminSubrouter.HandleFunc("/courses", adminTasksHandler.CreateCourse).Methods(http.MethodPost)
-	adminSubrouter.HandleFunc("/courses/{id}", adminTasksHandler.GetCourse).Methods(http.MethodGet)
-	adminSubrouter.HandleFunc("/courses/{id}", adminTasksHandler.UpdateCourse).Methods(http.MethodPut)
-	adminSubrouter.HandleFunc("/courses/{id}", adminTasksHandler.DeleteCourse).Methods(http.MethodDelete)
+	adminSubrouter.Handle("/courses/{id}/rollover", can(entity.PermCoursesWrite, termHandler.RolloverCourse)).Methods(http.MethodPost)
 
-	adminSubrouter.HandleFunc("/classes", adminTasksHandler.ListClasses).Methods(http.MethodGet)
-	adminSubrouter.HandleFunc("/classes", adminTasksHandler.CreateClass).Methods(http.MethodPost)
-	adminSubrouter.HandleFunc("/classes/{id}", adminTasksHandler.GetClass).Methods(http.MethodGet)
-	adminSubrouter.HandleFunc("/classes/{id}", adminTasksHandler.UpdateClass).Methods(http.MethodPut)
-	adminSubrouter.HandleFunc("/classes/{id}", adminTasksHandler.DeleteClass).Methods(http.MethodDelete)
+	adminSubrouter.Handle("/terms", can(entity.PermCoursesRead, termHandler.ListTerms)).Methods(http.MethodGet)
+	adminSubrouter.Handle("/terms", can(entity.PermTermsWrite, termHandler.CreateTerm)).Methods(http.MethodPost)
+	adminSubrouter.Handle("/terms/{id}", can(entity.PermCoursesRead, termHandler.GetTerm)).Methods(http.MethodGet)
+	adminSubrouter.Handle("/terms/{id}", can(entity.PermTermsWrite, termHandler.UpdateTerm)).Methods(http.MethodPut)
+	adminSubrouter.Handle("/terms/{id}", can(entity.PermTermsDelete, termHandler.DeleteTerm)).Methods(http.MethodDelete)
 
-	adminSubrouter.HandleFunc("/announcements", adminTasksHandler.ListAnnouncements).Methods(http.MethodGet)
-	adminSubrouter.HandleFunc("/announcements", adminTasksHandler.CreateAnnouncement).Methods(http.MethodPost)
-	adminSubrouter.HandleFunc("/announcements/{id}", adminTasksHandler.GetAnnouncement).Methods(http.MethodGet)
-	adminSubrouter.HandleFunc("/announcements/{id}", adminTasksHandler.UpdateAnnouncement).Methods(http.MethodPut)
-	adminSubrouter.HandleFunc("/announcements/{id}", adminTasksHandler.DeleteAnnouncement).Methods(http.MethodDelete)
+	adminSubrouter.Handle("/classes", can(entity.PermClassesRead, adminTasksHandler.ListClasses)).Methods(http.MethodGet)
+	adminSubrouter.Handle("/classes", can(entity.PermClassesWrite, adminTasksHandler.CreateClass)).Methods(http.MethodPost)
+	adminSubrouter.Handle("/classes/{id}", can(entity.PermClassesRead, adminTasksHandler.GetClass)).Methods(http.MethodGet)
+	adminSubrouter.Handle("/classes/{id}", can(entity.PermEnrollmentWrite, adminTasksHandler.UpdateClass)).Methods(http.MethodPut)
+	adminSubrouter.Handle("/classes/{id}", can(entity.PermClassesDelete, adminTasksHandler.DeleteClass)).Methods(http.MethodDelete)
+
+	adminSubrouter.Handle("/announcements", can(entity.PermAnnouncementsWrite, adminTasksHandler.ListAnnouncements)).Methods(http.MethodGet)
+	adminSubrouter.Handle("/announcements", can(entity.PermAnnouncementsWrite, adminTasksHandler.CreateAnnouncement)).Methods(http.MethodPost)
+	adminSubrouter.Handle("/announcements/{id}", can(entity.PermAnnouncementsWrite, adminTasksHandler.GetAnnouncement)).Methods(http.MethodGet)
+	adminSubrouter.Handle("/announcements/{id}", can(entity.PermAnnouncementsWrite, adminTasksHandler.UpdateAnnouncement)).Methods(http.MethodPut)
+	adminSubrouter.Handle("/announcements/{id}", can(entity.PermAnnouncementsDelete, adminTasksHandler.DeleteAnnouncement)).Methods(http.MethodDelete)
+
+	adminSubrouter.Handle("/roles", can(entity.PermRolesManage, roleHandler.ListRoles)).Methods(http.MethodGet)
+	adminSubrouter.Handle("/roles", can(entity.PermRolesManage, roleHandler.CreateRole)).Methods(http.MethodPost)
+	adminSubrouter.Handle("/roles/{name}", can(entity.PermRolesManage, roleHandler.UpdateRole)).Methods(http.MethodPut)
+	adminSubrouter.Handle("/roles/{name}", can(entity.PermRolesManage, roleHandler.DeleteRole)).Methods(http.MethodDelete)
+	adminSubrouter.Handle("/permissions", can(entity.PermRolesManage, roleHandler.ListPermissions)).Methods(http.MethodGet)
+
+	adminSubrouter.Handle("/audit", can(entity.PermAuditRead, auditHandler.ListAudit)).Methods(http.MethodGet)
+	adminSubrouter.Handle("/impersonations", can(entity.PermImpersonate, impersonationHandler.StartImpersonation)).Methods(http.MethodPost)
+	adminSubrouter.Handle("/impersonations", can(entity.PermAuditRead, impersonationHandler.ListImpersonations)).Methods(http.MethodGet)
+	adminSubrouter.Handle("/impersonations/{id}", can(entity.PermAuditRead, impersonationHandler.GetImpersonation)).Methods(http.MethodGet)
+	adminSubrouter.Handle("/impersonations/{id}", can(entity.PermImpersonate, impersonationHandler.EndImpersonation)).Methods(http.MethodDelete)
+
+	adminSubrouter.Handle("/trash", can(entity.PermTrashManage, trashHandler.ListTrash)).Methods(http.MethodGet)
+	adminSubrouter.Handle("/trash/purge", can(entity.PermTrashManage, trashHandler.PurgeExpired)).Methods(http.MethodPost)
+	adminSubrouter.Handle("/trash/{type}/{id}/restore", can(entity.PermTrashManage, trashHandler.Restore)).Methods(http.MethodPost)
+	adminSubrouter.Handle("/trash/{type}/{id}", can(entity.PermTrashManage, trashHandler.Purge)).Methods(http.MethodDelete)
+	adminSubrouter.Handle("/{type:courses|classes|users|assignments|announcements}/{id}/archive", can(entity.PermTrashManage, trashHandler.Archive)).Methods(http.MethodPost)
+	adminSubrouter.Handle("/{type:courses|classes|users|assignments|announcements}/{id}/archive", can(entity.PermTrashManage, trashHandler.Unarchive)).Methods(http.MethodDelete)
 
 
 	teacherSubrouter := router.PathPrefix("/v1/teacher").Subrouter()
 	teacherSubrouter.Use(func(next http.Handler) http.Handler {
-		return utils.JWTMiddleware(authUseCase, utils.RBACMiddleware(entity.RoleTeacher)(next))
+		return utils.JWTMiddleware(authUseCase, next)
 	})
-	teacherSubrouter.HandleFunc("/courses", teacherHandler.ListCourses).Methods(http.MethodGet)
-	teacherSubrouter.HandleFunc("/classes", teacherHandler.ListClasses).Methods(http.MethodGet)
-	teacherSubrouter.HandleFunc("/classes/{id}/students", teacherHandler.ListStudents).Methods(http.MethodGet)
+	teacherSubrouter.Handle("/courses", can(entity.PermTeachingView, teacherHandler.ListCourses)).Methods(http.MethodGet)
+	teacherSubrouter.Handle("/classes", can(entity.PermTeachingView, teacherHandler.ListClasses)).Methods(http.MethodGet)
+	teacherSubrouter.Handle("/classes/{id}/students", can(entity.PermTeachingView, teacherHandler.ListStudents)).Methods(http.MethodGet)
 
-	teacherSubrouter.HandleFunc("/assignments", teacherAdvancedHandler.ListAssignments).Methods(http.MethodGet)
-	teacherSubrouter.HandleFunc("/assignments", teacherAdvancedHandler.CreateAssignment).Methods(http.MethodPost)
+	teacherSubrouter.Handle("/assignments", can(entity.PermTeachingView, teacherAdvancedHandler.ListAssignments)).Methods(http.MethodGet)
+	teacherSubrouter.Handle("/assignments", can(entity.PermAssignmentsWrite, teacherAdvancedHandler.CreateAssignment)).Methods(http.MethodPost)
 	// Optional: Implement GET, PUT, DELETE for /assignments/{id} similarly
+	teacherSubrouter.Handle("/assessments", can(entity.PermTeachingView, teacherAdvancedHandler.ListAssessments)).Methods(http.MethodGet)
+
+	teacherSubrouter.Handle("/submissions/{id}/grade", can(entity.PermGradesWrite, teacherAdvancedHandler.GradeSubmission)).Methods(http.MethodPut)
 
-	teacherSubrouter.HandleFunc("/messages", teacherAdvancedHandler.ListMessages).Methods(http.MethodGet)
-	teacherSubrouter.HandleFunc("/messages", teacherAdvancedHandler.CreateMessage).Methods(http.MethodPost)
+	teacherSubrouter.Handle("/courses/{id}/modules", can(entity.PermTeachingView, contentHandler.ListModules)).Methods(http.MethodGet)
+	teacherSubrouter.Handle("/courses/{id}/modules", can(entity.PermContentWrite, contentHandler.CreateModule)).Methods(http.MethodPost)
+	teacherSubrouter.Handle("/modules/{id}", can(entity.PermContentWrite, contentHandler.UpdateModule)).Methods(http.MethodPut)
+	teacherSubrouter.Handle("/modules/{id}", can(entity.PermContentDelete, contentHandler.DeleteModule)).Methods(http.MethodDelete)
+	teacherSubrouter.Handle("/modules/{id}/lessons", can(entity.PermContentWrite, contentHandler.CreateLesson)).Methods(http.MethodPost)
+	teacherSubrouter.Handle("/lessons/{id}", can(entity.PermTeachingView, contentHandler.GetLesson)).Methods(http.MethodGet)
+	teacherSubrouter.Handle("/lessons/{id}", can(entity.PermContentWrite, contentHandler.UpdateLesson)).Methods(http.MethodPut)
+	teacherSubrouter.Handle("/lessons/{id}", can(entity.PermContentDelete, contentHandler.DeleteLesson)).Methods(http.MethodDelete)
+	teacherSubrouter.Handle("/lessons/{id}/attachments", can(entity.PermContentWrite, contentHandler.UploadAttachment)).Methods(http.MethodPost)
+	teacherSubrouter.Handle("/lessons/{id}/attachments/{attachmentId}", can(entity.PermTeachingView, contentHandler.DownloadAttachment)).Methods(http.MethodGet)
+	teacherSubrouter.Handle("/lessons/{id}/attachments/{attachmentId}", can(entity.PermContentDelete, contentHandler.DeleteAttachment)).Methods(http.MethodDelete)
+
+	teacherSubrouter.Handle("/lessons/{id}/release", can(entity.PermContentWrite, releaseHandler.SetLessonRelease)).Methods(http.MethodPut)
+	teacherSubrouter.Handle("/assignments/{id}/release", can(entity.PermContentWrite, releaseHandler.SetAssignmentRelease)).Methods(http.MethodPut)
+	teacherSubrouter.Handle("/assessments/{id}/release", can(entity.PermContentWrite, releaseHandler.SetAssessmentRelease)).Methods(http.MethodPut)
+
+	teacherSubrouter.Handle("/courses/{id}/completion-criteria", can(entity.PermContentWrite, progressHandler.UpdateCompletionCriteria)).Methods(http.MethodPut)
+	teacherSubrouter.Handle("/classes/{id}/progress", can(entity.PermGradesRead, progressHandler.GetClassProgress)).Methods(http.MethodGet)
+	teacherSubrouter.Handle("/assessments/{id}/results/{studentId}", can(entity.PermGradesWrite, progressHandler.RecordAssessmentResult)).Methods(http.MethodPut)
+
+	teacherSubrouter.Handle("/classes/{id}/sessions", can(entity.PermAttendanceRead, attendanceHandler.ListSessions)).Methods(http.MethodGet)
+	teacherSubrouter.Handle("/classes/{id}/sessions", can(entity.PermAttendanceWrite, attendanceHandler.CreateSession)).Methods(http.MethodPost)
+	teacherSubrouter.Handle("/classes/{id}/attendance", can(entity.PermAttendanceRead, attendanceHandler.GetClassReport)).Methods(http.MethodGet)
+	teacherSubrouter.Handle("/sessions/{id}", can(entity.PermAttendanceWrite, attendanceHandler.UpdateSession)).Methods(http.MethodPut)
+	teacherSubrouter.Handle("/sessions/{id}", can(entity.PermAttendanceDelete, attendanceHandler.DeleteSession)).Methods(http.MethodDelete)
+	teacherSubrouter.Handle("/sessions/{id}/attendance", can(entity.PermAttendanceRead, attendanceHandler.GetSessionAttendance)).Methods(http.MethodGet)
+	teacherSubrouter.Handle("/sessions/{id}/attendance", can(entity.PermAttendanceWrite, attendanceHandler.RecordAttendance)).Methods(http.MethodPut)
+	teacherSubrouter.Handle("/sessions/{id}/check-in-code", can(entity.PermAttendanceWrite, attendanceHandler.OpenCheckIn)).Methods(http.MethodPost)
+
+	teacherSubrouter.Handle("/messages", can(entity.PermTeachingView, teacherAdvancedHandler.ListMessages)).Methods(http.MethodGet)
+	teacherSubrouter.Handle("/messages", can(entity.PermMessagesWrite, teacherAdvancedHandler.CreateMessage)).Methods(http.MethodPost)
 	
+	studentSubrouter := router.PathPrefix("/v1/student").Subrouter()
+	studentSubrouter.Use(func(next http.Handler) http.Handler {
+		return utils.JWTMiddleware(authUseCase, next)
+	})
+	studentSubrouter.Handle("/courses/{id}/modules", can(entity.PermLearn, contentHandler.ListModulesForStudent)).Methods(http.MethodGet)
+	studentSubrouter.Handle("/lessons/{id}", can(entity.PermLearn, contentHandler.GetLessonForStudent)).Methods(http.MethodGet)
+	studentSubrouter.Handle("/lessons/{id}/attachments/{attachmentId}", can(entity.PermLearn, contentHandler.DownloadAttachmentForStudent)).Methods(http.MethodGet)
+	studentSubrouter.Handle("/lessons/{id}/complete", can(entity.PermLearn, progressHandler.CompleteLesson)).Methods(http.MethodPost)
+	studentSubrouter.Handle("/courses/{id}/progress", can(entity.PermLearn, progressHandler.GetMyProgress)).Methods(http.MethodGet)
+
+	studentSubrouter.Handle("/assignments", can(entity.PermLearn, studentHandler.ListAssignments)).Methods(http.MethodGet)
+	studentSubrouter.Handle("/assessments", can(entity.PermLearn, studentHandler.ListAssessments)).Methods(http.MethodGet)
+	studentSubrouter.Handle("/assignments/{id}/submissions", can(entity.PermLearn, studentHandler.SubmitAssignment)).Methods(http.MethodPost)
+	studentSubrouter.Handle("/submissions/{id}", can(entity.PermLearn, studentHandler.GetSubmission)).Methods(http.MethodGet)
+
+	studentSubrouter.Handle("/submissions", can(entity.PermLearn, studentHandler.ListSubmissions)).Methods(http.MethodGet)
+	studentSubrouter.Handle("/announcements", can(entity.PermLearn, studentHandler.ListAnnouncements)).Methods(http.MethodGet)
+
+	studentSubrouter.Handle("/guardians", can(entity.PermLearn, guardianHandler.ListMyGuardians)).Methods(http.MethodGet)
+	studentSubrouter.Handle("/guardians/invitations", can(entity.PermLearn, guardianHandler.InviteGuardian)).Methods(http.MethodPost)
+	studentSubrouter.Handle("/guardians/invitations/{id}", can(entity.PermLearn, guardianHandler.CancelInvitation)).Methods(http.MethodDelete)
+
+	studentSubrouter.Handle("/sessions/{id}/check-in", can(entity.PermLearn, attendanceHandler.CheckIn)).Methods(http.MethodPost)
+	studentSubrouter.Handle("/classes/{id}/attendance", can(entity.PermLearn, attendanceHandler.GetMyAttendance)).Methods(http.MethodGet)
+
+	guardianSubrouter := router.PathPrefix("/v1/guardian").Subrouter()
+	guardianSubrouter.Use(func(next http.Handler) http.Handler {
+		return utils.JWTMiddleware(authUseCase, next)
+	})
+	guardianSubrouter.Handle("/invitations/accept", can(entity.PermLinkedStudents, guardianHandler.AcceptInvitation)).Methods(http.MethodPost)
+	guardianSubrouter.Handle("/students", can(entity.PermLinkedStudents, guardianHandler.ListStudents)).Methods(http.MethodGet)
+	guardianSubrouter.Handle("/students/{id}/courses", can(entity.PermLinkedStudents, guardianHandler.ListStudentCourses)).Methods(http.MethodGet)
+	guardianSubrouter.Handle("/students/{id}/courses/{courseId}/progress", can(entity.PermLinkedStudents, guardianHandler.GetStudentProgress)).Methods(http.MethodGet)
+	guardianSubrouter.Handle("/students/{id}/assignments", can(entity.PermLinkedStudents, guardianHandler.ListStudentAssignments)).Methods(http.MethodGet)
+	guardianSubrouter.Handle("/students/{id}/assessments", can(entity.PermLinkedStudents, guardianHandler.ListStudentAssessments)).Methods(http.MethodGet)
+	guardianSubrouter.Handle("/students/{id}/submissions", can(entity.PermLinkedStudents, guardianHandler.ListStudentSubmissions)).Methods(http.MethodGet)
+	guardianSubrouter.Handle("/students/{id}/announcements", can(entity.PermLinkedStudents, guardianHandler.ListStudentAnnouncements)).Methods(http.MethodGet)
+
 	// router.Handle("/student-area", utils.JWTMiddleware(authUseCase, utils.RBACMiddleware(entity.RoleStudent)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 	// 	w.Write([]byte("Welcome to Student Area"))
 	// }))))
@@ -151,6 +535,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutting down server...")
+	stopWorkers()
 
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer shutdownCancel()
@@ -164,4 +549,309 @@ func main() {
 	}
 	log.Println("MongoDB connection closed")
 	log.Println("Server exited properly")
-}
\ No newline at end of file
+}
+
+// defaultJWTSecret is the JWT_SECRET of a development setup. It is public,
+// so it is refused unless APP_ENV is "development".
+const defaultJWTSecret = "supersecretkey"
+
+// loadJWTSecret returns JWT_SECRET. Tokens are signed with the key ring;
+// the secret only seals the ring's private keys in the database, so it has
+// to stay the same for as long as the keys are kept.
+func loadJWTSecret() (string, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret != "" && secret != defaultJWTSecret {
+		return secret, nil
+	}
+	if os.Getenv("APP_ENV") != "development" {
+		return "", errors.New("JWT_SECRET must be set to a secret value; the default is only accepted with APP_ENV=development")
+	}
+	log.Println("JWT_SECRET not set, using the development default")
+	return defaultJWTSecret, nil
+}
+
+// keyRotationPolicy reads the token signing settings: JWT_ALG (EdDSA or
+// RS256) and JWT_KEY_ROTATION, how long a key signs before it is replaced.
+// Replaced keys verify for as long as the longest lived token, a session.
+func keyRotationPolicy() (entity.KeyRotationPolicy, error) {
+	policy := entity.KeyRotationPolicy{
+		Algorithm:    "EdDSA",
+		RotateEvery:  30 * 24 * time.Hour,
+		PublishAhead: 24 * time.Hour,
+		VerifyFor:    72*time.Hour + time.Hour,
+	}
+	if v := os.Getenv("JWT_ALG"); v != "" {
+		policy.Algorithm = v
+	}
+	if v := os.Getenv("JWT_KEY_ROTATION"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return policy, fmt.Errorf("JWT_KEY_ROTATION: %w", err)
+		}
+		policy.RotateEvery = d
+	}
+	if policy.RotateEvery <= policy.PublishAhead {
+		return policy, fmt.Errorf("JWT_KEY_ROTATION: must be longer than %s", policy.PublishAhead)
+	}
+	return policy, nil
+}
+
+// newMailSender uses SMTP when SMTP_HOST is set and otherwise writes mails to
+// MAIL_SINK_DIR so development setups do not need a mail server.
+func newMailSender() (notification.Sender, error) {
+	from := "no-reply@e-learning.local"
+	if f := os.Getenv("SMTP_FROM"); f != "" {
+		from = f
+	}
+
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		port := "587"
+		if p := os.Getenv("SMTP_PORT"); p != "" {
+			port = p
+		}
+		return notification.NewSMTPSender(host, port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from), nil
+	}
+
+	dir := "mail-sink"
+	if d := os.Getenv("MAIL_SINK_DIR"); d != "" {
+		dir = d
+	}
+	log.Printf("SMTP_HOST not set, writing mails to %s", dir)
+	return notification.NewFileSender(dir, from)
+}
+
+// loginThrottlePolicies returns the throttling applied to failed sign-ins per
+// account and per client address. LOGIN_LOCKOUT_AFTER and
+// LOGIN_LOCKOUT_DURATION override when an account is locked and for how long.
+func loginThrottlePolicies() (entity.ThrottlePolicy, entity.ThrottlePolicy, error) {
+	account := entity.ThrottlePolicy{
+		FreeAttempts: 3,
+		BaseDelay:    2 * time.Second,
+		MaxDelay:     2 * time.Minute,
+		LockoutAfter: 10,
+		LockoutFor:   15 * time.Minute,
+		Window:       time.Hour,
+	}
+	ip := entity.ThrottlePolicy{
+		FreeAttempts: 20,
+		BaseDelay:    time.Second,
+		MaxDelay:     time.Minute,
+		LockoutAfter: 100,
+		LockoutFor:   time.Hour,
+		Window:       time.Hour,
+	}
+
+	if v := os.Getenv("LOGIN_LOCKOUT_AFTER"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return account, ip, fmt.Errorf("LOGIN_LOCKOUT_AFTER: %q is not a positive number", v)
+		}
+		account.LockoutAfter = n
+	}
+	if v := os.Getenv("LOGIN_LOCKOUT_DURATION"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return account, ip, fmt.Errorf("LOGIN_LOCKOUT_DURATION: %w", err)
+		}
+		account.LockoutFor = d
+	}
+	return account, ip, nil
+}
+
+// newPasswordService builds the password checks from the environment:
+// PASSWORD_MIN_LENGTH, PASSWORD_MIN_CLASSES, PASSWORD_DISALLOW (a comma
+// separated list added to the built-in one), PASSWORD_BREACH_LIST (a file
+// of SHA-1 hashes of compromised passwords), PASSWORD_HISTORY and
+// BCRYPT_COST.
+func newPasswordService() (*usecase.PasswordService, error) {
+	policy := entity.PasswordPolicy{
+		MinLength:  10,
+		MaxLength:  72, // bcrypt ignores anything longer
+		MinClasses: 2,
+		Disallowed: []string{"password", "password1", "password123", "qwertyuiop", "1234567890", "0123456789", "letmein123", "iloveyou12", "e-learning", "elearning123"},
+	}
+	history := 5
+	cost := bcrypt.DefaultCost
+
+	var err error
+	if policy.MinLength, err = envInt("PASSWORD_MIN_LENGTH", policy.MinLength); err != nil {
+		return nil, err
+	}
+	if policy.MinClasses, err = envInt("PASSWORD_MIN_CLASSES", policy.MinClasses); err != nil {
+		return nil, err
+	}
+	if history, err = envInt("PASSWORD_HISTORY", history); err != nil {
+		return nil, err
+	}
+	if cost, err = envInt("BCRYPT_COST", cost); err != nil {
+		return nil, err
+	}
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("BCRYPT_COST: must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	for _, d := range strings.Split(os.Getenv("PASSWORD_DISALLOW"), ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			policy.Disallowed = append(policy.Disallowed, d)
+		}
+	}
+
+	var breached usecase.BreachedPasswordRanges
+	if path := os.Getenv("PASSWORD_BREACH_LIST"); path != "" {
+		list, err := breach.LoadHashList(path)
+		if err != nil {
+			return nil, fmt.Errorf("PASSWORD_BREACH_LIST: %w", err)
+		}
+		breached = list
+	}
+
+	return usecase.NewPasswordService(policy, breached, cost, history), nil
+}
+
+// newOIDCProvider configures single sign-on from OIDC_ISSUER,
+// OIDC_CLIENT_ID, OIDC_CLIENT_SECRET, OIDC_REDIRECT_URL (by default the
+// callback below PUBLIC_BASE_URL), OIDC_SCOPES and OIDC_GROUPS_CLAIM.
+// OIDC_ROLE_MAP maps groups to roles, e.g. "staff=teacher,it=admin". Without
+// OIDC_ISSUER single sign-on is off and the provider is nil.
+func newOIDCProvider() (usecase.OIDCProvider, map[string]entity.Role, error) {
+	issuer := os.Getenv("OIDC_ISSUER")
+	if issuer == "" {
+		return nil, nil, nil
+	}
+
+	config := oidc.Config{
+		Issuer:       issuer,
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:       strings.Fields(os.Getenv("OIDC_SCOPES")),
+		GroupsClaim:  os.Getenv("OIDC_GROUPS_CLAIM"),
+	}
+	if config.ClientID == "" {
+		return nil, nil, fmt.Errorf("OIDC_CLIENT_ID is required with OIDC_ISSUER")
+	}
+	if config.RedirectURL == "" {
+		config.RedirectURL = strings.TrimSuffix(os.Getenv("PUBLIC_BASE_URL"), "/") + "/v1/auth/oidc/callback"
+	}
+
+	groupRoles := make(map[string]entity.Role)
+	for _, pair := range strings.Split(os.Getenv("OIDC_ROLE_MAP"), ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		group, role, ok := strings.Cut(pair, "=")
+		r := entity.Role(strings.TrimSpace(role))
+		if !ok || !entity.IsValidRoleName(r) {
+			return nil, nil, fmt.Errorf("OIDC_ROLE_MAP: %q is not group=role", pair)
+		}
+		groupRoles[strings.TrimSpace(group)] = r
+	}
+
+	return oidc.NewProvider(config), groupRoles, nil
+}
+
+// parseRoles reads a comma separated list of roles from the named variable,
+// or from def when it is not set. "none" stands for no roles.
+func parseRoles(name, def string) ([]entity.Role, error) {
+	raw := def
+	if v, ok := os.LookupEnv(name); ok {
+		raw = v
+	}
+	if strings.TrimSpace(raw) == "none" {
+		return nil, nil
+	}
+
+	var roles []entity.Role
+	for _, part := range strings.Split(raw, ",") {
+		r := entity.Role(strings.TrimSpace(part))
+		if r == "" {
+			continue
+		}
+		if !entity.IsValidRoleName(r) {
+			return nil, fmt.Errorf("%s: invalid role %q", name, r)
+		}
+		roles = append(roles, r)
+	}
+	return roles, nil
+}
+
+// envInt returns the non-negative integer in the named variable, or def if
+// it is not set.
+func envInt(name string, def int) (int, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s: %q is not a non-negative number", name, v)
+	}
+	return n, nil
+}
+
+// parseReminderOffsets reads REMINDER_OFFSETS as a comma separated list of
+// durations, e.g. "48h,2h".
+func parseReminderOffsets() ([]time.Duration, error) {
+	raw := "48h,2h"
+	if v := os.Getenv("REMINDER_OFFSETS"); v != "" {
+		raw = v
+	}
+
+	var offsets []time.Duration
+	for _, part := range strings.Split(raw, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		offsets = append(offsets, d)
+	}
+	return offsets, nil
+}
+
+func instanceID() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+func applyMigrations(r *migration.Runner) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	applied, err := r.Up(ctx)
+	for _, m := range applied {
+		log.Printf("Applied migration %d: %s", m.Version, m.Description)
+	}
+	return err
+}
+
+func runMigrateCommand(r *migration.Runner, args []string) error {
+	cmd := "up"
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+
+	switch cmd {
+	case "up":
+		return applyMigrations(r)
+	case "status":
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		applied, err := r.Applied(ctx)
+		if err != nil {
+			return err
+		}
+		for _, rec := range applied {
+			fmt.Printf("%4d  applied  %s  %s\n", rec.Version, rec.AppliedAt.Format(time.RFC3339), rec.Description)
+		}
+		pending, err := r.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		for _, m := range pending {
+			fmt.Printf("%4d  pending  %-20s  %s\n", m.Version, "", m.Description)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q (want up or status)", cmd)
+	}
+}
@@ -0,0 +1,212 @@
+// Command mockidp is a minimal OpenID Connect provider for trying single
+// sign-on locally. Its login page signs in as whatever email and groups are
+// entered; it accepts any client and must never be exposed.
+//
+//	go run ./cmd/mockidp
+//	OIDC_ISSUER=http://localhost:9000 OIDC_CLIENT_ID=e-learning go run ./cmd
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"html/template"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const keyID = "mock"
+
+type grant struct {
+	clientID    string
+	redirectURI string
+	nonce       string
+	challenge   string
+	email       string
+	groups      []string
+	expires     time.Time
+}
+
+type provider struct {
+	issuer string
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	grants map[string]*grant
+}
+
+var loginPage = template.Must(template.New("login").Parse(`<!doctype html>
+<title>Mock identity provider</title>
+<h1>Mock identity provider</h1>
+<form method="post">
+{{range $k, $v := .}}<input type="hidden" name="{{$k}}" value="{{index $v 0}}">
+{{end}}<p><label>Email <input name="email" type="email" required></label></p>
+<p><label>Groups <input name="groups" placeholder="space separated"></label></p>
+<p><button>Sign in</button></p>
+</form>`))
+
+func main() {
+	addr := ":9000"
+	if v := os.Getenv("MOCK_IDP_ADDR"); v != "" {
+		addr = v
+	}
+	issuer := "http://localhost" + addr
+	if v := os.Getenv("MOCK_IDP_ISSUER"); v != "" {
+		issuer = v
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatal(err)
+	}
+	p := &provider{issuer: issuer, key: key, grants: make(map[string]*grant)}
+
+	http.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	http.HandleFunc("/jwks", p.jwks)
+	http.HandleFunc("/authorize", p.authorize)
+	http.HandleFunc("/token", p.token)
+
+	log.Printf("Mock identity provider %s listening on %s", issuer, addr)
+	log.Fatal(http.ListenAndServe(addr, nil))
+}
+
+func (p *provider) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                                p.issuer,
+		"authorization_endpoint":                p.issuer + "/authorize",
+		"token_endpoint":                        p.issuer + "/token",
+		"jwks_uri":                              p.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *provider) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := p.key.PublicKey
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": keyID,
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// authorize shows the login form and, once it is submitted, redirects back
+// to the client with a code.
+func (p *provider) authorize(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		q := r.URL.Query()
+		if q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+			http.Error(w, "only the code flow with S256 PKCE is supported", http.StatusBadRequest)
+			return
+		}
+		loginPage.Execute(w, q)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	redirect, err := url.Parse(r.PostForm.Get("redirect_uri"))
+	if err != nil || redirect.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	code := randomString()
+	p.mu.Lock()
+	p.grants[code] = &grant{
+		clientID:    r.PostForm.Get("client_id"),
+		redirectURI: redirect.String(),
+		nonce:       r.PostForm.Get("nonce"),
+		challenge:   r.PostForm.Get("code_challenge"),
+		email:       r.PostForm.Get("email"),
+		groups:      strings.Fields(r.PostForm.Get("groups")),
+		expires:     time.Now().Add(time.Minute),
+	}
+	p.mu.Unlock()
+
+	q := redirect.Query()
+	q.Set("code", code)
+	q.Set("state", r.PostForm.Get("state"))
+	redirect.RawQuery = q.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// token redeems a code for an ID token after checking the PKCE verifier.
+func (p *provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	p.mu.Lock()
+	g, ok := p.grants[r.PostForm.Get("code")]
+	delete(p.grants, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || time.Now().After(g.expires) ||
+		r.PostForm.Get("redirect_uri") != g.redirectURI ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	clientID := g.clientID
+	if id, _, ok := r.BasicAuth(); ok {
+		clientID, _ = url.QueryUnescape(id)
+	}
+	subject := sha256.Sum256([]byte(g.email))
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            p.issuer,
+		"sub":            base64.RawURLEncoding.EncodeToString(subject[:12]),
+		"aud":            clientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          g.nonce,
+		"email":          g.email,
+		"email_verified": true,
+		"groups":         g.groups,
+	})
+	token.Header["kid"] = keyID
+	signed, err := token.SignedString(p.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     signed,
+	})
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -1,6 +1,7 @@
 package entity
 
 import (
+	"errors"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -21,4 +22,10 @@ type Announcement struct {
 	TargetAudience 	TargetAudience 		`bson:"target_audience" json:"target_audience"`
 	TargetID 		primitive.ObjectID  `bson:"target_id,omitempty" json:"target_id,omitempty"` // e.g. class or course ID when targeted
 	CreatedAt 		time.Time 			`bson:"created_at" json:"created_at"`
-}
\ No newline at end of file
+	ArchivedAt 		*time.Time 			`bson:"archived_at,omitempty" json:"archived_at,omitempty"`
+	DeletedAt 		*time.Time 			`bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+}
+
+var (
+	ErrAnnouncementNotFound = errors.New("announcement not found")
+)
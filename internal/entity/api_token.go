@@ -0,0 +1,80 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// APITokenPrefix starts every personal API token, which tells them apart
+// from session tokens and makes them easy to find in leaked text.
+const APITokenPrefix = "elp_"
+
+// APIScope limits what an API token may be used for. A token acts as its
+// owner, so the owner's role still applies on top of the scopes.
+type APIScope string
+
+const (
+	ScopeUsersRead        APIScope = "users:read"
+	ScopeUsersWrite       APIScope = "users:write"
+	ScopeCoursesRead      APIScope = "courses:read"
+	ScopeCoursesWrite     APIScope = "courses:write"
+	ScopeEnrollmentsRead  APIScope = "enrollments:read"
+	ScopeEnrollmentsWrite APIScope = "enrollments:write"
+	ScopeGradesRead       APIScope = "grades:read"
+	ScopeGradesWrite      APIScope = "grades:write"
+	ScopeAttendanceRead   APIScope = "attendance:read"
+	ScopeAttendanceWrite  APIScope = "attendance:write"
+)
+
+var apiScopes = map[APIScope]bool{
+	ScopeUsersRead: true, ScopeUsersWrite: true,
+	ScopeCoursesRead: true, ScopeCoursesWrite: true,
+	ScopeEnrollmentsRead: true, ScopeEnrollmentsWrite: true,
+	ScopeGradesRead: true, ScopeGradesWrite: true,
+	ScopeAttendanceRead: true, ScopeAttendanceWrite: true,
+}
+
+func IsValidAPIScope(s APIScope) bool {
+	return apiScopes[s]
+}
+
+// APIToken is a named, long-lived bearer credential for scripts and
+// integrations. Only the SHA-256 hash of the token is stored; Prefix keeps
+// its first characters so that users can tell their tokens apart.
+type APIToken struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Name       string             `bson:"name" json:"name"`
+	Prefix     string             `bson:"prefix" json:"prefix"`
+	Hash       string             `bson:"hash" json:"-"`
+	Scopes     []APIScope         `bson:"scopes" json:"scopes"`
+	CreatedBy  primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt  *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	LastUsedAt *time.Time         `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+}
+
+func (t *APIToken) HasScope(scope APIScope) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CreatedAPIToken is a new token together with its secret, which is shown
+// only this once.
+type CreatedAPIToken struct {
+	*APIToken
+	Token string `json:"token"`
+}
+
+var (
+	ErrAPITokenNotFound  = errors.New("api token not found")
+	ErrInvalidScope      = errors.New("invalid api token scope")
+	ErrInvalidAPIToken   = errors.New("api token needs a name, a scope and an expiry in the future")
+	ErrNotServiceAccount = errors.New("user is not a service account")
+)
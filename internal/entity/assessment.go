@@ -1,6 +1,7 @@
 package entity
 
 import (
+	"errors"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -14,4 +15,19 @@ type Assessment struct {
 	Date        time.Time          `bson:"date" json:"date"`
 	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt   time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
-}
\ No newline at end of file
+	Release     *ReleaseRule       `bson:"release,omitempty" json:"release,omitempty"`
+
+	Availability `bson:"-"`
+}
+
+func (a *Assessment) ReleaseRule() *ReleaseRule { return a.Release }
+
+// Lock withholds the description of the assessment.
+func (a *Assessment) Lock(av Availability) {
+	a.Availability = av
+	a.Description = ""
+}
+
+var (
+	ErrAssessmentNotFound = errors.New("assessment not found")
+)
@@ -1,6 +1,7 @@
 package entity
 
 import (
+	"errors"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -14,4 +15,21 @@ type Assignment struct {
 	DueDate     time.Time          `bson:"due_date" json:"due_date"`
 	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt   time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
-}
\ No newline at end of file
+	Release     *ReleaseRule       `bson:"release,omitempty" json:"release,omitempty"`
+	ArchivedAt  *time.Time         `bson:"archived_at,omitempty" json:"archived_at,omitempty"`
+	DeletedAt   *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+
+	Availability `bson:"-"`
+}
+
+func (a *Assignment) ReleaseRule() *ReleaseRule { return a.Release }
+
+// Lock withholds the description of the assignment.
+func (a *Assignment) Lock(av Availability) {
+	a.Availability = av
+	a.Description = ""
+}
+
+var (
+	ErrAssignmentNotFound = errors.New("assignment not found")
+)
@@ -0,0 +1,81 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AttendanceStatus string
+
+const (
+	AttendancePresent AttendanceStatus = "present"
+	AttendanceAbsent  AttendanceStatus = "absent"
+	AttendanceLate    AttendanceStatus = "late"
+	AttendanceExcused AttendanceStatus = "excused"
+)
+
+func IsValidAttendanceStatus(s AttendanceStatus) bool {
+	switch s {
+	case AttendancePresent, AttendanceAbsent, AttendanceLate, AttendanceExcused:
+		return true
+	}
+	return false
+}
+
+// ClassSession is a single meeting of a class.
+type ClassSession struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ClassID          primitive.ObjectID `bson:"class_id" json:"class_id"`
+	Title            string             `bson:"title" json:"title"`
+	StartsAt         time.Time          `bson:"starts_at" json:"starts_at"`
+	EndsAt           time.Time          `bson:"ends_at" json:"ends_at"`
+	CheckInCode      string             `bson:"check_in_code,omitempty" json:"check_in_code,omitempty"`
+	CheckInExpiresAt *time.Time         `bson:"check_in_expires_at,omitempty" json:"check_in_expires_at,omitempty"`
+	CreatedBy        primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt        time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// AttendanceRecord is the attendance of one student at one session. Records
+// created by a student check-in have SelfCheckIn set; a teacher can always
+// overwrite them.
+type AttendanceRecord struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	SessionID   primitive.ObjectID `bson:"session_id" json:"session_id"`
+	ClassID     primitive.ObjectID `bson:"class_id" json:"class_id"`
+	StudentID   primitive.ObjectID `bson:"student_id" json:"student_id"`
+	Status      AttendanceStatus   `bson:"status" json:"status"`
+	SelfCheckIn bool               `bson:"self_check_in" json:"self_check_in"`
+	RecordedBy  primitive.ObjectID `bson:"recorded_by" json:"recorded_by"`
+	RecordedAt  time.Time          `bson:"recorded_at" json:"recorded_at"`
+}
+
+// AttendanceRate summarises a student's attendance over the sessions of a
+// class that have already started. Students without a record for a session
+// count as absent; excused sessions do not count towards the rate.
+type AttendanceRate struct {
+	StudentID primitive.ObjectID `json:"student_id"`
+	Email     string             `json:"email,omitempty"`
+	Sessions  int                `json:"sessions"`
+	Present   int                `json:"present"`
+	Late      int                `json:"late"`
+	Absent    int                `json:"absent"`
+	Excused   int                `json:"excused"`
+	Rate      float64            `json:"rate"` // percentage
+}
+
+type ClassAttendanceReport struct {
+	ClassID     primitive.ObjectID `json:"class_id"`
+	Sessions    int                `json:"sessions"`
+	AverageRate float64            `json:"average_rate"`
+	Students    []*AttendanceRate  `json:"students"`
+}
+
+var (
+	ErrSessionNotFound         = errors.New("session not found")
+	ErrInvalidSession          = errors.New("invalid session")
+	ErrInvalidAttendanceStatus = errors.New("invalid attendance status")
+	ErrStudentNotInClass       = errors.New("student is not in this class")
+	ErrInvalidCheckInCode      = errors.New("invalid or expired check-in code")
+)
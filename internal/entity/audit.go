@@ -0,0 +1,70 @@
+package entity
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AuditAction string
+
+const (
+	AuditRoleChanged         AuditAction = "user.role_changed"
+	AuditPasswordReset       AuditAction = "user.password_reset"
+	AuditUserDeleted         AuditAction = "user.deleted"
+	AuditCourseDeleted       AuditAction = "course.deleted"
+	AuditClassDeleted        AuditAction = "class.deleted"
+	AuditAnnouncementDeleted AuditAction = "announcement.deleted"
+	AuditAssignmentDeleted   AuditAction = "assignment.deleted"
+	AuditAssessmentDeleted   AuditAction = "assessment.deleted"
+	AuditTermDeleted         AuditAction = "term.deleted"
+	AuditEnrollmentChanged   AuditAction = "class.enrollment_changed"
+	AuditSubmissionGraded    AuditAction = "submission.graded"
+	AuditResultRecorded      AuditAction = "assessment.result_recorded"
+	AuditItemRestored        AuditAction = "trash.restored"
+	AuditItemPurged          AuditAction = "trash.purged"
+	AuditMFAReset            AuditAction = "user.mfa_reset"
+	AuditMFAPolicyChanged    AuditAction = "settings.mfa_policy_changed"
+	AuditSigningKeyRotated   AuditAction = "settings.signing_key_rotated"
+	AuditSigningKeyRevoked   AuditAction = "settings.signing_key_revoked"
+
+	AuditServiceAccountCreated AuditAction = "user.service_account_created"
+	AuditAPITokenCreated       AuditAction = "user.api_token_created"
+	AuditAPITokenRevoked       AuditAction = "user.api_token_revoked"
+
+	AuditRoleCreated AuditAction = "settings.role_created"
+	AuditRoleUpdated AuditAction = "settings.role_updated"
+	AuditRoleDeleted AuditAction = "settings.role_deleted"
+
+	AuditGuardianLinked   AuditAction = "user.guardian_linked"
+	AuditGuardianUnlinked AuditAction = "user.guardian_unlinked"
+
+	AuditProfileUpdated AuditAction = "user.profile_updated"
+
+	AuditImpersonationStarted AuditAction = "user.impersonation_started"
+	AuditImpersonationEnded   AuditAction = "user.impersonation_ended"
+)
+
+// AuditChange is the value of one field before and after an action. Before
+// is empty for created values and After for removed ones.
+type AuditChange struct {
+	Before interface{} `bson:"before,omitempty" json:"before,omitempty"`
+	After  interface{} `bson:"after,omitempty" json:"after,omitempty"`
+}
+
+// AuditEntry records who did what to which record, and from where. Entries
+// are only ever appended.
+type AuditEntry struct {
+	ID         primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	Action     AuditAction            `bson:"action" json:"action"`
+	ActorID    primitive.ObjectID     `bson:"actor_id,omitempty" json:"actor_id,omitempty"`
+	ActorRole  Role                   `bson:"actor_role,omitempty" json:"actor_role,omitempty"`
+	TargetType string                 `bson:"target_type" json:"target_type"`
+	TargetID   primitive.ObjectID     `bson:"target_id" json:"target_id"`
+	SubjectID  primitive.ObjectID     `bson:"subject_id,omitempty" json:"subject_id,omitempty"`     // user the action was about, e.g. the graded student
+	OnBehalfOf primitive.ObjectID     `bson:"on_behalf_of,omitempty" json:"on_behalf_of,omitempty"` // user the actor was impersonating
+	Changes    map[string]AuditChange `bson:"changes,omitempty" json:"changes,omitempty"`
+	IP         string                 `bson:"ip,omitempty" json:"ip,omitempty"`
+	UserAgent  string                 `bson:"user_agent,omitempty" json:"user_agent,omitempty"`
+	CreatedAt  time.Time              `bson:"created_at" json:"created_at"`
+}
@@ -0,0 +1,7 @@
+package entity
+
+import "errors"
+
+var (
+	ErrBlobNotFound = errors.New("blob not found")
+)
@@ -0,0 +1,13 @@
+package entity
+
+import "time"
+
+// CalendarEvent is a single deadline or assessment published in a user's
+// calendar feed.
+type CalendarEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Start       time.Time
+	Updated     time.Time
+}
@@ -1,6 +1,7 @@
 package entity
 
 import (
+	"errors"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -12,5 +13,16 @@ type Class struct {
 	StudentIDs []primitive.ObjectID `bson:"student_ids" json:"student_ids"`
 	TeacherIDs []primitive.ObjectID `bson:"teacher_ids" json:"teacher_ids"`
 	CourseID   primitive.ObjectID   `bson:"course_id" json:"course_id"`
+	TermID     primitive.ObjectID   `bson:"term_id,omitempty" json:"term_id,omitempty"`
 	CreatedAt  time.Time            `bson:"created_at" json:"created_at"`
-}
\ No newline at end of file
+	ArchivedAt *time.Time           `bson:"archived_at,omitempty" json:"archived_at,omitempty"`
+	DeletedAt  *time.Time           `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+
+	// ClearTerm detaches the class from its term on update, for a request
+	// that sends "term_id": null.
+	ClearTerm bool `bson:"-" json:"-"`
+}
+
+var (
+	ErrClassNotFound = errors.New("class not found")
+)
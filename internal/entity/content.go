@@ -0,0 +1,87 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type LessonStatus string
+
+const (
+	LessonDraft     LessonStatus = "draft"
+	LessonPublished LessonStatus = "published"
+)
+
+type Module struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	CourseID  primitive.ObjectID `bson:"course_id" json:"course_id"`
+	Title     string             `bson:"title" json:"title"`
+	Position  int                `bson:"position" json:"position"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+}
+
+type LessonLink struct {
+	Title string `bson:"title" json:"title"`
+	URL   string `bson:"url" json:"url"`
+}
+
+type Attachment struct {
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	Name        string             `bson:"name" json:"name"`
+	ContentType string             `bson:"content_type" json:"content_type"`
+	Size        int64              `bson:"size" json:"size"`
+	Key         string             `bson:"key" json:"-"` // blob store key
+	UploadedAt  time.Time          `bson:"uploaded_at" json:"uploaded_at"`
+}
+
+type Lesson struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ModuleID    primitive.ObjectID `bson:"module_id" json:"module_id"`
+	CourseID    primitive.ObjectID `bson:"course_id" json:"course_id"`
+	Title       string             `bson:"title" json:"title"`
+	Body        string             `bson:"body" json:"body"` // markdown
+	Links       []LessonLink       `bson:"links" json:"links"`
+	Attachments []Attachment       `bson:"attachments" json:"attachments"`
+	Position    int                `bson:"position" json:"position"`
+	Status      LessonStatus       `bson:"status" json:"status"`
+	ReleaseAt   *time.Time         `bson:"release_at,omitempty" json:"release_at,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	Release     *ReleaseRule       `bson:"release,omitempty" json:"release,omitempty"`
+
+	Availability `bson:"-"`
+}
+
+// IsReleased reports whether students may see the lesson at the given time.
+func (l *Lesson) IsReleased(now time.Time) bool {
+	if l.Status != LessonPublished {
+		return false
+	}
+	return l.ReleaseAt == nil || !l.ReleaseAt.After(now)
+}
+
+func (l *Lesson) ReleaseRule() *ReleaseRule { return l.Release }
+
+// Lock withholds the body, links and attachments of the lesson.
+func (l *Lesson) Lock(a Availability) {
+	l.Availability = a
+	l.Body = ""
+	l.Links = nil
+	l.Attachments = nil
+}
+
+// ModuleContent is a module together with its lessons, ordered by position.
+type ModuleContent struct {
+	*Module
+	Lessons []*Lesson `json:"lessons"`
+}
+
+var (
+	ErrModuleNotFound     = errors.New("module not found")
+	ErrLessonNotFound     = errors.New("lesson not found")
+	ErrAttachmentNotFound = errors.New("attachment not found")
+	ErrInvalidLesson      = errors.New("invalid lesson")
+)
@@ -13,6 +13,24 @@ type Course struct {
 	Description 	string             	 `bson:"description,omitempty" json:"description,omitempty"`
 	AssignedTeacher []primitive.ObjectID `bson:"assigned_teachers" json:"assigned_teachers"`
 	CreatedAt 		time.Time		  	 `bson:"created_at" json:"created_at"`
+	TermID 			primitive.ObjectID 	 `bson:"term_id,omitempty" json:"term_id,omitempty"`
+	ClonedFrom 		primitive.ObjectID 	 `bson:"cloned_from,omitempty" json:"cloned_from,omitempty"`
+	ArchivedAt 		*time.Time 			 `bson:"archived_at,omitempty" json:"archived_at,omitempty"`
+	DeletedAt 		*time.Time 			 `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+
+	CompletionCriteria *CompletionCriteria `bson:"completion_criteria,omitempty" json:"completion_criteria,omitempty"`
+
+	// ClearTerm detaches the course from its term on update, for a
+	// request that sends "term_id": null.
+	ClearTerm bool `bson:"-" json:"-"`
+}
+
+// Criteria returns the course's completion criteria, or the defaults when none were configured.
+func (c *Course) Criteria() CompletionCriteria {
+	if c.CompletionCriteria == nil {
+		return DefaultCompletionCriteria()
+	}
+	return *c.CompletionCriteria
 }
 
 var (
@@ -0,0 +1,22 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EmailVerification is an outstanding proof of ownership of Email, sent to
+// that address. Only a hash of the token is stored. Email is either the
+// user's current, unverified address or the pending one it is changing to.
+type EmailVerification struct {
+	TokenHash string             `bson:"_id"`
+	UserID    primitive.ObjectID `bson:"user_id"`
+	Email     string             `bson:"email"`
+	ExpiresAt time.Time          `bson:"expires_at"`
+}
+
+var (
+	ErrEmailNotVerified = errors.New("email address has not been verified")
+)
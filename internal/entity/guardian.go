@@ -0,0 +1,49 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type GuardianLinkStatus string
+
+const (
+	GuardianLinkPending GuardianLinkStatus = "pending"
+	GuardianLinkActive  GuardianLinkStatus = "active"
+)
+
+// GuardianLink gives a guardian read-only access to a student. An admin
+// links the two accounts directly; a student can instead invite a guardian
+// by email, and the link becomes active once the guardian accepts. Only a
+// hash of the invitation token is stored.
+type GuardianLink struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	StudentID   primitive.ObjectID  `bson:"student_id" json:"student_id"`
+	GuardianID  *primitive.ObjectID `bson:"guardian_id,omitempty" json:"guardian_id,omitempty"` // unset while pending
+	Email       string              `bson:"email" json:"email"`                                 // the guardian's address
+	Status      GuardianLinkStatus  `bson:"status" json:"status"`
+	TokenHash   string              `bson:"token_hash,omitempty" json:"-"`
+	CreatedBy   primitive.ObjectID  `bson:"created_by" json:"created_by"`
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+	ExpiresAt   *time.Time          `bson:"expires_at,omitempty" json:"expires_at,omitempty"` // pending invitations only
+	ConfirmedAt *time.Time          `bson:"confirmed_at,omitempty" json:"confirmed_at,omitempty"`
+}
+
+// LinkedStudent is a student as their guardian sees them.
+type LinkedStudent struct {
+	ID       primitive.ObjectID `json:"id"`
+	Name     string             `json:"name"`
+	Email    string             `json:"email"`
+	LinkedAt time.Time          `json:"linked_at"`
+}
+
+var (
+	ErrGuardianLinkNotFound = errors.New("guardian link not found")
+	ErrGuardianLinkExists   = errors.New("guardian is already linked to this student")
+	ErrNotGuardian          = errors.New("user's role cannot be linked to students")
+	ErrNotStudent           = errors.New("user is not a student")
+	ErrInvitationMismatch   = errors.New("invitation was sent to a different email address")
+	ErrTooManyInvitations   = errors.New("too many pending guardian invitations")
+)
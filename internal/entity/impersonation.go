@@ -0,0 +1,52 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ImpersonationSession lets an admin use the application as another user,
+// to see what they see. The session token acts as the user, while the
+// audit log names the admin. Sessions are read-only unless the admin asked
+// for writes, and every write made in one is recorded on the session.
+type ImpersonationSession struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	ActorID     primitive.ObjectID  `bson:"actor_id" json:"actor_id"`
+	ActorRole   Role                `bson:"actor_role" json:"actor_role"`
+	UserID      primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	UserRole    Role                `bson:"user_role" json:"user_role"`
+	Reason      string              `bson:"reason" json:"reason"`
+	AllowWrites bool                `bson:"allow_writes" json:"allow_writes"`
+	Writes      []ImpersonatedWrite `bson:"writes,omitempty" json:"writes,omitempty"`
+	StartedAt   time.Time           `bson:"started_at" json:"started_at"`
+	ExpiresAt   time.Time           `bson:"expires_at" json:"expires_at"`
+	EndedAt     *time.Time          `bson:"ended_at,omitempty" json:"ended_at,omitempty"`
+}
+
+// ImpersonatedWrite is a request that changed something during an
+// impersonation session.
+type ImpersonatedWrite struct {
+	Method string    `bson:"method" json:"method"`
+	Path   string    `bson:"path" json:"path"`
+	Status int       `bson:"status" json:"status"`
+	At     time.Time `bson:"at" json:"at"`
+}
+
+func (s *ImpersonationSession) Active(now time.Time) bool {
+	return s.EndedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// Impersonation is a started session with the token that acts in it.
+type Impersonation struct {
+	Token   string                `json:"token"`
+	Session *ImpersonationSession `json:"session"`
+}
+
+var (
+	ErrImpersonationNotFound = errors.New("impersonation session not found")
+	ErrCannotImpersonate     = errors.New("this user cannot be impersonated")
+	ErrImpersonationReason   = errors.New("a reason of at most 500 characters is required")
+	ErrImpersonationReadOnly = errors.New("impersonation session is read-only")
+)
@@ -0,0 +1,46 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Dependents counts, per collection, the records that still refer to an item.
+// Collections without any referring record are left out.
+type Dependents map[string]int64
+
+// DependentsError is returned when an item is not deleted because other
+// records refer to it and the caller did not ask for a cascading delete.
+type DependentsError struct {
+	Dependents Dependents
+}
+
+func (e *DependentsError) Error() string {
+	return ErrHasDependents.Error()
+}
+
+func (e *DependentsError) Is(target error) bool {
+	return target == ErrHasDependents
+}
+
+// ReferenceError reports an ID in a create or update request that does not
+// point at an existing record of the expected kind.
+type ReferenceError struct {
+	Field string
+	ID    primitive.ObjectID
+}
+
+func (e *ReferenceError) Error() string {
+	return fmt.Sprintf("%s %s does not exist", e.Field, e.ID.Hex())
+}
+
+func (e *ReferenceError) Is(target error) bool {
+	return target == ErrInvalidReference
+}
+
+var (
+	ErrHasDependents    = errors.New("item is still referenced")
+	ErrInvalidReference = errors.New("invalid reference")
+)
@@ -1,6 +1,7 @@
 package entity
 
 import (
+	"errors"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -12,4 +13,8 @@ type Message struct {
 	ReceiverIDs []primitive.ObjectID `bson:"receiver_ids" json:"receiver_ids"` // Recipients: students or classes
 	Content     string               `bson:"content" json:"content"`
 	CreatedAt   time.Time            `bson:"created_at" json:"created_at"`
-}
\ No newline at end of file
+}
+
+var (
+	ErrMessageNotFound = errors.New("message not found")
+)
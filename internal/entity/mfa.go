@@ -0,0 +1,64 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+// MFASettings holds an account's TOTP second factor. PendingSecret is set
+// between starting an enrollment and confirming it with a first code.
+// RecoveryCodes are SHA-256 hashes of the unused one-time codes, and
+// LastStep is the time step of the last accepted code, so that a code
+// cannot be replayed.
+type MFASettings struct {
+	Secret        string     `bson:"secret,omitempty"`
+	PendingSecret string     `bson:"pending_secret,omitempty"`
+	RecoveryCodes []string   `bson:"recovery_codes,omitempty"`
+	LastStep      int64      `bson:"last_step,omitempty"`
+	EnabledAt     *time.Time `bson:"enabled_at,omitempty"`
+}
+
+// Enabled reports whether a second factor has been confirmed.
+func (m *MFASettings) Enabled() bool {
+	return m != nil && m.Secret != ""
+}
+
+// MFAPolicy lists the roles whose accounts have to use a second factor.
+type MFAPolicy struct {
+	RequiredRoles []Role `bson:"required_roles" json:"required_roles"`
+}
+
+func (p *MFAPolicy) Requires(role Role) bool {
+	for _, r := range p.RequiredRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// MFAEnrollment is what an authenticator app needs to add an account. The
+// provisioning URI is usually shown as a QR code.
+type MFAEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// LoginResult is the outcome of the password step of a login. Either Token
+// is a session token, or MFAToken is a short-lived challenge to be completed
+// with a second factor. EnrollmentRequired means the account has to enroll
+// one first, which the challenge allows.
+type LoginResult struct {
+	Token              string `json:"token,omitempty"`
+	MFAToken           string `json:"mfa_token,omitempty"`
+	MFARequired        bool   `json:"mfa_required,omitempty"`
+	EnrollmentRequired bool   `json:"enrollment_required,omitempty"`
+}
+
+var (
+	ErrInvalidMFACode    = errors.New("invalid authentication code")
+	ErrMFANotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrMFANoEnrollment   = errors.New("no two-factor enrollment in progress")
+	ErrMFARequired       = errors.New("two-factor authentication is required for this role")
+)
@@ -0,0 +1,91 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type NotificationKind string
+
+const (
+	NotificationPasswordReset NotificationKind = "password_reset"
+	NotificationNewAssignment NotificationKind = "new_assignment"
+	NotificationGrade         NotificationKind = "grade"
+	NotificationAnnouncement  NotificationKind = "announcement"
+	NotificationDueReminder   NotificationKind = "due_reminder"
+
+	NotificationEmailVerification NotificationKind = "email_verification"
+	NotificationGuardianInvite    NotificationKind = "guardian_invitation"
+)
+
+type NotificationStatus string
+
+const (
+	NotificationPending NotificationStatus = "pending"
+	NotificationSent    NotificationStatus = "sent"
+	NotificationFailed  NotificationStatus = "failed"
+)
+
+// Notification is an outbox entry. It is rendered when enqueued and
+// delivered later by the notification worker. The body is kept encrypted in
+// SealedBody, as mails such as password resets carry tokens; Body is only
+// set on entries written before bodies were encrypted.
+//
+// A broadcast entry is a job rather than a mail: the worker expands it into
+// an entry per user, each marked with its BroadcastID, from the template
+// data kept encrypted in SealedData. Cursor is how far the expansion got,
+// so that an interrupted one resumes where it stopped.
+type Notification struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID        primitive.ObjectID `bson:"user_id" json:"user_id"`
+	To            string             `bson:"to" json:"to"`
+	Kind          NotificationKind   `bson:"kind" json:"kind"`
+	Locale        string             `bson:"locale" json:"locale"`
+	Subject       string             `bson:"subject" json:"subject"`
+	Body          string             `bson:"body,omitempty" json:"-"`
+	SealedBody    []byte             `bson:"sealed_body,omitempty" json:"-"`
+	Status        NotificationStatus `bson:"status" json:"status"`
+	Attempts      int                `bson:"attempts" json:"attempts"`
+	LastError     string             `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	NextAttemptAt time.Time          `bson:"next_attempt_at" json:"next_attempt_at"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	SentAt        *time.Time         `bson:"sent_at,omitempty" json:"sent_at,omitempty"`
+
+	Broadcast   bool               `bson:"broadcast,omitempty" json:"broadcast,omitempty"`
+	SealedData  []byte             `bson:"sealed_data,omitempty" json:"-"`
+	Cursor      string             `bson:"cursor,omitempty" json:"-"`
+	BroadcastID primitive.ObjectID `bson:"broadcast_id,omitempty" json:"broadcast_id,omitempty"`
+}
+
+type NotificationPreferences struct {
+	Disabled []NotificationKind `bson:"disabled" json:"disabled"`
+}
+
+// Allows reports whether the user wants to receive notifications of the given kind.
+// Password reset, email verification and guardian invitation mails are
+// transactional and cannot be turned off.
+func (p NotificationPreferences) Allows(kind NotificationKind) bool {
+	if kind == NotificationPasswordReset || kind == NotificationEmailVerification || kind == NotificationGuardianInvite {
+		return true
+	}
+	for _, k := range p.Disabled {
+		if k == kind {
+			return false
+		}
+	}
+	return true
+}
+
+func IsValidNotificationKind(kind NotificationKind) bool {
+	switch kind {
+	case NotificationPasswordReset, NotificationNewAssignment, NotificationGrade, NotificationAnnouncement, NotificationDueReminder, NotificationEmailVerification, NotificationGuardianInvite:
+		return true
+	}
+	return false
+}
+
+var (
+	ErrNotificationKindInvalid = errors.New("invalid notification kind")
+)
@@ -0,0 +1,35 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+// OIDCFlow is a single sign-on login between the redirect to the identity
+// provider and its callback. It is looked up by State and can be used once.
+// BrowserHash is the hash of a secret kept in a cookie of the browser that
+// started the login, so that the callback only completes in that browser.
+type OIDCFlow struct {
+	State        string    `bson:"_id"`
+	Nonce        string    `bson:"nonce"`
+	CodeVerifier string    `bson:"code_verifier"`
+	BrowserHash  string    `bson:"browser_hash"`
+	ExpiresAt    time.Time `bson:"expires_at"`
+}
+
+// OIDCIdentity is what a verified ID token says about the person who signed
+// in. Subject is unique per identity provider.
+type OIDCIdentity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Groups        []string
+}
+
+var (
+	ErrOIDCNotConfigured = errors.New("single sign-on is not configured")
+	ErrOIDCInvalidState  = errors.New("single sign-on login expired or was already completed")
+	ErrOIDCNoEmail       = errors.New("identity provider did not return a verified email")
+	ErrOIDCLinkRequired  = errors.New("an account with this email already exists; an administrator has to allow single sign-on for it")
+)
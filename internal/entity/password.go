@@ -0,0 +1,92 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PasswordPolicy is what a new password has to satisfy. MinClasses counts
+// how many of lower case, upper case, digits and symbols have to appear.
+// Disallowed passwords are compared without regard to case.
+type PasswordPolicy struct {
+	MinLength  int
+	MaxLength  int
+	MinClasses int
+	Disallowed []string
+}
+
+// Check returns a *PasswordPolicyError listing every rule the password
+// breaks, or nil. The password may not contain the local part of email
+// either.
+func (p PasswordPolicy) Check(password, email string) error {
+	var problems []string
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		problems = append(problems, fmt.Sprintf("must be at most %d characters long", p.MaxLength))
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+	if classes < p.MinClasses {
+		problems = append(problems, fmt.Sprintf("must mix at least %d of lower case, upper case, digits and symbols", p.MinClasses))
+	}
+
+	folded := strings.ToLower(password)
+	for _, d := range p.Disallowed {
+		if folded == strings.ToLower(d) {
+			problems = append(problems, "is too common")
+			break
+		}
+	}
+	if local, _, _ := strings.Cut(strings.ToLower(email), "@"); len(local) >= 3 && strings.Contains(folded, local) {
+		problems = append(problems, "must not contain the email address")
+	}
+
+	if len(problems) > 0 {
+		return &PasswordPolicyError{Problems: problems}
+	}
+	return nil
+}
+
+// PasswordPolicyError lists why a password was refused.
+type PasswordPolicyError struct {
+	Problems []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return "password " + strings.Join(e.Problems, ", ")
+}
+
+func (e *PasswordPolicyError) Is(target error) bool {
+	return target == ErrWeakPassword
+}
+
+var (
+	ErrWeakPassword     = errors.New("password does not meet the policy")
+	ErrPasswordBreached = errors.New("password has appeared in a data breach")
+	ErrPasswordReused   = errors.New("password was used recently")
+)
@@ -0,0 +1,16 @@
+package entity
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PasswordReset is an outstanding password reset, sent to Email. Only a
+// hash of the token is stored, and the token works once.
+type PasswordReset struct {
+	TokenHash string             `bson:"_id"`
+	UserID    primitive.ObjectID `bson:"user_id"`
+	Email     string             `bson:"email"`
+	ExpiresAt time.Time          `bson:"expires_at"`
+}
@@ -0,0 +1,117 @@
+package entity
+
+import (
+	"errors"
+	"regexp"
+	"time"
+)
+
+// Permission is one thing a role allows. Routes and usecases check
+// permissions rather than role names, so that roles can be defined freely.
+type Permission string
+
+const (
+	PermUsersRead           Permission = "users.read"
+	PermUsersManage         Permission = "users.manage"
+	PermImpersonate         Permission = "users.impersonate"
+	PermRolesManage         Permission = "roles.manage"
+	PermSettingsManage      Permission = "settings.manage"
+	PermAuditRead           Permission = "audit.read"
+	PermCoursesRead         Permission = "courses.read"
+	PermCoursesWrite        Permission = "courses.write"
+	PermCoursesDelete       Permission = "courses.delete"
+	PermTermsWrite          Permission = "terms.write"
+	PermTermsDelete         Permission = "terms.delete"
+	PermClassesRead         Permission = "classes.read"
+	PermClassesWrite        Permission = "classes.write"
+	PermEnrollmentWrite     Permission = "enrollment.write" // the students of a class only
+	PermClassesDelete       Permission = "classes.delete"
+	PermAnnouncementsWrite  Permission = "announcements.write"
+	PermAnnouncementsDelete Permission = "announcements.delete"
+	PermTrashManage         Permission = "trash.manage"
+
+	// Teaching permissions apply to the courses and classes a user is
+	// assigned to.
+	PermTeachingView     Permission = "teaching.view"
+	PermContentWrite     Permission = "content.write"
+	PermContentDelete    Permission = "content.delete"
+	PermAssignmentsWrite Permission = "assignments.write"
+	PermGradesRead       Permission = "grades.read"
+	PermGradesWrite      Permission = "grades.write"
+	PermAttendanceRead   Permission = "attendance.read"
+	PermAttendanceWrite  Permission = "attendance.write"
+	PermAttendanceDelete Permission = "attendance.delete"
+	PermMessagesWrite    Permission = "messages.write"
+
+	// PermLearn lets a user take part in the classes they are enrolled in.
+	PermLearn Permission = "learning.participate"
+
+	// PermLinkedStudents lets a user follow the students linked to them,
+	// read-only.
+	PermLinkedStudents Permission = "guardian.view"
+)
+
+// AllPermissions lists every permission, in the order they are shown.
+var AllPermissions = []Permission{
+	PermUsersRead, PermUsersManage, PermImpersonate, PermRolesManage, PermSettingsManage, PermAuditRead,
+	PermCoursesRead, PermCoursesWrite, PermCoursesDelete, PermTermsWrite, PermTermsDelete,
+	PermClassesRead, PermClassesWrite, PermEnrollmentWrite, PermClassesDelete,
+	PermAnnouncementsWrite, PermAnnouncementsDelete, PermTrashManage,
+	PermTeachingView, PermContentWrite, PermContentDelete, PermAssignmentsWrite,
+	PermGradesRead, PermGradesWrite, PermAttendanceRead, PermAttendanceWrite, PermAttendanceDelete,
+	PermMessagesWrite, PermLearn, PermLinkedStudents,
+}
+
+func IsValidPermission(p Permission) bool {
+	for _, known := range AllPermissions {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPrivilegedPermission reports whether p decides who may do what. Only
+// users who hold such a permission can give it to others.
+func IsPrivilegedPermission(p Permission) bool {
+	return p == PermRolesManage || p == PermImpersonate
+}
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,31}$`)
+
+// IsValidRoleName reports whether name can name a role: lower case letters,
+// digits and underscores, starting with a letter.
+func IsValidRoleName(name Role) bool {
+	return roleNamePattern.MatchString(string(name))
+}
+
+// RoleDefinition is a named set of permissions. Built-in roles are seeded
+// with the application; their permissions can be changed but they cannot
+// be deleted.
+type RoleDefinition struct {
+	Name        Role         `bson:"_id" json:"name"`
+	Description string       `bson:"description" json:"description"`
+	Permissions []Permission `bson:"permissions" json:"permissions"`
+	Builtin     bool         `bson:"builtin" json:"builtin"`
+	CreatedAt   time.Time    `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time    `bson:"updated_at" json:"updated_at"`
+}
+
+func (d *RoleDefinition) Has(p Permission) bool {
+	for _, have := range d.Permissions {
+		if have == p {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	ErrRoleNotFound     = errors.New("role not found")
+	ErrRoleExists       = errors.New("role already exists")
+	ErrRoleInUse        = errors.New("role is assigned to users")
+	ErrBuiltinRole      = errors.New("built-in roles cannot be deleted")
+	ErrInvalidRole      = errors.New("invalid role name or permission")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrRoleLockout      = errors.New("the admin role has to keep roles.manage")
+)
@@ -0,0 +1,163 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Profile is how a user is named and shown. It is stored inline in the user
+// document. Users edit their own profile, except the student number, which
+// only admins set.
+type Profile struct {
+	FirstName       string     `bson:"first_name,omitempty" json:"first_name,omitempty"`
+	LastName        string     `bson:"last_name,omitempty" json:"last_name,omitempty"`
+	DisplayName     string     `bson:"display_name,omitempty" json:"display_name,omitempty"`
+	Locale          string     `bson:"locale,omitempty" json:"locale,omitempty"`
+	TimeZone        string     `bson:"time_zone,omitempty" json:"time_zone,omitempty"`
+	StudentNumber   string     `bson:"student_number,omitempty" json:"student_number,omitempty"`
+	AvatarKey       string     `bson:"avatar_key,omitempty" json:"-"`
+	AvatarUpdatedAt *time.Time `bson:"avatar_updated_at,omitempty" json:"avatar_updated_at,omitempty"`
+	// SortName is the lower case key lists sort by name on: the last and
+	// first name, or else the display name. It is empty for users without
+	// a name, who come first.
+	SortName string `bson:"sort_name" json:"-"`
+}
+
+// SupportedLocales are the locales the application has mail templates for.
+var SupportedLocales = []string{"en", "id"}
+
+// AvatarTypes are the image types accepted as avatars.
+var AvatarTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+const (
+	maxNameLength    = 100
+	MaxAvatarSize    = 2 << 20
+	studentNumberMax = 32
+)
+
+var studentNumberPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9./-]*$`)
+
+// ProfileUpdate holds the profile fields a user changes. Nil fields are
+// left as they are and empty ones are cleared.
+type ProfileUpdate struct {
+	FirstName   *string `json:"first_name"`
+	LastName    *string `json:"last_name"`
+	DisplayName *string `json:"display_name"`
+	Locale      *string `json:"locale"`
+	TimeZone    *string `json:"time_zone"`
+}
+
+// Apply validates the update and applies it to p. It returns an error
+// wrapping ErrInvalidProfile that names the first invalid field.
+func (u ProfileUpdate) Apply(p *Profile) error {
+	next := *p
+	for _, f := range []struct {
+		name  string
+		value *string
+		dst   *string
+	}{
+		{"first_name", u.FirstName, &next.FirstName},
+		{"last_name", u.LastName, &next.LastName},
+		{"display_name", u.DisplayName, &next.DisplayName},
+	} {
+		if f.value == nil {
+			continue
+		}
+		name := strings.Join(strings.Fields(*f.value), " ")
+		if utf8.RuneCountInString(name) > maxNameLength || strings.IndexFunc(name, unicode.IsControl) >= 0 {
+			return fmt.Errorf("%w: %s must be at most %d characters of text", ErrInvalidProfile, f.name, maxNameLength)
+		}
+		*f.dst = name
+	}
+
+	if u.Locale != nil {
+		locale := strings.TrimSpace(*u.Locale)
+		if locale != "" && !IsSupportedLocale(locale) {
+			return fmt.Errorf("%w: locale must be one of %s", ErrInvalidProfile, strings.Join(SupportedLocales, ", "))
+		}
+		next.Locale = locale
+	}
+
+	if u.TimeZone != nil {
+		tz := strings.TrimSpace(*u.TimeZone)
+		if tz != "" {
+			if _, err := time.LoadLocation(tz); err != nil || tz == "Local" {
+				return fmt.Errorf("%w: time_zone must be an IANA time zone such as Asia/Jakarta", ErrInvalidProfile)
+			}
+		}
+		next.TimeZone = tz
+	}
+
+	next.SortName = next.sortKey()
+	*p = next
+	return nil
+}
+
+func (p *Profile) sortKey() string {
+	name := strings.TrimSpace(p.LastName + " " + p.FirstName)
+	if name == "" {
+		name = p.DisplayName
+	}
+	return strings.ToLower(name)
+}
+
+// ValidateStudentNumber checks a student number: up to 32 letters, digits,
+// dots, slashes and dashes. An empty number removes it.
+func ValidateStudentNumber(n string) error {
+	if n != "" && (len(n) > studentNumberMax || !studentNumberPattern.MatchString(n)) {
+		return fmt.Errorf("%w: student_number must be up to %d letters, digits, '.', '/' or '-'", ErrInvalidProfile, studentNumberMax)
+	}
+	return nil
+}
+
+func IsSupportedLocale(locale string) bool {
+	for _, l := range SupportedLocales {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
+
+func IsAvatarType(contentType string) bool {
+	for _, t := range AvatarTypes {
+		if t == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// Name is how the user is addressed: the display name, or else the first
+// and last name, or else the email address.
+func (u *User) Name() string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	if name := strings.TrimSpace(u.FirstName + " " + u.LastName); name != "" {
+		return name
+	}
+	return u.Email
+}
+
+// Location is the user's time zone, or UTC if none is set.
+func (u *User) Location() *time.Location {
+	if u.TimeZone != "" {
+		if loc, err := time.LoadLocation(u.TimeZone); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+var (
+	ErrInvalidProfile      = errors.New("invalid profile")
+	ErrStudentNumberExists = errors.New("student number is already in use")
+	ErrInvalidAvatar       = errors.New("avatar must be a PNG, JPEG, GIF or WebP image of at most 2 MB")
+	ErrAvatarNotFound      = errors.New("user has no avatar")
+)
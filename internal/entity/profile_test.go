@@ -0,0 +1,66 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestProfileUpdateApply(t *testing.T) {
+	str := func(s string) *string { return &s }
+	stored := Profile{FirstName: "Ada", LastName: "Lovelace", Locale: "en", TimeZone: "Europe/London", StudentNumber: "S-1"}
+
+	tests := []struct {
+		name    string
+		update  ProfileUpdate
+		want    Profile
+		wantErr bool
+	}{
+		{
+			name:   "nil fields are kept",
+			update: ProfileUpdate{},
+			want:   Profile{FirstName: "Ada", LastName: "Lovelace", Locale: "en", TimeZone: "Europe/London", StudentNumber: "S-1", SortName: "lovelace ada"},
+		},
+		{
+			name:   "names are trimmed and spaces collapsed",
+			update: ProfileUpdate{FirstName: str("  Augusta \t Ada "), DisplayName: str(" Countess ")},
+			want:   Profile{FirstName: "Augusta Ada", LastName: "Lovelace", DisplayName: "Countess", Locale: "en", TimeZone: "Europe/London", StudentNumber: "S-1", SortName: "lovelace augusta ada"},
+		},
+		{
+			name:   "empty fields are cleared",
+			update: ProfileUpdate{FirstName: str(""), LastName: str(" "), DisplayName: str("Ada L."), Locale: str(""), TimeZone: str("")},
+			want:   Profile{DisplayName: "Ada L.", StudentNumber: "S-1", SortName: "ada l."},
+		},
+		{
+			name:   "supported locale and time zone",
+			update: ProfileUpdate{Locale: str(" id "), TimeZone: str("Asia/Jakarta")},
+			want:   Profile{FirstName: "Ada", LastName: "Lovelace", Locale: "id", TimeZone: "Asia/Jakarta", StudentNumber: "S-1", SortName: "lovelace ada"},
+		},
+		{name: "name too long", update: ProfileUpdate{LastName: str(strings.Repeat("a", maxNameLength+1))}, wantErr: true},
+		{name: "control character", update: ProfileUpdate{DisplayName: str("Ada\x00")}, wantErr: true},
+		{name: "unsupported locale", update: ProfileUpdate{Locale: str("fr")}, wantErr: true},
+		{name: "unknown time zone", update: ProfileUpdate{TimeZone: str("Mars/Olympus")}, wantErr: true},
+		{name: "local time zone", update: ProfileUpdate{TimeZone: str("Local")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := stored
+			err := tt.update.Apply(&p)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidProfile) {
+					t.Errorf("Apply() error = %v, want %v", err, ErrInvalidProfile)
+				}
+				if p != stored {
+					t.Errorf("Apply() changed the profile on error: %+v", p)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if p != tt.want {
+				t.Errorf("Apply() = %+v, want %+v", p, tt.want)
+			}
+		})
+	}
+}
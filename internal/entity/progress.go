@@ -0,0 +1,105 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ProgressItemType string
+
+const (
+	ProgressLesson     ProgressItemType = "lesson"
+	ProgressAssignment ProgressItemType = "assignment"
+	ProgressAssessment ProgressItemType = "assessment"
+)
+
+type ProgressStatus string
+
+const (
+	ProgressViewed    ProgressStatus = "viewed"
+	ProgressCompleted ProgressStatus = "completed"
+	ProgressSubmitted ProgressStatus = "submitted"
+	ProgressTaken     ProgressStatus = "taken"
+)
+
+// Progress is what a student has done with one lesson, assignment or
+// assessment. There is at most one record per student and item.
+type Progress struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	StudentID primitive.ObjectID `bson:"student_id" json:"student_id"`
+	CourseID  primitive.ObjectID `bson:"course_id" json:"course_id"`
+	ItemType  ProgressItemType   `bson:"item_type" json:"item_type"`
+	ItemID    primitive.ObjectID `bson:"item_id" json:"item_id"`
+	Status    ProgressStatus     `bson:"status" json:"status"`
+	Score     *float64           `bson:"score,omitempty" json:"score,omitempty"` // percentage, assessments only
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type LessonRequirement string
+
+const (
+	LessonRequirementNone      LessonRequirement = "none"
+	LessonRequirementViewed    LessonRequirement = "viewed"
+	LessonRequirementCompleted LessonRequirement = "completed"
+)
+
+// CompletionCriteria decides which items count towards a course's completion.
+type CompletionCriteria struct {
+	Lessons      LessonRequirement `bson:"lessons" json:"lessons"`
+	Assignments  bool              `bson:"assignments" json:"assignments"`
+	Assessments  bool              `bson:"assessments" json:"assessments"`
+	PassingScore float64           `bson:"passing_score" json:"passing_score"` // minimum assessment percentage
+}
+
+func DefaultCompletionCriteria() CompletionCriteria {
+	return CompletionCriteria{
+		Lessons:     LessonRequirementCompleted,
+		Assignments: true,
+		Assessments: true,
+	}
+}
+
+func (c CompletionCriteria) Validate() error {
+	switch c.Lessons {
+	case LessonRequirementNone, LessonRequirementViewed, LessonRequirementCompleted:
+	default:
+		return ErrInvalidCompletionCriteria
+	}
+	if c.PassingScore < 0 || c.PassingScore > 100 {
+		return ErrInvalidCompletionCriteria
+	}
+	return nil
+}
+
+type ItemProgress struct {
+	ItemType ProgressItemType   `json:"item_type"`
+	ItemID   primitive.ObjectID `json:"item_id"`
+	Title    string             `json:"title"`
+	Status   ProgressStatus     `json:"status,omitempty"`
+	Score    *float64           `json:"score,omitempty"`
+	Done     bool               `json:"done"`
+}
+
+type CourseProgress struct {
+	StudentID primitive.ObjectID `json:"student_id"`
+	Email     string             `json:"email,omitempty"`
+	CourseID  primitive.ObjectID `json:"course_id"`
+	Completed int                `json:"completed"`
+	Total     int                `json:"total"`
+	Percent   float64            `json:"percent"`
+	Items     []ItemProgress     `json:"items,omitempty"`
+}
+
+type ClassProgress struct {
+	ClassID        primitive.ObjectID `json:"class_id"`
+	CourseID       primitive.ObjectID `json:"course_id"`
+	AveragePercent float64            `json:"average_percent"`
+	Students       []*CourseProgress  `json:"students"`
+}
+
+var (
+	ErrInvalidCompletionCriteria = errors.New("invalid completion criteria")
+	ErrInvalidScore              = errors.New("score must be between 0 and 100")
+)
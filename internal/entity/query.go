@@ -0,0 +1,52 @@
+package entity
+
+import "errors"
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 200
+)
+
+// ListOptions describes one page of a listing. Sort names a sortable field,
+// prefixed with "-" for descending order; Cursor is the NextCursor of the
+// previous page. Filters maps filter names to values; a comma separated value
+// matches any of the listed values.
+type ListOptions struct {
+	Limit   int
+	Cursor  string
+	Sort    string
+	Filters map[string]string
+}
+
+// WithFilter returns a copy of the options with the filter set, leaving the
+// caller's map untouched.
+func (o ListOptions) WithFilter(name, value string) ListOptions {
+	filters := make(map[string]string, len(o.Filters)+1)
+	for k, v := range o.Filters {
+		filters[k] = v
+	}
+	filters[name] = value
+	o.Filters = filters
+	return o
+}
+
+// PageLimit returns the requested limit clamped to the allowed range.
+func (o ListOptions) PageLimit() int {
+	switch {
+	case o.Limit <= 0:
+		return DefaultPageLimit
+	case o.Limit > MaxPageLimit:
+		return MaxPageLimit
+	}
+	return o.Limit
+}
+
+// Page is one page of a listing. NextCursor is empty on the last page.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+var (
+	ErrInvalidListOptions = errors.New("invalid list options")
+)
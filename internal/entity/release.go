@@ -0,0 +1,55 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ReleaseConditionType string
+
+const (
+	ConditionLessonCompleted ReleaseConditionType = "lesson_completed"
+	ConditionAssessmentScore ReleaseConditionType = "assessment_score"
+	ConditionAfterDate       ReleaseConditionType = "after_date"
+)
+
+// ReleaseCondition is one prerequisite a student must meet before a lesson,
+// assignment or assessment becomes available.
+type ReleaseCondition struct {
+	Type         ReleaseConditionType `bson:"type" json:"type"`
+	LessonID     primitive.ObjectID   `bson:"lesson_id,omitempty" json:"lesson_id,omitempty"`
+	AssessmentID primitive.ObjectID   `bson:"assessment_id,omitempty" json:"assessment_id,omitempty"`
+	MinScore     float64              `bson:"min_score,omitempty" json:"min_score,omitempty"` // percentage
+	Date         *time.Time           `bson:"date,omitempty" json:"date,omitempty"`
+}
+
+// ReleaseRule gates an item behind conditions that must all be met. When
+// HideUntilMet is set, students do not see the item at all until then;
+// otherwise it is listed as locked with the reasons.
+type ReleaseRule struct {
+	Conditions   []ReleaseCondition `bson:"conditions" json:"conditions"`
+	HideUntilMet bool               `bson:"hide_until_met" json:"hide_until_met"`
+}
+
+// Availability is computed per student and never stored.
+type Availability struct {
+	Locked      bool     `bson:"-" json:"locked,omitempty"`
+	LockReasons []string `bson:"-" json:"lock_reasons,omitempty"`
+}
+
+// Gated is an item release rules apply to: a lesson, assignment or
+// assessment.
+type Gated interface {
+	// ReleaseRule returns the item's rule, or nil when it has none.
+	ReleaseRule() *ReleaseRule
+	// Lock marks the item as locked for a student and clears the content
+	// the rule gates. What identifies the item, such as its title, is kept.
+	Lock(a Availability)
+}
+
+var (
+	ErrInvalidReleaseRule = errors.New("invalid release rule")
+	ErrItemLocked         = errors.New("item is locked")
+)
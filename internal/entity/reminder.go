@@ -0,0 +1,37 @@
+package entity
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ReminderItemType string
+
+const (
+	ReminderAssignment ReminderItemType = "assignment"
+	ReminderAssessment ReminderItemType = "assessment"
+)
+
+// ReminderSend records that a reminder went out. Its ID is derived from the
+// item, the student and the offset so that the same reminder can only be
+// recorded once, even across restarts and replicas.
+type ReminderSend struct {
+	ID       string             `bson:"_id" json:"id"`
+	ItemType ReminderItemType   `bson:"item_type" json:"item_type"`
+	ItemID   primitive.ObjectID `bson:"item_id" json:"item_id"`
+	UserID   primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Offset   time.Duration      `bson:"offset" json:"offset"`
+	SentAt   time.Time          `bson:"sent_at" json:"sent_at"`
+}
+
+func NewReminderSend(itemType ReminderItemType, itemID, userID primitive.ObjectID, offset time.Duration) *ReminderSend {
+	return &ReminderSend{
+		ID:       string(itemType) + ":" + itemID.Hex() + ":" + userID.Hex() + ":" + offset.String(),
+		ItemType: itemType,
+		ItemID:   itemID,
+		UserID:   userID,
+		Offset:   offset,
+		SentAt:   time.Now().UTC(),
+	}
+}
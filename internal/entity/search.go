@@ -0,0 +1,44 @@
+package entity
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SearchType string
+
+const (
+	SearchCourses       SearchType = "courses"
+	SearchLessons       SearchType = "lessons"
+	SearchAssignments   SearchType = "assignments"
+	SearchAnnouncements SearchType = "announcements"
+	SearchUsers         SearchType = "users"
+)
+
+var SearchTypes = []SearchType{SearchCourses, SearchLessons, SearchAssignments, SearchAnnouncements, SearchUsers}
+
+// Scored pairs a search match with its MongoDB text score.
+type Scored[T any] struct {
+	Item  T
+	Score float64
+}
+
+type SearchHit struct {
+	ID       primitive.ObjectID `json:"id"`
+	Title    string             `json:"title"`
+	Snippet  string             `json:"snippet,omitempty"`
+	CourseID primitive.ObjectID `json:"course_id,omitempty"`
+	Score    float64            `json:"score"`
+}
+
+// SearchResults holds the hits of a search grouped by type, each group
+// ordered by descending text score.
+type SearchResults struct {
+	Query   string                     `json:"query"`
+	Results map[SearchType][]SearchHit `json:"results"`
+}
+
+var (
+	ErrInvalidSearch = errors.New("invalid search query")
+)
@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+// SigningKey is one key of the token signing key ring. A key is published
+// from the moment it is created, signs from ActiveFrom until a newer key
+// takes over, and is still accepted for verification until ExpiresAt, so
+// that tokens it signed stay valid for their lifetime. A zero ExpiresAt
+// means no successor has been created yet.
+type SigningKey struct {
+	ID         string    `bson:"_id" json:"kid"`
+	Algorithm  string    `bson:"alg" json:"alg"`
+	PrivateKey []byte    `bson:"private_key" json:"-"` // PKCS #8 DER
+	PublicKey  []byte    `bson:"public_key" json:"-"`  // PKIX DER
+	CreatedAt  time.Time `bson:"created_at" json:"created_at"`
+	ActiveFrom time.Time `bson:"active_from" json:"active_from"`
+	ExpiresAt  time.Time `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+}
+
+// KeyRotationPolicy says how the key ring is rotated. A new key is created
+// PublishAhead before it starts signing, so that verifiers that cache the
+// key set have it in time, and the previous key is kept VerifyFor after it
+// stops signing, which has to cover the longest token lifetime.
+type KeyRotationPolicy struct {
+	Algorithm    string
+	RotateEvery  time.Duration
+	PublishAhead time.Duration
+	VerifyFor    time.Duration
+}
+
+// JWK is the public part of a signing key as published in the JWKS document
+// (RFC 7517). RSA keys use N and E, Ed25519 keys Crv and X.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+var (
+	ErrSigningKeyNotFound   = errors.New("signing key not found")
+	ErrSigningKeyInUse      = errors.New("signing key is the current signing key, rotate first")
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+)
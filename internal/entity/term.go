@@ -0,0 +1,36 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Term is an academic period such as a semester. Courses and classes are
+// offered within a term.
+type Term struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name      string             `bson:"name" json:"name"`
+	StartDate time.Time          `bson:"start_date" json:"start_date"`
+	EndDate   time.Time          `bson:"end_date" json:"end_date"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// Contains reports whether t falls within the term. The end date is exclusive.
+func (t *Term) Contains(at time.Time) bool {
+	return !at.Before(t.StartDate) && at.Before(t.EndDate)
+}
+
+func (t *Term) Validate() error {
+	if t.Name == "" || t.StartDate.IsZero() || !t.EndDate.After(t.StartDate) {
+		return ErrInvalidTerm
+	}
+	return nil
+}
+
+var (
+	ErrTermNotFound    = errors.New("term not found")
+	ErrInvalidTerm     = errors.New("invalid term")
+	ErrCourseNotInTerm = errors.New("course is not scoped to a term")
+)
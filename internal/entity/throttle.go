@@ -0,0 +1,67 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+// LoginAttempts counts the recent failed attempts made for one account or
+// from one client address. The count starts over once Window has passed
+// since the last failure.
+type LoginAttempts struct {
+	Key          string    `bson:"_id"`
+	Failures     int       `bson:"failures"`
+	BlockedUntil time.Time `bson:"blocked_until,omitempty"`
+	ExpiresAt    time.Time `bson:"expires_at"`
+}
+
+// ThrottlePolicy decides how long further attempts are refused after a
+// number of failures. The first FreeAttempts failures cost nothing, each
+// one after that doubles the wait from BaseDelay up to MaxDelay, and after
+// LockoutAfter failures attempts are refused for LockoutFor.
+type ThrottlePolicy struct {
+	FreeAttempts int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	LockoutAfter int
+	LockoutFor   time.Duration
+	Window       time.Duration
+}
+
+// Delay returns how long to refuse attempts after the given number of
+// consecutive failures.
+func (p ThrottlePolicy) Delay(failures int) time.Duration {
+	if p.LockoutAfter > 0 && failures >= p.LockoutAfter {
+		return p.LockoutFor
+	}
+	if failures <= p.FreeAttempts {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := p.FreeAttempts + 1; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// ThrottledError is returned while attempts are refused. RetryAfter is how
+// long the client has to wait.
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return ErrTooManyAttempts.Error()
+}
+
+func (e *ThrottledError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
+
+var (
+	ErrTooManyAttempts = errors.New("too many attempts")
+)
@@ -0,0 +1,48 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TrashType names a collection whose documents are soft deleted: a delete
+// only sets deleted_at, and the document stays restorable until it is purged.
+type TrashType string
+
+const (
+	TrashCourses       TrashType = "courses"
+	TrashClasses       TrashType = "classes"
+	TrashUsers         TrashType = "users"
+	TrashAssignments   TrashType = "assignments"
+	TrashAnnouncements TrashType = "announcements"
+)
+
+// TrashTypes lists the trash types in the order expired items are purged:
+// items that refer to others go before the items they refer to.
+var TrashTypes = []TrashType{TrashAnnouncements, TrashAssignments, TrashClasses, TrashCourses, TrashUsers}
+
+func (t TrashType) Valid() bool {
+	for _, v := range TrashTypes {
+		if t == v {
+			return true
+		}
+	}
+	return false
+}
+
+// TrashItem is a soft-deleted document as listed in the trash. PurgeAt is
+// when the retention period runs out and the item is removed for good.
+type TrashItem struct {
+	Type      TrashType          `json:"type"`
+	ID        primitive.ObjectID `json:"id"`
+	Title     string             `json:"title"`
+	DeletedAt time.Time          `json:"deleted_at"`
+	PurgeAt   time.Time          `json:"purge_at"`
+}
+
+var (
+	ErrInvalidTrashType  = errors.New("invalid trash type")
+	ErrTrashItemNotFound = errors.New("item not found in trash")
+)
@@ -13,14 +13,37 @@ const (
 	RoleAdmin 	Role = "admin"
 	RoleTeacher Role = "teacher"
 	RoleStudent Role = "student"
+	RoleGuardian Role = "guardian"
 )
 
 type User struct {
 	ID 		  primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Email 	  string 		   	 `bson:"email" json:"email"`
 	Password  string			 `bson:"password" json:"password"`
+	EmailVerified bool			 `bson:"email_verified" json:"email_verified"`
+	PendingEmail string			 `bson:"pending_email,omitempty" json:"pending_email,omitempty"`
+	PasswordHistory []string	 `bson:"password_history,omitempty" json:"-"`
 	Role 	  Role			 	 `bson:"role" json:"role"`
 	CreatedAt time.Time			 `bson:"created_at" json:"created_at"` 			
+	ArchivedAt *time.Time		 `bson:"archived_at,omitempty" json:"archived_at,omitempty"`
+	DeletedAt  *time.Time		 `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+
+	NotificationPreferences NotificationPreferences `bson:"notification_preferences" json:"notification_preferences"`
+	// CalendarTokenHash is the SHA-256 of the calendar feed token; the
+	// token itself is only shown when it is created.
+	CalendarTokenHash 		string 					`bson:"calendar_token_hash,omitempty" json:"-"`
+	MFA 					*MFASettings 			`bson:"mfa,omitempty" json:"-"`
+	// OIDCSubject links the account to an identity provider account, as
+	// "<issuer>|<subject>".
+	OIDCSubject 			string 					`bson:"oidc_subject,omitempty" json:"-"`
+	// OIDCLinkAllowed lets the next single sign-on with the account's
+	// email link it to the identity provider account.
+	OIDCLinkAllowed 		bool 					`bson:"oidc_link_allowed,omitempty" json:"oidc_link_allowed,omitempty"`
+	// ServiceAccount marks an account that only signs in with API tokens
+	// an admin created for it. It has no password.
+	ServiceAccount 			bool 					`bson:"service_account,omitempty" json:"service_account,omitempty"`
+
+	Profile `bson:",inline"`
 }
 
 var (
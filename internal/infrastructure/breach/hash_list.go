@@ -0,0 +1,70 @@
+package breach
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// prefixLength is how many hex digits of a SHA-1 hash select a range, as in
+// the Pwned Passwords range API.
+const prefixLength = 5
+
+// HashList is an offline list of SHA-1 hashes of compromised passwords,
+// grouped into ranges by hash prefix. Lookups only ever hand out a range, so
+// callers never need to reveal a full hash, the same k-anonymity model as
+// the online range API.
+type HashList struct {
+	ranges map[string][]string
+}
+
+// LoadHashList reads a file with one upper or lower case hex SHA-1 hash per
+// line, optionally followed by ":" and a count as in the Pwned Passwords
+// downloads. Blank lines and lines starting with "#" are skipped.
+func LoadHashList(path string) (*HashList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	l := &HashList{ranges: make(map[string][]string)}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		hash, _, _ := strings.Cut(text, ":")
+		hash = strings.ToUpper(hash)
+		if !isSHA1Hex(hash) {
+			return nil, fmt.Errorf("%s:%d: not a SHA-1 hash", path, line)
+		}
+		prefix := hash[:prefixLength]
+		l.ranges[prefix] = append(l.ranges[prefix], hash[prefixLength:])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Range returns the upper case hash suffixes in the list that start with
+// the given five digit prefix.
+func (l *HashList) Range(ctx context.Context, prefix string) ([]string, error) {
+	return l.ranges[strings.ToUpper(prefix)], nil
+}
+
+func isSHA1Hex(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'A' || r > 'F') {
+			return false
+		}
+	}
+	return true
+}
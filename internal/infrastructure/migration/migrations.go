@@ -0,0 +1,603 @@
+package migration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// All lists the schema migrations of the application. Append new versions
+// at the end and never change one that has been released.
+var All = []Migration{
+	{
+		Version:     1,
+		Description: "index lookup fields and make emails unique",
+		Up:          createLookupIndexes,
+	},
+	{
+		Version:     2,
+		Description: "text indexes for search",
+		Up:          createSearchIndexes,
+	},
+	{
+		Version:     3,
+		Description: "json schema validators",
+		Up:          addValidators,
+	},
+	{
+		Version:     4,
+		Description: "index deleted_at for the trash",
+		Up:          createTrashIndexes,
+	},
+	{
+		Version:     5,
+		Description: "index the audit log",
+		Up:          createAuditIndexes,
+	},
+	{
+		Version:     6,
+		Description: "expire login attempt counters",
+		Up:          createAttemptIndexes,
+	},
+	{
+		Version:     7,
+		Description: "single sign-on links and login flows",
+		Up:          createOIDCIndexes,
+	},
+	{
+		Version:     8,
+		Description: "email verification",
+		Up:          addEmailVerification,
+	},
+	{
+		Version:     9,
+		Description: "expire retired signing keys",
+		Up:          createSigningKeyIndexes,
+	},
+	{
+		Version:     10,
+		Description: "index api tokens",
+		Up:          createAPITokenIndexes,
+	},
+	{
+		Version:     11,
+		Description: "roles as permission sets",
+		Up:          addRoleDefinitions,
+	},
+	{
+		Version:     12,
+		Description: "guardian role and links",
+		Up:          addGuardians,
+	},
+	{
+		Version:     13,
+		Description: "user profiles",
+		Up:          addUserProfiles,
+	},
+	{
+		Version:     14,
+		Description: "admin impersonation",
+		Up:          addImpersonation,
+	},
+	{
+		Version:     15,
+		Description: "hash calendar feed tokens",
+		Up:          hashCalendarTokens,
+	},
+	{
+		Version:     16,
+		Description: "page progress by course and student",
+		Up:          createProgressPageIndex,
+	},
+	{
+		Version:     17,
+		Description: "notification broadcasts",
+		Up:          createBroadcastIndex,
+	},
+	{
+		Version:     18,
+		Description: "password reset tokens",
+		Up:          createPasswordResetIndexes,
+	},
+}
+
+func createLookupIndexes(ctx context.Context, db *mongo.Database) error {
+	indexes := map[string][]mongo.IndexModel{
+		"users": {
+			{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true)},
+		},
+		"courses": {
+			{Keys: bson.D{{Key: "assigned_teachers", Value: 1}}},
+			{Keys: bson.D{{Key: "term_id", Value: 1}}},
+		},
+		"classes": {
+			{Keys: bson.D{{Key: "student_ids", Value: 1}}},
+			{Keys: bson.D{{Key: "teacher_ids", Value: 1}}},
+			{Keys: bson.D{{Key: "course_id", Value: 1}}},
+			{Keys: bson.D{{Key: "term_id", Value: 1}}},
+		},
+		"messages": {
+			{Keys: bson.D{{Key: "receiver_ids", Value: 1}}},
+			{Keys: bson.D{{Key: "sender_id", Value: 1}}},
+		},
+		"announcements": {
+			{Keys: bson.D{{Key: "target_audience", Value: 1}, {Key: "target_id", Value: 1}}},
+			{Keys: bson.D{{Key: "created_at", Value: -1}}},
+		},
+		"assignments": {
+			{Keys: bson.D{{Key: "course_id", Value: 1}, {Key: "due_date", Value: 1}}},
+		},
+		"assessments": {
+			{Keys: bson.D{{Key: "course_id", Value: 1}, {Key: "date", Value: 1}}},
+		},
+		"submissions": {
+			{Keys: bson.D{{Key: "assignment_id", Value: 1}, {Key: "student_id", Value: 1}}},
+			{Keys: bson.D{{Key: "student_id", Value: 1}}},
+		},
+		"modules": {
+			{Keys: bson.D{{Key: "course_id", Value: 1}, {Key: "position", Value: 1}}},
+		},
+		"lessons": {
+			{Keys: bson.D{{Key: "module_id", Value: 1}, {Key: "position", Value: 1}}},
+			{Keys: bson.D{{Key: "course_id", Value: 1}}},
+		},
+		"progress": {
+			{Keys: bson.D{{Key: "student_id", Value: 1}, {Key: "course_id", Value: 1}}},
+			{Keys: bson.D{{Key: "course_id", Value: 1}}},
+		},
+		"class_sessions": {
+			{Keys: bson.D{{Key: "class_id", Value: 1}, {Key: "starts_at", Value: 1}}},
+		},
+		"attendance": {
+			{Keys: bson.D{{Key: "session_id", Value: 1}, {Key: "student_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "class_id", Value: 1}}},
+		},
+		"notifications": {
+			{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+		},
+		"terms": {
+			{Keys: bson.D{{Key: "start_date", Value: -1}}},
+		},
+	}
+
+	for name, models := range indexes {
+		if _, err := db.Collection(name).Indexes().CreateMany(ctx, models); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createSearchIndexes adds the text indexes used by the search endpoint. A
+// collection can only have one text index, so each gets a fixed name.
+func createSearchIndexes(ctx context.Context, db *mongo.Database) error {
+	indexes := []struct {
+		collection string
+		keys       bson.D
+		weights    bson.D
+	}{
+		{"courses", bson.D{{Key: "name", Value: "text"}, {Key: "description", Value: "text"}}, bson.D{{Key: "name", Value: 3}}},
+		{"lessons", bson.D{{Key: "title", Value: "text"}, {Key: "body", Value: "text"}}, bson.D{{Key: "title", Value: 3}}},
+		{"assignments", bson.D{{Key: "title", Value: "text"}, {Key: "description", Value: "text"}}, bson.D{{Key: "title", Value: 3}}},
+		{"announcements", bson.D{{Key: "title", Value: "text"}, {Key: "content", Value: "text"}}, bson.D{{Key: "title", Value: 3}}},
+		{"users", bson.D{{Key: "email", Value: "text"}}, nil},
+	}
+
+	for _, idx := range indexes {
+		opts := options.Index().SetName("search_text")
+		if idx.weights != nil {
+			opts.SetWeights(idx.weights)
+		}
+		model := mongo.IndexModel{Keys: idx.keys, Options: opts}
+		if _, err := db.Collection(idx.collection).Indexes().CreateOne(ctx, model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addValidators installs JSON schema validators on the main collections.
+// They only describe the fields every document must have, and the moderate
+// level leaves existing documents that do not match alone until updated.
+func addValidators(ctx context.Context, db *mongo.Database) error {
+	objectID := bson.M{"bsonType": "objectId"}
+	str := bson.M{"bsonType": "string"}
+	date := bson.M{"bsonType": "date"}
+	ids := bson.M{"bsonType": bson.A{"array", "null"}, "items": objectID}
+
+	schemas := map[string]bson.M{
+		"users": schema(bson.M{
+			"email":    str,
+			"password": str,
+			"role":     bson.M{"enum": bson.A{"admin", "teacher", "student"}},
+		}),
+		"courses": schema(bson.M{
+			"name":              str,
+			"assigned_teachers": ids,
+		}),
+		"classes": schema(bson.M{
+			"name":        str,
+			"course_id":   objectID,
+			"student_ids": ids,
+			"teacher_ids": ids,
+		}),
+		"assignments": schema(bson.M{
+			"title":     str,
+			"course_id": objectID,
+			"due_date":  date,
+		}),
+		"assessments": schema(bson.M{
+			"title":     str,
+			"course_id": objectID,
+			"date":      date,
+		}),
+		"submissions": schema(bson.M{
+			"assignment_id": objectID,
+			"student_id":    objectID,
+			"submitted_at":  date,
+		}),
+		"messages": schema(bson.M{
+			"sender_id":    objectID,
+			"receiver_ids": ids,
+			"content":      str,
+		}),
+		"announcements": schema(bson.M{
+			"title":           str,
+			"content":         str,
+			"target_audience": str,
+		}),
+		"terms": schema(bson.M{
+			"name":       str,
+			"start_date": date,
+			"end_date":   date,
+		}),
+	}
+
+	for name, s := range schemas {
+		if err := setValidator(ctx, db, name, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createTrashIndexes indexes deleted_at on the soft-deletable collections.
+// The index is sparse, so it only holds the documents in the trash.
+func createTrashIndexes(ctx context.Context, db *mongo.Database) error {
+	model := mongo.IndexModel{
+		Keys:    bson.D{{Key: "deleted_at", Value: 1}},
+		Options: options.Index().SetSparse(true),
+	}
+	for _, name := range []string{"courses", "classes", "users", "assignments", "announcements"} {
+		if _, err := db.Collection(name).Indexes().CreateOne(ctx, model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createAuditIndexes supports the filters of the audit log listing, which is
+// sorted by time within each of them.
+func createAuditIndexes(ctx context.Context, db *mongo.Database) error {
+	var models []mongo.IndexModel
+	for _, field := range []string{"action", "actor_id", "target_id", "subject_id"} {
+		models = append(models, mongo.IndexModel{Keys: bson.D{{Key: field, Value: 1}, {Key: "created_at", Value: -1}}})
+	}
+	models = append(models, mongo.IndexModel{Keys: bson.D{{Key: "created_at", Value: -1}}})
+
+	_, err := db.Collection("audit_log").Indexes().CreateMany(ctx, models)
+	return err
+}
+
+// createAttemptIndexes lets MongoDB drop attempt counters once their window
+// or lockout has passed.
+func createAttemptIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("login_attempts").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// createOIDCIndexes makes an identity provider account link to at most one
+// user and expires single sign-on logins that were never completed.
+func createOIDCIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("users").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "oidc_subject", Value: 1}},
+		Options: options.Index().SetUnique(true).SetSparse(true),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = db.Collection("oidc_flows").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// addEmailVerification expires unused verification tokens and marks the
+// accounts that existed before verification was introduced as verified, so
+// that they can still sign in.
+func addEmailVerification(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("email_verifications").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = db.Collection("users").UpdateMany(ctx,
+		bson.M{"email_verified": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"email_verified": true}},
+	)
+	return err
+}
+
+// createSigningKeyIndexes removes signing keys once they may no longer
+// verify tokens. Keys without expires_at are still signing and are kept.
+func createSigningKeyIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("signing_keys").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// createAPITokenIndexes looks tokens up by hash and drops expired ones a
+// month after they expire, so that their owners still see why they stopped
+// working for a while.
+func createAPITokenIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("api_tokens").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(30 * 24 * 60 * 60)},
+	})
+	return err
+}
+
+// defaultRoles are the roles seeded by addRoleDefinitions. The first three
+// are the roles the application had before roles could be defined.
+var defaultRoles = []struct {
+	name        string
+	description string
+	builtin     bool
+	permissions bson.A
+}{
+	{"admin", "Manages users, courses, classes and settings", true, bson.A{
+		"users.read", "users.manage", "roles.manage", "settings.manage", "audit.read",
+		"courses.read", "courses.write", "courses.delete", "terms.write", "terms.delete",
+		"classes.read", "classes.write", "enrollment.write", "classes.delete",
+		"announcements.write", "announcements.delete", "trash.manage",
+	}},
+	{"teacher", "Teaches the courses and classes assigned to them", true, bson.A{
+		"teaching.view", "content.write", "content.delete", "assignments.write",
+		"grades.read", "grades.write", "attendance.read", "attendance.write", "attendance.delete",
+		"messages.write",
+	}},
+	{"student", "Takes part in the classes they are enrolled in", true, bson.A{
+		"learning.participate",
+	}},
+	{"teaching_assistant", "Grades and takes attendance in assigned classes, cannot delete", false, bson.A{
+		"teaching.view", "grades.read", "grades.write", "attendance.read", "attendance.write",
+		"messages.write",
+	}},
+	{"registrar", "Enrolls students in classes", false, bson.A{
+		"users.read", "courses.read", "classes.read", "enrollment.write",
+	}},
+}
+
+// addRoleDefinitions seeds the default roles, leaving alone any that exist
+// already, and lets users have roles other than the three original ones.
+func addRoleDefinitions(ctx context.Context, db *mongo.Database) error {
+	err := setValidator(ctx, db, "users", schema(bson.M{
+		"email":    bson.M{"bsonType": "string"},
+		"password": bson.M{"bsonType": "string"},
+		"role":     bson.M{"bsonType": "string"},
+	}))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	roles := db.Collection("roles")
+	for _, r := range defaultRoles {
+		_, err := roles.UpdateOne(ctx,
+			bson.M{"_id": r.name},
+			bson.M{"$setOnInsert": bson.M{
+				"description": r.description,
+				"permissions": r.permissions,
+				"builtin":     r.builtin,
+				"created_at":  now,
+				"updated_at":  now,
+			}},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addGuardians seeds the guardian role, makes a guardian linkable to a
+// student only once and expires invitations that were never accepted.
+func addGuardians(ctx context.Context, db *mongo.Database) error {
+	now := time.Now()
+	_, err := db.Collection("roles").UpdateOne(ctx,
+		bson.M{"_id": "guardian"},
+		bson.M{"$setOnInsert": bson.M{
+			"description": "Follows the students linked to them, read-only",
+			"permissions": bson.A{"guardian.view"},
+			"builtin":     true,
+			"created_at":  now,
+			"updated_at":  now,
+		}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Collection("guardian_links").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "student_id", Value: 1}, {Key: "guardian_id", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"guardian_id": bson.M{"$exists": true}}),
+		},
+		{Keys: bson.D{{Key: "guardian_id", Value: 1}, {Key: "status", Value: 1}}},
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
+
+// addUserProfiles gives existing users the empty sort name of users without
+// a name, so that sorting by name pages through them, and keeps student
+// numbers unique among the users that have one.
+func addUserProfiles(ctx context.Context, db *mongo.Database) error {
+	users := db.Collection("users")
+	_, err := users.UpdateMany(ctx,
+		bson.M{"sort_name": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"sort_name": ""}},
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = users.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "sort_name", Value: 1}, {Key: "_id", Value: 1}}},
+		{
+			Keys: bson.D{{Key: "student_number", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"student_number": bson.M{"$exists": true}}),
+		},
+	})
+	return err
+}
+
+// addImpersonation lets the admin role impersonate users and indexes the
+// sessions by admin and by impersonated user.
+func addImpersonation(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("roles").UpdateOne(ctx,
+		bson.M{"_id": "admin"},
+		bson.M{
+			"$addToSet": bson.M{"permissions": "users.impersonate"},
+			"$set":      bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Collection("impersonation_sessions").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "started_at", Value: -1}}},
+		{Keys: bson.D{{Key: "actor_id", Value: 1}, {Key: "started_at", Value: -1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "started_at", Value: -1}}},
+	})
+	return err
+}
+
+// hashCalendarTokens replaces the calendar feed tokens stored on users with
+// their SHA-256, so that feed URLs already handed out keep working.
+func hashCalendarTokens(ctx context.Context, db *mongo.Database) error {
+	users := db.Collection("users")
+	cursor, err := users.Find(ctx, bson.M{"calendar_token": bson.M{"$exists": true}},
+		options.Find().SetProjection(bson.M{"calendar_token": 1}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var u struct {
+			ID    interface{} `bson:"_id"`
+			Token string      `bson:"calendar_token"`
+		}
+		if err := cursor.Decode(&u); err != nil {
+			return err
+		}
+		sum := sha256.Sum256([]byte(u.Token))
+		_, err := users.UpdateOne(ctx, bson.M{"_id": u.ID}, bson.M{
+			"$set":   bson.M{"calendar_token_hash": hex.EncodeToString(sum[:])},
+			"$unset": bson.M{"calendar_token": ""},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	_, err = users.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "calendar_token_hash", Value: 1}},
+		Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{"calendar_token_hash": bson.M{"$exists": true}}),
+	})
+	return err
+}
+
+// createProgressPageIndex serves the class progress dashboard, which pages
+// through the records of a course in student order.
+func createProgressPageIndex(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("progress").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "course_id", Value: 1}, {Key: "student_id", Value: 1}, {Key: "_id", Value: 1}},
+	})
+	return err
+}
+
+// createBroadcastIndex lets a broadcast queue each user's notification only
+// once, however often its expansion is retried.
+func createBroadcastIndex(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("notifications").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "broadcast_id", Value: 1}, {Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{"broadcast_id": bson.M{"$exists": true}}),
+	})
+	return err
+}
+
+// createPasswordResetIndexes expires unused reset tokens and indexes them
+// by user, whose earlier tokens are dropped when a new one is sent.
+func createPasswordResetIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("password_resets").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	})
+	return err
+}
+
+// schema builds a $jsonSchema validator that requires every listed field.
+func schema(properties bson.M) bson.M {
+	required := make(bson.A, 0, len(properties))
+	for field := range properties {
+		required = append(required, field)
+	}
+	return bson.M{"$jsonSchema": bson.M{
+		"bsonType":   "object",
+		"required":   required,
+		"properties": properties,
+	}}
+}
+
+func setValidator(ctx context.Context, db *mongo.Database, collection string, validator bson.M) error {
+	err := db.RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: collection},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: "moderate"},
+	}).Err()
+
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == 26 { // NamespaceNotFound
+		opts := options.CreateCollection().SetValidator(validator).SetValidationLevel("moderate")
+		return db.CreateCollection(ctx, collection, opts)
+	}
+	return err
+}
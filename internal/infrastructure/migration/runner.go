@@ -0,0 +1,140 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Migration is one versioned change to the database. Up must be safe to run
+// again after a partial failure, since a version is only recorded once Up
+// has returned without error.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+}
+
+// Record is the entry written to schema_migrations for an applied version.
+type Record struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
+
+// Locker keeps two instances from migrating at the same time.
+type Locker interface {
+	TryAcquire(ctx context.Context) (bool, error)
+	Release(ctx context.Context) error
+}
+
+type Runner struct {
+	db         *mongo.Database
+	records    *mongo.Collection
+	lock       Locker
+	migrations []Migration
+}
+
+func NewRunner(db *mongo.Database, lock Locker, migrations []Migration) *Runner {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	return &Runner{
+		db:         db,
+		records:    db.Collection("schema_migrations"),
+		lock:       lock,
+		migrations: sorted,
+	}
+}
+
+// Applied returns the recorded versions in ascending order.
+func (r *Runner) Applied(ctx context.Context) ([]Record, error) {
+	cursor, err := r.records.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []Record
+	for cursor.Next(ctx) {
+		var rec Record
+		if err := cursor.Decode(&rec); err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+	return records, cursor.Err()
+}
+
+// Pending returns the migrations that have not been applied yet.
+func (r *Runner) Pending(ctx context.Context) ([]Migration, error) {
+	records, err := r.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int]bool, len(records))
+	for _, rec := range records {
+		applied[rec.Version] = true
+	}
+
+	var pending []Migration
+	for _, m := range r.migrations {
+		if !applied[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies every pending migration in version order and returns the ones
+// it applied. It waits for the lock when another instance is migrating.
+func (r *Runner) Up(ctx context.Context) ([]Migration, error) {
+	if err := r.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer r.lock.Release(context.Background())
+
+	pending, err := r.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range pending {
+		if err := m.Up(ctx, r.db); err != nil {
+			return done, fmt.Errorf("migration %d (%s): %w", m.Version, m.Description, err)
+		}
+		rec := Record{Version: m.Version, Description: m.Description, AppliedAt: time.Now().UTC()}
+		if _, err := r.records.InsertOne(ctx, rec); err != nil {
+			return done, fmt.Errorf("record migration %d: %w", m.Version, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+func (r *Runner) acquire(ctx context.Context) error {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		ok, err := r.lock.TryAcquire(ctx)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return errors.New("timed out waiting for the migration lock")
+		case <-ticker.C:
+		}
+	}
+}
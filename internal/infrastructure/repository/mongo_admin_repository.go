@@ -37,6 +37,7 @@ func NewMongoAnnouncementRepository(c *mongo.Collection) *MongoAnnouncementRepos
 // --- Course ---
 func (r *MongoCourseRepository) CreateCourse(ctx context.Context, course *entity.Course) error {
 	course.CreatedAt = course.CreatedAt.UTC()
+	course.DeletedAt = nil
 	_, err := r.collection.InsertOne(ctx, course)
 	return err
 }
@@ -49,7 +50,7 @@ func (r *MongoCourseRepository) GetCourse(ctx context.Context, id string) (*enti
 
 	var course entity.Course
 
-	err = r.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&course)
+	err = r.collection.FindOne(ctx, notDeleted(bson.M{"_id": oid})).Decode(&course)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, entity.ErrCourseNotFound
@@ -66,13 +67,17 @@ func (r *MongoCourseRepository) UpdateCourse(ctx context.Context, course *entity
 		return errors.New("course id required")
 	}
 
-	filter := bson.M{"_id": course.ID}
-	update := bson.M{
-		"$set": bson.M{
-			"name": 		   	 course.Name,
-			"description":    	 course.Description,
-			"assigned_teachers": course.AssignedTeacher,
-		},
+	filter := notDeleted(bson.M{"_id": course.ID})
+	set := bson.M{
+		"name": 		   	 course.Name,
+		"description":    	 course.Description,
+		"assigned_teachers": course.AssignedTeacher,
+	}
+	update := bson.M{"$set": set}
+	if course.ClearTerm {
+		update["$unset"] = bson.M{"term_id": ""}
+	} else if !course.TermID.IsZero() {
+		set["term_id"] = course.TermID
 	}
 
 	res, err := r.collection.UpdateOne(ctx, filter, update)
@@ -81,54 +86,52 @@ func (r *MongoCourseRepository) UpdateCourse(ctx context.Context, course *entity
 	}
 
 	if res.MatchedCount == 0 {
-		return errors.New("course not found")
+		return entity.ErrCourseNotFound
 	}
 
 	return nil
 }
 
+// DeleteCourse moves the course to the trash. It is only removed for good
+// by MongoTrashRepository.Purge.
 func (r *MongoCourseRepository) DeleteCourse(ctx context.Context, id string) error {
-	oid, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return err
-	}
-
-	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": oid})
-	if err != nil {
-		return err
-	}
+	return softDelete(ctx, r.collection, id, entity.ErrCourseNotFound)
+}
 
-	if res.DeletedCount == 0 {
-		return errors.New("course not found")
-	}
+var courseListSpec = listSpec{
+	sorts: map[string]string{
+		"name":       "name",
+		"created_at": "created_at",
+	},
+	filters: map[string]filterField{
+		"term_id":          {field: "term_id", objectID: true},
+		"term_id_or_unset": {field: "term_id", objectID: true, orUnset: true},
+		"teacher_id":       {field: "assigned_teachers", objectID: true},
+		"archived":         {field: "archived_at", presence: true},
+	},
+}
 
-	return nil
+func (r *MongoCourseRepository) ListCourses(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.Course], error) {
+	return findPage[entity.Course](ctx, r.collection, notDeleted(bson.M{}), opts, courseListSpec)
 }
 
-func (r *MongoCourseRepository) ListCourses(ctx context.Context) ([]*entity.Course, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{})
+func (r *MongoCourseRepository) UpdateCompletionCriteria(ctx context.Context, courseID primitive.ObjectID, criteria entity.CompletionCriteria) error {
+	res, err := r.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": courseID}), bson.M{"$set": bson.M{"completion_criteria": criteria}})
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer cursor.Close(ctx)
 
-	var courses []*entity.Course
-
-	for cursor.Next(ctx) {
-		var course entity.Course
-
-		if err := cursor.Decode(&course); err != nil {
-			return nil, err
-		}
-		courses = append(courses, &course)
+	if res.MatchedCount == 0 {
+		return entity.ErrCourseNotFound
 	}
 
-	return courses, cursor.Err()
+	return nil
 }
 
 // --- Class ---
 func (r *MongoClassRepository) CreateClass(ctx context.Context, class *entity.Class) error {
 	class.CreatedAt = class.CreatedAt.UTC()
+	class.DeletedAt = nil
 	_, err := r.collection.InsertOne(ctx, class)
 	return err
 }
@@ -141,10 +144,10 @@ func (r *MongoClassRepository) GetClass(ctx context.Context, id string) (*entity
 
 	var class entity.Class
 
-	err = r.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&class)
+	err = r.collection.FindOne(ctx, notDeleted(bson.M{"_id": oid})).Decode(&class)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("class not found")
+			return nil, entity.ErrClassNotFound
 		}
 
 		return nil, err
@@ -158,13 +161,17 @@ func (r *MongoClassRepository) UpdateClass(ctx context.Context, class *entity.Cl
 		return errors.New("class id required")
 	}
 
-	filter := bson.M{"_id": class.ID}
-	update := bson.M{
-		"$set": bson.M{
-			"name":        class.Name,
-			"student_ids": class.StudentIDs,
-			"teacher_ids": class.TeacherIDs,
-		},
+	filter := notDeleted(bson.M{"_id": class.ID})
+	set := bson.M{
+		"name":        class.Name,
+		"student_ids": class.StudentIDs,
+		"teacher_ids": class.TeacherIDs,
+	}
+	update := bson.M{"$set": set}
+	if class.ClearTerm {
+		update["$unset"] = bson.M{"term_id": ""}
+	} else if !class.TermID.IsZero() {
+		set["term_id"] = class.TermID
 	}
 
 	res, err := r.collection.UpdateOne(ctx, filter, update)
@@ -173,50 +180,38 @@ func (r *MongoClassRepository) UpdateClass(ctx context.Context, class *entity.Cl
 	}
 
 	if res.MatchedCount == 0 {
-		return errors.New("class not found")
+		return entity.ErrClassNotFound
 	}
 
 	return nil
 }
 
+// DeleteClass moves the class to the trash.
 func (r *MongoClassRepository) DeleteClass(ctx context.Context, id string) error {
-	oid, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return err
-	}
-
-	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": oid})
-	if err != nil {
-		return err
-	}
-
-	if res.DeletedCount == 0 {
-		return errors.New("class not found")
-	}
-
-	return nil
+	return softDelete(ctx, r.collection, id, entity.ErrClassNotFound)
 }
 
-func (r *MongoClassRepository) ListClasses(ctx context.Context) ([]*entity.Class, error) {
-	cursor, err := r.collection.Find(ctx, bson.D{})
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(ctx)
+var classListSpec = listSpec{
+	sorts: map[string]string{
+		"name":       "name",
+		"created_at": "created_at",
+	},
+	filters: map[string]filterField{
+		"term_id":          {field: "term_id", objectID: true},
+		"term_id_or_unset": {field: "term_id", objectID: true, orUnset: true},
+		"course_id":        {field: "course_id", objectID: true},
+		"teacher_id":       {field: "teacher_ids", objectID: true},
+		"student_id":       {field: "student_ids", objectID: true},
+		"archived":         {field: "archived_at", presence: true},
+	},
+}
 
-	var classes []*entity.Class
-	for cursor.Next(ctx) {
-		var class entity.Class
-		if err := cursor.Decode(&class); err != nil {
-			return nil, err
-		}
-		classes = append(classes, &class)
-	}
-	return classes, cursor.Err()
+func (r *MongoClassRepository) ListClasses(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.Class], error) {
+	return findPage[entity.Class](ctx, r.collection, notDeleted(bson.M{}), opts, classListSpec)
 }
 
 func (r *MongoClassRepository) ListClassesByStudent(ctx context.Context, studentID primitive.ObjectID) ([]*entity.Class, error) {
-	filter := bson.M{"student_ids": studentID}
+	filter := notDeleted(bson.M{"student_ids": studentID})
 	cursor, err := r.collection.Find(ctx, filter)
 	if err != nil {
 		return nil, err
@@ -240,6 +235,7 @@ func (r *MongoClassRepository) ListClassesByStudent(ctx context.Context, student
 // --- Announcement ---
 func (r *MongoAnnouncementRepository) CreateAnnouncement(ctx context.Context, ann *entity.Announcement) error {
 	ann.CreatedAt = ann.CreatedAt.UTC()
+	ann.DeletedAt = nil
 	_, err := r.collection.InsertOne(ctx, ann)
 	return err
 }
@@ -250,10 +246,10 @@ func (r *MongoAnnouncementRepository) GetAnnouncement(ctx context.Context, id st
 		return nil, err
 	}
 	var ann entity.Announcement
-	err = r.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&ann)
+	err = r.collection.FindOne(ctx, notDeleted(bson.M{"_id": oid})).Decode(&ann)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("announcement not found")
+			return nil, entity.ErrAnnouncementNotFound
 		}
 		return nil, err
 	}
@@ -264,7 +260,7 @@ func (r *MongoAnnouncementRepository) UpdateAnnouncement(ctx context.Context, an
 	if ann.ID.IsZero() {
 		return errors.New("announcement id required")
 	}
-	filter := bson.M{"_id": ann.ID}
+	filter := notDeleted(bson.M{"_id": ann.ID})
 	update := bson.M{
 		"$set": bson.M{
 			"title":           ann.Title,
@@ -278,40 +274,40 @@ func (r *MongoAnnouncementRepository) UpdateAnnouncement(ctx context.Context, an
 		return err
 	}
 	if res.MatchedCount == 0 {
-		return errors.New("announcement not found")
+		return entity.ErrAnnouncementNotFound
 	}
 	return nil
 }
 
+// DeleteAnnouncement moves the announcement to the trash.
 func (r *MongoAnnouncementRepository) DeleteAnnouncement(ctx context.Context, id string) error {
-	oid, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return err
-	}
-	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": oid})
-	if err != nil {
-		return err
-	}
-	if res.DeletedCount == 0 {
-		return errors.New("announcement not found")
-	}
-	return nil
+	return softDelete(ctx, r.collection, id, entity.ErrAnnouncementNotFound)
 }
 
-func (r *MongoAnnouncementRepository) ListAnnouncements(ctx context.Context) ([]*entity.Announcement, error) {
-	cursor, err := r.collection.Find(ctx, bson.D{})
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(ctx)
+var announcementListSpec = listSpec{
+	defaultSort: "-created_at",
+	sorts: map[string]string{
+		"title":      "title",
+		"created_at": "created_at",
+	},
+	filters: map[string]filterField{
+		"target_audience": {field: "target_audience"},
+		"target_id":       {field: "target_id", objectID: true},
+		"archived":        {field: "archived_at", presence: true},
+	},
+}
 
-	var anns []*entity.Announcement
-	for cursor.Next(ctx) {
-		var ann entity.Announcement
-		if err := cursor.Decode(&ann); err != nil {
-			return nil, err
-		}
-		anns = append(anns, &ann)
-	}
-	return anns, cursor.Err()
+func (r *MongoAnnouncementRepository) ListAnnouncements(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.Announcement], error) {
+	return findPage[entity.Announcement](ctx, r.collection, notDeleted(bson.M{}), opts, announcementListSpec)
+}
+
+// ListAnnouncementsForAudience lists the announcements addressed to
+// everyone or to one of the given courses or classes.
+func (r *MongoAnnouncementRepository) ListAnnouncementsForAudience(ctx context.Context, courseIDs, classIDs []primitive.ObjectID, opts entity.ListOptions) (*entity.Page[*entity.Announcement], error) {
+	base := notDeleted(bson.M{"$or": bson.A{
+		bson.M{"target_audience": entity.AudienceAll},
+		bson.M{"target_audience": entity.AudienceCourse, "target_id": bson.M{"$in": nonNil(courseIDs)}},
+		bson.M{"target_audience": entity.AudienceClass, "target_id": bson.M{"$in": nonNil(classIDs)}},
+	}})
+	return findPage[entity.Announcement](ctx, r.collection, base, opts, announcementListSpec)
 }
\ No newline at end of file
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoAPITokenRepository stores personal API tokens by the hash of their
+// secret. Expired tokens are removed by a TTL index some time after they
+// expire.
+type MongoAPITokenRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoAPITokenRepository(c *mongo.Collection) *MongoAPITokenRepository {
+	return &MongoAPITokenRepository{collection: c}
+}
+
+func (r *MongoAPITokenRepository) CreateAPIToken(ctx context.Context, token *entity.APIToken) error {
+	_, err := r.collection.InsertOne(ctx, token)
+	return err
+}
+
+// FindAPITokenByHash returns the unexpired token whose secret has the given
+// hash.
+func (r *MongoAPITokenRepository) FindAPITokenByHash(ctx context.Context, hash string, now time.Time) (*entity.APIToken, error) {
+	filter := bson.M{
+		"hash": hash,
+		"$or": bson.A{
+			bson.M{"expires_at": bson.M{"$exists": false}},
+			bson.M{"expires_at": bson.M{"$gt": now}},
+		},
+	}
+	var token entity.APIToken
+	err := r.collection.FindOne(ctx, filter).Decode(&token)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, entity.ErrAPITokenNotFound
+	}
+	return &token, err
+}
+
+// ListAPITokens returns the tokens of a user, newest first.
+func (r *MongoAPITokenRepository) ListAPITokens(ctx context.Context, userID string) ([]*entity.APIToken, error) {
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, entity.ErrUserNotFound
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": oid}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	tokens := []*entity.APIToken{}
+	if err := cursor.All(ctx, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// DeleteAPIToken revokes a token of the given user.
+func (r *MongoAPITokenRepository) DeleteAPIToken(ctx context.Context, userID, tokenID string) error {
+	uid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return entity.ErrAPITokenNotFound
+	}
+	tid, err := primitive.ObjectIDFromHex(tokenID)
+	if err != nil {
+		return entity.ErrAPITokenNotFound
+	}
+
+	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": tid, "user_id": uid})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return entity.ErrAPITokenNotFound
+	}
+	return nil
+}
+
+func (r *MongoAPITokenRepository) TouchAPIToken(ctx context.Context, tokenID primitive.ObjectID, at time.Time) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": tokenID}, bson.M{"$set": bson.M{"last_used_at": at}})
+	return err
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoAttemptRepository keeps the failed attempt counters shared by all
+// instances. Documents expire through a TTL index on expires_at.
+type MongoAttemptRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoAttemptRepository(c *mongo.Collection) *MongoAttemptRepository {
+	return &MongoAttemptRepository{collection: c}
+}
+
+// GetAttempts returns the counter for key, or nil when there is none.
+func (r *MongoAttemptRepository) GetAttempts(ctx context.Context, key string) (*entity.LoginAttempts, error) {
+	var a entity.LoginAttempts
+	err := r.collection.FindOne(ctx, bson.M{"_id": key}).Decode(&a)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// RecordFailure adds a failure to the counter for key and returns the new
+// count. A counter whose window has passed starts over at one. The update
+// is a single pipeline so that concurrent failures are all counted.
+func (r *MongoAttemptRepository) RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration) (int, error) {
+	now = now.UTC()
+	live := bson.M{"$gt": bson.A{"$expires_at", now}}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"failures":   bson.M{"$cond": bson.A{live, bson.M{"$add": bson.A{"$failures", 1}}, 1}},
+		"expires_at": bson.M{"$max": bson.A{"$expires_at", now.Add(window)}},
+	}}}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var a entity.LoginAttempts
+	if err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&a); err != nil {
+		return 0, err
+	}
+	return a.Failures, nil
+}
+
+// Block refuses attempts for key until the given time. The counter is kept
+// at least that long.
+func (r *MongoAttemptRepository) Block(ctx context.Context, key string, until time.Time) error {
+	until = until.UTC()
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": key}, bson.M{"$max": bson.M{
+		"blocked_until": until,
+		"expires_at":    until,
+	}})
+	return err
+}
+
+func (r *MongoAttemptRepository) ResetAttempts(ctx context.Context, key string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoSessionRepository struct {
+	collection *mongo.Collection
+}
+
+type MongoAttendanceRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoSessionRepository(c *mongo.Collection) *MongoSessionRepository {
+	return &MongoSessionRepository{collection: c}
+}
+
+func NewMongoAttendanceRepository(c *mongo.Collection) *MongoAttendanceRepository {
+	return &MongoAttendanceRepository{collection: c}
+}
+
+// --- Session ---
+func (r *MongoSessionRepository) CreateSession(ctx context.Context, s *entity.ClassSession) error {
+	res, err := r.collection.InsertOne(ctx, s)
+	if err != nil {
+		return err
+	}
+	if oid, ok := res.InsertedID.(primitive.ObjectID); ok {
+		s.ID = oid
+	}
+	return nil
+}
+
+func (r *MongoSessionRepository) GetSession(ctx context.Context, id string) (*entity.ClassSession, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, entity.ErrSessionNotFound
+	}
+	var s entity.ClassSession
+	err = r.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&s)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, entity.ErrSessionNotFound
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *MongoSessionRepository) UpdateSession(ctx context.Context, s *entity.ClassSession) error {
+	update := bson.M{
+		"$set": bson.M{
+			"title":     s.Title,
+			"starts_at": s.StartsAt,
+			"ends_at":   s.EndsAt,
+		},
+	}
+	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": s.ID}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return entity.ErrSessionNotFound
+	}
+	return nil
+}
+
+func (r *MongoSessionRepository) DeleteSession(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return entity.ErrSessionNotFound
+	}
+	return nil
+}
+
+func (r *MongoSessionRepository) SetCheckInCode(ctx context.Context, id primitive.ObjectID, code string, expiresAt time.Time) error {
+	update := bson.M{
+		"$set": bson.M{
+			"check_in_code":       code,
+			"check_in_expires_at": expiresAt.UTC(),
+		},
+	}
+	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return entity.ErrSessionNotFound
+	}
+	return nil
+}
+
+func (r *MongoSessionRepository) ListSessionsByClass(ctx context.Context, classID primitive.ObjectID) ([]*entity.ClassSession, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "starts_at", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"class_id": classID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var sessions []*entity.ClassSession
+	for cursor.Next(ctx) {
+		var s entity.ClassSession
+		if err := cursor.Decode(&s); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, &s)
+	}
+	return sessions, cursor.Err()
+}
+
+// --- Attendance ---
+func attendanceKey(a *entity.AttendanceRecord) bson.M {
+	return bson.M{
+		"session_id": a.SessionID,
+		"student_id": a.StudentID,
+	}
+}
+
+func attendanceFields(a *entity.AttendanceRecord) bson.M {
+	return bson.M{
+		"class_id":      a.ClassID,
+		"status":        a.Status,
+		"self_check_in": a.SelfCheckIn,
+		"recorded_by":   a.RecordedBy,
+		"recorded_at":   a.RecordedAt.UTC(),
+	}
+}
+
+// SaveAttendance creates or overwrites the student's record for the session.
+func (r *MongoAttendanceRepository) SaveAttendance(ctx context.Context, a *entity.AttendanceRecord) error {
+	_, err := r.collection.UpdateOne(ctx, attendanceKey(a), bson.M{"$set": attendanceFields(a)}, options.Update().SetUpsert(true))
+	return err
+}
+
+// SaveAttendanceIfAbsent creates the record only when the student has none
+// for the session yet, so a check-in never overrides what a teacher recorded.
+func (r *MongoAttendanceRepository) SaveAttendanceIfAbsent(ctx context.Context, a *entity.AttendanceRecord) error {
+	_, err := r.collection.UpdateOne(ctx, attendanceKey(a), bson.M{"$setOnInsert": attendanceFields(a)}, options.Update().SetUpsert(true))
+	return err
+}
+
+func (r *MongoAttendanceRepository) ListAttendanceBySession(ctx context.Context, sessionID primitive.ObjectID) ([]*entity.AttendanceRecord, error) {
+	return r.find(ctx, bson.M{"session_id": sessionID})
+}
+
+func (r *MongoAttendanceRepository) ListAttendanceByClass(ctx context.Context, classID primitive.ObjectID) ([]*entity.AttendanceRecord, error) {
+	return r.find(ctx, bson.M{"class_id": classID})
+}
+
+func (r *MongoAttendanceRepository) DeleteAttendanceBySession(ctx context.Context, sessionID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"session_id": sessionID})
+	return err
+}
+
+func (r *MongoAttendanceRepository) find(ctx context.Context, filter bson.M) ([]*entity.AttendanceRecord, error) {
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []*entity.AttendanceRecord
+	for cursor.Next(ctx) {
+		var a entity.AttendanceRecord
+		if err := cursor.Decode(&a); err != nil {
+			return nil, err
+		}
+		records = append(records, &a)
+	}
+	return records, cursor.Err()
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MongoAuditRepository stores the audit log. It has no way to change or
+// remove an entry once written.
+type MongoAuditRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoAuditRepository(c *mongo.Collection) *MongoAuditRepository {
+	return &MongoAuditRepository{collection: c}
+}
+
+func (r *MongoAuditRepository) AppendAudit(ctx context.Context, e *entity.AuditEntry) error {
+	e.CreatedAt = e.CreatedAt.UTC()
+	_, err := r.collection.InsertOne(ctx, e)
+	return err
+}
+
+var auditListSpec = listSpec{
+	defaultSort: "-created_at",
+	sorts: map[string]string{
+		"created_at": "created_at",
+	},
+	filters: map[string]filterField{
+		"action":       {field: "action"},
+		"actor_id":     {field: "actor_id", objectID: true},
+		"target_type":  {field: "target_type"},
+		"target_id":    {field: "target_id", objectID: true},
+		"subject_id":   {field: "subject_id", objectID: true},
+		"on_behalf_of": {field: "on_behalf_of", objectID: true},
+		"from":         {field: "created_at", timeOp: "$gte"},
+		"to":           {field: "created_at", timeOp: "$lt"},
+	},
+}
+
+func (r *MongoAuditRepository) ListAudit(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.AuditEntry], error) {
+	return findPage[entity.AuditEntry](ctx, r.collection, nil, opts, auditListSpec)
+}
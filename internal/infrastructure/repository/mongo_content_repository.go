@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoModuleRepository struct {
+	collection *mongo.Collection
+}
+
+type MongoLessonRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoModuleRepository(c *mongo.Collection) *MongoModuleRepository {
+	return &MongoModuleRepository{collection: c}
+}
+
+func NewMongoLessonRepository(c *mongo.Collection) *MongoLessonRepository {
+	return &MongoLessonRepository{collection: c}
+}
+
+var byPosition = options.Find().SetSort(bson.D{{Key: "position", Value: 1}, {Key: "_id", Value: 1}})
+
+// --- Module ---
+func (r *MongoModuleRepository) CreateModule(ctx context.Context, m *entity.Module) error {
+	m.CreatedAt = m.CreatedAt.UTC()
+	res, err := r.collection.InsertOne(ctx, m)
+	if err != nil {
+		return err
+	}
+	if oid, ok := res.InsertedID.(primitive.ObjectID); ok {
+		m.ID = oid
+	}
+	return nil
+}
+
+func (r *MongoModuleRepository) GetModule(ctx context.Context, id string) (*entity.Module, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, entity.ErrModuleNotFound
+	}
+	var m entity.Module
+	err = r.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&m)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, entity.ErrModuleNotFound
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *MongoModuleRepository) UpdateModule(ctx context.Context, m *entity.Module) error {
+	if m.ID.IsZero() {
+		return errors.New("module id required")
+	}
+	filter := bson.M{"_id": m.ID}
+	update := bson.M{
+		"$set": bson.M{
+			"title":      m.Title,
+			"position":   m.Position,
+			"updated_at": m.UpdatedAt,
+		},
+	}
+	res, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return entity.ErrModuleNotFound
+	}
+	return nil
+}
+
+func (r *MongoModuleRepository) DeleteModule(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return entity.ErrModuleNotFound
+	}
+	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return entity.ErrModuleNotFound
+	}
+	return nil
+}
+
+func (r *MongoModuleRepository) ListModulesByCourse(ctx context.Context, courseID primitive.ObjectID) ([]*entity.Module, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"course_id": courseID}, byPosition)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var modules []*entity.Module
+	for cursor.Next(ctx) {
+		var m entity.Module
+		if err := cursor.Decode(&m); err != nil {
+			return nil, err
+		}
+		modules = append(modules, &m)
+	}
+	return modules, cursor.Err()
+}
+
+// --- Lesson ---
+func (r *MongoLessonRepository) CreateLesson(ctx context.Context, l *entity.Lesson) error {
+	l.CreatedAt = l.CreatedAt.UTC()
+	res, err := r.collection.InsertOne(ctx, l)
+	if err != nil {
+		return err
+	}
+	if oid, ok := res.InsertedID.(primitive.ObjectID); ok {
+		l.ID = oid
+	}
+	return nil
+}
+
+func (r *MongoLessonRepository) GetLesson(ctx context.Context, id string) (*entity.Lesson, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, entity.ErrLessonNotFound
+	}
+	var l entity.Lesson
+	err = r.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&l)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, entity.ErrLessonNotFound
+		}
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (r *MongoLessonRepository) UpdateLesson(ctx context.Context, l *entity.Lesson) error {
+	if l.ID.IsZero() {
+		return errors.New("lesson id required")
+	}
+	filter := bson.M{"_id": l.ID}
+	update := bson.M{
+		"$set": bson.M{
+			"module_id":  l.ModuleID,
+			"title":      l.Title,
+			"body":       l.Body,
+			"links":      l.Links,
+			"position":   l.Position,
+			"status":     l.Status,
+			"release_at": l.ReleaseAt,
+			"updated_at": l.UpdatedAt,
+		},
+	}
+	res, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return entity.ErrLessonNotFound
+	}
+	return nil
+}
+
+func (r *MongoLessonRepository) DeleteLesson(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return entity.ErrLessonNotFound
+	}
+	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return entity.ErrLessonNotFound
+	}
+	return nil
+}
+
+func (r *MongoLessonRepository) ListLessonsByModule(ctx context.Context, moduleID primitive.ObjectID) ([]*entity.Lesson, error) {
+	return r.find(ctx, bson.M{"module_id": moduleID})
+}
+
+func (r *MongoLessonRepository) ListLessonsByCourse(ctx context.Context, courseID primitive.ObjectID) ([]*entity.Lesson, error) {
+	return r.find(ctx, bson.M{"course_id": courseID})
+}
+
+func (r *MongoLessonRepository) AddAttachment(ctx context.Context, lessonID primitive.ObjectID, a entity.Attachment) error {
+	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": lessonID}, bson.M{"$push": bson.M{"attachments": a}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return entity.ErrLessonNotFound
+	}
+	return nil
+}
+
+func (r *MongoLessonRepository) RemoveAttachment(ctx context.Context, lessonID, attachmentID primitive.ObjectID) error {
+	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": lessonID}, bson.M{"$pull": bson.M{"attachments": bson.M{"_id": attachmentID}}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return entity.ErrLessonNotFound
+	}
+	if res.ModifiedCount == 0 {
+		return entity.ErrAttachmentNotFound
+	}
+	return nil
+}
+
+func (r *MongoLessonRepository) UpdateLessonRelease(ctx context.Context, lessonID primitive.ObjectID, rule *entity.ReleaseRule) error {
+	found, err := updateRelease(ctx, r.collection, lessonID, rule)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return entity.ErrLessonNotFound
+	}
+	return nil
+}
+
+func (r *MongoLessonRepository) find(ctx context.Context, filter bson.M) ([]*entity.Lesson, error) {
+	cursor, err := r.collection.Find(ctx, filter, byPosition)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var lessons []*entity.Lesson
+	for cursor.Next(ctx) {
+		var l entity.Lesson
+		if err := cursor.Decode(&l); err != nil {
+			return nil, err
+		}
+		lessons = append(lessons, &l)
+	}
+	return lessons, cursor.Err()
+}
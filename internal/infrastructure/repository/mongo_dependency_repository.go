@@ -0,0 +1,274 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoDependencyRepository finds and removes the records that refer to a
+// course, class, user, assignment, assessment or term. It works across
+// collections, so it is built on the database rather than one collection.
+type MongoDependencyRepository struct {
+	db *mongo.Database
+}
+
+func NewMongoDependencyRepository(db *mongo.Database) *MongoDependencyRepository {
+	return &MongoDependencyRepository{db: db}
+}
+
+// dependency is a set of records referring to an item. On removal the
+// records are deleted, or changed with update when it is set. Uncounted
+// dependencies are bookkeeping that never blocks a delete.
+type dependency struct {
+	collection string
+	filter     bson.M
+	update     bson.M
+	uncounted  bool
+}
+
+// --- Course ---
+func (r *MongoDependencyRepository) CourseDependents(ctx context.Context, courseID primitive.ObjectID) (entity.Dependents, error) {
+	deps, err := r.courseDependencies(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	return r.count(ctx, deps)
+}
+
+// RemoveCourseDependents removes everything that belongs to the course and
+// returns the blob keys of the lesson attachments it removed, which the
+// caller deletes once the transaction has committed.
+func (r *MongoDependencyRepository) RemoveCourseDependents(ctx context.Context, courseID primitive.ObjectID) ([]string, error) {
+	deps, err := r.courseDependencies(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.db.Collection("lessons").Find(ctx, bson.M{"course_id": courseID}, options.Find().SetProjection(bson.M{"attachments": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var keys []string
+	for cursor.Next(ctx) {
+		var l entity.Lesson
+		if err := cursor.Decode(&l); err != nil {
+			return nil, err
+		}
+		for _, att := range l.Attachments {
+			keys = append(keys, att.Key)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, r.remove(ctx, deps)
+}
+
+func (r *MongoDependencyRepository) courseDependencies(ctx context.Context, courseID primitive.ObjectID) ([]dependency, error) {
+	classIDs, err := r.ids(ctx, "classes", bson.M{"course_id": courseID})
+	if err != nil {
+		return nil, err
+	}
+	assignmentIDs, err := r.ids(ctx, "assignments", bson.M{"course_id": courseID})
+	if err != nil {
+		return nil, err
+	}
+	assessmentIDs, err := r.ids(ctx, "assessments", bson.M{"course_id": courseID})
+	if err != nil {
+		return nil, err
+	}
+	items := append(append([]primitive.ObjectID{}, assignmentIDs...), assessmentIDs...)
+	byCourse := bson.M{"course_id": courseID}
+
+	deps := []dependency{
+		{collection: "classes", filter: byCourse},
+		{collection: "modules", filter: byCourse},
+		{collection: "lessons", filter: byCourse},
+		{collection: "assignments", filter: byCourse},
+		{collection: "assessments", filter: byCourse},
+		{collection: "submissions", filter: bson.M{"assignment_id": bson.M{"$in": nonNil(assignmentIDs)}}},
+		{collection: "progress", filter: byCourse},
+		{collection: "announcements", filter: bson.M{"target_audience": entity.AudienceCourse, "target_id": courseID}},
+		{collection: "reminder_sends", filter: bson.M{"item_id": bson.M{"$in": items}}, uncounted: true},
+	}
+	return append(deps, classDependencies(classIDs...)...), nil
+}
+
+// --- Class ---
+func (r *MongoDependencyRepository) ClassDependents(ctx context.Context, classID primitive.ObjectID) (entity.Dependents, error) {
+	return r.count(ctx, classDependencies(classID))
+}
+
+// RemoveClassDependents deletes the sessions, attendance and announcements of
+// the class and takes it off the recipients of messages.
+func (r *MongoDependencyRepository) RemoveClassDependents(ctx context.Context, classID primitive.ObjectID) error {
+	return r.remove(ctx, classDependencies(classID))
+}
+
+func classDependencies(classIDs ...primitive.ObjectID) []dependency {
+	in := bson.M{"$in": nonNil(classIDs)}
+	return []dependency{
+		{collection: "class_sessions", filter: bson.M{"class_id": in}},
+		{collection: "attendance", filter: bson.M{"class_id": in}},
+		{collection: "announcements", filter: bson.M{"target_audience": entity.AudienceClass, "target_id": in}},
+		{collection: "messages", filter: bson.M{"receiver_ids": in}, update: bson.M{"$pull": bson.M{"receiver_ids": in}}},
+	}
+}
+
+// --- User ---
+func (r *MongoDependencyRepository) UserDependents(ctx context.Context, userID primitive.ObjectID) (entity.Dependents, error) {
+	return r.count(ctx, userDependencies(userID))
+}
+
+// RemoveUserDependents takes the user off classes, courses and message
+// recipients and deletes their submissions, progress, attendance, sent
+// messages, guardian links, notifications and credentials. It returns the
+// blob key of the user's avatar, which the caller deletes once the
+// transaction has committed.
+func (r *MongoDependencyRepository) RemoveUserDependents(ctx context.Context, userID primitive.ObjectID) ([]string, error) {
+	var user entity.User
+	err := r.db.Collection("users").FindOne(ctx, bson.M{"_id": userID}, options.FindOne().SetProjection(bson.M{"avatar_key": 1})).Decode(&user)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	var keys []string
+	if user.AvatarKey != "" {
+		keys = append(keys, user.AvatarKey)
+	}
+	return keys, r.remove(ctx, userDependencies(userID))
+}
+
+func userDependencies(userID primitive.ObjectID) []dependency {
+	return []dependency{
+		{collection: "classes", filter: bson.M{"student_ids": userID}, update: bson.M{"$pull": bson.M{"student_ids": userID}}},
+		{collection: "classes", filter: bson.M{"teacher_ids": userID}, update: bson.M{"$pull": bson.M{"teacher_ids": userID}}},
+		{collection: "courses", filter: bson.M{"assigned_teachers": userID}, update: bson.M{"$pull": bson.M{"assigned_teachers": userID}}},
+		{collection: "submissions", filter: bson.M{"student_id": userID}},
+		{collection: "progress", filter: bson.M{"student_id": userID}},
+		{collection: "attendance", filter: bson.M{"student_id": userID}},
+		{collection: "messages", filter: bson.M{"sender_id": userID}},
+		{collection: "messages", filter: bson.M{"receiver_ids": userID}, update: bson.M{"$pull": bson.M{"receiver_ids": userID}}},
+		{collection: "notifications", filter: bson.M{"user_id": userID}, uncounted: true},
+		{collection: "reminder_sends", filter: bson.M{"user_id": userID}, uncounted: true},
+		{collection: "guardian_links", filter: bson.M{"$or": bson.A{bson.M{"student_id": userID}, bson.M{"guardian_id": userID}}}},
+		{collection: "api_tokens", filter: bson.M{"user_id": userID}, uncounted: true},
+		{collection: "email_verifications", filter: bson.M{"user_id": userID}, uncounted: true},
+		{collection: "impersonation_sessions", filter: bson.M{"$or": bson.A{bson.M{"user_id": userID}, bson.M{"actor_id": userID}}}, uncounted: true},
+	}
+}
+
+// --- Assignment ---
+func (r *MongoDependencyRepository) AssignmentDependents(ctx context.Context, assignmentID primitive.ObjectID) (entity.Dependents, error) {
+	return r.count(ctx, assignmentDependencies(assignmentID))
+}
+
+func (r *MongoDependencyRepository) RemoveAssignmentDependents(ctx context.Context, assignmentID primitive.ObjectID) error {
+	return r.remove(ctx, assignmentDependencies(assignmentID))
+}
+
+func assignmentDependencies(assignmentID primitive.ObjectID) []dependency {
+	return []dependency{
+		{collection: "submissions", filter: bson.M{"assignment_id": assignmentID}},
+		{collection: "progress", filter: bson.M{"item_id": assignmentID}},
+		{collection: "reminder_sends", filter: bson.M{"item_id": assignmentID}, uncounted: true},
+	}
+}
+
+// --- Assessment ---
+func (r *MongoDependencyRepository) AssessmentDependents(ctx context.Context, assessmentID primitive.ObjectID) (entity.Dependents, error) {
+	return r.count(ctx, assessmentDependencies(assessmentID))
+}
+
+// RemoveAssessmentDependents deletes the results of the assessment and drops
+// release conditions that require a score on it.
+func (r *MongoDependencyRepository) RemoveAssessmentDependents(ctx context.Context, assessmentID primitive.ObjectID) error {
+	return r.remove(ctx, assessmentDependencies(assessmentID))
+}
+
+func assessmentDependencies(assessmentID primitive.ObjectID) []dependency {
+	byCondition := bson.M{"release.conditions.assessment_id": assessmentID}
+	dropCondition := bson.M{"$pull": bson.M{"release.conditions": bson.M{"assessment_id": assessmentID}}}
+	return []dependency{
+		{collection: "progress", filter: bson.M{"item_id": assessmentID}},
+		{collection: "lessons", filter: byCondition, update: dropCondition},
+		{collection: "assignments", filter: byCondition, update: dropCondition},
+		{collection: "assessments", filter: byCondition, update: dropCondition},
+		{collection: "reminder_sends", filter: bson.M{"item_id": assessmentID}, uncounted: true},
+	}
+}
+
+// --- Term ---
+func (r *MongoDependencyRepository) TermDependents(ctx context.Context, termID primitive.ObjectID) (entity.Dependents, error) {
+	return r.count(ctx, termDependencies(termID))
+}
+
+// RemoveTermDependents detaches the courses and classes of the term from it.
+func (r *MongoDependencyRepository) RemoveTermDependents(ctx context.Context, termID primitive.ObjectID) error {
+	return r.remove(ctx, termDependencies(termID))
+}
+
+func termDependencies(termID primitive.ObjectID) []dependency {
+	detach := bson.M{"$unset": bson.M{"term_id": ""}}
+	return []dependency{
+		{collection: "courses", filter: bson.M{"term_id": termID}, update: detach},
+		{collection: "classes", filter: bson.M{"term_id": termID}, update: detach},
+	}
+}
+
+func (r *MongoDependencyRepository) count(ctx context.Context, deps []dependency) (entity.Dependents, error) {
+	counts := entity.Dependents{}
+	for _, d := range deps {
+		if d.uncounted {
+			continue
+		}
+		n, err := r.db.Collection(d.collection).CountDocuments(ctx, d.filter)
+		if err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			counts[d.collection] += n
+		}
+	}
+	return counts, nil
+}
+
+func (r *MongoDependencyRepository) remove(ctx context.Context, deps []dependency) error {
+	for _, d := range deps {
+		var err error
+		if d.update != nil {
+			_, err = r.db.Collection(d.collection).UpdateMany(ctx, d.filter, d.update)
+		} else {
+			_, err = r.db.Collection(d.collection).DeleteMany(ctx, d.filter)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *MongoDependencyRepository) ids(ctx context.Context, collection string, filter bson.M) ([]primitive.ObjectID, error) {
+	cursor, err := r.db.Collection(collection).Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var ids []primitive.ObjectID
+	for cursor.Next(ctx) {
+		if id, ok := cursor.Current.Lookup("_id").ObjectIDOK(); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, cursor.Err()
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MongoEmailVerificationRepository keeps outstanding email verification
+// tokens. Documents expire through a TTL index on expires_at.
+type MongoEmailVerificationRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoEmailVerificationRepository(c *mongo.Collection) *MongoEmailVerificationRepository {
+	return &MongoEmailVerificationRepository{collection: c}
+}
+
+// ReplaceEmailVerification stores v and drops the earlier tokens of the
+// same user, so that only the latest one sent works.
+func (r *MongoEmailVerificationRepository) ReplaceEmailVerification(ctx context.Context, v *entity.EmailVerification) error {
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"user_id": v.UserID}); err != nil {
+		return err
+	}
+	_, err := r.collection.InsertOne(ctx, v)
+	return err
+}
+
+// TakeEmailVerification removes and returns the unexpired verification with
+// the given token hash. An unknown or expired token is
+// entity.ErrInvalidToken.
+func (r *MongoEmailVerificationRepository) TakeEmailVerification(ctx context.Context, tokenHash string, now time.Time) (*entity.EmailVerification, error) {
+	var v entity.EmailVerification
+	err := r.collection.FindOneAndDelete(ctx, bson.M{"_id": tokenHash, "expires_at": bson.M{"$gt": now}}).Decode(&v)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, entity.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (r *MongoEmailVerificationRepository) DeleteEmailVerifications(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
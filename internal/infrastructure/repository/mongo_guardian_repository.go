@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoGuardianRepository stores the links between guardians and students.
+// A unique index keeps a guardian from being linked to a student twice, and
+// a TTL index removes invitations that were never accepted.
+type MongoGuardianRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoGuardianRepository(c *mongo.Collection) *MongoGuardianRepository {
+	return &MongoGuardianRepository{collection: c}
+}
+
+func (r *MongoGuardianRepository) CreateGuardianLink(ctx context.Context, link *entity.GuardianLink) error {
+	if link.ID.IsZero() {
+		link.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, link)
+	if mongo.IsDuplicateKeyError(err) {
+		return entity.ErrGuardianLinkExists
+	}
+	return err
+}
+
+func (r *MongoGuardianRepository) GetGuardianLink(ctx context.Context, id string) (*entity.GuardianLink, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, entity.ErrGuardianLinkNotFound
+	}
+
+	var link entity.GuardianLink
+	err = r.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&link)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, entity.ErrGuardianLinkNotFound
+	}
+	return &link, err
+}
+
+// FindGuardianInvitation returns the unexpired pending invitation whose
+// token has the given hash.
+func (r *MongoGuardianRepository) FindGuardianInvitation(ctx context.Context, tokenHash string, now time.Time) (*entity.GuardianLink, error) {
+	filter := bson.M{
+		"token_hash": tokenHash,
+		"status":     entity.GuardianLinkPending,
+		"expires_at": bson.M{"$gt": now},
+	}
+	var link entity.GuardianLink
+	err := r.collection.FindOne(ctx, filter).Decode(&link)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, entity.ErrInvalidToken
+	}
+	return &link, err
+}
+
+// ActivateGuardianLink turns a pending invitation into a link to guardianID.
+// The invitation can only be accepted once.
+func (r *MongoGuardianRepository) ActivateGuardianLink(ctx context.Context, id, guardianID primitive.ObjectID, at time.Time) error {
+	res, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "status": entity.GuardianLinkPending},
+		bson.M{
+			"$set":   bson.M{"status": entity.GuardianLinkActive, "guardian_id": guardianID, "confirmed_at": at.UTC()},
+			"$unset": bson.M{"token_hash": "", "expires_at": ""},
+		},
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return entity.ErrGuardianLinkExists
+	}
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return entity.ErrInvalidToken
+	}
+	return nil
+}
+
+// ListGuardianLinksByStudent returns the links and pending invitations of a
+// student, newest first.
+func (r *MongoGuardianRepository) ListGuardianLinksByStudent(ctx context.Context, studentID primitive.ObjectID) ([]*entity.GuardianLink, error) {
+	return r.list(ctx, bson.M{"student_id": studentID})
+}
+
+// ListGuardianLinksByGuardian returns the active links of a guardian.
+func (r *MongoGuardianRepository) ListGuardianLinksByGuardian(ctx context.Context, guardianID primitive.ObjectID) ([]*entity.GuardianLink, error) {
+	return r.list(ctx, bson.M{"guardian_id": guardianID, "status": entity.GuardianLinkActive})
+}
+
+func (r *MongoGuardianRepository) list(ctx context.Context, filter bson.M) ([]*entity.GuardianLink, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	links := []*entity.GuardianLink{}
+	if err := cursor.All(ctx, &links); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// CountPendingGuardianInvitations counts the unexpired invitations a student
+// has sent.
+func (r *MongoGuardianRepository) CountPendingGuardianInvitations(ctx context.Context, studentID primitive.ObjectID, now time.Time) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{
+		"student_id": studentID,
+		"status":     entity.GuardianLinkPending,
+		"expires_at": bson.M{"$gt": now},
+	})
+}
+
+// IsGuardianOf reports whether guardianID has an active link to studentID.
+func (r *MongoGuardianRepository) IsGuardianOf(ctx context.Context, guardianID, studentID primitive.ObjectID) (bool, error) {
+	n, err := r.collection.CountDocuments(ctx, bson.M{
+		"guardian_id": guardianID,
+		"student_id":  studentID,
+		"status":      entity.GuardianLinkActive,
+	}, options.Count().SetLimit(1))
+	return n > 0, err
+}
+
+func (r *MongoGuardianRepository) DeleteGuardianLink(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return entity.ErrGuardianLinkNotFound
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MongoImpersonationRepository stores impersonation sessions. Sessions are
+// kept after they end, as the record of what was done in them.
+type MongoImpersonationRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoImpersonationRepository(c *mongo.Collection) *MongoImpersonationRepository {
+	return &MongoImpersonationRepository{collection: c}
+}
+
+func (r *MongoImpersonationRepository) CreateImpersonation(ctx context.Context, s *entity.ImpersonationSession) error {
+	if s.ID.IsZero() {
+		s.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, s)
+	return err
+}
+
+func (r *MongoImpersonationRepository) GetImpersonation(ctx context.Context, id string) (*entity.ImpersonationSession, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, entity.ErrImpersonationNotFound
+	}
+
+	var s entity.ImpersonationSession
+	err = r.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&s)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, entity.ErrImpersonationNotFound
+	}
+	return &s, err
+}
+
+// EndImpersonation marks a session as ended, unless it has ended already.
+func (r *MongoImpersonationRepository) EndImpersonation(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	res, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "ended_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"ended_at": at.UTC()}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return entity.ErrImpersonationNotFound
+	}
+	return nil
+}
+
+func (r *MongoImpersonationRepository) RecordImpersonatedWrite(ctx context.Context, id primitive.ObjectID, w entity.ImpersonatedWrite) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$push": bson.M{"writes": w}})
+	return err
+}
+
+var impersonationListSpec = listSpec{
+	defaultSort: "-started_at",
+	sorts: map[string]string{
+		"started_at": "started_at",
+	},
+	filters: map[string]filterField{
+		"actor_id": {field: "actor_id", objectID: true},
+		"user_id":  {field: "user_id", objectID: true},
+		"from":     {field: "started_at", timeOp: "$gte"},
+		"to":       {field: "started_at", timeOp: "$lt"},
+	},
+}
+
+func (r *MongoImpersonationRepository) ListImpersonations(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.ImpersonationSession], error) {
+	return findPage[entity.ImpersonationSession](ctx, r.collection, nil, opts, impersonationListSpec)
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MongoOIDCFlowRepository keeps single sign-on logins in progress, so that
+// the callback can land on any instance. Documents expire through a TTL
+// index on expires_at.
+type MongoOIDCFlowRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoOIDCFlowRepository(c *mongo.Collection) *MongoOIDCFlowRepository {
+	return &MongoOIDCFlowRepository{collection: c}
+}
+
+func (r *MongoOIDCFlowRepository) SaveOIDCFlow(ctx context.Context, flow *entity.OIDCFlow) error {
+	_, err := r.collection.InsertOne(ctx, flow)
+	return err
+}
+
+// TakeOIDCFlow removes and returns the flow with the given state, so that
+// each can only be completed once. An unknown or expired state is
+// entity.ErrOIDCInvalidState.
+func (r *MongoOIDCFlowRepository) TakeOIDCFlow(ctx context.Context, state string, now time.Time) (*entity.OIDCFlow, error) {
+	var flow entity.OIDCFlow
+	err := r.collection.FindOneAndDelete(ctx, bson.M{"_id": state, "expires_at": bson.M{"$gt": now}}).Decode(&flow)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, entity.ErrOIDCInvalidState
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &flow, nil
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MongoPasswordResetRepository keeps outstanding password reset tokens.
+// Documents expire through a TTL index on expires_at.
+type MongoPasswordResetRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoPasswordResetRepository(c *mongo.Collection) *MongoPasswordResetRepository {
+	return &MongoPasswordResetRepository{collection: c}
+}
+
+// ReplacePasswordReset stores reset and drops the earlier tokens of the
+// same user, so that only the latest one sent works.
+func (r *MongoPasswordResetRepository) ReplacePasswordReset(ctx context.Context, reset *entity.PasswordReset) error {
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"user_id": reset.UserID}); err != nil {
+		return err
+	}
+	_, err := r.collection.InsertOne(ctx, reset)
+	return err
+}
+
+// FindPasswordReset returns the unexpired reset with the given token hash
+// and leaves it in place. An unknown or expired token is
+// entity.ErrInvalidToken.
+func (r *MongoPasswordResetRepository) FindPasswordReset(ctx context.Context, tokenHash string, now time.Time) (*entity.PasswordReset, error) {
+	return r.decode(r.collection.FindOne(ctx, bson.M{"_id": tokenHash, "expires_at": bson.M{"$gt": now}}))
+}
+
+// TakePasswordReset removes and returns the unexpired reset with the given
+// token hash. Of two requests with the same token only one gets it.
+func (r *MongoPasswordResetRepository) TakePasswordReset(ctx context.Context, tokenHash string, now time.Time) (*entity.PasswordReset, error) {
+	return r.decode(r.collection.FindOneAndDelete(ctx, bson.M{"_id": tokenHash, "expires_at": bson.M{"$gt": now}}))
+}
+
+func (r *MongoPasswordResetRepository) decode(res *mongo.SingleResult) (*entity.PasswordReset, error) {
+	var reset entity.PasswordReset
+	err := res.Decode(&reset)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, entity.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &reset, nil
+}
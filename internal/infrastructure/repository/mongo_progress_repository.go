@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoProgressRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoProgressRepository(c *mongo.Collection) *MongoProgressRepository {
+	return &MongoProgressRepository{collection: c}
+}
+
+func progressKey(p *entity.Progress) bson.M {
+	return bson.M{
+		"student_id": p.StudentID,
+		"item_type":  p.ItemType,
+		"item_id":    p.ItemID,
+	}
+}
+
+// SaveProgress creates or overwrites the record for the student and item.
+func (r *MongoProgressRepository) SaveProgress(ctx context.Context, p *entity.Progress) error {
+	set := bson.M{
+		"course_id":  p.CourseID,
+		"status":     p.Status,
+		"updated_at": p.UpdatedAt.UTC(),
+	}
+	if p.Score != nil {
+		set["score"] = *p.Score
+	}
+
+	_, err := r.collection.UpdateOne(ctx, progressKey(p), bson.M{"$set": set}, options.Update().SetUpsert(true))
+	return err
+}
+
+// SaveProgressIfAbsent creates the record only when the student has none for the item yet.
+func (r *MongoProgressRepository) SaveProgressIfAbsent(ctx context.Context, p *entity.Progress) error {
+	update := bson.M{"$setOnInsert": bson.M{
+		"course_id":  p.CourseID,
+		"status":     p.Status,
+		"updated_at": p.UpdatedAt.UTC(),
+	}}
+
+	_, err := r.collection.UpdateOne(ctx, progressKey(p), update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (r *MongoProgressRepository) ListProgressByStudentAndCourse(ctx context.Context, studentID, courseID primitive.ObjectID) ([]*entity.Progress, error) {
+	return r.find(ctx, bson.M{"student_id": studentID, "course_id": courseID})
+}
+
+var progressListSpec = listSpec{
+	sorts: map[string]string{
+		"student_id": "student_id",
+	},
+	filters: map[string]filterField{
+		"course_id":  {field: "course_id", objectID: true},
+		"student_id": {field: "student_id", objectID: true},
+	},
+}
+
+// ListProgress returns a page of progress records, filterable by course_id
+// and by student_id, which takes a comma separated list.
+func (r *MongoProgressRepository) ListProgress(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.Progress], error) {
+	return findPage[entity.Progress](ctx, r.collection, bson.M{}, opts, progressListSpec)
+}
+
+func (r *MongoProgressRepository) find(ctx context.Context, filter bson.M) ([]*entity.Progress, error) {
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []*entity.Progress
+	for cursor.Next(ctx) {
+		var p entity.Progress
+		if err := cursor.Decode(&p); err != nil {
+			return nil, err
+		}
+		records = append(records, &p)
+	}
+	return records, cursor.Err()
+}
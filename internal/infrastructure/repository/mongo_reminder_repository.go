@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MongoReminderRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoReminderRepository(c *mongo.Collection) *MongoReminderRepository {
+	return &MongoReminderRepository{collection: c}
+}
+
+// ClaimReminder records the send and reports false if it was already recorded.
+func (r *MongoReminderRepository) ClaimReminder(ctx context.Context, rec *entity.ReminderSend) (bool, error) {
+	_, err := r.collection.InsertOne(ctx, rec)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (r *MongoReminderRepository) ReleaseReminder(ctx context.Context, id string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoRoleRepository stores role definitions keyed by role name.
+type MongoRoleRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoRoleRepository(c *mongo.Collection) *MongoRoleRepository {
+	return &MongoRoleRepository{collection: c}
+}
+
+func (r *MongoRoleRepository) ListRoles(ctx context.Context) ([]*entity.RoleDefinition, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	roles := []*entity.RoleDefinition{}
+	if err := cursor.All(ctx, &roles); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (r *MongoRoleRepository) GetRole(ctx context.Context, name entity.Role) (*entity.RoleDefinition, error) {
+	var role entity.RoleDefinition
+	err := r.collection.FindOne(ctx, bson.M{"_id": name}).Decode(&role)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, entity.ErrRoleNotFound
+	}
+	return &role, err
+}
+
+func (r *MongoRoleRepository) CreateRole(ctx context.Context, role *entity.RoleDefinition) error {
+	_, err := r.collection.InsertOne(ctx, role)
+	if mongo.IsDuplicateKeyError(err) {
+		return entity.ErrRoleExists
+	}
+	return err
+}
+
+func (r *MongoRoleRepository) UpdateRole(ctx context.Context, name entity.Role, description string, permissions []entity.Permission, at time.Time) error {
+	update := bson.M{"$set": bson.M{
+		"description": description,
+		"permissions": permissions,
+		"updated_at":  at,
+	}}
+	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": name}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return entity.ErrRoleNotFound
+	}
+	return nil
+}
+
+// DeleteRole removes a role that is not built in.
+func (r *MongoRoleRepository) DeleteRole(ctx context.Context, name entity.Role) error {
+	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": name, "builtin": false})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return entity.ErrRoleNotFound
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoSearchRepository runs text searches over the collections that have a
+// search_text index (see the migration package). An empty ID list passed to
+// a search method means the search is not restricted by that field.
+type MongoSearchRepository struct {
+	courses       *mongo.Collection
+	lessons       *mongo.Collection
+	assignments   *mongo.Collection
+	announcements *mongo.Collection
+	users         *mongo.Collection
+}
+
+func NewMongoSearchRepository(courses, lessons, assignments, announcements, users *mongo.Collection) *MongoSearchRepository {
+	return &MongoSearchRepository{
+		courses:       courses,
+		lessons:       lessons,
+		assignments:   assignments,
+		announcements: announcements,
+		users:         users,
+	}
+}
+
+func (r *MongoSearchRepository) SearchCourses(ctx context.Context, q string, courseIDs []primitive.ObjectID, limit int) ([]entity.Scored[*entity.Course], error) {
+	filter := notDeleted(bson.M{})
+	if len(courseIDs) > 0 {
+		filter["_id"] = bson.M{"$in": courseIDs}
+	}
+	return searchText[entity.Course](ctx, r.courses, q, filter, limit)
+}
+
+// SearchLessons only returns published lessons whose release date has passed
+// when publishedOnly is set.
+func (r *MongoSearchRepository) SearchLessons(ctx context.Context, q string, courseIDs []primitive.ObjectID, publishedOnly bool, limit int) ([]entity.Scored[*entity.Lesson], error) {
+	filter := bson.M{}
+	if len(courseIDs) > 0 {
+		filter["course_id"] = bson.M{"$in": courseIDs}
+	}
+	if publishedOnly {
+		filter["status"] = entity.LessonPublished
+		filter["$or"] = bson.A{
+			bson.M{"release_at": nil},
+			bson.M{"release_at": bson.M{"$lte": time.Now()}},
+		}
+	}
+	return searchText[entity.Lesson](ctx, r.lessons, q, filter, limit)
+}
+
+func (r *MongoSearchRepository) SearchAssignments(ctx context.Context, q string, courseIDs []primitive.ObjectID, limit int) ([]entity.Scored[*entity.Assignment], error) {
+	filter := notDeleted(bson.M{})
+	if len(courseIDs) > 0 {
+		filter["course_id"] = bson.M{"$in": courseIDs}
+	}
+	return searchText[entity.Assignment](ctx, r.assignments, q, filter, limit)
+}
+
+// SearchAnnouncements returns announcements for everyone plus, when
+// restricted, those targeted at one of the given courses or classes.
+func (r *MongoSearchRepository) SearchAnnouncements(ctx context.Context, q string, restricted bool, courseIDs, classIDs []primitive.ObjectID, limit int) ([]entity.Scored[*entity.Announcement], error) {
+	filter := notDeleted(bson.M{})
+	if restricted {
+		filter["$or"] = bson.A{
+			bson.M{"target_audience": bson.M{"$in": bson.A{entity.AudienceAll, ""}}},
+			bson.M{"target_audience": entity.AudienceCourse, "target_id": bson.M{"$in": nonNil(courseIDs)}},
+			bson.M{"target_audience": entity.AudienceClass, "target_id": bson.M{"$in": nonNil(classIDs)}},
+		}
+	}
+	return searchText[entity.Announcement](ctx, r.announcements, q, filter, limit)
+}
+
+func (r *MongoSearchRepository) SearchUsers(ctx context.Context, q string, userIDs []primitive.ObjectID, limit int) ([]entity.Scored[*entity.User], error) {
+	filter := notDeleted(bson.M{})
+	if len(userIDs) > 0 {
+		filter["_id"] = bson.M{"$in": userIDs}
+	}
+	return searchText[entity.User](ctx, r.users, q, filter, limit)
+}
+
+func searchText[T any](ctx context.Context, c *mongo.Collection, q string, filter bson.M, limit int) ([]entity.Scored[*T], error) {
+	filter["$text"] = bson.M{"$search": q}
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"_score": score}).
+		SetSort(bson.D{{Key: "_score", Value: score}}).
+		SetLimit(int64(limit))
+
+	cursor, err := c.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var hits []entity.Scored[*T]
+	for cursor.Next(ctx) {
+		var item T
+		if err := cursor.Decode(&item); err != nil {
+			return nil, err
+		}
+		s, _ := cursor.Current.Lookup("_score").DoubleOK()
+		hits = append(hits, entity.Scored[*T]{Item: &item, Score: s})
+	}
+	return hits, cursor.Err()
+}
+
+func nonNil(ids []primitive.ObjectID) []primitive.ObjectID {
+	if ids == nil {
+		return []primitive.ObjectID{}
+	}
+	return ids
+}
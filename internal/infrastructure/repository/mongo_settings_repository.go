@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoSettingsRepository keeps application wide settings, one document per
+// kind of setting keyed by name.
+type MongoSettingsRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoSettingsRepository(c *mongo.Collection) *MongoSettingsRepository {
+	return &MongoSettingsRepository{collection: c}
+}
+
+const mfaPolicyID = "mfa_policy"
+
+// GetMFAPolicy returns the stored policy, or an empty one that requires no
+// second factor if none has been saved.
+func (r *MongoSettingsRepository) GetMFAPolicy(ctx context.Context) (*entity.MFAPolicy, error) {
+	var policy entity.MFAPolicy
+	err := r.collection.FindOne(ctx, bson.M{"_id": mfaPolicyID}).Decode(&policy)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return &entity.MFAPolicy{RequiredRoles: []entity.Role{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (r *MongoSettingsRepository) SaveMFAPolicy(ctx context.Context, policy *entity.MFAPolicy) error {
+	_, err := r.collection.ReplaceOne(ctx, bson.M{"_id": mfaPolicyID}, policy, options.Replace().SetUpsert(true))
+	return err
+}
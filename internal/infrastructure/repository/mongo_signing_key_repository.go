@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MongoSigningKeyRepository stores the token signing key ring. Private keys
+// are sealed with AES-GCM under sealKey before they are written, so a copy
+// of the database alone cannot be used to sign tokens. Documents expire
+// through a TTL index on expires_at.
+type MongoSigningKeyRepository struct {
+	collection *mongo.Collection
+	aead       cipher.AEAD
+}
+
+// NewMongoSigningKeyRepository returns the repository. sealKey has to be
+// 32 bytes long.
+func NewMongoSigningKeyRepository(c *mongo.Collection, sealKey []byte) (*MongoSigningKeyRepository, error) {
+	block, err := aes.NewCipher(sealKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &MongoSigningKeyRepository{collection: c, aead: aead}, nil
+}
+
+// ListSigningKeys returns every key that can still verify tokens, with the
+// private keys unsealed.
+func (r *MongoSigningKeyRepository) ListSigningKeys(ctx context.Context, now time.Time) ([]*entity.SigningKey, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"expires_at": bson.M{"$exists": false}},
+		bson.M{"expires_at": bson.M{"$gt": now}},
+	}}
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var keys []*entity.SigningKey
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	for _, k := range keys {
+		nonceSize := r.aead.NonceSize()
+		if len(k.PrivateKey) < nonceSize {
+			return nil, fmt.Errorf("signing key %s: sealed key too short", k.ID)
+		}
+		plain, err := r.aead.Open(nil, k.PrivateKey[:nonceSize], k.PrivateKey[nonceSize:], []byte(k.ID))
+		if err != nil {
+			return nil, fmt.Errorf("signing key %s: cannot unseal, was JWT_SECRET changed? %w", k.ID, err)
+		}
+		k.PrivateKey = plain
+	}
+	return keys, nil
+}
+
+func (r *MongoSigningKeyRepository) InsertSigningKey(ctx context.Context, key *entity.SigningKey) error {
+	nonce := make([]byte, r.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed := *key
+	sealed.PrivateKey = r.aead.Seal(nonce, nonce, key.PrivateKey, []byte(key.ID))
+
+	_, err := r.collection.InsertOne(ctx, &sealed)
+	return err
+}
+
+// ExpireSigningKeys sets the end of verification for every key that starts
+// signing before activeBefore and has no end yet. It is called when a
+// successor is created.
+func (r *MongoSigningKeyRepository) ExpireSigningKeys(ctx context.Context, activeBefore, expiresAt time.Time) error {
+	filter := bson.M{
+		"active_from": bson.M{"$lt": activeBefore},
+		"expires_at":  bson.M{"$exists": false},
+	}
+	_, err := r.collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"expires_at": expiresAt}})
+	return err
+}
+
+// DeleteSigningKey removes a key at once, which invalidates every token it
+// signed.
+func (r *MongoSigningKeyRepository) DeleteSigningKey(ctx context.Context, kid string) error {
+	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": kid})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return entity.ErrSigningKeyNotFound
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/srgjo27/e-learning/internal/entity"
 	"go.mongodb.org/mongo-driver/bson"
@@ -36,6 +37,7 @@ func NewMongoMessageRepository(c *mongo.Collection) *MongoMessageRepository {
 
 // --- Assignment ---
 func (r *MongoAssignmentRepository) CreateAssignment(ctx context.Context, a *entity.Assignment) error {
+	a.DeletedAt = nil
 	_, err := r.collection.InsertOne(ctx, a)
 	return err
 }
@@ -46,10 +48,10 @@ func (r *MongoAssignmentRepository) GetAssignment(ctx context.Context, id string
 		return nil, err
 	}
 	var assignment entity.Assignment
-	err = r.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&assignment)
+	err = r.collection.FindOne(ctx, notDeleted(bson.M{"_id": oid})).Decode(&assignment)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("assignment not found")
+			return nil, entity.ErrAssignmentNotFound
 		}
 		return nil, err
 	}
@@ -60,7 +62,7 @@ func (r *MongoAssignmentRepository) UpdateAssignment(ctx context.Context, a *ent
 	if a.ID.IsZero() {
 		return errors.New("assignment ID required")
 	}
-	filter := bson.M{"_id": a.ID}
+	filter := notDeleted(bson.M{"_id": a.ID})
 	update := bson.M{
 		"$set": bson.M{
 			"title":       a.Title,
@@ -75,28 +77,53 @@ func (r *MongoAssignmentRepository) UpdateAssignment(ctx context.Context, a *ent
 		return err
 	}
 	if res.MatchedCount == 0 {
-		return errors.New("assignment not found")
+		return entity.ErrAssignmentNotFound
 	}
 	return nil
 }
 
+// DeleteAssignment moves the assignment to the trash.
 func (r *MongoAssignmentRepository) DeleteAssignment(ctx context.Context, id string) error {
-	oid, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return err
-	}
-	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": oid})
+	return softDelete(ctx, r.collection, id, entity.ErrAssignmentNotFound)
+}
+
+var assignmentListSpec = listSpec{
+	sorts: map[string]string{
+		"title":      "title",
+		"due_date":   "due_date",
+		"created_at": "created_at",
+	},
+	filters: map[string]filterField{
+		"course_id": {field: "course_id", objectID: true},
+		"archived":  {field: "archived_at", presence: true},
+	},
+}
+
+func (r *MongoAssignmentRepository) ListAssignments(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.Assignment], error) {
+	return findPage[entity.Assignment](ctx, r.collection, notDeleted(bson.M{}), opts, assignmentListSpec)
+}
+
+func (r *MongoAssignmentRepository) ListAssignmentsByCourse(ctx context.Context, courseID primitive.ObjectID) ([]*entity.Assignment, error) {
+	cursor, err := r.collection.Find(ctx, notDeleted(bson.M{"course_id": courseID}))
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if res.DeletedCount == 0 {
-		return errors.New("assignment not found")
+	defer cursor.Close(ctx)
+
+	var assignments []*entity.Assignment
+	for cursor.Next(ctx) {
+		var a entity.Assignment
+		if err := cursor.Decode(&a); err != nil {
+			return nil, err
+		}
+		assignments = append(assignments, &a)
 	}
-	return nil
+	return assignments, cursor.Err()
 }
 
-func (r *MongoAssignmentRepository) ListAssignmentsByCourse(ctx context.Context, courseID primitive.ObjectID) ([]*entity.Assignment, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{"course_id": courseID})
+func (r *MongoAssignmentRepository) ListAssignmentsDueBetween(ctx context.Context, from, to time.Time) ([]*entity.Assignment, error) {
+	filter := notDeleted(bson.M{"due_date": bson.M{"$gt": from.UTC(), "$lte": to.UTC()}})
+	cursor, err := r.collection.Find(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -113,6 +140,17 @@ func (r *MongoAssignmentRepository) ListAssignmentsByCourse(ctx context.Context,
 	return assignments, cursor.Err()
 }
 
+func (r *MongoAssignmentRepository) UpdateAssignmentRelease(ctx context.Context, assignmentID primitive.ObjectID, rule *entity.ReleaseRule) error {
+	found, err := updateRelease(ctx, r.collection, assignmentID, rule)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return entity.ErrAssignmentNotFound
+	}
+	return nil
+}
+
 // --- Assessment ---
 func (r *MongoAssessmentRepository) CreateAssessment(ctx context.Context, a *entity.Assessment) error {
 	_, err := r.collection.InsertOne(ctx, a)
@@ -128,7 +166,7 @@ func (r *MongoAssessmentRepository) GetAssessment(ctx context.Context, id string
 	err = r.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&assessment)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("assessment not found")
+			return nil, entity.ErrAssessmentNotFound
 		}
 		return nil, err
 	}
@@ -154,7 +192,7 @@ func (r *MongoAssessmentRepository) UpdateAssessment(ctx context.Context, a *ent
 		return err
 	}
 	if res.MatchedCount == 0 {
-		return errors.New("assessment not found")
+		return entity.ErrAssessmentNotFound
 	}
 	return nil
 }
@@ -169,7 +207,7 @@ func (r *MongoAssessmentRepository) DeleteAssessment(ctx context.Context, id str
 		return err
 	}
 	if res.DeletedCount == 0 {
-		return errors.New("assessment not found")
+		return entity.ErrAssessmentNotFound
 	}
 	return nil
 }
@@ -194,23 +232,51 @@ func (r *MongoAssessmentRepository) ListAssessmentsByCourse(ctx context.Context,
 	return assessments, cursor.Err()
 }
 
-func (r *MongoAssessmentRepository) ListAssessments(ctx context.Context) ([]*entity.Assessment, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{})
+var assessmentListSpec = listSpec{
+	sorts: map[string]string{
+		"title":      "title",
+		"date":       "date",
+		"created_at": "created_at",
+	},
+	filters: map[string]filterField{
+		"course_id": {field: "course_id", objectID: true},
+	},
+}
+
+func (r *MongoAssessmentRepository) ListAssessments(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.Assessment], error) {
+	return findPage[entity.Assessment](ctx, r.collection, nil, opts, assessmentListSpec)
+}
+
+func (r *MongoAssessmentRepository) ListAssessmentsBetween(ctx context.Context, from, to time.Time) ([]*entity.Assessment, error) {
+	filter := bson.M{"date": bson.M{"$gt": from.UTC(), "$lte": to.UTC()}}
+	cursor, err := r.collection.Find(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
+
 	var assessments []*entity.Assessment
 	for cursor.Next(ctx) {
-		var assessment entity.Assessment
-		if err := cursor.Decode(&assessment); err != nil {
+		var a entity.Assessment
+		if err := cursor.Decode(&a); err != nil {
 			return nil, err
 		}
-		assessments = append(assessments, &assessment)
+		assessments = append(assessments, &a)
 	}
 	return assessments, cursor.Err()
 }
 
+func (r *MongoAssessmentRepository) UpdateAssessmentRelease(ctx context.Context, assessmentID primitive.ObjectID, rule *entity.ReleaseRule) error {
+	found, err := updateRelease(ctx, r.collection, assessmentID, rule)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return entity.ErrAssessmentNotFound
+	}
+	return nil
+}
+
 // --- Message ---
 func (r *MongoMessageRepository) CreateMessage(ctx context.Context, m *entity.Message) error {
 	_, err := r.collection.InsertOne(ctx, m)
@@ -226,7 +292,7 @@ func (r *MongoMessageRepository) GetMessage(ctx context.Context, id string) (*en
 	err = r.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&m)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("message not found")
+			return nil, entity.ErrMessageNotFound
 		}
 		return nil, err
 	}
@@ -249,7 +315,7 @@ func (r *MongoMessageRepository) UpdateMessage(ctx context.Context, m *entity.Me
 		return err
 	}
 	if res.MatchedCount == 0 {
-		return errors.New("message not found")
+		return entity.ErrMessageNotFound
 	}
 	return nil
 }
@@ -264,27 +330,24 @@ func (r *MongoMessageRepository) DeleteMessage(ctx context.Context, id string) e
 		return err
 	}
 	if res.DeletedCount == 0 {
-		return errors.New("message not found")
+		return entity.ErrMessageNotFound
 	}
 	return nil
 }
 
-func (r *MongoMessageRepository) ListMessagesBySender(ctx context.Context, senderID primitive.ObjectID) ([]*entity.Message, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{"sender_id": senderID})
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(ctx)
+var messageListSpec = listSpec{
+	defaultSort: "-created_at",
+	sorts: map[string]string{
+		"created_at": "created_at",
+	},
+	filters: map[string]filterField{
+		"sender_id":   {field: "sender_id", objectID: true},
+		"receiver_id": {field: "receiver_ids", objectID: true},
+	},
+}
 
-	var messages []*entity.Message
-	for cursor.Next(ctx) {
-		var m entity.Message
-		if err := cursor.Decode(&m); err != nil {
-			return nil, err
-		}
-		messages = append(messages, &m)
-	}
-	return messages, cursor.Err()
+func (r *MongoMessageRepository) ListMessages(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.Message], error) {
+	return findPage[entity.Message](ctx, r.collection, nil, opts, messageListSpec)
 }
 
 func (r *MongoMessageRepository) ListMessagesForReceiver(ctx context.Context, receiverID primitive.ObjectID) ([]*entity.Message, error) {
@@ -6,10 +6,11 @@ import (
 	"github.com/srgjo27/e-learning/internal/entity"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 func (r *MongoCourseRepository) ListCoursesByTeacher(ctx context.Context, teacherID primitive.ObjectID) ([]*entity.Course, error) {
-	filter := bson.M{"assigned_teachers": teacherID}
+	filter := notDeleted(bson.M{"assigned_teachers": teacherID})
 	cursor, err := r.collection.Find(ctx, filter)
 	if err != nil {
 		return nil, err
@@ -31,7 +32,7 @@ func (r *MongoCourseRepository) ListCoursesByTeacher(ctx context.Context, teache
 }
 
 func (r *MongoClassRepository) ListClassesByTeacher(ctx context.Context, teacherID primitive.ObjectID) ([]*entity.Class, error) {
-	filter := bson.M{"teacher_ids": teacherID}
+	filter := notDeleted(bson.M{"teacher_ids": teacherID})
 	cursor, err := r.collection.Find(ctx, filter)
 	if err != nil {
 		return nil, err
@@ -52,13 +53,38 @@ func (r *MongoClassRepository) ListClassesByTeacher(ctx context.Context, teacher
 	return classes, cursor.Err()
 }
 
-func (r *MongoUserRepository) FindUsersByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*entity.User, error) {
-	filter := bson.M{"_id": bson.M{"$in": ids}}
+func (r *MongoClassRepository) ListClassesByCourse(ctx context.Context, courseID primitive.ObjectID) ([]*entity.Class, error) {
+	filter := notDeleted(bson.M{"course_id": courseID})
 	cursor, err := r.collection.Find(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
+
+	var classes []*entity.Class
+
+	for cursor.Next(ctx) {
+		var cl entity.Class
+
+		if err := cursor.Decode(&cl); err != nil {
+			return nil, err
+		}
+		classes = append(classes, &cl)
+	}
+
+	return classes, cursor.Err()
+}
+
+// FindUsersByIDs returns the users with the given IDs ordered by name, with
+// the users that have no name set first, by email.
+func (r *MongoUserRepository) FindUsersByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*entity.User, error) {
+	filter := notDeleted(bson.M{"_id": bson.M{"$in": ids}})
+	opts := options.Find().SetSort(bson.D{{Key: "sort_name", Value: 1}, {Key: "email", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
 	var users []*entity.User
 	for cursor.Next(ctx) {
 		var u entity.User
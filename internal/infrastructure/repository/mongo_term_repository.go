@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoTermRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoTermRepository(c *mongo.Collection) *MongoTermRepository {
+	return &MongoTermRepository{collection: c}
+}
+
+func (r *MongoTermRepository) CreateTerm(ctx context.Context, t *entity.Term) error {
+	t.CreatedAt = t.CreatedAt.UTC()
+	res, err := r.collection.InsertOne(ctx, t)
+	if err != nil {
+		return err
+	}
+	if oid, ok := res.InsertedID.(primitive.ObjectID); ok {
+		t.ID = oid
+	}
+	return nil
+}
+
+func (r *MongoTermRepository) GetTerm(ctx context.Context, id string) (*entity.Term, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, entity.ErrTermNotFound
+	}
+	var t entity.Term
+	err = r.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&t)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, entity.ErrTermNotFound
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *MongoTermRepository) UpdateTerm(ctx context.Context, t *entity.Term) error {
+	update := bson.M{
+		"$set": bson.M{
+			"name":       t.Name,
+			"start_date": t.StartDate,
+			"end_date":   t.EndDate,
+		},
+	}
+	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": t.ID}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return entity.ErrTermNotFound
+	}
+	return nil
+}
+
+func (r *MongoTermRepository) DeleteTerm(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return entity.ErrTermNotFound
+	}
+	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return entity.ErrTermNotFound
+	}
+	return nil
+}
+
+var termListSpec = listSpec{
+	defaultSort: "-start_date",
+	sorts: map[string]string{
+		"name":       "name",
+		"start_date": "start_date",
+	},
+}
+
+func (r *MongoTermRepository) ListTerms(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.Term], error) {
+	return findPage[entity.Term](ctx, r.collection, nil, opts, termListSpec)
+}
+
+// FindTermAt returns the term running at the given time. When terms overlap
+// the one that started last wins.
+func (r *MongoTermRepository) FindTermAt(ctx context.Context, at time.Time) (*entity.Term, error) {
+	filter := bson.M{
+		"start_date": bson.M{"$lte": at},
+		"end_date":   bson.M{"$gt": at},
+	}
+	opts := options.FindOne().SetSort(bson.D{{Key: "start_date", Value: -1}})
+
+	var t entity.Term
+	err := r.collection.FindOne(ctx, filter, opts).Decode(&t)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, entity.ErrTermNotFound
+		}
+		return nil, err
+	}
+	return &t, nil
+}
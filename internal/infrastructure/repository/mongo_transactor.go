@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MongoTransactor runs functions in multi-document transactions. Repository
+// calls made with the context handed to the function join the transaction.
+type MongoTransactor struct {
+	client    *mongo.Client
+	supported bool
+}
+
+// NewMongoTransactor checks whether the deployment supports transactions.
+// Standalone servers do not; there the functions run without one, which
+// callers have to allow explicitly (see Supported).
+func NewMongoTransactor(ctx context.Context, client *mongo.Client) (*MongoTransactor, error) {
+	var hello bson.M
+	if err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return nil, err
+	}
+	_, replicaSet := hello["setName"]
+	sharded := hello["msg"] == "isdbgrid"
+
+	return &MongoTransactor{client: client, supported: replicaSet || sharded}, nil
+}
+
+// Supported reports whether functions run in transactions.
+func (t *MongoTransactor) Supported() bool {
+	return t.supported
+}
+
+// WithTransaction commits when fn returns nil and aborts otherwise. fn may be
+// called more than once when the transaction hits a transient error.
+func (t *MongoTransactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !t.supported {
+		return fn(ctx)
+	}
+
+	session, err := t.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// notDeleted adds the condition that keeps trashed documents out of a query
+// and returns the filter. Every read and update of a soft-deletable
+// collection goes through it.
+func notDeleted(filter bson.M) bson.M {
+	filter["deleted_at"] = nil
+	return filter
+}
+
+var inTrash = bson.M{"$ne": nil}
+
+// softDelete moves a document to the trash by setting its deleted_at.
+func softDelete(ctx context.Context, c *mongo.Collection, id string, notFound error) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return notFound
+	}
+
+	update := bson.M{"$set": bson.M{"deleted_at": time.Now().UTC()}}
+	res, err := c.UpdateOne(ctx, notDeleted(bson.M{"_id": oid}), update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// MongoTrashRepository lists, restores, archives and purges the documents of
+// the soft-deletable collections, which are named after their trash type.
+type MongoTrashRepository struct {
+	db *mongo.Database
+}
+
+func NewMongoTrashRepository(db *mongo.Database) *MongoTrashRepository {
+	return &MongoTrashRepository{db: db}
+}
+
+var trashNotFound = map[entity.TrashType]error{
+	entity.TrashCourses:       entity.ErrCourseNotFound,
+	entity.TrashClasses:       entity.ErrClassNotFound,
+	entity.TrashUsers:         entity.ErrUserNotFound,
+	entity.TrashAssignments:   entity.ErrAssignmentNotFound,
+	entity.TrashAnnouncements: entity.ErrAnnouncementNotFound,
+}
+
+func (r *MongoTrashRepository) collection(t entity.TrashType) (*mongo.Collection, error) {
+	if !t.Valid() {
+		return nil, entity.ErrInvalidTrashType
+	}
+	return r.db.Collection(string(t)), nil
+}
+
+// trashDoc holds the fields a trashed document is listed by. Which of name,
+// title and email is set depends on the collection.
+type trashDoc struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	Name      string             `bson:"name"`
+	Title     string             `bson:"title"`
+	Email     string             `bson:"email"`
+	DeletedAt time.Time          `bson:"deleted_at"`
+}
+
+func (d *trashDoc) item(t entity.TrashType) *entity.TrashItem {
+	title := d.Title
+	if title == "" {
+		title = d.Name
+	}
+	if title == "" {
+		title = d.Email
+	}
+	return &entity.TrashItem{Type: t, ID: d.ID, Title: title, DeletedAt: d.DeletedAt}
+}
+
+var trashListSpec = listSpec{
+	defaultSort: "-deleted_at",
+	sorts: map[string]string{
+		"deleted_at": "deleted_at",
+	},
+}
+
+func (r *MongoTrashRepository) ListTrash(ctx context.Context, t entity.TrashType, opts entity.ListOptions) (*entity.Page[*entity.TrashItem], error) {
+	c, err := r.collection(t)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := findPage[trashDoc](ctx, c, bson.M{"deleted_at": inTrash}, opts, trashListSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &entity.Page[*entity.TrashItem]{Items: make([]*entity.TrashItem, 0, len(docs.Items)), NextCursor: docs.NextCursor}
+	for _, d := range docs.Items {
+		page.Items = append(page.Items, d.item(t))
+	}
+	return page, nil
+}
+
+func (r *MongoTrashRepository) GetTrashItem(ctx context.Context, t entity.TrashType, id primitive.ObjectID) (*entity.TrashItem, error) {
+	c, err := r.collection(t)
+	if err != nil {
+		return nil, err
+	}
+
+	var d trashDoc
+	err = c.FindOne(ctx, bson.M{"_id": id, "deleted_at": inTrash}).Decode(&d)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, entity.ErrTrashItemNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d.item(t), nil
+}
+
+// ListDeletedBefore returns the IDs of the documents trashed before cutoff.
+func (r *MongoTrashRepository) ListDeletedBefore(ctx context.Context, t entity.TrashType, cutoff time.Time) ([]primitive.ObjectID, error) {
+	c, err := r.collection(t)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"deleted_at": bson.M{"$ne": nil, "$lt": cutoff.UTC()}}
+	cursor, err := c.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var ids []primitive.ObjectID
+	for cursor.Next(ctx) {
+		id, ok := cursor.Current.Lookup("_id").ObjectIDOK()
+		if ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, cursor.Err()
+}
+
+func (r *MongoTrashRepository) Restore(ctx context.Context, t entity.TrashType, id primitive.ObjectID) error {
+	c, err := r.collection(t)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.UpdateOne(ctx, bson.M{"_id": id, "deleted_at": inTrash}, bson.M{"$unset": bson.M{"deleted_at": ""}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return entity.ErrTrashItemNotFound
+	}
+	return nil
+}
+
+// Purge removes a trashed document for good. Documents that are not in the
+// trash are left alone.
+func (r *MongoTrashRepository) Purge(ctx context.Context, t entity.TrashType, id primitive.ObjectID) error {
+	c, err := r.collection(t)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.DeleteOne(ctx, bson.M{"_id": id, "deleted_at": inTrash})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return entity.ErrTrashItemNotFound
+	}
+	return nil
+}
+
+// SetArchived archives a document at the given time, or unarchives it when
+// at is nil. Trashed documents cannot be archived.
+func (r *MongoTrashRepository) SetArchived(ctx context.Context, t entity.TrashType, id primitive.ObjectID, at *time.Time) error {
+	c, err := r.collection(t)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{"$unset": bson.M{"archived_at": ""}}
+	if at != nil {
+		update = bson.M{"$set": bson.M{"archived_at": at.UTC()}}
+	}
+	res, err := c.UpdateOne(ctx, notDeleted(bson.M{"_id": id}), update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return trashNotFound[t]
+	}
+	return nil
+}
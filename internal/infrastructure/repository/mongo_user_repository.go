@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/srgjo27/e-learning/internal/entity"
 	"go.mongodb.org/mongo-driver/bson"
@@ -30,7 +31,7 @@ func (r *MongoUserRepository) Create(ctx context.Context, user *entity.User) err
 }
 
 func (r *MongoUserRepository) FindByEmail(ctx context.Context, email string) (*entity.User, error) {
-	filter := bson.M{"email": email}
+	filter := notDeleted(bson.M{"email": email})
 	var user entity.User
 	err := r.collection.FindOne(ctx, filter).Decode(&user)
 	if errors.Is(err, mongo.ErrNoDocuments) {
@@ -46,7 +47,7 @@ func (r *MongoUserRepository) FindByID(ctx context.Context, id string) (*entity.
 		return nil, entity.ErrUserNotFound
 	}
 	
-	filter := bson.M{"_id": oid}
+	filter := notDeleted(bson.M{"_id": oid})
 	var user entity.User
 	err = r.collection.FindOne(ctx, filter).Decode(&user)
 	if errors.Is(err, mongo.ErrNoDocuments) {
@@ -62,7 +63,7 @@ func (r *MongoUserRepository) UpdatePassword(ctx context.Context, userID string,
 		return entity.ErrUserNotFound
 	}
 
-	filter := bson.M{"_id": oid}
+	filter := notDeleted(bson.M{"_id": oid})
 	update := bson.M{"$set": bson.M{"password": newHashed}}
 	res, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
@@ -76,15 +77,51 @@ func (r *MongoUserRepository) UpdatePassword(ctx context.Context, userID string,
 	return nil
 }
 
+// ReplacePassword sets a new password hash and moves the current one into
+// password_history, which keeps at most the last keep hashes. It is a
+// single update, so the hash moved is the one being replaced.
+func (r *MongoUserRepository) ReplacePassword(ctx context.Context, userID string, newHashed string, keep int) error {
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return entity.ErrUserNotFound
+	}
+
+	var update interface{} = bson.M{
+		"$set":   bson.M{"password": newHashed},
+		"$unset": bson.M{"password_history": ""},
+	}
+	if keep > 0 {
+		history := bson.M{"$concatArrays": bson.A{bson.M{"$ifNull": bson.A{"$password_history", bson.A{}}}, bson.A{"$password"}}}
+		update = mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"password":         newHashed,
+			"password_history": bson.M{"$slice": bson.A{history, -keep}},
+		}}}}
+	}
+
+	res, err := r.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": oid}), update)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
+
 func (r *MongoUserRepository) UpdateEmail(ctx context.Context, userID string, newEmail string) error {
 	oid, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return entity.ErrUserNotFound
 	}
 
-	filter := bson.M{"_id": oid}
+	filter := notDeleted(bson.M{"_id": oid})
 	update := bson.M{"$set": bson.M{"email": newEmail}}
 	res, err := r.collection.UpdateOne(ctx, filter, update)
+	if mongo.IsDuplicateKeyError(err) {
+		return entity.ErrEmailExists
+	}
 	if err != nil {
 		return err
 	}
@@ -96,59 +133,351 @@ func (r *MongoUserRepository) UpdateEmail(ctx context.Context, userID string, ne
 	return nil
 }
 
+// Delete moves the user to the trash. A trashed user can no longer sign in
+// and keeps its email address until it is purged.
 func (r *MongoUserRepository) Delete(ctx context.Context, userID string) error {
+	return softDelete(ctx, r.collection, userID, entity.ErrUserNotFound)
+}
+
+var userListSpec = listSpec{
+	sorts: map[string]string{
+		"email":      "email",
+		"name":       "sort_name",
+		"created_at": "created_at",
+	},
+	filters: map[string]filterField{
+		"role":           {field: "role"},
+		"email":          {field: "email"},
+		"archived":       {field: "archived_at", presence: true},
+		"student_number": {field: "student_number"},
+	},
+}
+
+func (r *MongoUserRepository) ListUsers(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.User], error) {
+	return findPage[entity.User](ctx, r.collection, notDeleted(bson.M{}), opts, userListSpec)
+}
+
+func (r *MongoUserRepository) UpdateRole(ctx context.Context, userID string, role entity.Role) error {
 	oid, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return entity.ErrUserNotFound
 	}
 
-	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": oid})
+	filter := notDeleted(bson.M{"_id": oid})
+	update := bson.M{"$set": bson.M{"role": role}}
+	res, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return err
 	}
+	if res.MatchedCount == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// CountByRole counts the users with a role, including those in the trash,
+// which may be restored.
+func (r *MongoUserRepository) CountByRole(ctx context.Context, role entity.Role) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"role": role})
+}
+
+func (r *MongoUserRepository) UpdateNotificationPreferences(ctx context.Context, userID string, prefs entity.NotificationPreferences) error {
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return entity.ErrUserNotFound
+	}
 
-	if res.DeletedCount == 0 {
+	filter := notDeleted(bson.M{"_id": oid})
+	update := bson.M{"$set": bson.M{"notification_preferences": prefs}}
+	res, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
 		return entity.ErrUserNotFound
 	}
 
 	return nil
 }
 
-func (r *MongoUserRepository) ListAll(ctx context.Context) ([]*entity.User, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{})
+// FindByCalendarToken returns the user whose calendar feed token has the
+// given hash.
+func (r *MongoUserRepository) FindByCalendarToken(ctx context.Context, tokenHash string) (*entity.User, error) {
+	filter := notDeleted(bson.M{"calendar_token_hash": tokenHash})
+	var user entity.User
+	err := r.collection.FindOne(ctx, filter).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, entity.ErrUserNotFound
+	}
+
+	return &user, err
+}
+
+// UpdateCalendarToken stores the hash of a new calendar feed token.
+func (r *MongoUserRepository) UpdateCalendarToken(ctx context.Context, userID string, tokenHash string) error {
+	oid, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		return nil, err
+		return entity.ErrUserNotFound
+	}
+
+	filter := notDeleted(bson.M{"_id": oid})
+	update := bson.M{"$set": bson.M{"calendar_token_hash": tokenHash}}
+	res, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return entity.ErrUserNotFound
 	}
-	defer cursor.Close(ctx)
 
-	var users []*entity.User
+	return nil
+}
 
-	for cursor.Next(ctx) {
-		var user entity.User
+// UpdateMFA replaces the second factor settings of a user, or removes them
+// when mfa is nil.
+func (r *MongoUserRepository) UpdateMFA(ctx context.Context, userID string, mfa *entity.MFASettings) error {
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return entity.ErrUserNotFound
+	}
 
-		if err := cursor.Decode(&user); err != nil {
-			return nil, err
-		}
+	update := bson.M{"$unset": bson.M{"mfa": ""}}
+	if mfa != nil {
+		update = bson.M{"$set": bson.M{"mfa": mfa}}
+	}
+	res, err := r.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": oid}), update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UseMFAStep records step as the last used TOTP time step, unless the same
+// or a later one has been used already. It reports whether the step was
+// accepted; the check and the update are one operation, so a code can only
+// be used once even by concurrent requests.
+func (r *MongoUserRepository) UseMFAStep(ctx context.Context, userID string, step int64) (bool, error) {
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, entity.ErrUserNotFound
+	}
+
+	filter := notDeleted(bson.M{
+		"_id": oid,
+		"$or": bson.A{
+			bson.M{"mfa.last_step": bson.M{"$lt": step}},
+			bson.M{"mfa.last_step": bson.M{"$exists": false}},
+		},
+	})
+	res, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"mfa.last_step": step}})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount == 1, nil
+}
 
-		users = append(users, &user)
+// UseRecoveryCode removes a recovery code hash from the user and reports
+// whether it was there.
+func (r *MongoUserRepository) UseRecoveryCode(ctx context.Context, userID string, codeHash string) (bool, error) {
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, entity.ErrUserNotFound
 	}
 
-	if err := cursor.Err(); err != nil {
-		return nil, err
+	filter := notDeleted(bson.M{"_id": oid, "mfa.recovery_codes": codeHash})
+	res, err := r.collection.UpdateOne(ctx, filter, bson.M{"$pull": bson.M{"mfa.recovery_codes": codeHash}})
+	if err != nil {
+		return false, err
 	}
+	return res.ModifiedCount == 1, nil
+}
 
-	return users, nil
+// FindByOIDCSubject returns the user linked to an identity provider account.
+func (r *MongoUserRepository) FindByOIDCSubject(ctx context.Context, subject string) (*entity.User, error) {
+	var user entity.User
+	err := r.collection.FindOne(ctx, notDeleted(bson.M{"oidc_subject": subject})).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, entity.ErrUserNotFound
+	}
+
+	return &user, err
 }
 
-func (r *MongoUserRepository) UpdateRole(ctx context.Context, userID string, role entity.Role) error {
+// LinkOIDCSubject links the user to an identity provider account and uses
+// up the permission to link it.
+func (r *MongoUserRepository) LinkOIDCSubject(ctx context.Context, userID string, subject string) error {
 	oid, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return entity.ErrUserNotFound
 	}
 
-	filter := bson.M{"_id": oid}
-	update := bson.M{"$set": bson.M{"role": role}}
+	update := bson.M{"$set": bson.M{"oidc_subject": subject}, "$unset": bson.M{"oidc_link_allowed": ""}}
+	res, err := r.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": oid}), update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// AllowOIDCLink sets whether the next single sign-on with the user's email
+// may link the account.
+func (r *MongoUserRepository) AllowOIDCLink(ctx context.Context, userID string, allowed bool) error {
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return entity.ErrUserNotFound
+	}
+
+	update := bson.M{"$unset": bson.M{"oidc_link_allowed": ""}}
+	if allowed {
+		update = bson.M{"$set": bson.M{"oidc_link_allowed": true}}
+	}
+	res, err := r.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": oid}), update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// MarkEmailVerified records that the user proved to own email, provided it
+// is still the user's address.
+func (r *MongoUserRepository) MarkEmailVerified(ctx context.Context, userID string, email string) error {
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return entity.ErrUserNotFound
+	}
+
+	res, err := r.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": oid, "email": email}), bson.M{"$set": bson.M{"email_verified": true}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// SetPendingEmail records the address a user is changing to, or clears it
+// when email is empty.
+func (r *MongoUserRepository) SetPendingEmail(ctx context.Context, userID string, email string) error {
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return entity.ErrUserNotFound
+	}
+
+	update := bson.M{"$unset": bson.M{"pending_email": ""}}
+	if email != "" {
+		update = bson.M{"$set": bson.M{"pending_email": email}}
+	}
+	res, err := r.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": oid}), update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// ConfirmPendingEmail makes the pending address the user's verified email,
+// provided it is still the pending one. An address taken in the meantime is
+// entity.ErrEmailExists.
+func (r *MongoUserRepository) ConfirmPendingEmail(ctx context.Context, userID string, email string) error {
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return entity.ErrUserNotFound
+	}
+
+	filter := notDeleted(bson.M{"_id": oid, "pending_email": email})
+	update := bson.M{
+		"$set":   bson.M{"email": email, "email_verified": true},
+		"$unset": bson.M{"pending_email": ""},
+	}
 	res, err := r.collection.UpdateOne(ctx, filter, update)
+	if mongo.IsDuplicateKeyError(err) {
+		return entity.ErrEmailExists
+	}
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UpdateProfile stores the name, locale, time zone and student number of a
+// user. Empty fields are removed, so that the unique index on student
+// numbers only covers users that have one.
+func (r *MongoUserRepository) UpdateProfile(ctx context.Context, userID string, profile entity.Profile) error {
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return entity.ErrUserNotFound
+	}
+
+	set := bson.M{"sort_name": profile.SortName}
+	unset := bson.M{}
+	for field, value := range map[string]string{
+		"first_name":     profile.FirstName,
+		"last_name":      profile.LastName,
+		"display_name":   profile.DisplayName,
+		"locale":         profile.Locale,
+		"time_zone":      profile.TimeZone,
+		"student_number": profile.StudentNumber,
+	} {
+		if value == "" {
+			unset[field] = ""
+		} else {
+			set[field] = value
+		}
+	}
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	res, err := r.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": oid}), update)
+	if mongo.IsDuplicateKeyError(err) {
+		return entity.ErrStudentNumberExists
+	}
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UpdateAvatar points the user at a new avatar blob, or removes the avatar
+// when key is empty.
+func (r *MongoUserRepository) UpdateAvatar(ctx context.Context, userID string, key string, at time.Time) error {
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return entity.ErrUserNotFound
+	}
+
+	update := bson.M{"$unset": bson.M{"avatar_key": "", "avatar_updated_at": ""}}
+	if key != "" {
+		update = bson.M{"$set": bson.M{"avatar_key": key, "avatar_updated_at": at.UTC()}}
+	}
+	res, err := r.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": oid}), update)
 	if err != nil {
 		return err
 	}
@@ -157,4 +486,4 @@ func (r *MongoUserRepository) UpdateRole(ctx context.Context, userID string, rol
 	}
 
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,243 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// listSpec declares which sort keys and filters a collection accepts. Both
+// map public names to document fields; "id" is always sortable.
+type listSpec struct {
+	defaultSort string
+	sorts       map[string]string
+	filters     map[string]filterField
+}
+
+// filterField maps a list filter to a document field. A presence filter
+// takes "true" or "false" and matches documents that have the field set or
+// not, such as archived_at. A filter with a timeOp takes an RFC 3339 time
+// and compares the field to it with that operator, e.g. "$gte". An orUnset
+// filter also matches documents without the field.
+type filterField struct {
+	field    string
+	objectID bool
+	presence bool
+	timeOp   string
+	orUnset  bool
+}
+
+// pageCursor is the position after the last item of a page: the value of
+// the sort field and the _id that breaks ties. Sort is the sort the page was
+// listed in; the cursor means nothing in any other.
+type pageCursor struct {
+	Sort  string             `bson:"s"`
+	Value bson.RawValue      `bson:"v"`
+	ID    primitive.ObjectID `bson:"id"`
+}
+
+// findPage runs a keyset-paginated query. base is combined with the filters
+// from opts and is meant for constraints the caller must not be able to lift.
+func findPage[T any](ctx context.Context, c *mongo.Collection, base bson.M, opts entity.ListOptions, spec listSpec) (*entity.Page[*T], error) {
+	sortKey := opts.Sort
+	if sortKey == "" {
+		sortKey = spec.defaultSort
+	}
+	sortKey, desc := strings.CutPrefix(sortKey, "-")
+	field := "_id"
+	if sortKey != "" && sortKey != "id" {
+		f, ok := spec.sorts[sortKey]
+		if !ok {
+			return nil, entity.ErrInvalidListOptions
+		}
+		field = f
+	}
+	sortName := "id"
+	if sortKey != "" {
+		sortName = sortKey
+	}
+	if desc {
+		sortName = "-" + sortName
+	}
+	dir := 1
+	if desc {
+		dir = -1
+	}
+
+	clauses := bson.A{}
+	if len(base) > 0 {
+		clauses = append(clauses, base)
+	}
+	for name, value := range opts.Filters {
+		f, ok := spec.filters[name]
+		if !ok {
+			return nil, entity.ErrInvalidListOptions
+		}
+		clause, err := filterClause(f, value)
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, clause)
+	}
+
+	if opts.Cursor != "" {
+		cur, err := decodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if cur.Sort != sortName {
+			return nil, entity.ErrInvalidListOptions
+		}
+		clauses = append(clauses, afterCursor(field, desc, cur))
+	}
+
+	filter := bson.M{}
+	if len(clauses) > 0 {
+		filter = bson.M{"$and": clauses}
+	}
+
+	limit := opts.PageLimit()
+	sort := bson.D{{Key: field, Value: dir}}
+	if field != "_id" {
+		sort = append(sort, bson.E{Key: "_id", Value: dir})
+	}
+	findOpts := options.Find().SetSort(sort).SetLimit(int64(limit) + 1)
+
+	cursor, err := c.Find(ctx, filter, findOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	page := &entity.Page[*T]{Items: []*T{}}
+	last := pageCursor{Sort: sortName}
+	for cursor.Next(ctx) {
+		if len(page.Items) == limit {
+			next, err := encodeCursor(last)
+			if err != nil {
+				return nil, err
+			}
+			page.NextCursor = next
+			break
+		}
+
+		var item T
+		if err := cursor.Decode(&item); err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, &item)
+
+		last.ID, _ = cursor.Current.Lookup("_id").ObjectIDOK()
+		last.Value, err = cursor.Current.LookupErr(field)
+		if err != nil {
+			last.Value = bson.RawValue{Type: bsontype.Null}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// afterCursor matches the items that come after cur in the order of field.
+// Missing and null values sort before all others, and neither $gt nor $lt
+// ever matches them, so they get clauses of their own.
+func afterCursor(field string, desc bool, cur *pageCursor) bson.M {
+	cmp := "$gt"
+	if desc {
+		cmp = "$lt"
+	}
+	if field == "_id" {
+		return bson.M{"_id": bson.M{cmp: cur.ID}}
+	}
+
+	if cur.Value.Type == bsontype.Null || cur.Value.Type == bsontype.Undefined {
+		tie := bson.M{field: nil, "_id": bson.M{cmp: cur.ID}}
+		if desc {
+			return tie
+		}
+		return bson.M{"$or": bson.A{tie, bson.M{field: bson.M{"$ne": nil}}}}
+	}
+
+	after := bson.A{
+		bson.M{field: bson.M{cmp: cur.Value}},
+		bson.M{field: cur.Value, "_id": bson.M{cmp: cur.ID}},
+	}
+	if desc {
+		after = append(after, bson.M{field: nil})
+	}
+	return bson.M{"$or": after}
+}
+
+func filterClause(f filterField, value string) (bson.M, error) {
+	if f.presence {
+		set, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, entity.ErrInvalidListOptions
+		}
+		if set {
+			return bson.M{f.field: bson.M{"$ne": nil}}, nil
+		}
+		return bson.M{f.field: nil}, nil
+	}
+	if f.timeOp != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, entity.ErrInvalidListOptions
+		}
+		return bson.M{f.field: bson.M{f.timeOp: t.UTC()}}, nil
+	}
+
+	parts := strings.Split(value, ",")
+	values := make(bson.A, 0, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if !f.objectID {
+			values = append(values, p)
+			continue
+		}
+		oid, err := primitive.ObjectIDFromHex(p)
+		if err != nil {
+			return nil, entity.ErrInvalidListOptions
+		}
+		values = append(values, oid)
+	}
+
+	if f.orUnset {
+		values = append(values, nil)
+	}
+	if len(values) == 1 {
+		return bson.M{f.field: values[0]}, nil
+	}
+	return bson.M{f.field: bson.M{"$in": values}}, nil
+}
+
+func encodeCursor(c pageCursor) (string, error) {
+	b, err := bson.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeCursor(s string) (*pageCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, entity.ErrInvalidListOptions
+	}
+	var c pageCursor
+	if err := bson.Unmarshal(b, &c); err != nil || c.ID.IsZero() {
+		return nil, entity.ErrInvalidListOptions
+	}
+	return &c, nil
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func rawValue(t *testing.T, v interface{}) bson.RawValue {
+	t.Helper()
+	typ, b, err := bson.MarshalValue(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bson.RawValue{Type: typ, Value: b}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	for _, v := range []interface{}{"lovelace ada", int32(7), primitive.Null{}} {
+		c := pageCursor{Sort: "-name", Value: rawValue(t, v), ID: primitive.NewObjectID()}
+		s, err := encodeCursor(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := decodeCursor(s)
+		if err != nil {
+			t.Fatalf("decodeCursor(%q) error = %v", s, err)
+		}
+		if got.Sort != c.Sort || got.ID != c.ID || !got.Value.Equal(c.Value) {
+			t.Errorf("decodeCursor(encodeCursor(%+v)) = %+v", c, got)
+		}
+	}
+
+	noID, _ := encodeCursor(pageCursor{Sort: "id"})
+	for _, s := range []string{"", "not a cursor!", "AAAA", noID} {
+		if _, err := decodeCursor(s); !errors.Is(err, entity.ErrInvalidListOptions) {
+			t.Errorf("decodeCursor(%q) error = %v, want %v", s, err, entity.ErrInvalidListOptions)
+		}
+	}
+}
+
+func TestAfterCursor(t *testing.T) {
+	id := primitive.NewObjectID()
+	name := rawValue(t, "m")
+	null := bson.RawValue{Type: bsontype.Null}
+
+	tests := []struct {
+		name  string
+		field string
+		desc  bool
+		value bson.RawValue
+		want  bson.M
+	}{
+		{"id ascending", "_id", false, name, bson.M{"_id": bson.M{"$gt": id}}},
+		{"id descending", "_id", true, name, bson.M{"_id": bson.M{"$lt": id}}},
+		{"value ascending", "name", false, name, bson.M{"$or": bson.A{
+			bson.M{"name": bson.M{"$gt": name}},
+			bson.M{"name": name, "_id": bson.M{"$gt": id}},
+		}}},
+		{"value descending reaches nulls", "name", true, name, bson.M{"$or": bson.A{
+			bson.M{"name": bson.M{"$lt": name}},
+			bson.M{"name": name, "_id": bson.M{"$lt": id}},
+			bson.M{"name": nil},
+		}}},
+		{"null ascending", "name", false, null, bson.M{"$or": bson.A{
+			bson.M{"name": nil, "_id": bson.M{"$gt": id}},
+			bson.M{"name": bson.M{"$ne": nil}},
+		}}},
+		{"null descending", "name", true, null, bson.M{"name": nil, "_id": bson.M{"$lt": id}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := afterCursor(tt.field, tt.desc, &pageCursor{Value: tt.value, ID: id})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("afterCursor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterClause(t *testing.T) {
+	a, b := primitive.NewObjectID(), primitive.NewObjectID()
+	tests := []struct {
+		name    string
+		field   filterField
+		value   string
+		want    bson.M
+		wantErr bool
+	}{
+		{"plain value", filterField{field: "audience"}, "students", bson.M{"audience": "students"}, false},
+		{"object id", filterField{field: "term_id", objectID: true}, a.Hex(), bson.M{"term_id": a}, false},
+		{"object id list", filterField{field: "term_id", objectID: true}, a.Hex() + ", " + b.Hex(), bson.M{"term_id": bson.M{"$in": bson.A{a, b}}}, false},
+		{"or unset", filterField{field: "term_id", objectID: true, orUnset: true}, a.Hex(), bson.M{"term_id": bson.M{"$in": bson.A{a, nil}}}, false},
+		{"bad object id", filterField{field: "term_id", objectID: true}, "nope", nil, true},
+		{"present", filterField{field: "archived_at", presence: true}, "true", bson.M{"archived_at": bson.M{"$ne": nil}}, false},
+		{"absent", filterField{field: "archived_at", presence: true}, "false", bson.M{"archived_at": nil}, false},
+		{"bad presence", filterField{field: "archived_at", presence: true}, "maybe", nil, true},
+		{"bad time", filterField{field: "due_date", timeOp: "$gte"}, "tomorrow", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := filterClause(tt.field, tt.value)
+			if tt.wantErr {
+				if !errors.Is(err, entity.ErrInvalidListOptions) {
+					t.Errorf("filterClause() error = %v, want %v", err, entity.ErrInvalidListOptions)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filterClause() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+// TestFindPageRejectsOptions covers the options findPage turns down before
+// it queries, so it needs no collection.
+func TestFindPageRejectsOptions(t *testing.T) {
+	spec := listSpec{
+		sorts:   map[string]string{"name": "sort_name"},
+		filters: map[string]filterField{"archived": {field: "archived_at", presence: true}},
+	}
+	cursor := func(sort string) string {
+		s, err := encodeCursor(pageCursor{Sort: sort, Value: rawValue(t, "m"), ID: primitive.NewObjectID()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name string
+		opts entity.ListOptions
+	}{
+		{"unknown sort", entity.ListOptions{Sort: "email"}},
+		{"unknown filter", entity.ListOptions{Filters: map[string]string{"role": "admin"}}},
+		{"bad filter value", entity.ListOptions{Filters: map[string]string{"archived": "maybe"}}},
+		{"bad cursor", entity.ListOptions{Cursor: "not a cursor!"}},
+		{"cursor of the other direction", entity.ListOptions{Sort: "name", Cursor: cursor("-name")}},
+		{"cursor of another sort", entity.ListOptions{Sort: "name", Cursor: cursor("id")}},
+		{"cursor of a sort by default", entity.ListOptions{Cursor: cursor("name")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := findPage[entity.User](context.Background(), nil, nil, tt.opts, spec)
+			if !errors.Is(err, entity.ErrInvalidListOptions) {
+				t.Errorf("findPage() error = %v, want %v", err, entity.ErrInvalidListOptions)
+			}
+		})
+	}
+}
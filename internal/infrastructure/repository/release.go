@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// updateRelease stores the release rule of a gated item, or removes it when rule is nil.
+// It reports whether the item exists.
+func updateRelease(ctx context.Context, c *mongo.Collection, id primitive.ObjectID, rule *entity.ReleaseRule) (bool, error) {
+	update := bson.M{"$set": bson.M{"release": rule}}
+	if rule == nil {
+		update = bson.M{"$unset": bson.M{"release": ""}}
+	}
+
+	res, err := c.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
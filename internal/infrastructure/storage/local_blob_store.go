@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+)
+
+// LocalBlobStore keeps blobs as files below a root directory. The content
+// type of each blob is stored next to it in a small JSON sidecar file.
+type LocalBlobStore struct {
+	root string
+}
+
+func NewLocalBlobStore(root string) (*LocalBlobStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalBlobStore{root: root}, nil
+}
+
+type blobMeta struct {
+	ContentType string `json:"content_type"`
+}
+
+func (s *LocalBlobStore) Put(ctx context.Context, key, contentType string, r io.Reader) (int64, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return 0, err
+	}
+
+	f, err := os.Create(p)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(p)
+		return 0, err
+	}
+
+	meta, _ := json.Marshal(blobMeta{ContentType: contentType})
+	if err := os.WriteFile(p+".meta", meta, 0o644); err != nil {
+		os.Remove(p)
+		return 0, err
+	}
+
+	return n, nil
+}
+
+func (s *LocalBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, string, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	f, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, "", entity.ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	contentType := "application/octet-stream"
+	if raw, err := os.ReadFile(p + ".meta"); err == nil {
+		var meta blobMeta
+		if json.Unmarshal(raw, &meta) == nil && meta.ContentType != "" {
+			contentType = meta.ContentType
+		}
+	}
+
+	return f, contentType, nil
+}
+
+func (s *LocalBlobStore) Delete(ctx context.Context, key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	os.Remove(p + ".meta")
+	return nil
+}
+
+// path maps a slash separated key to a file below root and rejects keys that
+// would escape it.
+func (s *LocalBlobStore) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" || strings.Contains(key, "..") {
+		return "", entity.ErrBlobNotFound
+	}
+	return filepath.Join(s.root, filepath.FromSlash(clean)), nil
+}
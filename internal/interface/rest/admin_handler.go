@@ -3,17 +3,17 @@ package rest
 import (
 	"encoding/json"
 	"net/http"
-	"strings"
 
+	"github.com/gorilla/mux"
 	"github.com/srgjo27/e-learning/internal/entity"
 	"github.com/srgjo27/e-learning/internal/usecase"
 )
 
 type AdminHandler struct {
-	authUseCase *usecase.AuthUseCase
+	authUseCase *usecase.AuditedAuthUseCase
 }
 
-func NewAdminHandler(u *usecase.AuthUseCase) *AdminHandler {
+func NewAdminHandler(u *usecase.AuditedAuthUseCase) *AdminHandler {
 	return &AdminHandler{
 		authUseCase: u,
 	}
@@ -24,13 +24,19 @@ type updateRoleRequest struct {
 }
 
 func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
-	users, err := h.authUseCase.ListAllUsers(r.Context())
+	opts, err := parseListOptions(r, "role", "email", "archived", "student_number")
 	if err != nil {
-		http.Error(w, "Failed to retrieve users", http.StatusInternalServerError)
+		writeListError(w, err, "Failed to retrieve users")
 		return
 	}
 
-	for _, u := range users {
+	users, err := h.authUseCase.ListUsers(r.Context(), opts)
+	if err != nil {
+		writeListError(w, err, "Failed to retrieve users")
+		return
+	}
+
+	for _, u := range users.Items {
 		u.Password = ""
 	}
 
@@ -38,8 +44,7 @@ func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *AdminHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
-	id := strings.TrimPrefix(r.URL.Path, "/admin/users/")
-	id = strings.TrimSuffix(id, "/role")
+	id := mux.Vars(r)["id"]
 	if id == "" {
 		http.Error(w, "User ID required", http.StatusBadRequest)
 		return
@@ -51,13 +56,20 @@ func (h *AdminHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Role != entity.RoleAdmin && req.Role != entity.RoleTeacher && req.Role != entity.RoleStudent {
-		http.Error(w, "Invalid role", http.StatusBadRequest)
-		return
-	}
-
 	err := h.authUseCase.UpdateUserRole(r.Context(), id, req.Role)
 	if err != nil {
+		if err == entity.ErrUserNotFound {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		if err == entity.ErrRoleNotFound {
+			http.Error(w, "Invalid role", http.StatusBadRequest)
+			return
+		}
+		if err == entity.ErrPermissionDenied {
+			http.Error(w, "Forbidden - cannot grant this role", http.StatusForbidden)
+			return
+		}
 		http.Error(w, "Failed to update role", http.StatusInternalServerError)
 		return
 	}
@@ -66,7 +78,7 @@ func (h *AdminHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *AdminHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
-	id := strings.TrimPrefix(r.URL.Path, "/admin/users/")
+	id := mux.Vars(r)["id"]
 	if id == "" {
 		http.Error(w, "User ID required", http.StatusBadRequest)
 		return
@@ -74,7 +86,7 @@ func (h *AdminHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 
 	err := h.authUseCase.DeleteUser(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Failed to delete user", http.StatusInternalServerError)
+		writeDeleteError(w, err, "Failed to delete user")
 		return
 	}
 
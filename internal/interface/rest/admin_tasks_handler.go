@@ -2,6 +2,8 @@ package rest
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 
 	"github.com/gorilla/mux"
@@ -11,19 +13,25 @@ import (
 )
 
 type AdminTasksHandler struct {
-	adminUseCase *usecase.AdminUseCase
+	adminUseCase *usecase.AuditedAdminUseCase
 }
 
-func NewAdminTasksHandler(adminUseCase *usecase.AdminUseCase) *AdminTasksHandler {
+func NewAdminTasksHandler(adminUseCase *usecase.AuditedAdminUseCase) *AdminTasksHandler {
 	return &AdminTasksHandler{
 		adminUseCase: adminUseCase,
 	}
 }
 
 func(h *AdminTasksHandler) ListCourses(w http.ResponseWriter, r *http.Request) {
-	courses, err := h.adminUseCase.ListCourses(r.Context())
+	opts, err := parseListOptions(r, "term", "teacher_id", "archived")
 	if err != nil {
-		http.Error(w, "Failed to list courses", http.StatusInternalServerError)
+		writeListError(w, err, "Failed to list courses")
+		return
+	}
+
+	courses, err := h.adminUseCase.ListCourses(r.Context(), opts)
+	if err != nil {
+		writeListError(w, err, "Failed to list courses")
 		return
 	}
 
@@ -40,6 +48,10 @@ func(h *AdminTasksHandler) CreateCourse(w http.ResponseWriter, r *http.Request)
 
 	course.CreatedAt = course.CreatedAt.UTC()
 	if err := h.adminUseCase.CreateCourse(r.Context(), &course); err != nil {
+		if err == entity.ErrTermNotFound || errors.Is(err, entity.ErrInvalidReference) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "Failed to create course", http.StatusInternalServerError)
 		return
 	}
@@ -61,10 +73,12 @@ func (h *AdminTasksHandler) GetCourse(w http.ResponseWriter, r *http.Request) {
 func (h *AdminTasksHandler) UpdateCourse(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 	var course entity.Course
-	if err := json.NewDecoder(r.Body).Decode(&course); err != nil {
+	clearTerm, err := decodeTermUpdate(r, &course)
+	if err != nil {
 		http.Error(w, "Invalid payload", http.StatusBadRequest)
 		return
 	}
+	course.ClearTerm = clearTerm
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		http.Error(w, "Invalid ID format", http.StatusBadRequest)
@@ -72,6 +86,10 @@ func (h *AdminTasksHandler) UpdateCourse(w http.ResponseWriter, r *http.Request)
 	}
 	course.ID = oid
 	if err := h.adminUseCase.UpdateCourse(r.Context(), &course); err != nil {
+		if err == entity.ErrTermNotFound || errors.Is(err, entity.ErrInvalidReference) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "Failed to update course", http.StatusInternalServerError)
 		return
 	}
@@ -79,10 +97,29 @@ func (h *AdminTasksHandler) UpdateCourse(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Course updated successfully"})
 }
 
+// decodeTermUpdate decodes a course or class update into v and reports
+// whether it sent "term_id": null, which detaches it from its term. Leaving
+// term_id out keeps the term.
+func decodeTermUpdate(r *http.Request, v interface{}) (bool, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return false, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return false, err
+	}
+	raw, ok := fields["term_id"]
+	return ok && string(raw) == "null", nil
+}
+
 func (h *AdminTasksHandler) DeleteCourse(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 	if err := h.adminUseCase.DeleteCourse(r.Context(), id); err != nil {
-		http.Error(w, "Failed to delete course", http.StatusInternalServerError)
+		writeDeleteError(w, err, "Failed to delete course")
 		return
 	}
 
@@ -90,9 +127,15 @@ func (h *AdminTasksHandler) DeleteCourse(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *AdminTasksHandler) ListClasses(w http.ResponseWriter, r *http.Request) {
-	classes, err := h.adminUseCase.ListClasses(r.Context())
+	opts, err := parseListOptions(r, "term", "course_id", "teacher_id", "student_id", "archived")
+	if err != nil {
+		writeListError(w, err, "Failed to list classes")
+		return
+	}
+
+	classes, err := h.adminUseCase.ListClasses(r.Context(), opts)
 	if err != nil {
-		http.Error(w, "Failed to list classes", http.StatusInternalServerError)
+		writeListError(w, err, "Failed to list classes")
 		return
 	}
 
@@ -107,6 +150,10 @@ func (h *AdminTasksHandler) CreateClass(w http.ResponseWriter, r *http.Request)
 	}
 	class.CreatedAt = class.CreatedAt.UTC()
 	if err := h.adminUseCase.CreateClass(r.Context(), &class); err != nil {
+		if err == entity.ErrTermNotFound || errors.Is(err, entity.ErrInvalidReference) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "Failed to create class", http.StatusInternalServerError)
 		return
 	}
@@ -128,10 +175,12 @@ func (h *AdminTasksHandler) GetClass(w http.ResponseWriter, r *http.Request) {
 func (h *AdminTasksHandler) UpdateClass(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 	var class entity.Class
-	if err := json.NewDecoder(r.Body).Decode(&class); err != nil {
+	clearTerm, err := decodeTermUpdate(r, &class)
+	if err != nil {
 		http.Error(w, "Invalid payload", http.StatusBadRequest)
 		return
 	}
+	class.ClearTerm = clearTerm
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		http.Error(w, "Invalid ID format", http.StatusBadRequest)
@@ -139,6 +188,10 @@ func (h *AdminTasksHandler) UpdateClass(w http.ResponseWriter, r *http.Request)
 	}
 	class.ID = oid
 	if err := h.adminUseCase.UpdateClass(r.Context(), &class); err != nil {
+		if err == entity.ErrTermNotFound || errors.Is(err, entity.ErrInvalidReference) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "Failed to update class", http.StatusInternalServerError)
 		return
 	}
@@ -149,7 +202,7 @@ func (h *AdminTasksHandler) UpdateClass(w http.ResponseWriter, r *http.Request)
 func (h *AdminTasksHandler) DeleteClass(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 	if err := h.adminUseCase.DeleteClass(r.Context(), id); err != nil {
-		http.Error(w, "Failed to delete class", http.StatusInternalServerError)
+		writeDeleteError(w, err, "Failed to delete class")
 		return
 	}
 
@@ -157,9 +210,15 @@ func (h *AdminTasksHandler) DeleteClass(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *AdminTasksHandler) ListAnnouncements(w http.ResponseWriter, r *http.Request) {
-	anns, err := h.adminUseCase.ListAnnouncements(r.Context())
+	opts, err := parseListOptions(r, "target_audience", "target_id", "archived")
+	if err != nil {
+		writeListError(w, err, "Failed to list announcements")
+		return
+	}
+
+	anns, err := h.adminUseCase.ListAnnouncements(r.Context(), opts)
 	if err != nil {
-		http.Error(w, "Failed to list announcements", http.StatusInternalServerError)
+		writeListError(w, err, "Failed to list announcements")
 		return
 	}
 	
@@ -174,6 +233,10 @@ func (h *AdminTasksHandler) CreateAnnouncement(w http.ResponseWriter, r *http.Re
 	}
 	ann.CreatedAt = ann.CreatedAt.UTC()
 	if err := h.adminUseCase.CreateAnnouncement(r.Context(), &ann); err != nil {
+		if errors.Is(err, entity.ErrInvalidReference) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "Failed to create announcement", http.StatusInternalServerError)
 		return
 	}
@@ -206,6 +269,10 @@ func (h *AdminTasksHandler) UpdateAnnouncement(w http.ResponseWriter, r *http.Re
 	}
 	ann.ID = oid
 	if err := h.adminUseCase.UpdateAnnouncement(r.Context(), &ann); err != nil {
+		if errors.Is(err, entity.ErrInvalidReference) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "Failed to update announcement", http.StatusInternalServerError)
 		return
 	}
@@ -216,7 +283,7 @@ func (h *AdminTasksHandler) UpdateAnnouncement(w http.ResponseWriter, r *http.Re
 func (h *AdminTasksHandler) DeleteAnnouncement(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 	if err := h.adminUseCase.DeleteAnnouncement(r.Context(), id); err != nil {
-		http.Error(w, "Failed to delete announcement", http.StatusInternalServerError)
+		writeDeleteError(w, err, "Failed to delete announcement")
 		return
 	}
 
@@ -0,0 +1,161 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/srgjo27/e-learning/internal/entity"
+	"github.com/srgjo27/e-learning/internal/usecase"
+)
+
+// APITokenHandler manages personal API tokens, and lets admins create
+// service accounts and their tokens.
+type APITokenHandler struct {
+	authUseCase *usecase.AuditedAuthUseCase
+}
+
+func NewAPITokenHandler(u *usecase.AuditedAuthUseCase) *APITokenHandler {
+	return &APITokenHandler{
+		authUseCase: u,
+	}
+}
+
+type createAPITokenRequest struct {
+	Name      string            `json:"name"`
+	Scopes    []entity.APIScope `json:"scopes"`
+	ExpiresAt *time.Time        `json:"expires_at,omitempty"`
+}
+
+type createServiceAccountRequest struct {
+	Email string      `json:"email"`
+	Role  entity.Role `json:"role"`
+}
+
+func writeAPITokenError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, entity.ErrInvalidScope), errors.Is(err, entity.ErrInvalidAPIToken):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, entity.ErrRoleNotFound):
+		http.Error(w, "Invalid role", http.StatusBadRequest)
+	case errors.Is(err, entity.ErrPermissionDenied):
+		http.Error(w, "Forbidden - cannot grant this role", http.StatusForbidden)
+	case errors.Is(err, entity.ErrNotServiceAccount):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, entity.ErrEmailExists):
+		http.Error(w, "Email already exists", http.StatusConflict)
+	case errors.Is(err, entity.ErrAPITokenNotFound):
+		http.Error(w, "API token not found", http.StatusNotFound)
+	case errors.Is(err, entity.ErrUserNotFound):
+		http.Error(w, "User not found", http.StatusNotFound)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+func (h *APITokenHandler) ListTokens(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	tokens, err := h.authUseCase.ListAPITokens(r.Context(), userID)
+	if err != nil {
+		writeAPITokenError(w, err, "Failed to list API tokens")
+		return
+	}
+
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// CreateToken creates a token for the signed-in user. The response holds
+// the token itself, which is not shown again.
+func (h *APITokenHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	var req createAPITokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	token, err := h.authUseCase.CreateAPIToken(r.Context(), userID, userID, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		writeAPITokenError(w, err, "Failed to create API token")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(token)
+}
+
+func (h *APITokenHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	if err := h.authUseCase.RevokeAPIToken(r.Context(), userID, mux.Vars(r)["id"]); err != nil {
+		writeAPITokenError(w, err, "Failed to revoke API token")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "API token revoked"})
+}
+
+func (h *APITokenHandler) CreateServiceAccount(w http.ResponseWriter, r *http.Request) {
+	var req createServiceAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if req.Email == "" {
+		http.Error(w, "Email required", http.StatusBadRequest)
+		return
+	}
+	user, err := h.authUseCase.CreateServiceAccount(r.Context(), req.Email, req.Role)
+	if err != nil {
+		writeAPITokenError(w, err, "Failed to create service account")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(user)
+}
+
+// ListUserTokens lists the tokens of any user, for admins.
+func (h *APITokenHandler) ListUserTokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := h.authUseCase.ListAPITokens(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeAPITokenError(w, err, "Failed to list API tokens")
+		return
+	}
+
+	json.NewEncoder(w).Encode(tokens)
+}
+
+func (h *APITokenHandler) CreateServiceAccountToken(w http.ResponseWriter, r *http.Request) {
+	adminID := r.Context().Value("userID").(string)
+
+	var req createAPITokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	token, err := h.authUseCase.CreateServiceAccountToken(r.Context(), adminID, mux.Vars(r)["id"], req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		writeAPITokenError(w, err, "Failed to create API token")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(token)
+}
+
+// RevokeUserToken revokes a token of any user, for admins.
+func (h *APITokenHandler) RevokeUserToken(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := h.authUseCase.RevokeAPIToken(r.Context(), vars["id"], vars["tokenId"]); err != nil {
+		writeAPITokenError(w, err, "Failed to revoke API token")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "API token revoked"})
+}
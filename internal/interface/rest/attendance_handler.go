@@ -0,0 +1,281 @@
+package rest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/srgjo27/e-learning/internal/entity"
+	"github.com/srgjo27/e-learning/internal/usecase"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AttendanceHandler struct {
+	attendanceUseCase *usecase.AttendanceUseCase
+}
+
+func NewAttendanceHandler(u *usecase.AttendanceUseCase) *AttendanceHandler {
+	return &AttendanceHandler{
+		attendanceUseCase: u,
+	}
+}
+
+func writeAttendanceError(w http.ResponseWriter, err error, fallback string) {
+	if writeThrottled(w, err) {
+		return
+	}
+	switch err {
+	case entity.ErrSessionNotFound, entity.ErrCourseNotFound, entity.ErrUserNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case entity.ErrUnauthorized:
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	case entity.ErrStudentNotInClass:
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case entity.ErrInvalidSession, entity.ErrInvalidAttendanceStatus, entity.ErrInvalidCheckInCode:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// --- Teacher ---
+
+func (h *AttendanceHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessions, err := h.attendanceUseCase.ListSessions(r.Context(), userID, mux.Vars(r)["id"])
+	if err != nil {
+		writeAttendanceError(w, err, "Failed to list sessions")
+		return
+	}
+
+	json.NewEncoder(w).Encode(sessions)
+}
+
+func (h *AttendanceHandler) CreateSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	classID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	var s entity.ClassSession
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	s.ClassID = classID
+
+	if err := h.attendanceUseCase.CreateSession(r.Context(), userID, &s); err != nil {
+		writeAttendanceError(w, err, "Failed to create session")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(s)
+}
+
+func (h *AttendanceHandler) UpdateSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	oid, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	var s entity.ClassSession
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	s.ID = oid
+
+	if err := h.attendanceUseCase.UpdateSession(r.Context(), userID, &s); err != nil {
+		writeAttendanceError(w, err, "Failed to update session")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Session updated successfully"})
+}
+
+func (h *AttendanceHandler) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.attendanceUseCase.DeleteSession(r.Context(), userID, mux.Vars(r)["id"]); err != nil {
+		writeAttendanceError(w, err, "Failed to delete session")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AttendanceHandler) OpenCheckIn(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		TTLMinutes int `json:"ttl_minutes"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+	}
+
+	session, err := h.attendanceUseCase.OpenCheckIn(r.Context(), userID, mux.Vars(r)["id"], time.Duration(req.TTLMinutes)*time.Minute)
+	if err != nil {
+		writeAttendanceError(w, err, "Failed to open check-in")
+		return
+	}
+
+	json.NewEncoder(w).Encode(session)
+}
+
+func (h *AttendanceHandler) GetSessionAttendance(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	records, err := h.attendanceUseCase.GetSessionAttendance(r.Context(), userID, mux.Vars(r)["id"])
+	if err != nil {
+		writeAttendanceError(w, err, "Failed to get attendance")
+		return
+	}
+
+	json.NewEncoder(w).Encode(records)
+}
+
+func (h *AttendanceHandler) RecordAttendance(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var entries []usecase.AttendanceEntry
+	if err := json.NewDecoder(r.Body).Decode(&entries); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.attendanceUseCase.RecordAttendance(r.Context(), userID, mux.Vars(r)["id"], entries); err != nil {
+		writeAttendanceError(w, err, "Failed to record attendance")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Attendance recorded successfully"})
+}
+
+// GetClassReport returns the attendance report as JSON, or as a CSV download
+// with ?format=csv.
+func (h *AttendanceHandler) GetClassReport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	report, err := h.attendanceUseCase.GetClassReport(r.Context(), userID, mux.Vars(r)["id"])
+	if err != nil {
+		writeAttendanceError(w, err, "Failed to build attendance report")
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		writeAttendanceCSV(w, report)
+		return
+	}
+
+	json.NewEncoder(w).Encode(report)
+}
+
+// --- Student ---
+
+func (h *AttendanceHandler) CheckIn(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	record, err := h.attendanceUseCase.CheckIn(r.Context(), userID, mux.Vars(r)["id"], req.Code)
+	if err != nil {
+		writeAttendanceError(w, err, "Failed to check in")
+		return
+	}
+
+	json.NewEncoder(w).Encode(record)
+}
+
+func (h *AttendanceHandler) GetMyAttendance(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rate, err := h.attendanceUseCase.GetStudentReport(r.Context(), userID, mux.Vars(r)["id"])
+	if err != nil {
+		writeAttendanceError(w, err, "Failed to get attendance")
+		return
+	}
+
+	json.NewEncoder(w).Encode(rate)
+}
+
+func writeAttendanceCSV(w http.ResponseWriter, report *entity.ClassAttendanceReport) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="attendance-`+report.ClassID.Hex()+`.csv"`)
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"student_id", "email", "sessions", "present", "late", "absent", "excused", "rate"})
+	for _, s := range report.Students {
+		cw.Write([]string{
+			s.StudentID.Hex(),
+			s.Email,
+			strconv.Itoa(s.Sessions),
+			strconv.Itoa(s.Present),
+			strconv.Itoa(s.Late),
+			strconv.Itoa(s.Absent),
+			strconv.Itoa(s.Excused),
+			strconv.FormatFloat(s.Rate, 'f', 1, 64),
+		})
+	}
+	cw.Flush()
+}
@@ -0,0 +1,34 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/srgjo27/e-learning/internal/usecase"
+)
+
+type AuditHandler struct {
+	auditUseCase *usecase.AuditUseCase
+}
+
+func NewAuditHandler(u *usecase.AuditUseCase) *AuditHandler {
+	return &AuditHandler{
+		auditUseCase: u,
+	}
+}
+
+func (h *AuditHandler) ListAudit(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseListOptions(r, "action", "actor_id", "target_type", "target_id", "subject_id", "on_behalf_of", "from", "to")
+	if err != nil {
+		writeListError(w, err, "Failed to list audit log")
+		return
+	}
+
+	entries, err := h.auditUseCase.ListAudit(r.Context(), opts)
+	if err != nil {
+		writeListError(w, err, "Failed to list audit log")
+		return
+	}
+
+	json.NewEncoder(w).Encode(entries)
+}
@@ -2,17 +2,20 @@ package rest
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/srgjo27/e-learning/internal/entity"
 	"github.com/srgjo27/e-learning/internal/usecase"
 )
 
 type AuthHandler struct {
-	authUseCase *usecase.AuthUseCase
+	authUseCase *usecase.AuditedAuthUseCase
 }
 
-func NewAuthHandler(u *usecase.AuthUseCase) *AuthHandler {
+func NewAuthHandler(u *usecase.AuditedAuthUseCase) *AuthHandler {
 	return &AuthHandler{
 		authUseCase: u,
 	}
@@ -24,6 +27,14 @@ type registerRequest struct {
 	Role 	 entity.Role `json:"role"`
 }
 
+type verifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type resendVerificationRequest struct {
+	Email string `json:"email"`
+}
+
 type loginRequest struct {
 	Email	 string `json:"email"`
 	Password string `json:"password"`
@@ -62,12 +73,19 @@ func (h *AuthHandler) HandleRegister(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Email already registered", http.StatusConflict)
 			return
 		}
+		if err == entity.ErrPermissionDenied {
+			http.Error(w, "Forbidden - only student accounts can be registered", http.StatusForbidden)
+			return
+		}
+		if writePasswordRejected(w, err) {
+			return
+		}
 		http.Error(w, "Registration failed", http.StatusInternalServerError)
 		return
 	}
 
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{"message": "User registered successfully"})
+	json.NewEncoder(w).Encode(map[string]string{"message": "User registered successfully, confirm your email address with the token sent to it"})
 }
 
 func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
@@ -88,17 +106,26 @@ func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.authUseCase.Login(r.Context(), req.Email, req.Password)
+	result, err := h.authUseCase.Login(r.Context(), req.Email, req.Password)
 	if err != nil {
 		if err == entity.ErrUserNotFound || err == entity.ErrInvalidPassword{
 			http.Error(w, "Invalid email or password", http.StatusUnauthorized)
 			return
 		}
+		if writeThrottled(w, err) {
+			return
+		}
+		if err == entity.ErrEmailNotVerified {
+			http.Error(w, "Email address not verified", http.StatusForbidden)
+			return
+		}
 		http.Error(w, "Login failed", http.StatusInternalServerError)
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{"token": token})
+	// With a second factor the result is a challenge to be completed at
+	// /v1/auth/login/mfa rather than a session token.
+	json.NewEncoder(w).Encode(result)
 }
 
 func (h *AuthHandler) HandlePasswordResetRequest(w http.ResponseWriter, r *http.Request) {
@@ -119,17 +146,73 @@ func (h *AuthHandler) HandlePasswordResetRequest(w http.ResponseWriter, r *http.
 		return
 	}
 
-	token, err := h.authUseCase.RequestPasswordReset(r.Context(), req.Email)
-	if err != nil {
-		if err == entity.ErrUserNotFound {
-			http.Error(w, "Email not found", http.StatusNotFound)
+	// The answer is the same whether or not the email is registered. The
+	// token itself only ever goes out by email.
+	if err := h.authUseCase.RequestPasswordReset(r.Context(), req.Email); err != nil {
+		if writeThrottled(w, err) {
 			return
 		}
 		http.Error(w, "Could not generate reset token", http.StatusInternalServerError)
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{"reset_token": token})
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message": "If the email is registered, a reset link has been sent to it"})
+}
+
+func (h *AuthHandler) HandleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req verifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "Token required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authUseCase.VerifyEmail(r.Context(), req.Token); err != nil {
+		switch err {
+		case entity.ErrInvalidToken, entity.ErrUserNotFound:
+			http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+		case entity.ErrEmailExists:
+			http.Error(w, "Email already registered", http.StatusConflict)
+		default:
+			http.Error(w, "Email verification failed", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Email address verified"})
+}
+
+// HandleResendVerification answers the same whether or not the email is
+// registered or already verified.
+func (h *AuthHandler) HandleResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req resendVerificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		http.Error(w, "Email required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authUseCase.ResendEmailVerification(r.Context(), req.Email); err != nil {
+		if writeThrottled(w, err) {
+			return
+		}
+		http.Error(w, "Could not send verification", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message": "If the email is registered and not yet verified, a new token has been sent to it"})
+}
+
+// writeThrottled answers 429 with a Retry-After header if err says that
+// attempts are being refused, and reports whether it did.
+func writeThrottled(w http.ResponseWriter, err error) bool {
+	var throttled *entity.ThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+	http.Error(w, "Too many attempts, try again later", http.StatusTooManyRequests)
+	return true
 }
 
 func (h *AuthHandler) HandlePasswordReset(w http.ResponseWriter, r *http.Request) {
@@ -150,15 +233,27 @@ func (h *AuthHandler) HandlePasswordReset(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err := h.authUseCase.ResetPassword(r.Context(), req.Token, req.NewPassword)
+	_, err := h.authUseCase.ResetPassword(r.Context(), req.Token, req.NewPassword)
 	if err != nil {
 		if err == entity.ErrInvalidToken {
 			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
 			return
 		}
+		if writePasswordRejected(w, err) {
+			return
+		}
 		http.Error(w, "Password reset failed", http.StatusInternalServerError)
 		return
 	}
 
 	json.NewEncoder(w).Encode(map[string]string{"message": "Password reset successful"})
-}
\ No newline at end of file
+}
+// writePasswordRejected answers 400 with the reason if err says that a new
+// password was refused, and reports whether it did.
+func writePasswordRejected(w http.ResponseWriter, err error) bool {
+	if errors.Is(err, entity.ErrWeakPassword) || errors.Is(err, entity.ErrPasswordBreached) || errors.Is(err, entity.ErrPasswordReused) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/srgjo27/e-learning/internal/entity"
+	"github.com/srgjo27/e-learning/internal/usecase"
+)
+
+type CalendarHandler struct {
+	calendarUseCase *usecase.CalendarUseCase
+	baseURL         string
+}
+
+// NewCalendarHandler builds the feed handler. baseURL is the public origin used
+// in feed links; when empty it is derived from the incoming request.
+func NewCalendarHandler(u *usecase.CalendarUseCase, baseURL string) *CalendarHandler {
+	return &CalendarHandler{
+		calendarUseCase: u,
+		baseURL:         strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// GetFeedURL reports whether the user has a calendar feed. The URL cannot
+// be shown again after it was created; RegenerateFeedURL makes a new one.
+func (h *CalendarHandler) GetFeedURL(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	enabled, err := h.calendarUseCase.HasFeed(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to get calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]bool{"enabled": enabled})
+}
+
+// RegenerateFeedURL creates the calendar feed, or replaces its URL, and
+// answers with the URL. This is the only time it is shown.
+func (h *CalendarHandler) RegenerateFeedURL(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	token, err := h.calendarUseCase.RegenerateToken(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to regenerate calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"url": h.feedURL(r, token)})
+}
+
+func (h *CalendarHandler) ServeFeed(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+
+	events, err := h.calendarUseCase.Events(r.Context(), token)
+	if err != nil {
+		if err == entity.ErrInvalidToken {
+			http.Error(w, "Calendar not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to build calendar", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=900")
+	w.Write(encodeICalendar(events, time.Now()))
+}
+
+func (h *CalendarHandler) feedURL(r *http.Request, token string) string {
+	base := h.baseURL
+	if base == "" {
+		scheme := "http"
+		if isHTTPS(r) {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+	return base + "/v1/calendar/" + token + ".ics"
+}
+
+// encodeICalendar renders events as an RFC 5545 VCALENDAR.
+func encodeICalendar(events []*entity.CalendarEvent, now time.Time) []byte {
+	var b strings.Builder
+	line := func(s string) {
+		b.WriteString(foldICalLine(s))
+		b.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//e-learning//Deadlines//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:" + escapeICalText("E-learning deadlines"))
+	for _, e := range events {
+		stamp := e.Updated
+		if stamp.IsZero() {
+			stamp = now
+		}
+
+		line("BEGIN:VEVENT")
+		line("UID:" + e.UID)
+		line("DTSTAMP:" + icalTime(stamp))
+		line("DTSTART:" + icalTime(e.Start))
+		line("DTEND:" + icalTime(e.Start))
+		line("SUMMARY:" + escapeICalText(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION:" + escapeICalText(e.Description))
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+
+	return []byte(b.String())
+}
+
+func icalTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+func escapeICalText(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, ";", `\;`)
+	s = strings.ReplaceAll(s, ",", `\,`)
+	s = strings.ReplaceAll(s, "\r\n", `\n`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return s
+}
+
+// foldICalLine splits content lines longer than 75 octets, continuing them on
+// lines that start with a single space. It never splits a UTF-8 sequence.
+func foldICalLine(s string) string {
+	const limit = 75
+	if len(s) <= limit {
+		return s
+	}
+
+	var b strings.Builder
+	width := 0
+	for _, r := range s {
+		size := len(string(r))
+		if width+size > limit {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
@@ -0,0 +1,341 @@
+package rest
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/srgjo27/e-learning/internal/entity"
+	"github.com/srgjo27/e-learning/internal/usecase"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const maxAttachmentSize = 25 << 20
+
+type ContentHandler struct {
+	contentUseCase *usecase.ContentUseCase
+}
+
+func NewContentHandler(u *usecase.ContentUseCase) *ContentHandler {
+	return &ContentHandler{
+		contentUseCase: u,
+	}
+}
+
+func writeContentError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case entity.ErrCourseNotFound, entity.ErrModuleNotFound, entity.ErrLessonNotFound, entity.ErrAttachmentNotFound, entity.ErrBlobNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case entity.ErrUnauthorized:
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	case entity.ErrItemLocked:
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case entity.ErrInvalidLesson:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// --- Teacher ---
+
+func (h *ContentHandler) ListModules(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	modules, err := h.contentUseCase.ListModulesForTeacher(r.Context(), userID, mux.Vars(r)["id"])
+	if err != nil {
+		writeContentError(w, err, "Failed to list modules")
+		return
+	}
+
+	json.NewEncoder(w).Encode(modules)
+}
+
+func (h *ContentHandler) CreateModule(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	courseID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	var m entity.Module
+	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	m.CourseID = courseID
+
+	if err := h.contentUseCase.CreateModule(r.Context(), userID, &m); err != nil {
+		writeContentError(w, err, "Failed to create module")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(m)
+}
+
+func (h *ContentHandler) UpdateModule(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	oid, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	var m entity.Module
+	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	m.ID = oid
+
+	if err := h.contentUseCase.UpdateModule(r.Context(), userID, &m); err != nil {
+		writeContentError(w, err, "Failed to update module")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Module updated successfully"})
+}
+
+func (h *ContentHandler) DeleteModule(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.contentUseCase.DeleteModule(r.Context(), userID, mux.Vars(r)["id"]); err != nil {
+		writeContentError(w, err, "Failed to delete module")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ContentHandler) CreateLesson(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	moduleID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	var l entity.Lesson
+	if err := json.NewDecoder(r.Body).Decode(&l); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	l.ModuleID = moduleID
+
+	if err := h.contentUseCase.CreateLesson(r.Context(), userID, &l); err != nil {
+		writeContentError(w, err, "Failed to create lesson")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(l)
+}
+
+func (h *ContentHandler) GetLesson(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	lesson, err := h.contentUseCase.GetLessonForTeacher(r.Context(), userID, mux.Vars(r)["id"])
+	if err != nil {
+		writeContentError(w, err, "Failed to get lesson")
+		return
+	}
+
+	json.NewEncoder(w).Encode(lesson)
+}
+
+func (h *ContentHandler) UpdateLesson(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	oid, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	var l entity.Lesson
+	if err := json.NewDecoder(r.Body).Decode(&l); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	l.ID = oid
+
+	if err := h.contentUseCase.UpdateLesson(r.Context(), userID, &l); err != nil {
+		writeContentError(w, err, "Failed to update lesson")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Lesson updated successfully"})
+}
+
+func (h *ContentHandler) DeleteLesson(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.contentUseCase.DeleteLesson(r.Context(), userID, mux.Vars(r)["id"]); err != nil {
+		writeContentError(w, err, "Failed to delete lesson")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ContentHandler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAttachmentSize)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "file form field required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	contentType := header.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	att, err := h.contentUseCase.AddAttachment(r.Context(), userID, mux.Vars(r)["id"], header.Filename, contentType, file)
+	if err != nil {
+		writeContentError(w, err, "Failed to upload attachment")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(att)
+}
+
+func (h *ContentHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	if err := h.contentUseCase.RemoveAttachment(r.Context(), userID, vars["id"], vars["attachmentId"]); err != nil {
+		writeContentError(w, err, "Failed to delete attachment")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ContentHandler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	att, rc, err := h.contentUseCase.OpenAttachmentForTeacher(r.Context(), userID, vars["id"], vars["attachmentId"])
+	if err != nil {
+		writeContentError(w, err, "Failed to open attachment")
+		return
+	}
+
+	serveAttachment(w, att, rc)
+}
+
+// --- Student ---
+
+func (h *ContentHandler) ListModulesForStudent(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	modules, err := h.contentUseCase.ListModulesForStudent(r.Context(), userID, mux.Vars(r)["id"])
+	if err != nil {
+		writeContentError(w, err, "Failed to list modules")
+		return
+	}
+
+	json.NewEncoder(w).Encode(modules)
+}
+
+func (h *ContentHandler) GetLessonForStudent(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	lesson, err := h.contentUseCase.GetLessonForStudent(r.Context(), userID, mux.Vars(r)["id"])
+	if err != nil {
+		writeContentError(w, err, "Failed to get lesson")
+		return
+	}
+
+	json.NewEncoder(w).Encode(lesson)
+}
+
+func (h *ContentHandler) DownloadAttachmentForStudent(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	att, rc, err := h.contentUseCase.OpenAttachmentForStudent(r.Context(), userID, vars["id"], vars["attachmentId"])
+	if err != nil {
+		writeContentError(w, err, "Failed to open attachment")
+		return
+	}
+
+	serveAttachment(w, att, rc)
+}
+
+func serveAttachment(w http.ResponseWriter, att *entity.Attachment, rc io.ReadCloser) {
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", att.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": att.Name}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	io.Copy(w, rc)
+}
@@ -0,0 +1,41 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+)
+
+// cascadeRequested reports whether a delete was sent with ?cascade=true.
+func cascadeRequested(r *http.Request) bool {
+	cascade, _ := strconv.ParseBool(r.URL.Query().Get("cascade"))
+	return cascade
+}
+
+// writeDeleteError answers a refused delete with 409 and the number of
+// dependents per collection, so the client can decide whether to cascade.
+func writeDeleteError(w http.ResponseWriter, err error, fallback string) {
+	var depErr *entity.DependentsError
+	switch {
+	case errors.As(err, &depErr):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":      depErr.Error(),
+			"dependents": depErr.Dependents,
+		})
+	case errors.Is(err, entity.ErrCourseNotFound),
+		errors.Is(err, entity.ErrClassNotFound),
+		errors.Is(err, entity.ErrUserNotFound),
+		errors.Is(err, entity.ErrAssignmentNotFound),
+		errors.Is(err, entity.ErrAssessmentNotFound),
+		errors.Is(err, entity.ErrAnnouncementNotFound),
+		errors.Is(err, entity.ErrTermNotFound),
+		errors.Is(err, entity.ErrTrashItemNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,278 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/srgjo27/e-learning/internal/entity"
+	"github.com/srgjo27/e-learning/internal/usecase"
+)
+
+// GuardianHandler serves the guardian's read-only view of their linked
+// students, the invitations students send to their guardians and the links
+// admins manage.
+type GuardianHandler struct {
+	guardianUseCase *usecase.AuditedGuardianUseCase
+}
+
+func NewGuardianHandler(u *usecase.AuditedGuardianUseCase) *GuardianHandler {
+	return &GuardianHandler{
+		guardianUseCase: u,
+	}
+}
+
+type linkGuardianRequest struct {
+	GuardianID string `json:"guardian_id"`
+	StudentID  string `json:"student_id"`
+}
+
+type inviteGuardianRequest struct {
+	Email string `json:"email"`
+}
+
+type acceptInvitationRequest struct {
+	Token string `json:"token"`
+}
+
+func writeGuardianError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, entity.ErrGuardianLinkNotFound):
+		http.Error(w, "Student not found", http.StatusNotFound)
+	case errors.Is(err, entity.ErrUserNotFound), errors.Is(err, entity.ErrCourseNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, entity.ErrNotGuardian), errors.Is(err, entity.ErrNotStudent),
+		errors.Is(err, entity.ErrInvalidToken), errors.Is(err, entity.ErrInvalidListOptions):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, entity.ErrInvitationMismatch), errors.Is(err, entity.ErrUnauthorized):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, entity.ErrGuardianLinkExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, entity.ErrTooManyInvitations):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// --- Admin ---
+
+func (h *GuardianHandler) LinkGuardian(w http.ResponseWriter, r *http.Request) {
+	adminID := r.Context().Value("userID").(string)
+
+	var req linkGuardianRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	link, err := h.guardianUseCase.LinkGuardian(r.Context(), adminID, req.GuardianID, req.StudentID)
+	if err != nil {
+		writeGuardianError(w, err, "Failed to link guardian")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(link)
+}
+
+// ListUserGuardians lists the guardians and open invitations of a student.
+func (h *GuardianHandler) ListUserGuardians(w http.ResponseWriter, r *http.Request) {
+	links, err := h.guardianUseCase.ListStudentGuardians(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeGuardianError(w, err, "Failed to list guardians")
+		return
+	}
+
+	json.NewEncoder(w).Encode(links)
+}
+
+func (h *GuardianHandler) RemoveLink(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.guardianUseCase.RemoveGuardianLink(r.Context(), mux.Vars(r)["id"]); err != nil {
+		if errors.Is(err, entity.ErrGuardianLinkNotFound) {
+			http.Error(w, "Guardian link not found", http.StatusNotFound)
+			return
+		}
+		writeGuardianError(w, err, "Failed to remove guardian link")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Guardian link removed"})
+}
+
+// --- Student ---
+
+func (h *GuardianHandler) ListMyGuardians(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	links, err := h.guardianUseCase.ListStudentGuardians(r.Context(), userID)
+	if err != nil {
+		writeGuardianError(w, err, "Failed to list guardians")
+		return
+	}
+
+	json.NewEncoder(w).Encode(links)
+}
+
+func (h *GuardianHandler) InviteGuardian(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	var req inviteGuardianRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if req.Email == "" {
+		http.Error(w, "Email required", http.StatusBadRequest)
+		return
+	}
+
+	link, err := h.guardianUseCase.InviteGuardian(r.Context(), userID, req.Email)
+	if err != nil {
+		writeGuardianError(w, err, "Failed to invite guardian")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(link)
+}
+
+func (h *GuardianHandler) CancelInvitation(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	if err := h.guardianUseCase.CancelGuardianInvitation(r.Context(), userID, mux.Vars(r)["id"]); err != nil {
+		if errors.Is(err, entity.ErrGuardianLinkNotFound) {
+			http.Error(w, "Invitation not found", http.StatusNotFound)
+			return
+		}
+		writeGuardianError(w, err, "Failed to cancel invitation")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Invitation cancelled"})
+}
+
+// --- Guardian ---
+
+func (h *GuardianHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	var req acceptInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	link, err := h.guardianUseCase.AcceptGuardianInvitation(r.Context(), userID, req.Token)
+	if err != nil {
+		writeGuardianError(w, err, "Failed to accept invitation")
+		return
+	}
+
+	json.NewEncoder(w).Encode(link)
+}
+
+func (h *GuardianHandler) ListStudents(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	students, err := h.guardianUseCase.ListGuardianStudents(r.Context(), userID)
+	if err != nil {
+		writeGuardianError(w, err, "Failed to list students")
+		return
+	}
+
+	json.NewEncoder(w).Encode(students)
+}
+
+func (h *GuardianHandler) ListStudentCourses(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	courses, err := h.guardianUseCase.StudentCourses(r.Context(), userID, mux.Vars(r)["id"])
+	if err != nil {
+		writeGuardianError(w, err, "Failed to list courses")
+		return
+	}
+
+	json.NewEncoder(w).Encode(courses)
+}
+
+func (h *GuardianHandler) ListStudentAssignments(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	opts, err := parseListOptions(r, "course_id")
+	if err != nil {
+		writeListError(w, err, "Failed to list assignments")
+		return
+	}
+
+	assignments, err := h.guardianUseCase.StudentAssignments(r.Context(), userID, mux.Vars(r)["id"], opts)
+	if err != nil {
+		writeGuardianError(w, err, "Failed to list assignments")
+		return
+	}
+
+	json.NewEncoder(w).Encode(assignments)
+}
+
+func (h *GuardianHandler) ListStudentAssessments(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	opts, err := parseListOptions(r, "course_id")
+	if err != nil {
+		writeListError(w, err, "Failed to list assessments")
+		return
+	}
+
+	assessments, err := h.guardianUseCase.StudentAssessments(r.Context(), userID, mux.Vars(r)["id"], opts)
+	if err != nil {
+		writeGuardianError(w, err, "Failed to list assessments")
+		return
+	}
+
+	json.NewEncoder(w).Encode(assessments)
+}
+
+// ListStudentSubmissions lists the student's submissions with their grades.
+func (h *GuardianHandler) ListStudentSubmissions(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	submissions, err := h.guardianUseCase.StudentSubmissions(r.Context(), userID, mux.Vars(r)["id"])
+	if err != nil {
+		writeGuardianError(w, err, "Failed to list submissions")
+		return
+	}
+
+	json.NewEncoder(w).Encode(submissions)
+}
+
+func (h *GuardianHandler) GetStudentProgress(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	vars := mux.Vars(r)
+
+	progress, err := h.guardianUseCase.StudentProgress(r.Context(), userID, vars["id"], vars["courseId"])
+	if err != nil {
+		writeGuardianError(w, err, "Failed to get progress")
+		return
+	}
+
+	json.NewEncoder(w).Encode(progress)
+}
+
+func (h *GuardianHandler) ListStudentAnnouncements(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	opts, err := parseListOptions(r, "target_audience")
+	if err != nil {
+		writeListError(w, err, "Failed to list announcements")
+		return
+	}
+
+	announcements, err := h.guardianUseCase.StudentAnnouncements(r.Context(), userID, mux.Vars(r)["id"], opts)
+	if err != nil {
+		writeGuardianError(w, err, "Failed to list announcements")
+		return
+	}
+
+	json.NewEncoder(w).Encode(announcements)
+}
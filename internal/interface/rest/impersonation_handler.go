@@ -0,0 +1,137 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/srgjo27/e-learning/internal/entity"
+	"github.com/srgjo27/e-learning/internal/usecase"
+)
+
+// ImpersonationHandler lets admins use the application as another user for
+// support, and review the sessions in which they did.
+type ImpersonationHandler struct {
+	authUseCase *usecase.AuditedAuthUseCase
+}
+
+func NewImpersonationHandler(u *usecase.AuditedAuthUseCase) *ImpersonationHandler {
+	return &ImpersonationHandler{
+		authUseCase: u,
+	}
+}
+
+type startImpersonationRequest struct {
+	UserID      string `json:"user_id"`
+	Reason      string `json:"reason"`
+	Minutes     int    `json:"minutes,omitempty"`
+	AllowWrites bool   `json:"allow_writes,omitempty"`
+}
+
+func writeImpersonationError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, entity.ErrImpersonationReason):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, entity.ErrCannotImpersonate):
+		http.Error(w, "Forbidden - "+err.Error(), http.StatusForbidden)
+	case errors.Is(err, entity.ErrPermissionDenied):
+		http.Error(w, "Forbidden - insufficient permissions", http.StatusForbidden)
+	case errors.Is(err, entity.ErrImpersonationNotFound):
+		http.Error(w, "Impersonation session not found", http.StatusNotFound)
+	case errors.Is(err, entity.ErrUserNotFound):
+		http.Error(w, "User not found", http.StatusNotFound)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// StartImpersonation returns the token of a new session as the given user.
+// Sessions last 15 minutes unless minutes asks otherwise, and at most an
+// hour.
+func (h *ImpersonationHandler) StartImpersonation(w http.ResponseWriter, r *http.Request) {
+	adminID := r.Context().Value("userID").(string)
+
+	var req startImpersonationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	ttl := time.Duration(req.Minutes) * time.Minute
+	imp, err := h.authUseCase.StartImpersonation(r.Context(), adminID, req.UserID, req.Reason, ttl, req.AllowWrites)
+	if err != nil {
+		writeImpersonationError(w, err, "Failed to start impersonation")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(imp)
+}
+
+func (h *ImpersonationHandler) ListImpersonations(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseListOptions(r, "actor_id", "user_id", "from", "to")
+	if err != nil {
+		writeListError(w, err, "Failed to list impersonation sessions")
+		return
+	}
+
+	sessions, err := h.authUseCase.ListImpersonations(r.Context(), opts)
+	if err != nil {
+		writeListError(w, err, "Failed to list impersonation sessions")
+		return
+	}
+
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// GetImpersonation returns a session with the writes made in it.
+func (h *ImpersonationHandler) GetImpersonation(w http.ResponseWriter, r *http.Request) {
+	session, err := h.authUseCase.GetImpersonation(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeImpersonationError(w, err, "Failed to get impersonation session")
+		return
+	}
+
+	json.NewEncoder(w).Encode(session)
+}
+
+// EndImpersonation ends any session, for admins.
+func (h *ImpersonationHandler) EndImpersonation(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.authUseCase.EndImpersonation(r.Context(), mux.Vars(r)["id"]); err != nil {
+		writeImpersonationError(w, err, "Failed to end impersonation")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Impersonation ended"})
+}
+
+// CurrentSession returns the session the request is made in, so that
+// clients can show that the user is being impersonated, and by whom.
+func (h *ImpersonationHandler) CurrentSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := r.Context().Value("impersonation").(*entity.ImpersonationSession)
+	if !ok {
+		http.Error(w, "Not impersonating", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(session)
+}
+
+// EndCurrentSession ends the session the request is made in. It is the one
+// write a read-only session may make.
+func (h *ImpersonationHandler) EndCurrentSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := r.Context().Value("impersonation").(*entity.ImpersonationSession)
+	if !ok {
+		http.Error(w, "Not impersonating", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.authUseCase.EndImpersonation(r.Context(), session.ID.Hex()); err != nil {
+		writeImpersonationError(w, err, "Failed to end impersonation")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Impersonation ended"})
+}
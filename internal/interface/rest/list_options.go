@@ -0,0 +1,46 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+)
+
+// parseListOptions reads limit, cursor and sort from the query string along
+// with the named filters that are present.
+func parseListOptions(r *http.Request, filters ...string) (entity.ListOptions, error) {
+	q := r.URL.Query()
+	opts := entity.ListOptions{
+		Cursor:  q.Get("cursor"),
+		Sort:    q.Get("sort"),
+		Filters: make(map[string]string),
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return opts, entity.ErrInvalidListOptions
+		}
+		opts.Limit = limit
+	}
+
+	for _, name := range filters {
+		if v := q.Get(name); v != "" {
+			opts.Filters[name] = v
+		}
+	}
+
+	return opts, nil
+}
+
+// writeListError reports invalid paging parameters and unknown terms as bad
+// requests and everything else with the fallback message.
+func writeListError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case entity.ErrInvalidListOptions, entity.ErrTermNotFound:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
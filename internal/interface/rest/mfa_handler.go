@@ -0,0 +1,216 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/srgjo27/e-learning/internal/entity"
+	"github.com/srgjo27/e-learning/internal/usecase"
+)
+
+// MFAHandler serves the second step of a login, the management of a user's
+// own second factor and the admin MFA settings.
+type MFAHandler struct {
+	authUseCase *usecase.AuditedAuthUseCase
+}
+
+func NewMFAHandler(u *usecase.AuditedAuthUseCase) *MFAHandler {
+	return &MFAHandler{
+		authUseCase: u,
+	}
+}
+
+type mfaCodeRequest struct {
+	MFAToken string `json:"mfa_token,omitempty"`
+	Code     string `json:"code"`
+}
+
+type mfaPolicyRequest struct {
+	RequiredRoles []entity.Role `json:"required_roles"`
+}
+
+func writeMFAError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case writeThrottled(w, err):
+	case errors.Is(err, entity.ErrInvalidToken):
+		http.Error(w, "Invalid or expired MFA token", http.StatusUnauthorized)
+	case errors.Is(err, entity.ErrInvalidMFACode):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, entity.ErrMFARequired):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, entity.ErrMFAAlreadyEnabled), errors.Is(err, entity.ErrMFANotEnabled), errors.Is(err, entity.ErrMFANoEnrollment):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, entity.ErrUserNotFound):
+		http.Error(w, "User not found", http.StatusNotFound)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+func decodeMFACode(w http.ResponseWriter, r *http.Request, needToken bool) (*mfaCodeRequest, bool) {
+	var req mfaCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return nil, false
+	}
+	if needToken && req.MFAToken == "" {
+		http.Error(w, "mfa_token required", http.StatusBadRequest)
+		return nil, false
+	}
+	return &req, true
+}
+
+// CompleteLogin exchanges the challenge from the password step and a code
+// for a session token.
+func (h *MFAHandler) CompleteLogin(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeMFACode(w, r, true)
+	if !ok {
+		return
+	}
+	if req.Code == "" {
+		http.Error(w, "code required", http.StatusBadRequest)
+		return
+	}
+
+	token, err := h.authUseCase.CompleteMFALogin(r.Context(), req.MFAToken, req.Code)
+	if err != nil {
+		writeMFAError(w, err, "Login failed")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"token": token})
+}
+
+// BeginLoginEnrollment starts an enrollment for an account whose role
+// requires a second factor it does not have yet.
+func (h *MFAHandler) BeginLoginEnrollment(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeMFACode(w, r, true)
+	if !ok {
+		return
+	}
+
+	enrollment, err := h.authUseCase.BeginChallengeEnrollment(r.Context(), req.MFAToken)
+	if err != nil {
+		writeMFAError(w, err, "Failed to start enrollment")
+		return
+	}
+
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+// ConfirmLoginEnrollment confirms that enrollment and completes the login.
+func (h *MFAHandler) ConfirmLoginEnrollment(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeMFACode(w, r, true)
+	if !ok {
+		return
+	}
+
+	token, codes, err := h.authUseCase.ConfirmChallengeEnrollment(r.Context(), req.MFAToken, req.Code)
+	if err != nil {
+		writeMFAError(w, err, "Failed to confirm enrollment")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"token": token, "recovery_codes": codes})
+}
+
+func (h *MFAHandler) BeginEnrollment(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	enrollment, err := h.authUseCase.BeginMFAEnrollment(r.Context(), userID)
+	if err != nil {
+		writeMFAError(w, err, "Failed to start enrollment")
+		return
+	}
+
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+// ConfirmEnrollment turns the second factor on and returns the recovery
+// codes, which are not shown again.
+func (h *MFAHandler) ConfirmEnrollment(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	req, ok := decodeMFACode(w, r, false)
+	if !ok {
+		return
+	}
+
+	codes, err := h.authUseCase.ConfirmMFAEnrollment(r.Context(), userID, req.Code)
+	if err != nil {
+		writeMFAError(w, err, "Failed to confirm enrollment")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string][]string{"recovery_codes": codes})
+}
+
+func (h *MFAHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	req, ok := decodeMFACode(w, r, false)
+	if !ok {
+		return
+	}
+
+	if err := h.authUseCase.DisableMFA(r.Context(), userID, req.Code); err != nil {
+		writeMFAError(w, err, "Failed to disable two-factor authentication")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Two-factor authentication disabled"})
+}
+
+func (h *MFAHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+	req, ok := decodeMFACode(w, r, false)
+	if !ok {
+		return
+	}
+
+	codes, err := h.authUseCase.RegenerateRecoveryCodes(r.Context(), userID, req.Code)
+	if err != nil {
+		writeMFAError(w, err, "Failed to regenerate recovery codes")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string][]string{"recovery_codes": codes})
+}
+
+// ResetUserMFA removes the second factor of a user who has lost access to it.
+func (h *MFAHandler) ResetUserMFA(w http.ResponseWriter, r *http.Request) {
+	if err := h.authUseCase.ResetUserMFA(r.Context(), mux.Vars(r)["id"]); err != nil {
+		writeMFAError(w, err, "Failed to reset two-factor authentication")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Two-factor authentication reset"})
+}
+
+func (h *MFAHandler) GetPolicy(w http.ResponseWriter, r *http.Request) {
+	policy, err := h.authUseCase.GetMFAPolicy(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to get MFA policy", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(policy)
+}
+
+func (h *MFAHandler) UpdatePolicy(w http.ResponseWriter, r *http.Request) {
+	var req mfaPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if err := h.authUseCase.SetMFAPolicy(r.Context(), req.RequiredRoles); err != nil {
+		if errors.Is(err, entity.ErrRoleNotFound) {
+			http.Error(w, "Invalid role", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to update MFA policy", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "MFA policy updated"})
+}
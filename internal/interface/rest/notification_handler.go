@@ -0,0 +1,64 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"github.com/srgjo27/e-learning/internal/usecase"
+)
+
+type NotificationHandler struct {
+	notificationUseCase *usecase.NotificationUseCase
+}
+
+func NewNotificationHandler(u *usecase.NotificationUseCase) *NotificationHandler {
+	return &NotificationHandler{
+		notificationUseCase: u,
+	}
+}
+
+func (h *NotificationHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	prefs, err := h.notificationUseCase.GetPreferences(r.Context(), userID)
+	if err != nil {
+		if err == entity.ErrUserNotFound {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get notification preferences", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(prefs)
+}
+
+func (h *NotificationHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var prefs entity.NotificationPreferences
+	if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.notificationUseCase.UpdatePreferences(r.Context(), userID, prefs); err != nil {
+		if err == entity.ErrNotificationKindInvalid {
+			http.Error(w, "Invalid notification kind", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to update notification preferences", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Notification preferences updated"})
+}
@@ -0,0 +1,152 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+	"github.com/srgjo27/e-learning/internal/entity"
+	"github.com/srgjo27/e-learning/internal/usecase"
+)
+
+// OIDCHandler serves single sign-on through an OpenID Connect identity
+// provider. When postLoginURL is set, the callback sends the browser there
+// with the login result in the URL fragment; otherwise it answers with the
+// result as JSON, like the password login does.
+type OIDCHandler struct {
+	oidcUseCase  *usecase.OIDCUseCase
+	postLoginURL string
+}
+
+func NewOIDCHandler(u *usecase.OIDCUseCase, postLoginURL string) *OIDCHandler {
+	return &OIDCHandler{
+		oidcUseCase:  u,
+		postLoginURL: postLoginURL,
+	}
+}
+
+// oidcBrowserCookie holds the secret that ties a login to the browser that
+// started it. SameSite=Lax still sends it on the identity provider's
+// top-level redirect back to the callback.
+const oidcBrowserCookie = "oidc_login"
+
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+	redirect, secret, err := h.oidcUseCase.BeginLogin(r.Context())
+	if err != nil {
+		if errors.Is(err, entity.ErrOIDCNotConfigured) {
+			http.Error(w, "Single sign-on is not configured", http.StatusNotFound)
+			return
+		}
+		log.Printf("oidc: failed to start login: %v", err)
+		http.Error(w, "Failed to start single sign-on", http.StatusBadGateway)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcBrowserCookie,
+		Value:    secret,
+		Path:     "/v1/auth/oidc",
+		MaxAge:   int(usecase.OIDCFlowTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		http.Error(w, "Single sign-on failed: "+e, http.StatusUnauthorized)
+		return
+	}
+	if q.Get("state") == "" || q.Get("code") == "" {
+		http.Error(w, "state and code required", http.StatusBadRequest)
+		return
+	}
+
+	var secret string
+	if c, err := r.Cookie(oidcBrowserCookie); err == nil {
+		secret = c.Value
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcBrowserCookie,
+		Path:     "/v1/auth/oidc",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	result, err := h.oidcUseCase.CompleteLogin(r.Context(), q.Get("state"), q.Get("code"), secret)
+	if err != nil {
+		switch {
+		case errors.Is(err, entity.ErrOIDCNotConfigured):
+			http.Error(w, "Single sign-on is not configured", http.StatusNotFound)
+		case errors.Is(err, entity.ErrOIDCInvalidState):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, entity.ErrOIDCNoEmail), errors.Is(err, entity.ErrOIDCLinkRequired), errors.Is(err, entity.ErrEmailExists):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			log.Printf("oidc: failed to complete login: %v", err)
+			http.Error(w, "Single sign-on failed", http.StatusUnauthorized)
+		}
+		return
+	}
+
+	if h.postLoginURL == "" {
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+
+	// The fragment is not sent to servers, so the token stays out of logs.
+	v := url.Values{}
+	for k, s := range map[string]string{"token": result.Token, "mfa_token": result.MFAToken} {
+		if s != "" {
+			v.Set(k, s)
+		}
+	}
+	if result.MFARequired {
+		v.Set("mfa_required", "true")
+	}
+	if result.EnrollmentRequired {
+		v.Set("enrollment_required", "true")
+	}
+	http.Redirect(w, r, h.postLoginURL+"#"+v.Encode(), http.StatusFound)
+}
+
+// AllowLink lets an admin allow, or take back, linking an existing account
+// to the identity provider account with the same email on its next single
+// sign-on.
+func (h *OIDCHandler) AllowLink(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Allowed bool `json:"allowed"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.oidcUseCase.AllowLink(r.Context(), mux.Vars(r)["id"], req.Allowed); err != nil {
+		switch {
+		case errors.Is(err, entity.ErrUserNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, entity.ErrPermissionDenied):
+			http.Error(w, "Forbidden - you cannot manage this account", http.StatusForbidden)
+		default:
+			http.Error(w, "Failed to update single sign-on linking", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Single sign-on linking updated"})
+}
+
+// isHTTPS reports whether the client reached the server over HTTPS, directly
+// or through a proxy.
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
@@ -2,25 +2,54 @@ package rest
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 
+	"github.com/gorilla/mux"
 	"github.com/srgjo27/e-learning/internal/entity"
 	"github.com/srgjo27/e-learning/internal/usecase"
 )
 
 type ProfileHandler struct {
 	authUseCase *usecase.AuthUseCase
+	profileUseCase *usecase.AuditedProfileUseCase
 }
 
-func NewProfileHandler(u *usecase.AuthUseCase) *ProfileHandler {
+func NewProfileHandler(u *usecase.AuthUseCase, p *usecase.AuditedProfileUseCase) *ProfileHandler {
 	return &ProfileHandler{
 		authUseCase : u,
+		profileUseCase : p,
 	}
 }
 
 type profileUpdateRequest struct {
 	Email string `json:"email,omitempty"`
 	Password string `json:"new_password,omitempty"`
+	entity.ProfileUpdate
+}
+
+type userProfileRequest struct {
+	entity.ProfileUpdate
+	StudentNumber *string `json:"student_number"`
+}
+
+// maxAvatarUpload leaves room for the multipart framing around the image.
+const maxAvatarUpload = entity.MaxAvatarSize + 64<<10
+
+func writeProfileError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, entity.ErrUserNotFound):
+		http.Error(w, "User not found", http.StatusNotFound)
+	case errors.Is(err, entity.ErrAvatarNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, entity.ErrInvalidProfile), errors.Is(err, entity.ErrInvalidAvatar):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, entity.ErrStudentNumberExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
 }
 
 func (h *ProfileHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -61,6 +90,22 @@ func (h *ProfileHandler) getProfile(w http.ResponseWriter, r *http.Request, user
 	json.NewEncoder(w).Encode(user)
 }
 
+// CancelEmailChange drops a pending email change before it is confirmed.
+func (h *ProfileHandler) CancelEmailChange(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	if err := h.authUseCase.CancelEmailChange(r.Context(), userID); err != nil {
+		if err == entity.ErrUserNotFound {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to cancel email change", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Email change cancelled"})
+}
+
 func (h *ProfileHandler) updateProfile(w http.ResponseWriter, r *http.Request, userID string) {
 	var req profileUpdateRequest
 
@@ -69,11 +114,103 @@ func (h *ProfileHandler) updateProfile(w http.ResponseWriter, r *http.Request, u
 		return
 	}
 
-	err := h.authUseCase.UpdateProfile(r.Context(), userID, req.Email, req.Password)
+	// The profile fields are checked before the email or password change,
+	// so that an invalid field does not leave the request half applied.
+	if err := req.ProfileUpdate.Apply(&entity.Profile{}); err != nil {
+		writeProfileError(w, err, "Failed to update profile")
+		return
+	}
+
+	emailPending, err := h.authUseCase.UpdateProfile(r.Context(), userID, req.Email, req.Password)
 	if err != nil {
+		if err == entity.ErrEmailExists {
+			http.Error(w, "Email already exists", http.StatusConflict)
+			return
+		}
+		if writePasswordRejected(w, err) {
+			return
+		}
 		http.Error(w, "Failed to update profile", http.StatusInternalServerError)
 		return
 	}
 
+	if _, err := h.profileUseCase.UpdateProfile(r.Context(), userID, req.ProfileUpdate); err != nil {
+		writeProfileError(w, err, "Failed to update profile")
+		return
+	}
+
+	if emailPending {
+		json.NewEncoder(w).Encode(map[string]string{"message": "Profile updated, confirm the new email address with the token sent to it"})
+		return
+	}
 	json.NewEncoder(w).Encode(map[string]string{"message": "Profile updated"})
-}
\ No newline at end of file
+}
+
+// UploadAvatar replaces the signed-in user's avatar with the image in the
+// "file" form field.
+func (h *ProfileHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAvatarUpload)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "file form field required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	user, err := h.profileUseCase.SetAvatar(r.Context(), userID, file)
+	if err != nil {
+		writeProfileError(w, err, "Failed to upload avatar")
+		return
+	}
+
+	user.Password = ""
+	json.NewEncoder(w).Encode(user)
+}
+
+func (h *ProfileHandler) DeleteAvatar(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(string)
+
+	if err := h.profileUseCase.RemoveAvatar(r.Context(), userID); err != nil {
+		writeProfileError(w, err, "Failed to remove avatar")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Avatar removed"})
+}
+
+// GetAvatar serves the avatar of any user to signed-in users, so that lists
+// of people can show their pictures.
+func (h *ProfileHandler) GetAvatar(w http.ResponseWriter, r *http.Request) {
+	rc, contentType, err := h.profileUseCase.OpenAvatar(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeProfileError(w, err, "Failed to get avatar")
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	io.Copy(w, rc)
+}
+
+// UpdateUserProfile lets an admin change a user's profile, including the
+// student number users cannot set themselves.
+func (h *ProfileHandler) UpdateUserProfile(w http.ResponseWriter, r *http.Request) {
+	var req userProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.profileUseCase.UpdateUserProfile(r.Context(), mux.Vars(r)["id"], req.ProfileUpdate, req.StudentNumber)
+	if err != nil {
+		writeProfileError(w, err, "Failed to update profile")
+		return
+	}
+
+	user.Password = ""
+	json.NewEncoder(w).Encode(user)
+}
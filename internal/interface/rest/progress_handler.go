@@ -0,0 +1,135 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/srgjo27/e-learning/internal/entity"
+	"github.com/srgjo27/e-learning/internal/usecase"
+)
+
+type ProgressHandler struct {
+	progressUseCase *usecase.AuditedProgressUseCase
+}
+
+func NewProgressHandler(u *usecase.AuditedProgressUseCase) *ProgressHandler {
+	return &ProgressHandler{
+		progressUseCase: u,
+	}
+}
+
+func writeProgressError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case entity.ErrCourseNotFound, entity.ErrLessonNotFound, entity.ErrUserNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case entity.ErrUnauthorized:
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	case entity.ErrItemLocked:
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case entity.ErrInvalidCompletionCriteria, entity.ErrInvalidScore:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// --- Student ---
+
+func (h *ProgressHandler) CompleteLesson(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.progressUseCase.CompleteLesson(r.Context(), userID, mux.Vars(r)["id"]); err != nil {
+		writeProgressError(w, err, "Failed to complete lesson")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Lesson marked as completed"})
+}
+
+func (h *ProgressHandler) GetMyProgress(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	progress, err := h.progressUseCase.GetStudentProgress(r.Context(), userID, mux.Vars(r)["id"])
+	if err != nil {
+		writeProgressError(w, err, "Failed to get progress")
+		return
+	}
+
+	json.NewEncoder(w).Encode(progress)
+}
+
+// --- Teacher ---
+
+func (h *ProgressHandler) GetClassProgress(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	progress, err := h.progressUseCase.GetClassProgress(r.Context(), userID, mux.Vars(r)["id"])
+	if err != nil {
+		writeProgressError(w, err, "Failed to get class progress")
+		return
+	}
+
+	json.NewEncoder(w).Encode(progress)
+}
+
+func (h *ProgressHandler) RecordAssessmentResult(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Score *float64 `json:"score"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if req.Score == nil {
+		http.Error(w, "score required", http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+	if err := h.progressUseCase.RecordAssessmentResult(r.Context(), userID, vars["id"], vars["studentId"], *req.Score); err != nil {
+		writeProgressError(w, err, "Failed to record assessment result")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Assessment result recorded"})
+}
+
+func (h *ProgressHandler) UpdateCompletionCriteria(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var criteria entity.CompletionCriteria
+	if err := json.NewDecoder(r.Body).Decode(&criteria); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.progressUseCase.UpdateCompletionCriteria(r.Context(), userID, mux.Vars(r)["id"], criteria); err != nil {
+		writeProgressError(w, err, "Failed to update completion criteria")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Completion criteria updated"})
+}
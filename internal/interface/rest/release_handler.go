@@ -0,0 +1,80 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/srgjo27/e-learning/internal/entity"
+	"github.com/srgjo27/e-learning/internal/usecase"
+)
+
+type ReleaseHandler struct {
+	releaseUseCase *usecase.ReleaseUseCase
+}
+
+func NewReleaseHandler(u *usecase.ReleaseUseCase) *ReleaseHandler {
+	return &ReleaseHandler{
+		releaseUseCase: u,
+	}
+}
+
+func writeReleaseError(w http.ResponseWriter, err error) {
+	switch err {
+	case entity.ErrCourseNotFound, entity.ErrLessonNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case entity.ErrUnauthorized:
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	case entity.ErrInvalidReleaseRule:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Failed to update release conditions", http.StatusInternalServerError)
+	}
+}
+
+// decodeReleaseRule reads a release rule from the request body. An empty body
+// or JSON null clears the rule.
+func decodeReleaseRule(r *http.Request) (*entity.ReleaseRule, error) {
+	var rule *entity.ReleaseRule
+	if r.ContentLength == 0 {
+		return nil, nil
+	}
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (h *ReleaseHandler) SetLessonRelease(w http.ResponseWriter, r *http.Request) {
+	h.setRelease(w, r, h.releaseUseCase.SetLessonRelease)
+}
+
+func (h *ReleaseHandler) SetAssignmentRelease(w http.ResponseWriter, r *http.Request) {
+	h.setRelease(w, r, h.releaseUseCase.SetAssignmentRelease)
+}
+
+func (h *ReleaseHandler) SetAssessmentRelease(w http.ResponseWriter, r *http.Request) {
+	h.setRelease(w, r, h.releaseUseCase.SetAssessmentRelease)
+}
+
+func (h *ReleaseHandler) setRelease(w http.ResponseWriter, r *http.Request, set func(ctx context.Context, teacherID, id string, rule *entity.ReleaseRule) error) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rule, err := decodeReleaseRule(r)
+	if err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	if err := set(r.Context(), userID, mux.Vars(r)["id"], rule); err != nil {
+		writeReleaseError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Release conditions updated successfully"})
+}
@@ -0,0 +1,122 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/srgjo27/e-learning/internal/entity"
+	"github.com/srgjo27/e-learning/internal/usecase"
+)
+
+// RoleHandler lets admins define roles as sets of permissions, and lets
+// every user see what their own role allows.
+type RoleHandler struct {
+	roles *usecase.AuditedRoleUseCase
+}
+
+func NewRoleHandler(roles *usecase.AuditedRoleUseCase) *RoleHandler {
+	return &RoleHandler{
+		roles: roles,
+	}
+}
+
+type roleRequest struct {
+	Name        entity.Role         `json:"name"`
+	Description string              `json:"description"`
+	Permissions []entity.Permission `json:"permissions"`
+}
+
+func writeRoleError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, entity.ErrInvalidRole):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, entity.ErrPermissionDenied):
+		http.Error(w, "Forbidden - cannot grant permissions you do not have", http.StatusForbidden)
+	case errors.Is(err, entity.ErrRoleNotFound):
+		http.Error(w, "Role not found", http.StatusNotFound)
+	case errors.Is(err, entity.ErrRoleExists), errors.Is(err, entity.ErrRoleInUse),
+		errors.Is(err, entity.ErrBuiltinRole), errors.Is(err, entity.ErrRoleLockout):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+func (h *RoleHandler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.roles.ListRoles(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to list roles", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(roles)
+}
+
+func (h *RoleHandler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	var req roleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	role := &entity.RoleDefinition{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: req.Permissions,
+	}
+	if err := h.roles.CreateRole(r.Context(), role); err != nil {
+		writeRoleError(w, err, "Failed to create role")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(role)
+}
+
+// UpdateRole replaces the description and permissions of a role. The name
+// cannot be changed, as users refer to their role by name.
+func (h *RoleHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	var req roleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	name := entity.Role(mux.Vars(r)["name"])
+	if err := h.roles.UpdateRole(r.Context(), name, req.Description, req.Permissions); err != nil {
+		writeRoleError(w, err, "Failed to update role")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Role updated"})
+}
+
+func (h *RoleHandler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	if err := h.roles.DeleteRole(r.Context(), entity.Role(mux.Vars(r)["name"])); err != nil {
+		writeRoleError(w, err, "Failed to delete role")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Role deleted"})
+}
+
+// ListPermissions lists every permission a role can be given.
+func (h *RoleHandler) ListPermissions(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(entity.AllPermissions)
+}
+
+// MyPermissions lists the permissions of the signed-in user's role, so that
+// clients can show only what the user may do.
+func (h *RoleHandler) MyPermissions(w http.ResponseWriter, r *http.Request) {
+	role, _ := r.Context().Value("userRole").(entity.Role)
+
+	perms, err := h.roles.Permissions(r.Context(), role)
+	if err != nil {
+		http.Error(w, "Failed to get permissions", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"role": role, "permissions": perms})
+}
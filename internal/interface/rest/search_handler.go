@@ -0,0 +1,65 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"github.com/srgjo27/e-learning/internal/usecase"
+)
+
+type SearchHandler struct {
+	searchUseCase *usecase.SearchUseCase
+}
+
+func NewSearchHandler(u *usecase.SearchUseCase) *SearchHandler {
+	return &SearchHandler{
+		searchUseCase: u,
+	}
+}
+
+// Search handles GET /v1/search?q=. The optional type parameter is a comma
+// separated list of result types and limit caps the hits per type.
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	role, _ := r.Context().Value("userRole").(entity.Role)
+
+	q := r.URL.Query()
+	var types []entity.SearchType
+	for _, t := range strings.Split(q.Get("type"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, entity.SearchType(t))
+		}
+	}
+
+	limit := 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	results, err := h.searchUseCase.Search(r.Context(), userID, role, q.Get("q"), types, limit)
+	if err != nil {
+		switch err {
+		case entity.ErrInvalidSearch:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case entity.ErrUnauthorized:
+			http.Error(w, "Forbidden", http.StatusForbidden)
+		default:
+			http.Error(w, "Failed to search", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	json.NewEncoder(w).Encode(results)
+}
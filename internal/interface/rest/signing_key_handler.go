@@ -0,0 +1,82 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/srgjo27/e-learning/internal/entity"
+	"github.com/srgjo27/e-learning/internal/usecase"
+)
+
+// SigningKeyHandler publishes the public token signing keys and lets admins
+// rotate and revoke them.
+type SigningKeyHandler struct {
+	keyRing *usecase.AuditedKeyRing
+}
+
+func NewSigningKeyHandler(k *usecase.AuditedKeyRing) *SigningKeyHandler {
+	return &SigningKeyHandler{
+		keyRing: k,
+	}
+}
+
+type rotateKeyRequest struct {
+	Immediate bool `json:"immediate"`
+}
+
+// JWKS serves the key set at /.well-known/jwks.json. Keys are published a
+// while before they sign, so a short cache is safe.
+func (h *SigningKeyHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(map[string][]entity.JWK{"keys": h.keyRing.JWKS()})
+}
+
+func (h *SigningKeyHandler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.keyRing.ListKeys(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to list signing keys", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(keys)
+}
+
+// Rotate creates a new signing key. The body is optional; {"immediate": true}
+// makes the key sign at once instead of after the publication delay.
+func (h *SigningKeyHandler) Rotate(w http.ResponseWriter, r *http.Request) {
+	var req rotateKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	key, err := h.keyRing.Rotate(r.Context(), req.Immediate)
+	if err != nil {
+		http.Error(w, "Failed to rotate signing key", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(key)
+}
+
+func (h *SigningKeyHandler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	err := h.keyRing.RevokeKey(r.Context(), mux.Vars(r)["kid"])
+	switch {
+	case errors.Is(err, entity.ErrSigningKeyNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, entity.ErrSigningKeyInUse):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, "Failed to revoke signing key", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Signing key revoked"})
+}
@@ -0,0 +1,180 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/srgjo27/e-learning/internal/entity"
+	"github.com/srgjo27/e-learning/internal/usecase"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type StudentHandler struct {
+	studentUseCase *usecase.StudentUseCase
+}
+
+func NewStudentHandler(u *usecase.StudentUseCase) *StudentHandler {
+	return &StudentHandler{
+		studentUseCase: u,
+	}
+}
+
+func (h *StudentHandler) SubmitAssignment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	assignmentID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	studentID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Content string `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if req.Content == "" {
+		http.Error(w, "content required", http.StatusBadRequest)
+		return
+	}
+
+	submission := &entity.Submission{
+		AssignmentID: assignmentID,
+		StudentID:    studentID,
+		Content:      req.Content,
+		SubmittedAt:  time.Now(),
+	}
+
+	if err := h.studentUseCase.SubmitAssignment(r.Context(), submission); err != nil {
+		if err == entity.ErrUnauthorized {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if err == entity.ErrItemLocked {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if err == entity.ErrAssignmentNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to submit assignment", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Assignment submitted successfully"})
+}
+
+func (h *StudentHandler) GetSubmission(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	submission, err := h.studentUseCase.GetSubmission(r.Context(), userID, mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Submission not found", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(submission)
+}
+
+func (h *StudentHandler) ListAssignments(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	opts, err := parseListOptions(r, "course_id")
+	if err != nil {
+		writeListError(w, err, "Failed to list assignments")
+		return
+	}
+
+	assignments, err := h.studentUseCase.ListAssignmentsForStudent(r.Context(), userID, opts)
+	if err != nil {
+		writeListError(w, err, "Failed to list assignments")
+		return
+	}
+
+	json.NewEncoder(w).Encode(assignments)
+}
+
+func (h *StudentHandler) ListAssessments(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	opts, err := parseListOptions(r, "course_id")
+	if err != nil {
+		writeListError(w, err, "Failed to list assessments")
+		return
+	}
+
+	assessments, err := h.studentUseCase.ListAssessmentsForStudent(r.Context(), userID, opts)
+	if err != nil {
+		writeListError(w, err, "Failed to list assessments")
+		return
+	}
+
+	json.NewEncoder(w).Encode(assessments)
+}
+
+func (h *StudentHandler) ListAnnouncements(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	opts, err := parseListOptions(r, "target_audience")
+	if err != nil {
+		writeListError(w, err, "Failed to list announcements")
+		return
+	}
+
+	announcements, err := h.studentUseCase.ListAnnouncementsForStudent(r.Context(), userID, opts)
+	if err != nil {
+		writeListError(w, err, "Failed to list announcements")
+		return
+	}
+
+	json.NewEncoder(w).Encode(announcements)
+}
+
+// ListSubmissions lists the student's submissions with their grades.
+func (h *StudentHandler) ListSubmissions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	submissions, err := h.studentUseCase.ListSubmissionsForStudent(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to list submissions", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(submissions)
+}
@@ -2,6 +2,7 @@ package rest
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
@@ -12,10 +13,10 @@ import (
 )
 
 type TeacherAdvancedHandler struct {
-	usecase *usecase.TeacherAdvancedUseCase
+	usecase *usecase.AuditedTeacherAdvancedUseCase
 }
 
-func NewTeacherAdvancedHandler(u *usecase.TeacherAdvancedUseCase) *TeacherAdvancedHandler {
+func NewTeacherAdvancedHandler(u *usecase.AuditedTeacherAdvancedUseCase) *TeacherAdvancedHandler {
 	return &TeacherAdvancedHandler{
 		usecase: u,
 	}
@@ -34,9 +35,15 @@ func (h *TeacherAdvancedHandler) ListAssignments(w http.ResponseWriter, r *http.
 		return
 	}
 
-	assignments, err := h.usecase.ListAssignmentsByCourse(r.Context(), courseID)
+	opts, err := parseListOptions(r, "archived")
 	if err != nil {
-		http.Error(w, "failed to list assignments", http.StatusInternalServerError)
+		writeListError(w, err, "failed to list assignments")
+		return
+	}
+
+	assignments, err := h.usecase.ListAssignmentsByCourse(r.Context(), courseID, opts)
+	if err != nil {
+		writeListError(w, err, "failed to list assignments")
 		return
 	}
 
@@ -78,6 +85,10 @@ func (h *TeacherAdvancedHandler) CreateAssignment(w http.ResponseWriter, r *http
 	newAssignment.CourseID = id
 
 	if err := h.usecase.CreateAssignment(r.Context(), newAssignment); err != nil {
+		if errors.Is(err, entity.ErrInvalidReference) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "failed to create assignment", http.StatusInternalServerError)
 		return
 	}
@@ -112,6 +123,10 @@ func (h *TeacherAdvancedHandler) UpdateAssignment(w http.ResponseWriter, r *http
 
 	a.ID = oid
 	if err := h.usecase.UpdateAssignment(r.Context(), &a); err != nil {
+		if errors.Is(err, entity.ErrInvalidReference) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "Failed to update assignment", http.StatusInternalServerError)
 		return
 	}
@@ -122,13 +137,44 @@ func (h *TeacherAdvancedHandler) UpdateAssignment(w http.ResponseWriter, r *http
 func (h *TeacherAdvancedHandler) DeleteAssignment(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 	if err := h.usecase.DeleteAssignment(r.Context(), id); err != nil {
-		http.Error(w, "Failed to delete assignment", http.StatusInternalServerError)
+		writeDeleteError(w, err, "Failed to delete assignment")
 		return
 	}
 
 	json.NewEncoder(w).Encode(map[string]string{"message": "assignment deleted successfully"})
 }
 
+// --- Submissions ---
+
+func (h *TeacherAdvancedHandler) GradeSubmission(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var g struct {
+		Grade    *float64 `json:"grade"`
+		Feedback *string  `json:"feedback"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&g); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	if g.Grade == nil {
+		http.Error(w, "grade required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.usecase.GradeSubmission(r.Context(), id, *g.Grade, g.Feedback); err != nil {
+		if err == entity.ErrSubmissionNotFound {
+			http.Error(w, "submission not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "failed to grade submission", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "submission graded successfully"})
+}
+
 // --- Assessments ---
 
 func (h *TeacherAdvancedHandler) ListAssessments(w http.ResponseWriter, r *http.Request) {
@@ -138,9 +184,15 @@ func (h *TeacherAdvancedHandler) ListAssessments(w http.ResponseWriter, r *http.
 		return
 	}
 
-	assessments, err := h.usecase.ListAssessmentsByCourse(r.Context(), courseID)
+	opts, err := parseListOptions(r)
+	if err != nil {
+		writeListError(w, err, "failed to list assessments")
+		return
+	}
+
+	assessments, err := h.usecase.ListAssessmentsByCourse(r.Context(), courseID, opts)
 	if err != nil {
-		http.Error(w, "failed to list assessments", http.StatusInternalServerError)
+		writeListError(w, err, "failed to list assessments")
 		return
 	}
 
@@ -182,6 +234,10 @@ func (h *TeacherAdvancedHandler) CreateAssessment(w http.ResponseWriter, r *http
 	newAssessment.CourseID = id
 
 	if err := h.usecase.CreateAssessment(r.Context(), newAssessment); err != nil {
+		if errors.Is(err, entity.ErrInvalidReference) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "failed to create assessment", http.StatusInternalServerError)
 		return
 	}
@@ -216,6 +272,10 @@ func (h *TeacherAdvancedHandler) UpdateAssessment(w http.ResponseWriter, r *http
 
 	a.ID = oid
 	if err := h.usecase.UpdateAssessment(r.Context(), &a); err != nil {
+		if errors.Is(err, entity.ErrInvalidReference) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "Failed to update assessment", http.StatusInternalServerError)
 		return
 	}
@@ -225,8 +285,8 @@ func (h *TeacherAdvancedHandler) UpdateAssessment(w http.ResponseWriter, r *http
 
 func (h *TeacherAdvancedHandler) DeleteAssessment(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
-	if err := h.usecase.DeleteAssessment(r.Context(), id); err != nil {
-		http.Error(w, "Failed to delete assessment", http.StatusInternalServerError)
+	if err := h.usecase.DeleteAssessment(r.Context(), id, cascadeRequested(r)); err != nil {
+		writeDeleteError(w, err, "Failed to delete assessment")
 		return
 	}
 
@@ -241,9 +301,15 @@ func (h *TeacherAdvancedHandler) ListMessages(w http.ResponseWriter, r *http.Req
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
-	messages, err := h.usecase.ListMessagesBySender(r.Context(), userID)
+	opts, err := parseListOptions(r, "receiver_id")
+	if err != nil {
+		writeListError(w, err, "failed to list messages")
+		return
+	}
+
+	messages, err := h.usecase.ListMessagesBySender(r.Context(), userID, opts)
 	if err != nil {
-		http.Error(w, "failed to list messages", http.StatusInternalServerError)
+		writeListError(w, err, "failed to list messages")
 		return
 	}
 
@@ -296,6 +362,10 @@ func (h *TeacherAdvancedHandler) CreateMessage(w http.ResponseWriter, r *http.Re
 	}
 
 	if err := h.usecase.CreateMessage(r.Context(), message); err != nil {
+		if errors.Is(err, entity.ErrInvalidReference) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "failed to create message", http.StatusInternalServerError)
 		return
 	}
@@ -24,9 +24,15 @@ func (h * TeacherHandler) ListCourses(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	courses, err := h.teacherUseCase.GetAssignedCourses(r.Context(), userID)
+	opts, err := parseListOptions(r, "term")
 	if err != nil {
-		http.Error(w, "Failed to get courses", http.StatusInternalServerError)
+		writeListError(w, err, "Failed to get courses")
+		return
+	}
+
+	courses, err := h.teacherUseCase.GetAssignedCourses(r.Context(), userID, opts)
+	if err != nil {
+		writeListError(w, err, "Failed to get courses")
 		return
 	}
 
@@ -39,9 +45,15 @@ func (h *TeacherHandler) ListClasses(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	classes, err := h.teacherUseCase.GetAssignedClasses(r.Context(), userID)
+	opts, err := parseListOptions(r, "term", "course_id")
+	if err != nil {
+		writeListError(w, err, "Failed to get classes")
+		return
+	}
+
+	classes, err := h.teacherUseCase.GetAssignedClasses(r.Context(), userID, opts)
 	if err != nil {
-		http.Error(w, "Failed to get classes", http.StatusInternalServerError)
+		writeListError(w, err, "Failed to get classes")
 		return
 	}
 
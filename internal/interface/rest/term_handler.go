@@ -0,0 +1,135 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/srgjo27/e-learning/internal/entity"
+	"github.com/srgjo27/e-learning/internal/usecase"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TermHandler struct {
+	termUseCase *usecase.AuditedTermUseCase
+}
+
+func NewTermHandler(u *usecase.AuditedTermUseCase) *TermHandler {
+	return &TermHandler{
+		termUseCase: u,
+	}
+}
+
+func writeTermError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case entity.ErrTermNotFound, entity.ErrCourseNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case entity.ErrInvalidTerm, entity.ErrCourseNotInTerm:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+func (h *TermHandler) ListTerms(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseListOptions(r)
+	if err != nil {
+		writeListError(w, err, "Failed to list terms")
+		return
+	}
+
+	terms, err := h.termUseCase.ListTerms(r.Context(), opts)
+	if err != nil {
+		writeListError(w, err, "Failed to list terms")
+		return
+	}
+
+	json.NewEncoder(w).Encode(terms)
+}
+
+func (h *TermHandler) GetCurrentTerm(w http.ResponseWriter, r *http.Request) {
+	term, err := h.termUseCase.CurrentTerm(r.Context())
+	if err != nil {
+		writeTermError(w, err, "Failed to get current term")
+		return
+	}
+
+	json.NewEncoder(w).Encode(term)
+}
+
+func (h *TermHandler) CreateTerm(w http.ResponseWriter, r *http.Request) {
+	var term entity.Term
+	if err := json.NewDecoder(r.Body).Decode(&term); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.termUseCase.CreateTerm(r.Context(), &term); err != nil {
+		writeTermError(w, err, "Failed to create term")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(term)
+}
+
+func (h *TermHandler) GetTerm(w http.ResponseWriter, r *http.Request) {
+	term, err := h.termUseCase.GetTerm(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeTermError(w, err, "Failed to get term")
+		return
+	}
+
+	json.NewEncoder(w).Encode(term)
+}
+
+func (h *TermHandler) UpdateTerm(w http.ResponseWriter, r *http.Request) {
+	oid, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	var term entity.Term
+	if err := json.NewDecoder(r.Body).Decode(&term); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	term.ID = oid
+
+	if err := h.termUseCase.UpdateTerm(r.Context(), &term); err != nil {
+		writeTermError(w, err, "Failed to update term")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Term updated successfully"})
+}
+
+func (h *TermHandler) DeleteTerm(w http.ResponseWriter, r *http.Request) {
+	if err := h.termUseCase.DeleteTerm(r.Context(), mux.Vars(r)["id"], cascadeRequested(r)); err != nil {
+		writeDeleteError(w, err, "Failed to delete term")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RolloverCourse clones a course into the term given in the body.
+func (h *TermHandler) RolloverCourse(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TermID string `json:"term_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.TermID == "" {
+		http.Error(w, "term_id required", http.StatusBadRequest)
+		return
+	}
+
+	course, err := h.termUseCase.Rollover(r.Context(), mux.Vars(r)["id"], req.TermID)
+	if err != nil {
+		writeTermError(w, err, "Failed to roll over course")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(course)
+}
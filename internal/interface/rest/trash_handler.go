@@ -0,0 +1,105 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/srgjo27/e-learning/internal/entity"
+	"github.com/srgjo27/e-learning/internal/usecase"
+)
+
+type TrashHandler struct {
+	trashUseCase *usecase.AuditedTrashUseCase
+}
+
+func NewTrashHandler(u *usecase.AuditedTrashUseCase) *TrashHandler {
+	return &TrashHandler{
+		trashUseCase: u,
+	}
+}
+
+func writeTrashError(w http.ResponseWriter, err error, fallback string) {
+	if errors.Is(err, entity.ErrInvalidTrashType) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeDeleteError(w, err, fallback)
+}
+
+// ListTrash lists the trashed items of the type given by ?type=.
+func (h *TrashHandler) ListTrash(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseListOptions(r)
+	if err != nil {
+		writeListError(w, err, "Failed to list trash")
+		return
+	}
+
+	typ := entity.TrashType(r.URL.Query().Get("type"))
+	page, err := h.trashUseCase.ListTrash(r.Context(), typ, opts)
+	if err != nil {
+		if errors.Is(err, entity.ErrInvalidTrashType) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeListError(w, err, "Failed to list trash")
+		return
+	}
+
+	json.NewEncoder(w).Encode(page)
+}
+
+func (h *TrashHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := h.trashUseCase.Restore(r.Context(), entity.TrashType(vars["type"]), vars["id"]); err != nil {
+		writeTrashError(w, err, "Failed to restore item")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Item restored"})
+}
+
+// Purge removes a trashed item for good. Like a delete before the trash
+// existed, it answers 409 with the dependents unless ?cascade=true is given.
+func (h *TrashHandler) Purge(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := h.trashUseCase.Purge(r.Context(), entity.TrashType(vars["type"]), vars["id"], cascadeRequested(r)); err != nil {
+		writeTrashError(w, err, "Failed to purge item")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PurgeExpired purges everything whose retention period has run out without
+// waiting for the scheduled run.
+func (h *TrashHandler) PurgeExpired(w http.ResponseWriter, r *http.Request) {
+	if err := h.trashUseCase.PurgeExpired(r.Context(), time.Now()); err != nil {
+		http.Error(w, "Failed to purge trash", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Expired items purged"})
+}
+
+func (h *TrashHandler) Archive(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := h.trashUseCase.Archive(r.Context(), entity.TrashType(vars["type"]), vars["id"]); err != nil {
+		writeTrashError(w, err, "Failed to archive item")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Item archived"})
+}
+
+func (h *TrashHandler) Unarchive(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := h.trashUseCase.Unarchive(r.Context(), entity.TrashType(vars["type"]), vars["id"]); err != nil {
+		writeTrashError(w, err, "Failed to unarchive item")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Item unarchived"})
+}
@@ -0,0 +1,259 @@
+package notification
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Outbox stores rendered notifications until the Worker delivers them.
+// It implements usecase.Notifier.
+type Outbox struct {
+	collection *mongo.Collection
+	renderer   *Renderer
+	aead       cipher.AEAD
+}
+
+// NewOutbox returns an outbox that encrypts bodies with key, a 32 byte
+// AES-256 key. Entries can only be delivered by workers with the same key.
+func NewOutbox(c *mongo.Collection, renderer *Renderer, key []byte) (*Outbox, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Outbox{
+		collection: c,
+		renderer:   renderer,
+		aead:       aead,
+	}, nil
+}
+
+// Notify renders the notification for the user and writes it to the outbox.
+// Users who opted out of the kind are skipped silently. The write uses ctx,
+// so it is part of a transaction the caller runs ctx in.
+func (o *Outbox) Notify(ctx context.Context, user *entity.User, kind entity.NotificationKind, data map[string]interface{}) error {
+	return o.enqueue(ctx, user, kind, data, primitive.NilObjectID)
+}
+
+// Broadcast writes a job to the outbox that the Worker expands into a
+// notification for every user, so that the caller does not wait for the
+// fan-out. Like Notify, it joins the transaction of ctx. data must be
+// representable in BSON.
+func (o *Outbox) Broadcast(ctx context.Context, kind entity.NotificationKind, data map[string]interface{}) error {
+	raw, err := bson.Marshal(data)
+	if err != nil {
+		return err
+	}
+	sealed, err := o.seal(raw)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	_, err = o.collection.InsertOne(ctx, &entity.Notification{
+		Kind:          kind,
+		Broadcast:     true,
+		SealedData:    sealed,
+		Status:        entity.NotificationPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	})
+	return err
+}
+
+// enqueue renders and writes the notification of one user. Entries of a
+// broadcast are written once per user; writing one again is a no-op.
+func (o *Outbox) enqueue(ctx context.Context, user *entity.User, kind entity.NotificationKind, data map[string]interface{}, broadcastID primitive.ObjectID) error {
+	if !user.NotificationPreferences.Allows(kind) {
+		return nil
+	}
+
+	locale := localeFor(user)
+	subject, body, err := o.renderer.Render(kind, locale, inZone(data, user.Location()))
+	if err != nil {
+		return err
+	}
+
+	sealed, err := o.seal([]byte(body))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	n := &entity.Notification{
+		UserID:        user.ID,
+		To:            user.Email,
+		Kind:          kind,
+		Locale:        locale,
+		Subject:       subject,
+		SealedBody:    sealed,
+		Status:        entity.NotificationPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		BroadcastID:   broadcastID,
+	}
+
+	_, err = o.collection.InsertOne(ctx, n)
+	if !broadcastID.IsZero() && mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+// seal encrypts plain, prefixed with a random nonce.
+func (o *Outbox) seal(plain []byte) ([]byte, error) {
+	nonce := make([]byte, o.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return o.aead.Seal(nonce, nonce, plain, nil), nil
+}
+
+// open decrypts what seal encrypted.
+func (o *Outbox) open(sealed []byte) ([]byte, error) {
+	size := o.aead.NonceSize()
+	if len(sealed) < size {
+		return nil, errors.New("notification is truncated")
+	}
+	plain, err := o.aead.Open(nil, sealed[:size], sealed[size:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("notification cannot be decrypted: %w", err)
+	}
+	return plain, nil
+}
+
+// body returns the plain body of n.
+func (o *Outbox) body(n *entity.Notification) (string, error) {
+	if n.SealedBody == nil {
+		return n.Body, nil
+	}
+	plain, err := o.open(n.SealedBody)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// data returns the template data of broadcast n, with its dates as
+// time.Time again so that inZone moves them.
+func (o *Outbox) data(n *entity.Notification) (map[string]interface{}, error) {
+	plain, err := o.open(n.SealedData)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(plain, &doc); err != nil {
+		return nil, err
+	}
+	data := make(map[string]interface{}, len(doc))
+	for k, v := range doc {
+		if t, ok := v.(primitive.DateTime); ok {
+			v = t.Time().UTC()
+		}
+		data[k] = v
+	}
+	return data, nil
+}
+
+// localeFor returns the locale the user chose, or the default locale for
+// users who have not chosen one.
+func localeFor(user *entity.User) string {
+	if user.Locale != "" {
+		return user.Locale
+	}
+	return DefaultLocale
+}
+
+// inZone returns a copy of data with its times moved to loc, so that
+// templates show dates in the recipient's time zone.
+func inZone(data map[string]interface{}, loc *time.Location) map[string]interface{} {
+	out := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		switch t := v.(type) {
+		case time.Time:
+			v = t.In(loc)
+		case *time.Time:
+			if t != nil {
+				in := t.In(loc)
+				v = &in
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// claimNext leases the oldest due notification so that concurrent workers do
+// not pick it up. The lease expires after leaseFor, after which the entry is
+// retried if it was never marked as sent or failed.
+func (o *Outbox) claimNext(ctx context.Context, leaseFor time.Duration) (*entity.Notification, error) {
+	now := time.Now().UTC()
+	filter := bson.M{
+		"status":          entity.NotificationPending,
+		"next_attempt_at": bson.M{"$lte": now},
+	}
+	update := bson.M{
+		"$set": bson.M{"next_attempt_at": now.Add(leaseFor)},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var n entity.Notification
+	err := o.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&n)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &n, nil
+}
+
+// saveCursor records how far the expansion of a broadcast got and renews
+// its lease.
+func (o *Outbox) saveCursor(ctx context.Context, id primitive.ObjectID, cursor string, leaseFor time.Duration) error {
+	_, err := o.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{"cursor": cursor, "next_attempt_at": time.Now().UTC().Add(leaseFor)},
+	})
+	return err
+}
+
+func (o *Outbox) markSent(ctx context.Context, id primitive.ObjectID) error {
+	now := time.Now().UTC()
+	_, err := o.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set":   bson.M{"status": entity.NotificationSent, "sent_at": now},
+		"$unset": bson.M{"last_error": ""},
+	})
+	return err
+}
+
+func (o *Outbox) markRetry(ctx context.Context, id primitive.ObjectID, next time.Time, cause error) error {
+	_, err := o.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{"next_attempt_at": next.UTC(), "last_error": cause.Error()},
+	})
+	return err
+}
+
+func (o *Outbox) markFailed(ctx context.Context, id primitive.ObjectID, cause error) error {
+	_, err := o.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{"status": entity.NotificationFailed, "last_error": cause.Error()},
+	})
+	return err
+}
@@ -0,0 +1,297 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newTestOutbox(t *testing.T, key byte) *Outbox {
+	t.Helper()
+	o, err := NewOutbox(nil, nil, bytes.Repeat([]byte{key}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func TestOutboxBody(t *testing.T) {
+	o := newTestOutbox(t, 1)
+	sealed, err := o.seal([]byte("<p>reset token abc</p>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, []byte("abc")) {
+		t.Fatal("sealed body contains the plain text")
+	}
+	again, _ := o.seal([]byte("<p>reset token abc</p>"))
+	if bytes.Equal(sealed, again) {
+		t.Error("sealing the same body twice gave the same bytes")
+	}
+
+	tampered := append([]byte(nil), sealed...)
+	tampered[len(tampered)-1] ^= 1
+
+	tests := []struct {
+		name    string
+		outbox  *Outbox
+		n       entity.Notification
+		want    string
+		wantErr bool
+	}{
+		{"sealed", o, entity.Notification{SealedBody: sealed}, "<p>reset token abc</p>", false},
+		{"legacy plain body", o, entity.Notification{Body: "<p>hello</p>"}, "<p>hello</p>", false},
+		{"other key", newTestOutbox(t, 2), entity.Notification{SealedBody: sealed}, "", true},
+		{"tampered", o, entity.Notification{SealedBody: tampered}, "", true},
+		{"truncated", o, entity.Notification{SealedBody: sealed[:4]}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.outbox.body(&tt.n)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("body() = %q, %v, want %q (error: %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{8, 64 * time.Minute},
+		{10, 256 * time.Minute},
+		{11, defaultMaxBackoff},
+		{100, defaultMaxBackoff},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+// memQueue hands out its notifications once each and records what the
+// worker did with them.
+type memQueue struct {
+	*Outbox
+	pending []*entity.Notification
+	claimed []*entity.Notification
+	leases  []time.Duration
+	sent    []primitive.ObjectID
+	retries map[primitive.ObjectID]time.Time
+	failed  []primitive.ObjectID
+	queued  map[primitive.ObjectID][]primitive.ObjectID // by broadcast
+}
+
+func (q *memQueue) claimNext(ctx context.Context, leaseFor time.Duration) (*entity.Notification, error) {
+	q.leases = append(q.leases, leaseFor)
+	if len(q.pending) == 0 {
+		return nil, nil
+	}
+	n := q.pending[0]
+	q.pending = q.pending[1:]
+	q.claimed = append(q.claimed, n)
+	n.Attempts++
+	return n, nil
+}
+
+func (q *memQueue) enqueue(ctx context.Context, user *entity.User, kind entity.NotificationKind, data map[string]interface{}, broadcastID primitive.ObjectID) error {
+	q.queued[broadcastID] = append(q.queued[broadcastID], user.ID)
+	return nil
+}
+
+func (q *memQueue) saveCursor(ctx context.Context, id primitive.ObjectID, cursor string, leaseFor time.Duration) error {
+	for _, n := range q.claimed {
+		if n.ID == id {
+			n.Cursor = cursor
+		}
+	}
+	q.leases = append(q.leases, leaseFor)
+	return nil
+}
+
+func (q *memQueue) markSent(ctx context.Context, id primitive.ObjectID) error {
+	q.sent = append(q.sent, id)
+	return nil
+}
+
+func (q *memQueue) markRetry(ctx context.Context, id primitive.ObjectID, next time.Time, cause error) error {
+	q.retries[id] = next
+	return nil
+}
+
+// requeue makes n due again, as a retry does once its time has come.
+func (q *memQueue) requeue(n *entity.Notification) {
+	q.pending = append(q.pending, n)
+}
+
+func (q *memQueue) markFailed(ctx context.Context, id primitive.ObjectID, cause error) error {
+	q.failed = append(q.failed, id)
+	return nil
+}
+
+type failingSender struct{ failFor map[string]bool }
+
+func (s failingSender) Send(ctx context.Context, to, subject, htmlBody string) error {
+	if s.failFor[to] {
+		return errors.New("mailbox unavailable")
+	}
+	return nil
+}
+
+func TestWorkerRetries(t *testing.T) {
+	o := newTestOutbox(t, 1)
+	sealed := mustSeal(t, o, "<p>hi</p>")
+
+	tests := []struct {
+		name      string
+		to        string
+		attempts  int // before this claim
+		body      []byte
+		wantSent  bool
+		wantRetry bool
+	}{
+		{"delivered", "ok@example.com", 0, sealed, true, false},
+		{"first failure", "down@example.com", 0, sealed, false, true},
+		{"later failure", "down@example.com", 4, sealed, false, true},
+		{"last attempt", "down@example.com", defaultMaxAttempts - 1, sealed, false, false},
+		{"body of another key", "ok@example.com", 0, mustSeal(t, newTestOutbox(t, 2), "<p>hi</p>"), false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &entity.Notification{ID: primitive.NewObjectID(), To: tt.to, Attempts: tt.attempts, SealedBody: tt.body}
+			q := &memQueue{Outbox: o, pending: []*entity.Notification{n}, retries: map[primitive.ObjectID]time.Time{}}
+			w := NewWorker(o, failingSender{failFor: map[string]bool{"down@example.com": true}}, nil)
+			w.outbox = q
+
+			before := time.Now()
+			w.drain(context.Background())
+
+			for _, lease := range q.leases {
+				if lease != defaultLease {
+					t.Errorf("claimed with a lease of %s, want %s", lease, defaultLease)
+				}
+			}
+			if sent := len(q.sent) == 1; sent != tt.wantSent {
+				t.Errorf("sent = %v, want %v", sent, tt.wantSent)
+			}
+			next, retried := q.retries[n.ID]
+			if retried != tt.wantRetry {
+				t.Fatalf("retried = %v, want %v", retried, tt.wantRetry)
+			}
+			if retried {
+				want := before.Add(backoff(n.Attempts))
+				if next.Before(want) || next.After(time.Now().Add(backoff(n.Attempts))) {
+					t.Errorf("retry at %s, want about %s", next, want)
+				}
+			}
+			if failed := len(q.failed) == 1; failed != (!tt.wantSent && !tt.wantRetry) {
+				t.Errorf("failed = %v, want %v", failed, !tt.wantSent && !tt.wantRetry)
+			}
+		})
+	}
+}
+
+func mustSeal(t *testing.T, o *Outbox, body string) []byte {
+	t.Helper()
+	sealed, err := o.seal([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sealed
+}
+
+// userPages lists users in pages of two, failing once at the page after
+// failAt.
+type userPages struct {
+	users  []*entity.User
+	failAt string
+}
+
+func (p *userPages) ListUsers(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.User], error) {
+	if opts.Cursor != "" && opts.Cursor == p.failAt {
+		p.failAt = ""
+		return nil, errors.New("connection reset")
+	}
+	start := 0
+	if opts.Cursor != "" {
+		for i, u := range p.users {
+			if u.ID.Hex() == opts.Cursor {
+				start = i + 1
+			}
+		}
+	}
+	end := min(start+2, len(p.users))
+	page := &entity.Page[*entity.User]{Items: p.users[start:end]}
+	if end < len(p.users) {
+		page.NextCursor = p.users[end-1].ID.Hex()
+	}
+	return page, nil
+}
+
+func TestWorkerExpandsBroadcasts(t *testing.T) {
+	o := newTestOutbox(t, 1)
+	raw, err := bson.Marshal(map[string]interface{}{"Title": "Closed", "At": time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := make([]*entity.User, 5)
+	for i := range users {
+		users[i] = &entity.User{ID: primitive.NewObjectID()}
+	}
+	n := &entity.Notification{ID: primitive.NewObjectID(), Kind: entity.NotificationAnnouncement, Broadcast: true, SealedData: mustSeal(t, o, string(raw))}
+	q := &memQueue{Outbox: o, pending: []*entity.Notification{n}, retries: map[primitive.ObjectID]time.Time{}, queued: map[primitive.ObjectID][]primitive.ObjectID{}}
+	w := NewWorker(o, failingSender{}, &userPages{users: users, failAt: users[3].ID.Hex()})
+	w.outbox = q
+
+	w.drain(context.Background())
+	if _, retried := q.retries[n.ID]; !retried || len(q.sent) != 0 {
+		t.Fatalf("failed expansion: retried %v, sent %v", retried, q.sent)
+	}
+	if got := len(q.queued[n.ID]); got != 4 {
+		t.Fatalf("queued %d users before the failure, want 4", got)
+	}
+	if n.Cursor != users[3].ID.Hex() {
+		t.Fatalf("cursor = %q, want the end of the second page", n.Cursor)
+	}
+
+	q.requeue(n)
+	w.drain(context.Background())
+	if len(q.sent) != 1 || q.sent[0] != n.ID {
+		t.Fatalf("sent = %v, want the broadcast done", q.sent)
+	}
+	var want []primitive.ObjectID
+	for _, u := range users {
+		want = append(want, u.ID)
+	}
+	if got := q.queued[n.ID]; !reflect.DeepEqual(got, want) {
+		t.Errorf("queued %v, want every user once: %v", got, want)
+	}
+	for _, lease := range q.leases {
+		if lease != defaultLease {
+			t.Errorf("leased for %s, want %s", lease, defaultLease)
+		}
+	}
+
+	data, err := o.data(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if at, ok := data["At"].(time.Time); data["Title"] != "Closed" || !ok || !at.Equal(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("data() = %v", data)
+	}
+}
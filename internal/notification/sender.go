@@ -0,0 +1,74 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Sender delivers a rendered HTML email.
+type Sender interface {
+	Send(ctx context.Context, to, subject, htmlBody string) error
+}
+
+type SMTPSender struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPSender(host, port, username, password, from string) *SMTPSender {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTPSender{
+		addr: net.JoinHostPort(host, port),
+		auth: auth,
+		from: from,
+	}
+}
+
+func (s *SMTPSender) Send(ctx context.Context, to, subject, htmlBody string) error {
+	return smtp.SendMail(s.addr, s.auth, s.from, []string{to}, buildMessage(s.from, to, subject, htmlBody))
+}
+
+// FileSender writes every email as an .eml file into a directory. It stands in
+// for an SMTP server during local development and tests.
+type FileSender struct {
+	dir  string
+	from string
+}
+
+func NewFileSender(dir, from string) (*FileSender, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &FileSender{dir: dir, from: from}, nil
+}
+
+func (s *FileSender) Send(ctx context.Context, to, subject, htmlBody string) error {
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), strings.ReplaceAll(to, "@", "_at_"))
+	return os.WriteFile(filepath.Join(s.dir, name), buildMessage(s.from, to, subject, htmlBody), 0o644)
+}
+
+func buildMessage(from, to, subject, htmlBody string) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + to + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/html; charset=\"utf-8\"\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(htmlBody)
+	return []byte(b.String())
+}
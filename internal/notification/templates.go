@@ -0,0 +1,78 @@
+package notification
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html"
+	"html/template"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+)
+
+const DefaultLocale = "en"
+
+//go:embed templates
+var templateFS embed.FS
+
+// Renderer holds the parsed mail templates, keyed by locale and notification kind.
+// Each template file lives at templates/<locale>/<kind>.html and defines a
+// "subject" and a "body" block.
+type Renderer struct {
+	templates map[string]map[entity.NotificationKind]*template.Template
+}
+
+func NewRenderer() (*Renderer, error) {
+	r := &Renderer{templates: make(map[string]map[entity.NotificationKind]*template.Template)}
+
+	err := fs.WalkDir(templateFS, "templates", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(p) != ".html" {
+			return err
+		}
+
+		locale := path.Base(path.Dir(p))
+		kind := entity.NotificationKind(strings.TrimSuffix(path.Base(p), ".html"))
+
+		tmpl, err := template.ParseFS(templateFS, p)
+		if err != nil {
+			return err
+		}
+
+		if r.templates[locale] == nil {
+			r.templates[locale] = make(map[entity.NotificationKind]*template.Template)
+		}
+		r.templates[locale][kind] = tmpl
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Render executes the template for kind in the requested locale, falling back
+// to DefaultLocale when no localized variant exists.
+func (r *Renderer) Render(kind entity.NotificationKind, locale string, data interface{}) (string, string, error) {
+	tmpl, ok := r.templates[locale][kind]
+	if !ok {
+		tmpl, ok = r.templates[DefaultLocale][kind]
+	}
+	if !ok {
+		return "", "", fmt.Errorf("no template for notification kind %q", kind)
+	}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return "", "", err
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return "", "", err
+	}
+
+	// The subject is a mail header, not HTML, so undo the escaping html/template applied.
+	return strings.TrimSpace(html.UnescapeString(subject.String())), body.String(), nil
+}
@@ -0,0 +1,156 @@
+package notification
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultPollInterval = 5 * time.Second
+	defaultLease        = time.Minute
+	defaultBaseBackoff  = 30 * time.Second
+	defaultMaxBackoff   = 6 * time.Hour
+	defaultMaxAttempts  = 8
+)
+
+// queue is the part of the Outbox the Worker uses.
+type queue interface {
+	claimNext(ctx context.Context, leaseFor time.Duration) (*entity.Notification, error)
+	body(n *entity.Notification) (string, error)
+	data(n *entity.Notification) (map[string]interface{}, error)
+	enqueue(ctx context.Context, user *entity.User, kind entity.NotificationKind, data map[string]interface{}, broadcastID primitive.ObjectID) error
+	saveCursor(ctx context.Context, id primitive.ObjectID, cursor string, leaseFor time.Duration) error
+	markSent(ctx context.Context, id primitive.ObjectID) error
+	markRetry(ctx context.Context, id primitive.ObjectID, next time.Time, cause error) error
+	markFailed(ctx context.Context, id primitive.ObjectID, cause error) error
+}
+
+// Recipients lists the users a broadcast goes to.
+type Recipients interface {
+	ListUsers(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.User], error)
+}
+
+// Worker polls the outbox and hands due notifications to a Sender, retrying
+// failed deliveries with exponential backoff. Broadcasts are expanded into
+// a notification per user, which are delivered like any other.
+type Worker struct {
+	outbox       queue
+	sender       Sender
+	users        Recipients
+	pollInterval time.Duration
+	maxAttempts  int
+}
+
+func NewWorker(outbox *Outbox, sender Sender, users Recipients) *Worker {
+	return &Worker{
+		outbox:       outbox,
+		sender:       sender,
+		users:        users,
+		pollInterval: defaultPollInterval,
+		maxAttempts:  defaultMaxAttempts,
+	}
+}
+
+// Run blocks until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		w.drain(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Worker) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		n, err := w.outbox.claimNext(ctx, defaultLease)
+		if err != nil {
+			log.Printf("notification: claim failed: %v", err)
+			return
+		}
+		if n == nil {
+			return
+		}
+
+		var sendErr error
+		if n.Broadcast {
+			sendErr = w.expand(ctx, n)
+		} else {
+			sendErr = w.deliver(ctx, n)
+		}
+		switch {
+		case sendErr == nil:
+			err = w.outbox.markSent(ctx, n.ID)
+		case n.Attempts >= w.maxAttempts:
+			log.Printf("notification: giving up on %s after %d attempts: %v", n.ID.Hex(), n.Attempts, sendErr)
+			err = w.outbox.markFailed(ctx, n.ID, sendErr)
+		default:
+			err = w.outbox.markRetry(ctx, n.ID, time.Now().Add(backoff(n.Attempts)), sendErr)
+		}
+		if err != nil {
+			log.Printf("notification: failed to update %s: %v", n.ID.Hex(), err)
+		}
+	}
+}
+
+// deliver sends n. A body this worker cannot decrypt is retried like a
+// failed delivery, so that a worker with the right key can pick it up.
+func (w *Worker) deliver(ctx context.Context, n *entity.Notification) error {
+	body, err := w.outbox.body(n)
+	if err != nil {
+		return err
+	}
+	return w.sender.Send(ctx, n.To, n.Subject, body)
+}
+
+// expand queues the notifications of broadcast n a page of users at a time.
+// The cursor is saved after each page, which also renews the lease. A page
+// cut short is queued again on the next attempt, where the entries already
+// written are skipped.
+func (w *Worker) expand(ctx context.Context, n *entity.Notification) error {
+	data, err := w.outbox.data(n)
+	if err != nil {
+		return err
+	}
+
+	opts := entity.ListOptions{Limit: entity.MaxPageLimit, Cursor: n.Cursor}
+	for {
+		page, err := w.users.ListUsers(ctx, opts)
+		if err != nil {
+			return err
+		}
+		for _, u := range page.Items {
+			if err := w.outbox.enqueue(ctx, u, n.Kind, data, n.ID); err != nil {
+				return err
+			}
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		opts.Cursor = page.NextCursor
+		if err := w.outbox.saveCursor(ctx, n.ID, opts.Cursor, defaultLease); err != nil {
+			return err
+		}
+	}
+}
+
+func backoff(attempts int) time.Duration {
+	d := defaultBaseBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= defaultMaxBackoff {
+			return defaultMaxBackoff
+		}
+	}
+	return d
+}
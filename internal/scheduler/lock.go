@@ -0,0 +1,59 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoLock is a lease stored as a single document keyed by name. Whoever
+// holds an unexpired lease is the leader; everyone else skips the work.
+type MongoLock struct {
+	collection *mongo.Collection
+	name       string
+	owner      string
+	ttl        time.Duration
+}
+
+func NewMongoLock(c *mongo.Collection, name, owner string, ttl time.Duration) *MongoLock {
+	return &MongoLock{
+		collection: c,
+		name:       name,
+		owner:      owner,
+		ttl:        ttl,
+	}
+}
+
+// TryAcquire takes or renews the lease. It reports false when another owner
+// holds an unexpired lease.
+func (l *MongoLock) TryAcquire(ctx context.Context) (bool, error) {
+	now := time.Now().UTC()
+	filter := bson.M{
+		"_id": l.name,
+		"$or": bson.A{
+			bson.M{"owner": l.owner},
+			bson.M{"expires_at": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"owner": l.owner, "expires_at": now.Add(l.ttl)}}
+
+	_, err := l.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// The document exists and is held by someone else, so the upsert
+		// tried to insert a second document with the same _id.
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (l *MongoLock) Release(ctx context.Context) error {
+	_, err := l.collection.DeleteOne(ctx, bson.M{"_id": l.name, "owner": l.owner})
+	return err
+}
@@ -0,0 +1,100 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Job is one unit of periodic work.
+type Job func(ctx context.Context, now time.Time) error
+
+// Scheduler runs a job on a fixed interval, but only on the replica that
+// holds the lock.
+type Scheduler struct {
+	name     string
+	lock     *MongoLock
+	interval time.Duration
+	job      Job
+}
+
+func New(name string, lock *MongoLock, interval time.Duration, job Job) *Scheduler {
+	return &Scheduler{
+		name:     name,
+		lock:     lock,
+		interval: interval,
+		job:      job,
+	}
+}
+
+// Run blocks until ctx is cancelled and then gives up the lock.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := s.lock.Release(releaseCtx); err != nil {
+				log.Printf("scheduler %s: release lock failed: %v", s.name, err)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) tick(ctx context.Context) {
+	leader, err := s.lock.TryAcquire(ctx)
+	if err != nil {
+		log.Printf("scheduler %s: acquire lock failed: %v", s.name, err)
+		return
+	}
+	if !leader {
+		return
+	}
+
+	// The lease is renewed while the job runs, and the job is cancelled if
+	// it is lost, so that a long run never overlaps one on another replica.
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go s.keepLease(jobCtx, cancel)
+
+	if err := s.job(jobCtx, time.Now().UTC()); err != nil {
+		log.Printf("scheduler %s: run failed: %v", s.name, err)
+	}
+}
+
+// keepLease renews the lease at a third of its lifetime until ctx is done,
+// and calls lost when it can no longer be renewed.
+func (s *Scheduler) keepLease(ctx context.Context, lost context.CancelFunc) {
+	ticker := time.NewTicker(s.lock.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		leader, err := s.lock.TryAcquire(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("scheduler %s: renew lock failed, stopping run: %v", s.name, err)
+			lost()
+			return
+		}
+		if !leader {
+			log.Printf("scheduler %s: lock taken over, stopping run", s.name)
+			lost()
+			return
+		}
+	}
+}
@@ -12,8 +12,9 @@ type CourseRepository interface {
 	GetCourse(ctx context.Context, id string) (*entity.Course, error)
 	UpdateCourse(ctx context.Context, course *entity.Course) error 
 	DeleteCourse(ctx context.Context, id string) error
-	ListCourses(ctx context.Context) ([]*entity.Course, error)
+	ListCourses(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.Course], error)
 	ListCoursesByTeacher(ctx context.Context, teacherID primitive.ObjectID) ([]*entity.Course, error)
+	UpdateCompletionCriteria(ctx context.Context, courseID primitive.ObjectID, criteria entity.CompletionCriteria) error
 }
 
 type ClassRepository interface {
@@ -21,9 +22,10 @@ type ClassRepository interface {
 	GetClass(ctx context.Context, id string) (*entity.Class, error)
 	UpdateClass(ctx context.Context, class *entity.Class) error
 	DeleteClass(ctx context.Context, id string) error
-	ListClasses(ctx context.Context) ([]*entity.Class, error)
+	ListClasses(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.Class], error)
 	ListClassesByTeacher(ctx context.Context, teacherID primitive.ObjectID) ([]*entity.Class, error)
 	ListClassesByStudent(ctx context.Context, studentID primitive.ObjectID) ([]*entity.Class, error)
+	ListClassesByCourse(ctx context.Context, courseID primitive.ObjectID) ([]*entity.Class, error)
 }
 
 type AnnouncementRepository interface {
@@ -31,25 +33,39 @@ type AnnouncementRepository interface {
 	GetAnnouncement(ctx context.Context, id string) (*entity.Announcement, error)
 	UpdateAnnouncement(ctx context.Context, ann *entity.Announcement) error
 	DeleteAnnouncement(ctx context.Context, id string) error
-	ListAnnouncements(ctx context.Context) ([]*entity.Announcement, error)
+	ListAnnouncements(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.Announcement], error)
+	ListAnnouncementsForAudience(ctx context.Context, courseIDs, classIDs []primitive.ObjectID, opts entity.ListOptions) (*entity.Page[*entity.Announcement], error)
 }
 
 type AdminUseCase struct {
 	courseRepo 	 	 CourseRepository
 	classRepo 	 	 ClassRepository
 	announcementRepo AnnouncementRepository
+	userRepo 		 UserRepository
+	notifier 		 Notifier
+	termRepo 		 TermRepository
+	roles 			 *RoleUseCase
+	tx 				 Transactor
 }
 
-func NewAdminUseCase(courseRepo CourseRepository, classRepo ClassRepository, announcementRepo AnnouncementRepository) *AdminUseCase {
+func NewAdminUseCase(courseRepo CourseRepository, classRepo ClassRepository, announcementRepo AnnouncementRepository, userRepo UserRepository, notifier Notifier, termRepo TermRepository, roles *RoleUseCase, tx Transactor) *AdminUseCase {
 	return &AdminUseCase{
 		courseRepo: 	  courseRepo,
 		classRepo: 		  classRepo,
 		announcementRepo: announcementRepo,
+		userRepo: 		  userRepo,
+		notifier: 		  notifier,
+		termRepo: 		  termRepo,
+		roles: 			  roles,
+		tx: 			  tx,
 	}
 }
 
 // --- Course ---
 func (a *AdminUseCase) CreateCourse(ctx context.Context, course *entity.Course) error {
+	if err := a.checkCourseRefs(ctx, course); err != nil {
+		return err
+	}
 	return a.courseRepo.CreateCourse(ctx, course)
 }
 
@@ -58,19 +74,53 @@ func (a *AdminUseCase) GetCourse(ctx context.Context, id string) (*entity.Course
 }
 
 func (a *AdminUseCase) UpdateCourse(ctx context.Context, course *entity.Course) error {
+	if err := a.checkCourseRefs(ctx, course); err != nil {
+		return err
+	}
 	return a.courseRepo.UpdateCourse(ctx, course)
 }
 
+func (a *AdminUseCase) checkCourseRefs(ctx context.Context, course *entity.Course) error {
+	if !course.TermID.IsZero() {
+		if _, err := a.termRepo.GetTerm(ctx, course.TermID.Hex()); err != nil {
+			return err
+		}
+	}
+	return checkUsers(ctx, a.userRepo, a.roles, "assigned_teachers", course.AssignedTeacher, entity.PermTeachingView)
+}
+
+// DeleteCourse moves the course to the trash. What refers to it is left in
+// place until the course is purged; see TrashUseCase.
 func (a *AdminUseCase) DeleteCourse(ctx context.Context, id string) error {
 	return a.courseRepo.DeleteCourse(ctx, id)
 }
 
-func (a *AdminUseCase) ListCourses(ctx context.Context) ([]*entity.Course, error) {
-	return a.courseRepo.ListCourses(ctx)
+// ListCourses returns a page of courses. Without a "term" filter only the
+// courses of the current term are listed; see applyTermFilter.
+func (a *AdminUseCase) ListCourses(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.Course], error) {
+	opts, err := applyTermFilter(ctx, a.termRepo, opts)
+	if err != nil {
+		return nil, err
+	}
+	return a.courseRepo.ListCourses(ctx, opts)
 }
 
 // --- Class ---
+// CreateClass creates a class in the given term, defaulting to the term of
+// its course.
 func (a *AdminUseCase) CreateClass(ctx context.Context, class *entity.Class) error {
+	course, err := checkCourse(ctx, a.courseRepo, "course_id", class.CourseID)
+	if err != nil {
+		return err
+	}
+	if class.TermID.IsZero() {
+		class.TermID = course.TermID
+	} else if _, err := a.termRepo.GetTerm(ctx, class.TermID.Hex()); err != nil {
+		return err
+	}
+	if err := a.checkClassMembers(ctx, class); err != nil {
+		return err
+	}
 	return a.classRepo.CreateClass(ctx, class)
 }
 
@@ -78,21 +128,102 @@ func (a *AdminUseCase) GetClass(ctx context.Context, id string) (*entity.Class,
 	return a.classRepo.GetClass(ctx, id)
 }
 
+// UpdateClass saves the class. Users who may change enrollments but not
+// classes only get the student list applied; the rest of the class is kept.
 func (a *AdminUseCase) UpdateClass(ctx context.Context, class *entity.Class) error {
+	canWrite, err := a.roles.ActorCan(ctx, entity.PermClassesWrite)
+	if err != nil {
+		return err
+	}
+	if !canWrite {
+		stored, err := a.classRepo.GetClass(ctx, class.ID.Hex())
+		if err != nil {
+			return err
+		}
+		stored.StudentIDs = class.StudentIDs
+		*class = *stored
+	}
+
+	if !class.TermID.IsZero() {
+		if _, err := a.termRepo.GetTerm(ctx, class.TermID.Hex()); err != nil {
+			return err
+		}
+	}
+	if err := a.checkClassMembers(ctx, class); err != nil {
+		return err
+	}
 	return a.classRepo.UpdateClass(ctx, class)
 }
 
+func (a *AdminUseCase) checkClassMembers(ctx context.Context, class *entity.Class) error {
+	if err := checkUsers(ctx, a.userRepo, a.roles, "student_ids", class.StudentIDs, entity.PermLearn); err != nil {
+		return err
+	}
+	return checkUsers(ctx, a.userRepo, a.roles, "teacher_ids", class.TeacherIDs, entity.PermTeachingView)
+}
+
+// DeleteClass moves the class to the trash.
 func (a *AdminUseCase) DeleteClass(ctx context.Context, id string) error {
 	return a.classRepo.DeleteClass(ctx, id)
 }
 
-func (a *AdminUseCase) ListClasses(ctx context.Context) ([]*entity.Class, error) {
-	return a.classRepo.ListClasses(ctx)
+func (a *AdminUseCase) ListClasses(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.Class], error) {
+	opts, err := applyTermFilter(ctx, a.termRepo, opts)
+	if err != nil {
+		return nil, err
+	}
+	return a.classRepo.ListClasses(ctx, opts)
 }
 
 // --- Announcement ---
+// CreateAnnouncement saves the announcement and queues its mails in one
+// transaction. An announcement to everyone queues a single broadcast job.
 func (a *AdminUseCase) CreateAnnouncement(ctx context.Context, ann *entity.Announcement) error {
-	return a.announcementRepo.CreateAnnouncement(ctx, ann)
+	if err := a.checkAnnouncementTarget(ctx, ann); err != nil {
+		return err
+	}
+	return a.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := a.announcementRepo.CreateAnnouncement(ctx, ann); err != nil {
+			return err
+		}
+		return a.notifyAnnouncement(ctx, ann)
+	})
+}
+
+func (a *AdminUseCase) notifyAnnouncement(ctx context.Context, ann *entity.Announcement) error {
+	data := map[string]interface{}{
+		"Title":   ann.Title,
+		"Content": ann.Content,
+	}
+
+	if ann.TargetAudience != entity.AudienceClass && ann.TargetAudience != entity.AudienceCourse {
+		return a.notifier.Broadcast(ctx, entity.NotificationAnnouncement, data)
+	}
+
+	recipients, err := a.announcementAudience(ctx, ann)
+	if err != nil {
+		return err
+	}
+
+	return notifyUsers(ctx, a.notifier, recipients, entity.NotificationAnnouncement, data)
+}
+
+// announcementAudience resolves the recipients of a class or course announcement.
+func (a *AdminUseCase) announcementAudience(ctx context.Context, ann *entity.Announcement) ([]*entity.User, error) {
+	switch ann.TargetAudience {
+	case entity.AudienceClass:
+		class, err := a.classRepo.GetClass(ctx, ann.TargetID.Hex())
+		if err != nil {
+			return nil, err
+		}
+		ids := append(append([]primitive.ObjectID{}, class.StudentIDs...), class.TeacherIDs...)
+		if len(ids) == 0 {
+			return []*entity.User{}, nil
+		}
+		return a.userRepo.FindUsersByIDs(ctx, ids)
+	default:
+		return studentsInCourse(ctx, a.classRepo, a.userRepo, ann.TargetID)
+	}
 }
 
 func (a *AdminUseCase) GetAnnouncement(ctx context.Context, id string) (*entity.Announcement, error) {
@@ -100,13 +231,27 @@ func (a *AdminUseCase) GetAnnouncement(ctx context.Context, id string) (*entity.
 }
 
 func (a *AdminUseCase) UpdateAnnouncement(ctx context.Context, ann *entity.Announcement) error {
+	if err := a.checkAnnouncementTarget(ctx, ann); err != nil {
+		return err
+	}
 	return a.announcementRepo.UpdateAnnouncement(ctx, ann)
 }
 
+func (a *AdminUseCase) checkAnnouncementTarget(ctx context.Context, ann *entity.Announcement) error {
+	switch ann.TargetAudience {
+	case entity.AudienceClass:
+		return checkClass(ctx, a.classRepo, "target_id", ann.TargetID)
+	case entity.AudienceCourse:
+		_, err := checkCourse(ctx, a.courseRepo, "target_id", ann.TargetID)
+		return err
+	}
+	return nil
+}
+
 func (a *AdminUseCase) DeleteAnnouncement(ctx context.Context, id string) error {
 	return a.announcementRepo.DeleteAnnouncement(ctx, id)
 }
 
-func (a *AdminUseCase) ListAnnouncements(ctx context.Context) ([]*entity.Announcement, error) {
-	return a.announcementRepo.ListAnnouncements(ctx)
+func (a *AdminUseCase) ListAnnouncements(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.Announcement], error) {
+	return a.announcementRepo.ListAnnouncements(ctx, opts)
 }
\ No newline at end of file
@@ -0,0 +1,444 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultCheckInTTL = 10 * time.Minute
+	maxCheckInTTL     = 2 * time.Hour
+	checkInCodeDigits = 6
+	// Students checking in later than this after the session started are marked late.
+	checkInGrace = 15 * time.Minute
+)
+
+type SessionRepository interface {
+	CreateSession(ctx context.Context, s *entity.ClassSession) error
+	GetSession(ctx context.Context, id string) (*entity.ClassSession, error)
+	UpdateSession(ctx context.Context, s *entity.ClassSession) error
+	DeleteSession(ctx context.Context, id primitive.ObjectID) error
+	SetCheckInCode(ctx context.Context, id primitive.ObjectID, code string, expiresAt time.Time) error
+	ListSessionsByClass(ctx context.Context, classID primitive.ObjectID) ([]*entity.ClassSession, error)
+}
+
+type AttendanceRepository interface {
+	SaveAttendance(ctx context.Context, a *entity.AttendanceRecord) error
+	SaveAttendanceIfAbsent(ctx context.Context, a *entity.AttendanceRecord) error
+	ListAttendanceBySession(ctx context.Context, sessionID primitive.ObjectID) ([]*entity.AttendanceRecord, error)
+	ListAttendanceByClass(ctx context.Context, classID primitive.ObjectID) ([]*entity.AttendanceRecord, error)
+	DeleteAttendanceBySession(ctx context.Context, sessionID primitive.ObjectID) error
+}
+
+// AttendanceEntry is one line of a teacher's attendance sheet.
+type AttendanceEntry struct {
+	StudentID primitive.ObjectID      `json:"student_id"`
+	Status    entity.AttendanceStatus `json:"status"`
+}
+
+type AttendanceUseCase struct {
+	sessionRepo    SessionRepository
+	attendanceRepo AttendanceRepository
+	classRepo      ClassRepository
+	courseRepo     CourseRepository
+	userRepo       UserRepository
+	throttle       *AttemptThrottle
+}
+
+// NewAttendanceUseCase returns the attendance usecase. throttle limits the
+// check-in codes each student may try per session.
+func NewAttendanceUseCase(
+	sessionRepo SessionRepository,
+	attendanceRepo AttendanceRepository,
+	classRepo ClassRepository,
+	courseRepo CourseRepository,
+	userRepo UserRepository,
+	throttle *AttemptThrottle,
+) *AttendanceUseCase {
+	return &AttendanceUseCase{
+		sessionRepo:    sessionRepo,
+		attendanceRepo: attendanceRepo,
+		classRepo:      classRepo,
+		courseRepo:     courseRepo,
+		userRepo:       userRepo,
+		throttle:       throttle,
+	}
+}
+
+// --- Sessions ---
+func (a *AttendanceUseCase) CreateSession(ctx context.Context, teacherID string, s *entity.ClassSession) error {
+	if _, err := a.teacherClass(ctx, teacherID, s.ClassID.Hex()); err != nil {
+		return err
+	}
+	if err := validateSession(s); err != nil {
+		return err
+	}
+
+	tid, _ := primitive.ObjectIDFromHex(teacherID)
+	s.ID = primitive.NilObjectID
+	s.CheckInCode = ""
+	s.CheckInExpiresAt = nil
+	s.CreatedBy = tid
+	s.CreatedAt = time.Now()
+
+	return a.sessionRepo.CreateSession(ctx, s)
+}
+
+func (a *AttendanceUseCase) ListSessions(ctx context.Context, teacherID, classID string) ([]*entity.ClassSession, error) {
+	class, err := a.teacherClass(ctx, teacherID, classID)
+	if err != nil {
+		return nil, err
+	}
+	return a.sessionRepo.ListSessionsByClass(ctx, class.ID)
+}
+
+func (a *AttendanceUseCase) UpdateSession(ctx context.Context, teacherID string, s *entity.ClassSession) error {
+	existing, err := a.teacherSession(ctx, teacherID, s.ID.Hex())
+	if err != nil {
+		return err
+	}
+	if err := validateSession(s); err != nil {
+		return err
+	}
+
+	existing.Title = s.Title
+	existing.StartsAt = s.StartsAt
+	existing.EndsAt = s.EndsAt
+	return a.sessionRepo.UpdateSession(ctx, existing)
+}
+
+func (a *AttendanceUseCase) DeleteSession(ctx context.Context, teacherID, sessionID string) error {
+	session, err := a.teacherSession(ctx, teacherID, sessionID)
+	if err != nil {
+		return err
+	}
+
+	if err := a.attendanceRepo.DeleteAttendanceBySession(ctx, session.ID); err != nil {
+		return err
+	}
+	return a.sessionRepo.DeleteSession(ctx, session.ID)
+}
+
+// OpenCheckIn generates a fresh numeric code students can use to check in to
+// the session until it expires. A zero ttl uses the default.
+func (a *AttendanceUseCase) OpenCheckIn(ctx context.Context, teacherID, sessionID string, ttl time.Duration) (*entity.ClassSession, error) {
+	session, err := a.teacherSession(ctx, teacherID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if ttl == 0 {
+		ttl = defaultCheckInTTL
+	}
+	if ttl < 0 || ttl > maxCheckInTTL {
+		return nil, entity.ErrInvalidSession
+	}
+
+	code, err := randomDigits(checkInCodeDigits)
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(ttl)
+	if err := a.sessionRepo.SetCheckInCode(ctx, session.ID, code, expiresAt); err != nil {
+		return nil, err
+	}
+
+	session.CheckInCode = code
+	session.CheckInExpiresAt = &expiresAt
+	return session, nil
+}
+
+// --- Attendance ---
+
+// RecordAttendance stores the teacher's attendance sheet for a session,
+// overwriting earlier records and student check-ins for the listed students.
+func (a *AttendanceUseCase) RecordAttendance(ctx context.Context, teacherID, sessionID string, entries []AttendanceEntry) error {
+	session, err := a.teacherSession(ctx, teacherID, sessionID)
+	if err != nil {
+		return err
+	}
+	class, err := a.classRepo.GetClass(ctx, session.ClassID.Hex())
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if !entity.IsValidAttendanceStatus(e.Status) {
+			return entity.ErrInvalidAttendanceStatus
+		}
+		if !containsID(class.StudentIDs, e.StudentID) {
+			return entity.ErrStudentNotInClass
+		}
+	}
+
+	tid, _ := primitive.ObjectIDFromHex(teacherID)
+	now := time.Now()
+	for _, e := range entries {
+		err := a.attendanceRepo.SaveAttendance(ctx, &entity.AttendanceRecord{
+			SessionID:  session.ID,
+			ClassID:    session.ClassID,
+			StudentID:  e.StudentID,
+			Status:     e.Status,
+			RecordedBy: tid,
+			RecordedAt: now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *AttendanceUseCase) GetSessionAttendance(ctx context.Context, teacherID, sessionID string) ([]*entity.AttendanceRecord, error) {
+	session, err := a.teacherSession(ctx, teacherID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return a.attendanceRepo.ListAttendanceBySession(ctx, session.ID)
+}
+
+// CheckIn marks the student present, or late once the grace period after the
+// session start has passed, using the code the teacher opened. Wrong codes
+// are throttled per student and session, so that the code cannot be
+// guessed.
+func (a *AttendanceUseCase) CheckIn(ctx context.Context, studentID, sessionID, code string) (*entity.AttendanceRecord, error) {
+	sid, err := primitive.ObjectIDFromHex(studentID)
+	if err != nil {
+		return nil, entity.ErrUserNotFound
+	}
+
+	session, err := a.sessionRepo.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	class, err := a.classRepo.GetClass(ctx, session.ClassID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if !containsID(class.StudentIDs, sid) {
+		return nil, entity.ErrStudentNotInClass
+	}
+
+	throttleKey := studentID + ":" + session.ID.Hex()
+	if err := a.throttle.Check(ctx, "check_in", throttleKey); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	code = strings.TrimSpace(code)
+	if session.CheckInCode == "" || session.CheckInExpiresAt == nil || now.After(*session.CheckInExpiresAt) ||
+		subtle.ConstantTimeCompare([]byte(code), []byte(session.CheckInCode)) != 1 {
+		if err := a.throttle.Fail(ctx, "check_in", throttleKey); err != nil {
+			return nil, err
+		}
+		return nil, entity.ErrInvalidCheckInCode
+	}
+
+	status := entity.AttendancePresent
+	if now.After(session.StartsAt.Add(checkInGrace)) {
+		status = entity.AttendanceLate
+	}
+
+	record := &entity.AttendanceRecord{
+		SessionID:   session.ID,
+		ClassID:     session.ClassID,
+		StudentID:   sid,
+		Status:      status,
+		SelfCheckIn: true,
+		RecordedBy:  sid,
+		RecordedAt:  now,
+	}
+	if err := a.attendanceRepo.SaveAttendanceIfAbsent(ctx, record); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// --- Reports ---
+
+// GetClassReport computes the attendance rate of every student in the class
+// over the sessions that have already started.
+func (a *AttendanceUseCase) GetClassReport(ctx context.Context, teacherID, classID string) (*entity.ClassAttendanceReport, error) {
+	class, err := a.teacherClass(ctx, teacherID, classID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, records, err := a.classAttendance(ctx, class.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	emails := make(map[primitive.ObjectID]string)
+	if len(class.StudentIDs) > 0 {
+		users, err := a.userRepo.FindUsersByIDs(ctx, class.StudentIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			emails[u.ID] = u.Email
+		}
+	}
+
+	report := &entity.ClassAttendanceReport{
+		ClassID:  class.ID,
+		Sessions: len(sessions),
+		Students: make([]*entity.AttendanceRate, 0, len(class.StudentIDs)),
+	}
+	var sum float64
+	for _, sid := range class.StudentIDs {
+		rate := attendanceRate(sid, sessions, records)
+		rate.Email = emails[sid]
+		report.Students = append(report.Students, rate)
+		sum += rate.Rate
+	}
+	if len(report.Students) > 0 {
+		report.AverageRate = sum / float64(len(report.Students))
+	}
+
+	return report, nil
+}
+
+// GetStudentReport returns the student's own attendance rate in a class.
+func (a *AttendanceUseCase) GetStudentReport(ctx context.Context, studentID, classID string) (*entity.AttendanceRate, error) {
+	sid, err := primitive.ObjectIDFromHex(studentID)
+	if err != nil {
+		return nil, entity.ErrUserNotFound
+	}
+
+	class, err := a.classRepo.GetClass(ctx, classID)
+	if err != nil {
+		return nil, err
+	}
+	if !containsID(class.StudentIDs, sid) {
+		return nil, entity.ErrUnauthorized
+	}
+
+	sessions, records, err := a.classAttendance(ctx, class.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return attendanceRate(sid, sessions, records), nil
+}
+
+// classAttendance loads the sessions of a class that have started and their
+// records keyed by session and student.
+func (a *AttendanceUseCase) classAttendance(ctx context.Context, classID primitive.ObjectID) ([]*entity.ClassSession, map[[2]primitive.ObjectID]*entity.AttendanceRecord, error) {
+	all, err := a.sessionRepo.ListSessionsByClass(ctx, classID)
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	var sessions []*entity.ClassSession
+	for _, s := range all {
+		if !s.StartsAt.After(now) {
+			sessions = append(sessions, s)
+		}
+	}
+
+	list, err := a.attendanceRepo.ListAttendanceByClass(ctx, classID)
+	if err != nil {
+		return nil, nil, err
+	}
+	records := make(map[[2]primitive.ObjectID]*entity.AttendanceRecord, len(list))
+	for _, r := range list {
+		records[[2]primitive.ObjectID{r.SessionID, r.StudentID}] = r
+	}
+
+	return sessions, records, nil
+}
+
+func attendanceRate(studentID primitive.ObjectID, sessions []*entity.ClassSession, records map[[2]primitive.ObjectID]*entity.AttendanceRecord) *entity.AttendanceRate {
+	rate := &entity.AttendanceRate{StudentID: studentID, Sessions: len(sessions)}
+	for _, s := range sessions {
+		status := entity.AttendanceAbsent
+		if r, ok := records[[2]primitive.ObjectID{s.ID, studentID}]; ok {
+			status = r.Status
+		}
+		switch status {
+		case entity.AttendancePresent:
+			rate.Present++
+		case entity.AttendanceLate:
+			rate.Late++
+		case entity.AttendanceExcused:
+			rate.Excused++
+		default:
+			rate.Absent++
+		}
+	}
+
+	if counted := rate.Sessions - rate.Excused; counted > 0 {
+		rate.Rate = float64(rate.Present+rate.Late) * 100 / float64(counted)
+	}
+	return rate
+}
+
+// --- helpers ---
+func (a *AttendanceUseCase) teacherClass(ctx context.Context, teacherID, classID string) (*entity.Class, error) {
+	class, err := a.classRepo.GetClass(ctx, classID)
+	if err != nil {
+		return nil, err
+	}
+	if teachesClass(class, teacherID) {
+		return class, nil
+	}
+
+	course, err := a.courseRepo.GetCourse(ctx, class.CourseID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if !isAssignedTeacher(course, teacherID) {
+		return nil, entity.ErrUnauthorized
+	}
+	return class, nil
+}
+
+func (a *AttendanceUseCase) teacherSession(ctx context.Context, teacherID, sessionID string) (*entity.ClassSession, error) {
+	session, err := a.sessionRepo.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := a.teacherClass(ctx, teacherID, session.ClassID.Hex()); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func validateSession(s *entity.ClassSession) error {
+	if s.StartsAt.IsZero() || !s.EndsAt.After(s.StartsAt) {
+		return entity.ErrInvalidSession
+	}
+	return nil
+}
+
+func containsID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func randomDigits(n int) (string, error) {
+	max := big.NewInt(10)
+	b := make([]byte, n)
+	for i := range b {
+		d, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = byte('0' + d.Int64())
+	}
+	return string(b), nil
+}
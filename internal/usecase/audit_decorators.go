@@ -0,0 +1,770 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The Audited* types wrap a usecase and record its audited actions once they
+// have succeeded. Every other method is passed through unchanged.
+
+// AuditedAuthUseCase records role changes, account deletions, password
+// resets, changes to who has to use a second factor, service accounts, API
+// tokens and impersonation sessions.
+type AuditedAuthUseCase struct {
+	*AuthUseCase
+	audit *AuditUseCase
+}
+
+func NewAuditedAuthUseCase(u *AuthUseCase, audit *AuditUseCase) *AuditedAuthUseCase {
+	return &AuditedAuthUseCase{AuthUseCase: u, audit: audit}
+}
+
+func (a *AuditedAuthUseCase) UpdateUserRole(ctx context.Context, userID string, role entity.Role) error {
+	user, err := a.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := a.AuthUseCase.UpdateUserRole(ctx, userID, role); err != nil {
+		return err
+	}
+
+	if user.Role != role {
+		a.audit.Record(ctx, &entity.AuditEntry{
+			Action:     entity.AuditRoleChanged,
+			TargetType: "user",
+			TargetID:   user.ID,
+			Changes:    map[string]entity.AuditChange{"role": {Before: user.Role, After: role}},
+		})
+	}
+	return nil
+}
+
+func (a *AuditedAuthUseCase) DeleteUser(ctx context.Context, userID string) error {
+	user, err := a.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := a.AuthUseCase.DeleteUser(ctx, userID); err != nil {
+		return err
+	}
+
+	a.audit.Record(ctx, &entity.AuditEntry{
+		Action:     entity.AuditUserDeleted,
+		TargetType: "user",
+		TargetID:   user.ID,
+		Changes:    map[string]entity.AuditChange{"email": {Before: user.Email}},
+	})
+	return nil
+}
+
+func (a *AuditedAuthUseCase) ResetUserMFA(ctx context.Context, userID string) error {
+	user, err := a.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := a.AuthUseCase.ResetUserMFA(ctx, userID); err != nil {
+		return err
+	}
+
+	a.audit.Record(ctx, &entity.AuditEntry{
+		Action:     entity.AuditMFAReset,
+		TargetType: "user",
+		TargetID:   user.ID,
+		Changes:    map[string]entity.AuditChange{"mfa_enabled": {Before: user.MFA.Enabled(), After: false}},
+	})
+	return nil
+}
+
+func (a *AuditedAuthUseCase) SetMFAPolicy(ctx context.Context, roles []entity.Role) error {
+	before, err := a.settingsRepo.GetMFAPolicy(ctx)
+	if err != nil {
+		return err
+	}
+	if err := a.AuthUseCase.SetMFAPolicy(ctx, roles); err != nil {
+		return err
+	}
+
+	a.audit.Record(ctx, &entity.AuditEntry{
+		Action:     entity.AuditMFAPolicyChanged,
+		TargetType: "settings",
+		Changes:    map[string]entity.AuditChange{"required_roles": {Before: before.RequiredRoles, After: roles}},
+	})
+	return nil
+}
+
+func (a *AuditedAuthUseCase) CreateServiceAccount(ctx context.Context, email string, role entity.Role) (*entity.User, error) {
+	user, err := a.AuthUseCase.CreateServiceAccount(ctx, email, role)
+	if err != nil {
+		return nil, err
+	}
+
+	a.audit.Record(ctx, &entity.AuditEntry{
+		Action:     entity.AuditServiceAccountCreated,
+		TargetType: "user",
+		TargetID:   user.ID,
+		Changes: map[string]entity.AuditChange{
+			"email": {After: user.Email},
+			"role":  {After: user.Role},
+		},
+	})
+	return user, nil
+}
+
+func (a *AuditedAuthUseCase) CreateAPIToken(ctx context.Context, userID, createdBy, name string, scopes []entity.APIScope, expiresAt *time.Time) (*entity.CreatedAPIToken, error) {
+	token, err := a.AuthUseCase.CreateAPIToken(ctx, userID, createdBy, name, scopes, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	a.recordAPITokenCreated(ctx, token.APIToken)
+	return token, nil
+}
+
+func (a *AuditedAuthUseCase) CreateServiceAccountToken(ctx context.Context, adminID, accountID, name string, scopes []entity.APIScope, expiresAt *time.Time) (*entity.CreatedAPIToken, error) {
+	token, err := a.AuthUseCase.CreateServiceAccountToken(ctx, adminID, accountID, name, scopes, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	a.recordAPITokenCreated(ctx, token.APIToken)
+	return token, nil
+}
+
+func (a *AuditedAuthUseCase) recordAPITokenCreated(ctx context.Context, token *entity.APIToken) {
+	a.audit.Record(ctx, &entity.AuditEntry{
+		Action:     entity.AuditAPITokenCreated,
+		TargetType: "user",
+		TargetID:   token.UserID,
+		Changes: map[string]entity.AuditChange{
+			"name":   {After: token.Name},
+			"scopes": {After: token.Scopes},
+		},
+	})
+}
+
+func (a *AuditedAuthUseCase) RevokeAPIToken(ctx context.Context, userID, tokenID string) error {
+	if err := a.AuthUseCase.RevokeAPIToken(ctx, userID, tokenID); err != nil {
+		return err
+	}
+
+	uid, _ := primitive.ObjectIDFromHex(userID)
+	a.audit.Record(ctx, &entity.AuditEntry{
+		Action:     entity.AuditAPITokenRevoked,
+		TargetType: "user",
+		TargetID:   uid,
+		Changes:    map[string]entity.AuditChange{"token_id": {Before: tokenID}},
+	})
+	return nil
+}
+
+// StartImpersonation records the session under the admin who started it,
+// with the impersonated user as the subject.
+func (a *AuditedAuthUseCase) StartImpersonation(ctx context.Context, actorID, userID, reason string, ttl time.Duration, allowWrites bool) (*entity.Impersonation, error) {
+	imp, err := a.AuthUseCase.StartImpersonation(ctx, actorID, userID, reason, ttl, allowWrites)
+	if err != nil {
+		return nil, err
+	}
+
+	a.audit.Record(ctx, &entity.AuditEntry{
+		Action:     entity.AuditImpersonationStarted,
+		TargetType: "impersonation",
+		TargetID:   imp.Session.ID,
+		SubjectID:  imp.Session.UserID,
+		Changes: map[string]entity.AuditChange{
+			"reason":       {After: imp.Session.Reason},
+			"allow_writes": {After: imp.Session.AllowWrites},
+			"expires_at":   {After: imp.Session.ExpiresAt},
+		},
+	})
+	return imp, nil
+}
+
+// EndImpersonation records the end of a session by an admin or from inside
+// the session, in which case the admin of the session is the actor.
+func (a *AuditedAuthUseCase) EndImpersonation(ctx context.Context, sessionID string) (*entity.ImpersonationSession, error) {
+	session, err := a.AuthUseCase.EndImpersonation(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	a.audit.Record(ctx, &entity.AuditEntry{
+		Action:     entity.AuditImpersonationEnded,
+		TargetType: "impersonation",
+		TargetID:   session.ID,
+		SubjectID:  session.UserID,
+		Changes: map[string]entity.AuditChange{
+			"writes": {After: len(session.Writes)},
+		},
+	})
+	return session, nil
+}
+
+// ResetPassword records the reset with the account itself as the actor, as
+// the request carries a reset token rather than a session.
+func (a *AuditedAuthUseCase) ResetPassword(ctx context.Context, tokenStr, newPassword string) (*entity.User, error) {
+	user, err := a.AuthUseCase.ResetPassword(ctx, tokenStr, newPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	a.audit.Record(ctx, &entity.AuditEntry{
+		Action:     entity.AuditPasswordReset,
+		ActorID:    user.ID,
+		ActorRole:  user.Role,
+		TargetType: "user",
+		TargetID:   user.ID,
+	})
+	return user, nil
+}
+
+// AuditedAdminUseCase records class membership changes and the deletion of
+// courses, classes and announcements.
+type AuditedAdminUseCase struct {
+	*AdminUseCase
+	audit *AuditUseCase
+}
+
+func NewAuditedAdminUseCase(u *AdminUseCase, audit *AuditUseCase) *AuditedAdminUseCase {
+	return &AuditedAdminUseCase{AdminUseCase: u, audit: audit}
+}
+
+func (a *AuditedAdminUseCase) CreateClass(ctx context.Context, class *entity.Class) error {
+	if err := a.AdminUseCase.CreateClass(ctx, class); err != nil {
+		return err
+	}
+	a.recordEnrollment(ctx, &entity.Class{}, class)
+	return nil
+}
+
+func (a *AuditedAdminUseCase) UpdateClass(ctx context.Context, class *entity.Class) error {
+	before, err := a.classRepo.GetClass(ctx, class.ID.Hex())
+	if err != nil {
+		return err
+	}
+	if err := a.AdminUseCase.UpdateClass(ctx, class); err != nil {
+		return err
+	}
+	a.recordEnrollment(ctx, before, class)
+	return nil
+}
+
+func (a *AuditedAdminUseCase) recordEnrollment(ctx context.Context, before, after *entity.Class) {
+	changes := map[string]entity.AuditChange{}
+	if !sameIDs(before.StudentIDs, after.StudentIDs) {
+		changes["student_ids"] = entity.AuditChange{Before: before.StudentIDs, After: after.StudentIDs}
+	}
+	if !sameIDs(before.TeacherIDs, after.TeacherIDs) {
+		changes["teacher_ids"] = entity.AuditChange{Before: before.TeacherIDs, After: after.TeacherIDs}
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	a.audit.Record(ctx, &entity.AuditEntry{
+		Action:     entity.AuditEnrollmentChanged,
+		TargetType: "class",
+		TargetID:   after.ID,
+		Changes:    changes,
+	})
+}
+
+func (a *AuditedAdminUseCase) DeleteCourse(ctx context.Context, id string) error {
+	course, err := a.courseRepo.GetCourse(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := a.AdminUseCase.DeleteCourse(ctx, id); err != nil {
+		return err
+	}
+
+	a.audit.Record(ctx, &entity.AuditEntry{
+		Action:     entity.AuditCourseDeleted,
+		TargetType: "course",
+		TargetID:   course.ID,
+		Changes:    map[string]entity.AuditChange{"name": {Before: course.Name}},
+	})
+	return nil
+}
+
+func (a *AuditedAdminUseCase) DeleteClass(ctx context.Context, id string) error {
+	class, err := a.classRepo.GetClass(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := a.AdminUseCase.DeleteClass(ctx, id); err != nil {
+		return err
+	}
+
+	a.audit.Record(ctx, &entity.AuditEntry{
+		Action:     entity.AuditClassDeleted,
+		TargetType: "class",
+		TargetID:   class.ID,
+		Changes:    map[string]entity.AuditChange{"name": {Before: class.Name}},
+	})
+	return nil
+}
+
+func (a *AuditedAdminUseCase) DeleteAnnouncement(ctx context.Context, id string) error {
+	ann, err := a.announcementRepo.GetAnnouncement(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := a.AdminUseCase.DeleteAnnouncement(ctx, id); err != nil {
+		return err
+	}
+
+	a.audit.Record(ctx, &entity.AuditEntry{
+		Action:     entity.AuditAnnouncementDeleted,
+		TargetType: "announcement",
+		TargetID:   ann.ID,
+		Changes:    map[string]entity.AuditChange{"title": {Before: ann.Title}},
+	})
+	return nil
+}
+
+// AuditedTeacherAdvancedUseCase records grading and the deletion of
+// assignments and assessments.
+type AuditedTeacherAdvancedUseCase struct {
+	*TeacherAdvancedUseCase
+	audit *AuditUseCase
+}
+
+func NewAuditedTeacherAdvancedUseCase(u *TeacherAdvancedUseCase, audit *AuditUseCase) *AuditedTeacherAdvancedUseCase {
+	return &AuditedTeacherAdvancedUseCase{TeacherAdvancedUseCase: u, audit: audit}
+}
+
+// GradeSubmission compares the submission before and after grading rather
+// than relying on the result, as the grade is saved before the student is
+// notified and a failed notification still leaves the new grade in place.
+func (t *AuditedTeacherAdvancedUseCase) GradeSubmission(ctx context.Context, submissionID string, grade float64, feedback *string) error {
+	before, err := t.submitRepo.GetSubmission(ctx, submissionID)
+	if err != nil {
+		return err
+	}
+	gradeErr := t.TeacherAdvancedUseCase.GradeSubmission(ctx, submissionID, grade, feedback)
+
+	after, err := t.submitRepo.GetSubmission(ctx, submissionID)
+	if err != nil {
+		return gradeErr
+	}
+	changes := map[string]entity.AuditChange{}
+	if b, a := deref(before.Grade), deref(after.Grade); b != a {
+		changes["grade"] = entity.AuditChange{Before: b, After: a}
+	}
+	if b, a := deref(before.Feedback), deref(after.Feedback); b != a {
+		changes["feedback"] = entity.AuditChange{Before: b, After: a}
+	}
+	if len(changes) > 0 {
+		t.audit.Record(ctx, &entity.AuditEntry{
+			Action:     entity.AuditSubmissionGraded,
+			TargetType: "submission",
+			TargetID:   after.ID,
+			SubjectID:  after.StudentID,
+			Changes:    changes,
+		})
+	}
+	return gradeErr
+}
+
+func (t *AuditedTeacherAdvancedUseCase) DeleteAssignment(ctx context.Context, id string) error {
+	assignment, err := t.assignmentRepo.GetAssignment(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := t.TeacherAdvancedUseCase.DeleteAssignment(ctx, id); err != nil {
+		return err
+	}
+
+	t.audit.Record(ctx, &entity.AuditEntry{
+		Action:     entity.AuditAssignmentDeleted,
+		TargetType: "assignment",
+		TargetID:   assignment.ID,
+		Changes:    map[string]entity.AuditChange{"title": {Before: assignment.Title}},
+	})
+	return nil
+}
+
+func (t *AuditedTeacherAdvancedUseCase) DeleteAssessment(ctx context.Context, id string, cascade bool) error {
+	assessment, err := t.assessmentRepo.GetAssessment(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := t.TeacherAdvancedUseCase.DeleteAssessment(ctx, id, cascade); err != nil {
+		return err
+	}
+
+	t.audit.Record(ctx, &entity.AuditEntry{
+		Action:     entity.AuditAssessmentDeleted,
+		TargetType: "assessment",
+		TargetID:   assessment.ID,
+		Changes: map[string]entity.AuditChange{
+			"title":   {Before: assessment.Title},
+			"cascade": {After: cascade},
+		},
+	})
+	return nil
+}
+
+// AuditedProgressUseCase records assessment results entered by teachers.
+type AuditedProgressUseCase struct {
+	*ProgressUseCase
+	audit *AuditUseCase
+}
+
+func NewAuditedProgressUseCase(u *ProgressUseCase, audit *AuditUseCase) *AuditedProgressUseCase {
+	return &AuditedProgressUseCase{ProgressUseCase: u, audit: audit}
+}
+
+func (p *AuditedProgressUseCase) RecordAssessmentResult(ctx context.Context, teacherID, assessmentID, studentID string, score float64) error {
+	before := p.previousScore(ctx, assessmentID, studentID)
+	if err := p.ProgressUseCase.RecordAssessmentResult(ctx, teacherID, assessmentID, studentID, score); err != nil {
+		return err
+	}
+
+	// The result has been saved, so the assessment and student are known to exist.
+	assessment, err := p.assessmentRepo.GetAssessment(ctx, assessmentID)
+	if err != nil {
+		return nil
+	}
+	sid, _ := primitive.ObjectIDFromHex(studentID)
+	p.audit.Record(ctx, &entity.AuditEntry{
+		Action:     entity.AuditResultRecorded,
+		TargetType: "assessment",
+		TargetID:   assessment.ID,
+		SubjectID:  sid,
+		Changes:    map[string]entity.AuditChange{"score": {Before: before, After: score}},
+	})
+	return nil
+}
+
+// previousScore returns the student's current score for the assessment, or
+// nil when there is none yet. Lookup errors are left for the usecase to report.
+func (p *AuditedProgressUseCase) previousScore(ctx context.Context, assessmentID, studentID string) interface{} {
+	assessment, err := p.assessmentRepo.GetAssessment(ctx, assessmentID)
+	if err != nil {
+		return nil
+	}
+	sid, err := primitive.ObjectIDFromHex(studentID)
+	if err != nil {
+		return nil
+	}
+	records, err := p.progressRepo.ListProgressByStudentAndCourse(ctx, sid, assessment.CourseID)
+	if err != nil {
+		return nil
+	}
+	for _, rec := range records {
+		if rec.ItemType == entity.ProgressAssessment && rec.ItemID == assessment.ID {
+			return deref(rec.Score)
+		}
+	}
+	return nil
+}
+
+// AuditedTrashUseCase records restores and purges of trashed items.
+type AuditedTrashUseCase struct {
+	*TrashUseCase
+	audit *AuditUseCase
+}
+
+func NewAuditedTrashUseCase(u *TrashUseCase, audit *AuditUseCase) *AuditedTrashUseCase {
+	return &AuditedTrashUseCase{TrashUseCase: u, audit: audit}
+}
+
+func (t *AuditedTrashUseCase) Restore(ctx context.Context, typ entity.TrashType, id string) error {
+	if err := t.TrashUseCase.Restore(ctx, typ, id); err != nil {
+		return err
+	}
+
+	oid, _ := primitive.ObjectIDFromHex(id)
+	t.audit.Record(ctx, &entity.AuditEntry{
+		Action:     entity.AuditItemRestored,
+		TargetType: string(typ),
+		TargetID:   oid,
+	})
+	return nil
+}
+
+func (t *AuditedTrashUseCase) Purge(ctx context.Context, typ entity.TrashType, id string, cascade bool) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return entity.ErrTrashItemNotFound
+	}
+	item, err := t.trashRepo.GetTrashItem(ctx, typ, oid)
+	if err != nil {
+		return err
+	}
+	if err := t.TrashUseCase.Purge(ctx, typ, id, cascade); err != nil {
+		return err
+	}
+
+	t.audit.Record(ctx, &entity.AuditEntry{
+		Action:     entity.AuditItemPurged,
+		TargetType: string(typ),
+		TargetID:   oid,
+		Changes: map[string]entity.AuditChange{
+			"title":   {Before: item.Title},
+			"cascade": {After: cascade},
+		},
+	})
+	return nil
+}
+
+// AuditedTermUseCase records the deletion of terms.
+type AuditedTermUseCase struct {
+	*TermUseCase
+	audit *AuditUseCase
+}
+
+func NewAuditedTermUseCase(u *TermUseCase, audit *AuditUseCase) *AuditedTermUseCase {
+	return &AuditedTermUseCase{TermUseCase: u, audit: audit}
+}
+
+func (t *AuditedTermUseCase) DeleteTerm(ctx context.Context, id string, cascade bool) error {
+	term, err := t.termRepo.GetTerm(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := t.TermUseCase.DeleteTerm(ctx, id, cascade); err != nil {
+		return err
+	}
+
+	t.audit.Record(ctx, &entity.AuditEntry{
+		Action:     entity.AuditTermDeleted,
+		TargetType: "term",
+		TargetID:   term.ID,
+		Changes: map[string]entity.AuditChange{
+			"name":    {Before: term.Name},
+			"cascade": {After: cascade},
+		},
+	})
+	return nil
+}
+
+// AuditedKeyRing records manual rotations and revocations of signing keys.
+// Scheduled rotations are not recorded.
+type AuditedKeyRing struct {
+	*KeyRing
+	audit *AuditUseCase
+}
+
+func NewAuditedKeyRing(k *KeyRing, audit *AuditUseCase) *AuditedKeyRing {
+	return &AuditedKeyRing{KeyRing: k, audit: audit}
+}
+
+func (k *AuditedKeyRing) Rotate(ctx context.Context, immediate bool) (*entity.SigningKey, error) {
+	key, err := k.KeyRing.Rotate(ctx, immediate)
+	if err != nil {
+		return nil, err
+	}
+
+	k.audit.Record(ctx, &entity.AuditEntry{
+		Action:     entity.AuditSigningKeyRotated,
+		TargetType: "signing_key",
+		Changes: map[string]entity.AuditChange{
+			"kid":         {After: key.ID},
+			"active_from": {After: key.ActiveFrom},
+		},
+	})
+	return key, nil
+}
+
+func (k *AuditedKeyRing) RevokeKey(ctx context.Context, kid string) error {
+	if err := k.KeyRing.RevokeKey(ctx, kid); err != nil {
+		return err
+	}
+
+	k.audit.Record(ctx, &entity.AuditEntry{
+		Action:     entity.AuditSigningKeyRevoked,
+		TargetType: "signing_key",
+		Changes:    map[string]entity.AuditChange{"kid": {Before: kid}},
+	})
+	return nil
+}
+
+// AuditedRoleUseCase records changes to the role definitions.
+type AuditedRoleUseCase struct {
+	*RoleUseCase
+	audit *AuditUseCase
+}
+
+func NewAuditedRoleUseCase(u *RoleUseCase, audit *AuditUseCase) *AuditedRoleUseCase {
+	return &AuditedRoleUseCase{RoleUseCase: u, audit: audit}
+}
+
+func (r *AuditedRoleUseCase) CreateRole(ctx context.Context, role *entity.RoleDefinition) error {
+	if err := r.RoleUseCase.CreateRole(ctx, role); err != nil {
+		return err
+	}
+
+	r.audit.Record(ctx, &entity.AuditEntry{
+		Action:     entity.AuditRoleCreated,
+		TargetType: "role",
+		Changes: map[string]entity.AuditChange{
+			"name":        {After: role.Name},
+			"permissions": {After: role.Permissions},
+		},
+	})
+	return nil
+}
+
+func (r *AuditedRoleUseCase) UpdateRole(ctx context.Context, name entity.Role, description string, permissions []entity.Permission) error {
+	before, err := r.RoleUseCase.GetRole(ctx, name)
+	if err != nil {
+		return err
+	}
+	if err := r.RoleUseCase.UpdateRole(ctx, name, description, permissions); err != nil {
+		return err
+	}
+
+	r.audit.Record(ctx, &entity.AuditEntry{
+		Action:     entity.AuditRoleUpdated,
+		TargetType: "role",
+		Changes: map[string]entity.AuditChange{
+			"name":        {Before: name},
+			"permissions": {Before: before.Permissions, After: permissions},
+		},
+	})
+	return nil
+}
+
+func (r *AuditedRoleUseCase) DeleteRole(ctx context.Context, name entity.Role) error {
+	before, err := r.RoleUseCase.GetRole(ctx, name)
+	if err != nil {
+		return err
+	}
+	if err := r.RoleUseCase.DeleteRole(ctx, name); err != nil {
+		return err
+	}
+
+	r.audit.Record(ctx, &entity.AuditEntry{
+		Action:     entity.AuditRoleDeleted,
+		TargetType: "role",
+		Changes: map[string]entity.AuditChange{
+			"name":        {Before: name},
+			"permissions": {Before: before.Permissions},
+		},
+	})
+	return nil
+}
+
+// AuditedGuardianUseCase records guardians being linked to students and
+// unlinked from them.
+type AuditedGuardianUseCase struct {
+	*GuardianUseCase
+	audit *AuditUseCase
+}
+
+func NewAuditedGuardianUseCase(u *GuardianUseCase, audit *AuditUseCase) *AuditedGuardianUseCase {
+	return &AuditedGuardianUseCase{GuardianUseCase: u, audit: audit}
+}
+
+func (g *AuditedGuardianUseCase) LinkGuardian(ctx context.Context, adminID, guardianID, studentID string) (*entity.GuardianLink, error) {
+	link, err := g.GuardianUseCase.LinkGuardian(ctx, adminID, guardianID, studentID)
+	if err != nil {
+		return nil, err
+	}
+	g.recordLink(ctx, entity.AuditGuardianLinked, link)
+	return link, nil
+}
+
+func (g *AuditedGuardianUseCase) AcceptGuardianInvitation(ctx context.Context, guardianID, token string) (*entity.GuardianLink, error) {
+	link, err := g.GuardianUseCase.AcceptGuardianInvitation(ctx, guardianID, token)
+	if err != nil {
+		return nil, err
+	}
+	g.recordLink(ctx, entity.AuditGuardianLinked, link)
+	return link, nil
+}
+
+func (g *AuditedGuardianUseCase) RemoveGuardianLink(ctx context.Context, linkID string) (*entity.GuardianLink, error) {
+	link, err := g.GuardianUseCase.RemoveGuardianLink(ctx, linkID)
+	if err != nil {
+		return nil, err
+	}
+	g.recordLink(ctx, entity.AuditGuardianUnlinked, link)
+	return link, nil
+}
+
+func (g *AuditedGuardianUseCase) recordLink(ctx context.Context, action entity.AuditAction, link *entity.GuardianLink) {
+	change := entity.AuditChange{After: link.Email}
+	if action == entity.AuditGuardianUnlinked {
+		change = entity.AuditChange{Before: link.Email}
+	}
+	entry := &entity.AuditEntry{
+		Action:     action,
+		TargetType: "guardian_link",
+		TargetID:   link.ID,
+		SubjectID:  link.StudentID,
+		Changes:    map[string]entity.AuditChange{"guardian": change},
+	}
+	g.audit.Record(ctx, entry)
+}
+
+// AuditedProfileUseCase records admins changing the profile of a user.
+// Users changing their own profile are not recorded.
+type AuditedProfileUseCase struct {
+	*ProfileUseCase
+	audit *AuditUseCase
+}
+
+func NewAuditedProfileUseCase(u *ProfileUseCase, audit *AuditUseCase) *AuditedProfileUseCase {
+	return &AuditedProfileUseCase{ProfileUseCase: u, audit: audit}
+}
+
+func (p *AuditedProfileUseCase) UpdateUserProfile(ctx context.Context, userID string, update entity.ProfileUpdate, studentNumber *string) (*entity.User, error) {
+	before, err := p.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := p.ProfileUseCase.UpdateUserProfile(ctx, userID, update, studentNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]entity.AuditChange{}
+	for field, values := range map[string][2]string{
+		"first_name":     {before.FirstName, user.FirstName},
+		"last_name":      {before.LastName, user.LastName},
+		"display_name":   {before.DisplayName, user.DisplayName},
+		"locale":         {before.Locale, user.Locale},
+		"time_zone":      {before.TimeZone, user.TimeZone},
+		"student_number": {before.StudentNumber, user.StudentNumber},
+	} {
+		if values[0] != values[1] {
+			changes[field] = entity.AuditChange{Before: values[0], After: values[1]}
+		}
+	}
+	if len(changes) > 0 {
+		p.audit.Record(ctx, &entity.AuditEntry{
+			Action:     entity.AuditProfileUpdated,
+			TargetType: "user",
+			TargetID:   user.ID,
+			Changes:    changes,
+		})
+	}
+	return user, nil
+}
+
+// sameIDs reports whether both lists hold the same IDs, in any order.
+func sameIDs(a, b []primitive.ObjectID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, id := range a {
+		if !containsID(b, id) {
+			return false
+		}
+	}
+	return true
+}
+
+// deref returns the value p points to, or nil for a nil pointer, so that
+// optional fields compare and encode by value.
+func deref[T comparable](p *T) interface{} {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
@@ -0,0 +1,63 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AuditRepository interface {
+	AppendAudit(ctx context.Context, e *entity.AuditEntry) error
+	ListAudit(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.AuditEntry], error)
+}
+
+// AuditUseCase writes and reads the audit log. Entries are written by the
+// Audited* decorators around the usecases whose actions are audited.
+type AuditUseCase struct {
+	auditRepo AuditRepository
+}
+
+func NewAuditUseCase(auditRepo AuditRepository) *AuditUseCase {
+	return &AuditUseCase{auditRepo: auditRepo}
+}
+
+// Record appends an entry for an action that has succeeded. The actor, unless
+// already set, and the client address and user agent are taken from the
+// request context. In an impersonation session the actor is the admin, and
+// the impersonated user is kept as OnBehalfOf. The action cannot be undone
+// at this point, so a failure to write the entry is logged rather than
+// returned.
+func (a *AuditUseCase) Record(ctx context.Context, e *entity.AuditEntry) {
+	session, _ := ctx.Value("impersonation").(*entity.ImpersonationSession)
+	if e.ActorID.IsZero() {
+		if session != nil {
+			e.ActorID, e.ActorRole = session.ActorID, session.ActorRole
+		} else {
+			if id, ok := ctx.Value("userID").(string); ok {
+				e.ActorID, _ = primitive.ObjectIDFromHex(id)
+			}
+			e.ActorRole, _ = ctx.Value("userRole").(entity.Role)
+		}
+	}
+	if session != nil {
+		e.OnBehalfOf = session.UserID
+	}
+	e.IP, _ = ctx.Value("clientIP").(string)
+	e.UserAgent, _ = ctx.Value("userAgent").(string)
+	e.CreatedAt = time.Now()
+
+	// The entry is written even if the client has gone away in the meantime.
+	if err := a.auditRepo.AppendAudit(context.WithoutCancel(ctx), e); err != nil {
+		log.Printf("audit: failed to record %s on %s %s: %v", e.Action, e.TargetType, e.TargetID.Hex(), err)
+	}
+}
+
+// ListAudit returns a page of audit entries, newest first, filterable by
+// action, actor_id, target_type, target_id, subject_id, on_behalf_of and a
+// from/to time range.
+func (a *AuditUseCase) ListAudit(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.AuditEntry], error) {
+	return a.auditRepo.ListAudit(ctx, opts)
+}
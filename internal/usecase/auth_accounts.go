@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+)
+
+// Session tokens are checked against the stored account on every request,
+// so deleting or archiving a user locks them out before their token
+// expires, and a changed role applies without signing in again. The lookups are kept for accountCacheTTL; a change made on
+// another instance applies there within that time, changes made here apply
+// at once.
+const accountCacheTTL = 30 * time.Second
+
+type accountEntry struct {
+	role     entity.Role
+	loadedAt time.Time
+}
+
+// accountCache holds the roles of recently seen active accounts. The zero
+// value is ready to use.
+type accountCache struct {
+	mu      sync.Mutex
+	entries map[string]accountEntry
+}
+
+func (c *accountCache) get(userID string) (entity.Role, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[userID]
+	if !ok || time.Since(e.loadedAt) >= accountCacheTTL {
+		return "", false
+	}
+	return e.role, true
+}
+
+// put stores the role of userID and drops the entries that have expired,
+// so the cache holds no more than the accounts seen within the TTL.
+func (c *accountCache) put(userID string, role entity.Role) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if c.entries == nil {
+		c.entries = make(map[string]accountEntry)
+	}
+	for id, e := range c.entries {
+		if now.Sub(e.loadedAt) >= accountCacheTTL {
+			delete(c.entries, id)
+		}
+	}
+	c.entries[userID] = accountEntry{role: role, loadedAt: now}
+}
+
+func (c *accountCache) forget(userID string) {
+	c.mu.Lock()
+	delete(c.entries, userID)
+	c.mu.Unlock()
+}
+
+// activeRole returns the stored role of an account that may still sign in.
+// A deleted or archived account yields ErrInvalidToken.
+func (a *AuthUseCase) activeRole(ctx context.Context, userID string) (entity.Role, error) {
+	if role, ok := a.accounts.get(userID); ok {
+		return role, nil
+	}
+	user, err := a.userRepo.FindByID(ctx, userID)
+	if errors.Is(err, entity.ErrUserNotFound) {
+		return "", entity.ErrInvalidToken
+	}
+	if err != nil {
+		return "", err
+	}
+	if user.ArchivedAt != nil {
+		return "", entity.ErrInvalidToken
+	}
+	a.accounts.put(userID, user.Role)
+	return user.Role, nil
+}
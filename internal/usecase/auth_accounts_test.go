@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+)
+
+func TestParseTokenChecksAccount(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(a *AuthUseCase, u *entity.User) error
+		wantErr error
+	}{
+		{"active account", func(a *AuthUseCase, u *entity.User) error { return nil }, nil},
+		{"deleted account", func(a *AuthUseCase, u *entity.User) error {
+			return a.DeleteUser(context.Background(), u.ID.Hex())
+		}, entity.ErrInvalidToken},
+		{"archived account", func(a *AuthUseCase, u *entity.User) error {
+			now := time.Now()
+			u.ArchivedAt = &now
+			a.accounts.forget(u.ID.Hex())
+			return nil
+		}, entity.ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := testUser(entity.RoleStudent)
+			a := newImpersonationAuth(t, user)
+			token, err := a.issueSessionToken(user)
+			if err != nil {
+				t.Fatalf("issueSessionToken() error = %v", err)
+			}
+			// The first request caches the account.
+			if _, _, _, err := a.ParseToken(context.Background(), token); err != nil {
+				t.Fatalf("ParseToken() before the change: error = %v", err)
+			}
+
+			if err := tt.change(a, user); err != nil {
+				t.Fatalf("change: %v", err)
+			}
+			_, _, _, err = a.ParseToken(context.Background(), token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ParseToken() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseTokenUsesStoredRole(t *testing.T) {
+	admin := testUser(entity.RoleAdmin)
+	teacher := testUser(entity.RoleTeacher)
+	a := newImpersonationAuth(t, admin, teacher)
+	token, err := a.issueSessionToken(teacher)
+	if err != nil {
+		t.Fatalf("issueSessionToken() error = %v", err)
+	}
+	if _, role, _, err := a.ParseToken(context.Background(), token); err != nil || role != string(entity.RoleTeacher) {
+		t.Fatalf("ParseToken() = %q, %v, want teacher", role, err)
+	}
+
+	ctx := actorContext(admin.ID, admin.Role)
+	if err := a.UpdateUserRole(ctx, teacher.ID.Hex(), entity.RoleStudent); err != nil {
+		t.Fatalf("UpdateUserRole() error = %v", err)
+	}
+	_, role, _, err := a.ParseToken(context.Background(), token)
+	if err != nil {
+		t.Fatalf("ParseToken() error = %v", err)
+	}
+	if role != string(entity.RoleStudent) {
+		t.Errorf("role after the change = %q, want student", role)
+	}
+}
+
+func TestRegisterRefusesOtherRoles(t *testing.T) {
+	a := newImpersonationAuth(t)
+	for _, role := range []entity.Role{entity.RoleAdmin, entity.RoleTeacher, entity.RoleGuardian, "registrar"} {
+		t.Run(string(role), func(t *testing.T) {
+			err := a.Register(context.Background(), "new@example.com", "a long passphrase", role)
+			if !errors.Is(err, entity.ErrPermissionDenied) {
+				t.Errorf("Register() error = %v, want %v", err, entity.ErrPermissionDenied)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type APITokenRepository interface {
+	CreateAPIToken(ctx context.Context, token *entity.APIToken) error
+	FindAPITokenByHash(ctx context.Context, hash string, now time.Time) (*entity.APIToken, error)
+	ListAPITokens(ctx context.Context, userID string) ([]*entity.APIToken, error)
+	DeleteAPIToken(ctx context.Context, userID, tokenID string) error
+	TouchAPIToken(ctx context.Context, tokenID primitive.ObjectID, at time.Time) error
+}
+
+// The last use of a token is written at most once per apiTokenTouchEvery,
+// so that busy integrations do not cause a write per request.
+const apiTokenTouchEvery = time.Minute
+
+// CreateAPIToken creates a token for userID on behalf of createdBy. It
+// returns the secret, which is not stored and cannot be shown again.
+func (a *AuthUseCase) CreateAPIToken(ctx context.Context, userID, createdBy, name string, scopes []entity.APIScope, expiresAt *time.Time) (*entity.CreatedAPIToken, error) {
+	user, err := a.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	creator, err := primitive.ObjectIDFromHex(createdBy)
+	if err != nil {
+		return nil, entity.ErrUserNotFound
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" || len(scopes) == 0 {
+		return nil, entity.ErrInvalidAPIToken
+	}
+	for _, s := range scopes {
+		if !entity.IsValidAPIScope(s) {
+			return nil, entity.ErrInvalidScope
+		}
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, entity.ErrInvalidAPIToken
+	}
+
+	secret, err := randomURLToken(32)
+	if err != nil {
+		return nil, err
+	}
+	secret = entity.APITokenPrefix + secret
+	token := &entity.APIToken{
+		ID:        primitive.NewObjectID(),
+		UserID:    user.ID,
+		Name:      name,
+		Prefix:    secret[:len(entity.APITokenPrefix)+6],
+		Hash:      hashToken(secret),
+		Scopes:    scopes,
+		CreatedBy: creator,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}
+	if err := a.apiTokenRepo.CreateAPIToken(ctx, token); err != nil {
+		return nil, err
+	}
+	return &entity.CreatedAPIToken{APIToken: token, Token: secret}, nil
+}
+
+// CreateServiceAccountToken lets an admin create a token for a service
+// account. Tokens for people are only created by the people themselves.
+func (a *AuthUseCase) CreateServiceAccountToken(ctx context.Context, adminID, accountID, name string, scopes []entity.APIScope, expiresAt *time.Time) (*entity.CreatedAPIToken, error) {
+	user, err := a.userRepo.FindByID(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.ServiceAccount {
+		return nil, entity.ErrNotServiceAccount
+	}
+	return a.CreateAPIToken(ctx, accountID, adminID, name, scopes, expiresAt)
+}
+
+// CreateServiceAccount creates an account for an integration. It has no
+// password and can only be used through API tokens.
+func (a *AuthUseCase) CreateServiceAccount(ctx context.Context, email string, role entity.Role) (*entity.User, error) {
+	if _, err := a.userRepo.FindByEmail(ctx, email); err == nil {
+		return nil, entity.ErrEmailExists
+	}
+	if err := a.roles.CheckRole(ctx, role); err != nil {
+		return nil, err
+	}
+	if err := a.checkGrant(ctx, role); err != nil {
+		return nil, err
+	}
+
+	user := &entity.User{
+		ID:             primitive.NewObjectID(),
+		Email:          email,
+		EmailVerified:  true,
+		Role:           role,
+		ServiceAccount: true,
+		CreatedAt:      time.Now(),
+	}
+	if err := a.userRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (a *AuthUseCase) ListAPITokens(ctx context.Context, userID string) ([]*entity.APIToken, error) {
+	return a.apiTokenRepo.ListAPITokens(ctx, userID)
+}
+
+func (a *AuthUseCase) RevokeAPIToken(ctx context.Context, userID, tokenID string) error {
+	return a.apiTokenRepo.DeleteAPIToken(ctx, userID, tokenID)
+}
+
+// ParseAPIToken returns the token with the given secret and the current
+// role of its owner. Unknown, expired and orphaned tokens are
+// entity.ErrInvalidToken.
+func (a *AuthUseCase) ParseAPIToken(ctx context.Context, secret string) (*entity.APIToken, entity.Role, error) {
+	now := time.Now()
+	token, err := a.apiTokenRepo.FindAPITokenByHash(ctx, hashToken(secret), now)
+	if err != nil {
+		return nil, "", entity.ErrInvalidToken
+	}
+	user, err := a.userRepo.FindByID(ctx, token.UserID.Hex())
+	if err != nil || user.ArchivedAt != nil {
+		return nil, "", entity.ErrInvalidToken
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= apiTokenTouchEvery {
+		if err := a.apiTokenRepo.TouchAPIToken(ctx, token.ID, now); err != nil {
+			return nil, "", err
+		}
+	}
+	return token, user.Role, nil
+}
@@ -0,0 +1,123 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type EmailVerificationRepository interface {
+	ReplaceEmailVerification(ctx context.Context, v *entity.EmailVerification) error
+	TakeEmailVerification(ctx context.Context, tokenHash string, now time.Time) (*entity.EmailVerification, error)
+	DeleteEmailVerifications(ctx context.Context, userID primitive.ObjectID) error
+}
+
+// emailVerificationTTL is how long a verification token can be used.
+const emailVerificationTTL = 24 * time.Hour
+
+// sendEmailVerification mails a new verification token for email to that
+// address. email is the user's own address after registering, or the one
+// the user is changing to.
+func (a *AuthUseCase) sendEmailVerification(ctx context.Context, user *entity.User, email string) error {
+	token, err := randomURLToken(32)
+	if err != nil {
+		return err
+	}
+	err = a.verificationRepo.ReplaceEmailVerification(ctx, &entity.EmailVerification{
+		TokenHash: hashToken(token),
+		UserID:    user.ID,
+		Email:     email,
+		ExpiresAt: time.Now().Add(emailVerificationTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	recipient := *user
+	recipient.Email = email
+	return a.notifier.Notify(ctx, &recipient, entity.NotificationEmailVerification, map[string]interface{}{
+		"Email":  email,
+		"Token":  token,
+		"Change": email != user.Email,
+		"Hours":  int(emailVerificationTTL.Hours()),
+	})
+}
+
+// VerifyEmail redeems a verification token. It either confirms the user's
+// current address or applies the pending change it was sent for.
+func (a *AuthUseCase) VerifyEmail(ctx context.Context, token string) error {
+	v, err := a.verificationRepo.TakeEmailVerification(ctx, hashToken(token), time.Now())
+	if err != nil {
+		return err
+	}
+	user, err := a.userRepo.FindByID(ctx, v.UserID.Hex())
+	if err != nil {
+		return entity.ErrInvalidToken
+	}
+
+	switch v.Email {
+	case user.Email:
+		return a.userRepo.MarkEmailVerified(ctx, user.ID.Hex(), v.Email)
+	case user.PendingEmail:
+		return a.userRepo.ConfirmPendingEmail(ctx, user.ID.Hex(), v.Email)
+	}
+	// The address changed again after the token was sent.
+	return entity.ErrInvalidToken
+}
+
+// ResendEmailVerification sends a new token to an account that has not
+// verified its address. Like a password reset request, it answers the same
+// whether or not the account exists, and every request is throttled.
+func (a *AuthUseCase) ResendEmailVerification(ctx context.Context, email string) error {
+	if err := a.throttle.Check(ctx, "email_verification", email); err != nil {
+		return err
+	}
+	if err := a.throttle.Fail(ctx, "email_verification", email); err != nil {
+		return err
+	}
+
+	user, err := a.userRepo.FindByEmail(ctx, email)
+	if errors.Is(err, entity.ErrUserNotFound) || (err == nil && user.EmailVerified) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return a.sendEmailVerification(ctx, user, user.Email)
+}
+
+// CancelEmailChange drops a pending email change and its token.
+func (a *AuthUseCase) CancelEmailChange(ctx context.Context, userID string) error {
+	user, err := a.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.PendingEmail == "" {
+		return nil
+	}
+	if err := a.userRepo.SetPendingEmail(ctx, userID, ""); err != nil {
+		return err
+	}
+	return a.verificationRepo.DeleteEmailVerifications(ctx, user.ID)
+}
+
+// requiresVerifiedEmail reports whether accounts of role have to verify
+// their address before they can sign in.
+func (a *AuthUseCase) requiresVerifiedEmail(role entity.Role) bool {
+	for _, r := range a.verifyRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
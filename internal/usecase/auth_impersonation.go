@@ -0,0 +1,157 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ImpersonationRepository interface {
+	CreateImpersonation(ctx context.Context, s *entity.ImpersonationSession) error
+	GetImpersonation(ctx context.Context, id string) (*entity.ImpersonationSession, error)
+	EndImpersonation(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	RecordImpersonatedWrite(ctx context.Context, id primitive.ObjectID, w entity.ImpersonatedWrite) error
+	ListImpersonations(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.ImpersonationSession], error)
+}
+
+// An impersonation session lasts defaultImpersonationTTL unless the admin
+// asks for another duration, and never longer than maxImpersonationTTL. It
+// cannot be extended; the admin starts a new one instead.
+const (
+	defaultImpersonationTTL = 15 * time.Minute
+	maxImpersonationTTL     = time.Hour
+	maxImpersonationReason  = 500
+)
+
+// StartImpersonation starts a session in which actorID acts as userID and
+// returns the token for it. The actor has to hold users.impersonate. Users
+// whose role decides who may do what, by managing roles or impersonating
+// others, cannot be impersonated, so that a session never leads to more
+// power than the actor already has.
+func (a *AuthUseCase) StartImpersonation(ctx context.Context, actorID, userID, reason string, ttl time.Duration, allowWrites bool) (*entity.Impersonation, error) {
+	if ctx.Value("impersonation") != nil {
+		return nil, entity.ErrCannotImpersonate
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" || utf8.RuneCountInString(reason) > maxImpersonationReason {
+		return nil, entity.ErrImpersonationReason
+	}
+	if ttl <= 0 {
+		ttl = defaultImpersonationTTL
+	}
+	if ttl > maxImpersonationTTL {
+		ttl = maxImpersonationTTL
+	}
+
+	actor, err := a.userRepo.FindByID(ctx, actorID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := a.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.ID == actor.ID || user.ServiceAccount || user.ArchivedAt != nil {
+		return nil, entity.ErrCannotImpersonate
+	}
+	allowed, err := a.roles.ActorCan(ctx, entity.PermImpersonate)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, entity.ErrPermissionDenied
+	}
+	perms, err := a.roles.Permissions(ctx, user.Role)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range perms {
+		if entity.IsPrivilegedPermission(p) {
+			return nil, entity.ErrCannotImpersonate
+		}
+	}
+
+	now := time.Now().UTC()
+	session := &entity.ImpersonationSession{
+		ID:          primitive.NewObjectID(),
+		ActorID:     actor.ID,
+		ActorRole:   actor.Role,
+		UserID:      user.ID,
+		UserRole:    user.Role,
+		Reason:      reason,
+		AllowWrites: allowWrites,
+		StartedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+	}
+	if err := a.impersonationRepo.CreateImpersonation(ctx, session); err != nil {
+		return nil, err
+	}
+
+	token, err := a.keys.Sign(tokenImpersonation, jwt.MapClaims{
+		"userId": user.ID.Hex(),
+		"email":  user.Email,
+		"role":   string(user.Role),
+		"act":    map[string]string{"sub": actor.ID.Hex()},
+		"sid":    session.ID.Hex(),
+		"exp":    session.ExpiresAt.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &entity.Impersonation{Token: token, Session: session}, nil
+}
+
+// parseImpersonation returns the session of an impersonation token for
+// userID, provided it has been neither ended nor expired.
+func (a *AuthUseCase) parseImpersonation(ctx context.Context, sessionID, userID string) (*entity.ImpersonationSession, error) {
+	session, err := a.impersonationRepo.GetImpersonation(ctx, sessionID)
+	if err != nil || session.UserID.Hex() != userID || !session.Active(time.Now()) {
+		return nil, entity.ErrInvalidToken
+	}
+	return session, nil
+}
+
+// EndImpersonation ends a session before it expires. Its token stops being
+// accepted at once.
+func (a *AuthUseCase) EndImpersonation(ctx context.Context, sessionID string) (*entity.ImpersonationSession, error) {
+	session, err := a.impersonationRepo.GetImpersonation(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if !session.Active(now) {
+		return nil, entity.ErrImpersonationNotFound
+	}
+	if err := a.impersonationRepo.EndImpersonation(ctx, session.ID, now); err != nil {
+		return nil, err
+	}
+	session.EndedAt = &now
+	return session, nil
+}
+
+// RecordImpersonatedWrite notes a request that changed something on the
+// session it was made in. The request has been served by then, so it is
+// written even if the client has gone away.
+func (a *AuthUseCase) RecordImpersonatedWrite(ctx context.Context, session *entity.ImpersonationSession, method, path string, status int) error {
+	return a.impersonationRepo.RecordImpersonatedWrite(context.WithoutCancel(ctx), session.ID, entity.ImpersonatedWrite{
+		Method: method,
+		Path:   path,
+		Status: status,
+		At:     time.Now().UTC(),
+	})
+}
+
+func (a *AuthUseCase) GetImpersonation(ctx context.Context, sessionID string) (*entity.ImpersonationSession, error) {
+	return a.impersonationRepo.GetImpersonation(ctx, sessionID)
+}
+
+// ListImpersonations returns a page of sessions, newest first, filterable
+// by actor_id, user_id and a from/to time range.
+func (a *AuthUseCase) ListImpersonations(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.ImpersonationSession], error) {
+	return a.impersonationRepo.ListImpersonations(ctx, opts)
+}
@@ -0,0 +1,104 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newImpersonationAuth(t *testing.T, users ...*entity.User) *AuthUseCase {
+	t.Helper()
+	keys := newTestKeyRing(t)
+	roles := NewRoleUseCase(seededRoles(), &memRoles{})
+	impersonations := &memImpersonations{sessions: map[primitive.ObjectID]*entity.ImpersonationSession{}}
+	return NewAuthUseCase(newMemUsers(users...), keys, nil, nil, nil, nil, "", nil, nil, nil, roles, impersonations, nil)
+}
+
+func testUser(role entity.Role) *entity.User {
+	return &entity.User{ID: primitive.NewObjectID(), Email: string(role) + "@example.com", Role: role}
+}
+
+func TestStartImpersonation(t *testing.T) {
+	admin := testUser(entity.RoleAdmin)
+	otherAdmin := testUser(entity.RoleAdmin)
+	student := testUser(entity.RoleStudent)
+	teacher := testUser(entity.RoleTeacher)
+	registrar := testUser("registrar")
+	service := testUser(entity.RoleStudent)
+	service.ServiceAccount = true
+	a := newImpersonationAuth(t, admin, otherAdmin, student, teacher, registrar, service)
+
+	tests := []struct {
+		name    string
+		actor   *entity.User
+		target  *entity.User
+		reason  string
+		wantErr error
+	}{
+		{"admin impersonates student", admin, student, "ticket 42", nil},
+		{"admin impersonates teacher", admin, teacher, "ticket 42", nil},
+		{"admin impersonates registrar", admin, registrar, "ticket 42", nil},
+		{"reason is required", admin, student, "  ", entity.ErrImpersonationReason},
+		{"not oneself", admin, admin, "ticket 42", entity.ErrCannotImpersonate},
+		{"not another admin", admin, otherAdmin, "ticket 42", entity.ErrCannotImpersonate},
+		{"not a service account", admin, service, "ticket 42", entity.ErrCannotImpersonate},
+		{"registrar may not impersonate", registrar, student, "ticket 42", entity.ErrPermissionDenied},
+		{"unknown user", admin, testUser(entity.RoleStudent), "ticket 42", entity.ErrUserNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := actorContext(tt.actor.ID, tt.actor.Role)
+			imp, err := a.StartImpersonation(ctx, tt.actor.ID.Hex(), tt.target.ID.Hex(), tt.reason, 0, false)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("StartImpersonation() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			userID, role, session, err := a.ParseToken(context.Background(), imp.Token)
+			if err != nil {
+				t.Fatalf("ParseToken() error = %v", err)
+			}
+			if userID != tt.target.ID.Hex() || role != string(tt.target.Role) {
+				t.Errorf("token acts as %s (%s), want %s (%s)", userID, role, tt.target.ID.Hex(), tt.target.Role)
+			}
+			if session == nil || session.ActorID != tt.actor.ID {
+				t.Errorf("token session = %+v, want one started by %s", session, tt.actor.ID.Hex())
+			}
+			if got := session.ExpiresAt.Sub(session.StartedAt); got != defaultImpersonationTTL {
+				t.Errorf("session lasts %s, want %s", got, defaultImpersonationTTL)
+			}
+		})
+	}
+}
+
+func TestImpersonationTimeLimit(t *testing.T) {
+	admin := testUser(entity.RoleAdmin)
+	student := testUser(entity.RoleStudent)
+	a := newImpersonationAuth(t, admin, student)
+	ctx := actorContext(admin.ID, admin.Role)
+
+	imp, err := a.StartImpersonation(ctx, admin.ID.Hex(), student.ID.Hex(), "ticket 42", 24*time.Hour, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := imp.Session.ExpiresAt.Sub(imp.Session.StartedAt); got != maxImpersonationTTL {
+		t.Errorf("session lasts %s, want the limit of %s", got, maxImpersonationTTL)
+	}
+
+	if _, err := a.StartImpersonation(context.WithValue(ctx, "impersonation", imp.Session), admin.ID.Hex(), student.ID.Hex(), "ticket 42", 0, false); !errors.Is(err, entity.ErrCannotImpersonate) {
+		t.Errorf("nested StartImpersonation() error = %v, want %v", err, entity.ErrCannotImpersonate)
+	}
+
+	if _, err := a.EndImpersonation(ctx, imp.Session.ID.Hex()); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := a.ParseToken(context.Background(), imp.Token); !errors.Is(err, entity.ErrInvalidToken) {
+		t.Errorf("ParseToken() after end error = %v, want %v", err, entity.ErrInvalidToken)
+	}
+}
@@ -0,0 +1,313 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/srgjo27/e-learning/internal/entity"
+)
+
+type SettingsRepository interface {
+	GetMFAPolicy(ctx context.Context) (*entity.MFAPolicy, error)
+	SaveMFAPolicy(ctx context.Context, policy *entity.MFAPolicy) error
+}
+
+// A login whose password step has passed but which still needs a second
+// factor is carried by a challenge token. It is signed like a session token
+// but has no userId claim, so it is never accepted as one.
+const (
+	mfaChallengeTTL   = 5 * time.Minute
+	mfaPurposeVerify  = "mfa_verify"
+	mfaPurposeEnroll  = "mfa_enroll"
+	recoveryCodeCount = 10
+)
+
+// completeFirstFactor decides what a login that got the password right, or
+// came back from single sign-on, returns: a session token, or a challenge
+// when the account has a second factor or its role requires one.
+func (a *AuthUseCase) completeFirstFactor(ctx context.Context, user *entity.User) (*entity.LoginResult, error) {
+	if user.MFA.Enabled() {
+		token, err := a.issueMFAChallenge(user, mfaPurposeVerify)
+		if err != nil {
+			return nil, err
+		}
+		return &entity.LoginResult{MFAToken: token, MFARequired: true}, nil
+	}
+
+	policy, err := a.settingsRepo.GetMFAPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if policy.Requires(user.Role) {
+		token, err := a.issueMFAChallenge(user, mfaPurposeEnroll)
+		if err != nil {
+			return nil, err
+		}
+		return &entity.LoginResult{MFAToken: token, MFARequired: true, EnrollmentRequired: true}, nil
+	}
+
+	token, err := a.issueSessionToken(user)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.LoginResult{Token: token}, nil
+}
+
+func (a *AuthUseCase) issueMFAChallenge(user *entity.User, purpose string) (string, error) {
+	return a.keys.Sign(tokenMFAChallenge, jwt.MapClaims{
+		"mfaUser": user.ID.Hex(),
+		"purpose": purpose,
+		"exp":     time.Now().Add(mfaChallengeTTL).Unix(),
+	})
+}
+
+// parseMFAChallenge returns the user a challenge token was issued to, if it
+// is valid and was issued for purpose.
+func (a *AuthUseCase) parseMFAChallenge(tokenStr, purpose string) (string, error) {
+	token, _, err := a.keys.Parse(tokenStr, jwt.MapClaims{}, tokenMFAChallenge)
+	if err != nil || !token.Valid {
+		return "", entity.ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != purpose {
+		return "", entity.ErrInvalidToken
+	}
+	userID, ok := claims["mfaUser"].(string)
+	if !ok {
+		return "", entity.ErrInvalidToken
+	}
+	return userID, nil
+}
+
+// CompleteMFALogin finishes a login with a code from the authenticator app
+// or one of the recovery codes, and returns the session token.
+func (a *AuthUseCase) CompleteMFALogin(ctx context.Context, mfaToken, code string) (string, error) {
+	userID, err := a.parseMFAChallenge(mfaToken, mfaPurposeVerify)
+	if err != nil {
+		return "", err
+	}
+	user, err := a.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return "", entity.ErrInvalidToken
+	}
+	if !user.MFA.Enabled() {
+		return "", entity.ErrMFANotEnabled
+	}
+
+	if err := a.checkSecondFactor(ctx, user, code); err != nil {
+		return "", err
+	}
+	return a.issueSessionToken(user)
+}
+
+// checkSecondFactor accepts a current TOTP code that has not been used yet,
+// or an unused recovery code, which is used up. Wrong codes count towards
+// the throttle like wrong passwords do.
+func (a *AuthUseCase) checkSecondFactor(ctx context.Context, user *entity.User, code string) error {
+	userID := user.ID.Hex()
+	if err := a.throttle.Check(ctx, "mfa", userID); err != nil {
+		return err
+	}
+
+	ok := false
+	if step, match := matchTOTP(user.MFA.Secret, code, time.Now()); match {
+		used, err := a.userRepo.UseMFAStep(ctx, userID, step)
+		if err != nil {
+			return err
+		}
+		ok = used
+	} else {
+		used, err := a.userRepo.UseRecoveryCode(ctx, userID, hashRecoveryCode(code))
+		if err != nil {
+			return err
+		}
+		ok = used
+	}
+
+	if !ok {
+		if err := a.throttle.Fail(ctx, "mfa", userID); err != nil {
+			return err
+		}
+		return entity.ErrInvalidMFACode
+	}
+	return a.throttle.Succeed(ctx, "mfa", userID)
+}
+
+// BeginMFAEnrollment creates a new secret for the user, which takes effect
+// once it is confirmed with a first code. Starting over replaces a secret
+// that was never confirmed.
+func (a *AuthUseCase) BeginMFAEnrollment(ctx context.Context, userID string) (*entity.MFAEnrollment, error) {
+	user, err := a.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFA.Enabled() {
+		return nil, entity.ErrMFAAlreadyEnabled
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := a.userRepo.UpdateMFA(ctx, userID, &entity.MFASettings{PendingSecret: secret}); err != nil {
+		return nil, err
+	}
+
+	return &entity.MFAEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(a.mfaIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmMFAEnrollment turns the second factor on once the app shows the
+// right code, and returns the recovery codes. They are only stored hashed,
+// so this is the one time they can be shown.
+func (a *AuthUseCase) ConfirmMFAEnrollment(ctx context.Context, userID, code string) ([]string, error) {
+	user, err := a.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFA.Enabled() {
+		return nil, entity.ErrMFAAlreadyEnabled
+	}
+	if user.MFA == nil || user.MFA.PendingSecret == "" {
+		return nil, entity.ErrMFANoEnrollment
+	}
+
+	if err := a.throttle.Check(ctx, "mfa", userID); err != nil {
+		return nil, err
+	}
+	step, ok := matchTOTP(user.MFA.PendingSecret, code, time.Now())
+	if !ok {
+		if err := a.throttle.Fail(ctx, "mfa", userID); err != nil {
+			return nil, err
+		}
+		return nil, entity.ErrInvalidMFACode
+	}
+
+	codes, hashes, err := newRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	err = a.userRepo.UpdateMFA(ctx, userID, &entity.MFASettings{
+		Secret:        user.MFA.PendingSecret,
+		RecoveryCodes: hashes,
+		LastStep:      step,
+		EnabledAt:     &now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, a.throttle.Succeed(ctx, "mfa", userID)
+}
+
+// BeginChallengeEnrollment starts an enrollment for an account that has to
+// enroll before it can finish signing in.
+func (a *AuthUseCase) BeginChallengeEnrollment(ctx context.Context, mfaToken string) (*entity.MFAEnrollment, error) {
+	userID, err := a.parseMFAChallenge(mfaToken, mfaPurposeEnroll)
+	if err != nil {
+		return nil, err
+	}
+	return a.BeginMFAEnrollment(ctx, userID)
+}
+
+// ConfirmChallengeEnrollment confirms such an enrollment and completes the
+// login, returning the session token and the recovery codes.
+func (a *AuthUseCase) ConfirmChallengeEnrollment(ctx context.Context, mfaToken, code string) (string, []string, error) {
+	userID, err := a.parseMFAChallenge(mfaToken, mfaPurposeEnroll)
+	if err != nil {
+		return "", nil, err
+	}
+	codes, err := a.ConfirmMFAEnrollment(ctx, userID, code)
+	if err != nil {
+		return "", nil, err
+	}
+	user, err := a.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return "", nil, err
+	}
+	token, err := a.issueSessionToken(user)
+	return token, codes, err
+}
+
+// DisableMFA turns the second factor off after checking a current code. It
+// cannot be turned off while the user's role requires it.
+func (a *AuthUseCase) DisableMFA(ctx context.Context, userID, code string) error {
+	user, err := a.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.MFA.Enabled() {
+		return entity.ErrMFANotEnabled
+	}
+	policy, err := a.settingsRepo.GetMFAPolicy(ctx)
+	if err != nil {
+		return err
+	}
+	if policy.Requires(user.Role) {
+		return entity.ErrMFARequired
+	}
+
+	if err := a.checkSecondFactor(ctx, user, code); err != nil {
+		return err
+	}
+	return a.userRepo.UpdateMFA(ctx, userID, nil)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a
+// current code, and returns the new ones.
+func (a *AuthUseCase) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	user, err := a.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.MFA.Enabled() {
+		return nil, entity.ErrMFANotEnabled
+	}
+	if err := a.checkSecondFactor(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	// Read again so that the step just used is kept.
+	user, err = a.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	mfa := *user.MFA
+	mfa.RecoveryCodes = hashes
+	if err := a.userRepo.UpdateMFA(ctx, userID, &mfa); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// ResetUserMFA removes the second factor of a user who has lost it. If the
+// role requires one, the user enrolls again at the next login.
+func (a *AuthUseCase) ResetUserMFA(ctx context.Context, userID string) error {
+	return a.userRepo.UpdateMFA(ctx, userID, nil)
+}
+
+func (a *AuthUseCase) GetMFAPolicy(ctx context.Context) (*entity.MFAPolicy, error) {
+	return a.settingsRepo.GetMFAPolicy(ctx)
+}
+
+// SetMFAPolicy sets the roles that have to use a second factor. Accounts
+// with those roles and without one are asked to enroll at their next login.
+func (a *AuthUseCase) SetMFAPolicy(ctx context.Context, roles []entity.Role) error {
+	if roles == nil {
+		roles = []entity.Role{}
+	}
+	for _, r := range roles {
+		if err := a.roles.CheckRole(ctx, r); err != nil {
+			return err
+		}
+	}
+	return a.settingsRepo.SaveMFAPolicy(ctx, &entity.MFAPolicy{RequiredRoles: roles})
+}
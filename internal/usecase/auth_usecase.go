@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"context"
+	"log"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -15,198 +16,369 @@ type UserRepository interface {
 	FindByEmail(ctx context.Context, email string) (*entity.User, error)
 	FindByID(ctx context.Context, id string) (*entity.User, error)
 	UpdatePassword(ctx context.Context, userID string, newHashed string) error
+	ReplacePassword(ctx context.Context, userID string, newHashed string, keep int) error
 	UpdateEmail(ctx context.Context, userID string, newEmail string) error
 	Delete(ctx context.Context, userID string) error
-	ListAll(ctx context.Context) ([]*entity.User, error)
+	ListUsers(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.User], error)
 	UpdateRole(ctx context.Context, userID string, role entity.Role) error
 	FindUsersByIDs(ctx context.Context, studentIDs []primitive.ObjectID) ([]*entity.User, error)
+	UpdateNotificationPreferences(ctx context.Context, userID string, prefs entity.NotificationPreferences) error
+	FindByCalendarToken(ctx context.Context, tokenHash string) (*entity.User, error)
+	UpdateCalendarToken(ctx context.Context, userID string, tokenHash string) error
+	UpdateMFA(ctx context.Context, userID string, mfa *entity.MFASettings) error
+	UseMFAStep(ctx context.Context, userID string, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID string, codeHash string) (bool, error)
+	FindByOIDCSubject(ctx context.Context, subject string) (*entity.User, error)
+	LinkOIDCSubject(ctx context.Context, userID string, subject string) error
+	AllowOIDCLink(ctx context.Context, userID string, allowed bool) error
+	MarkEmailVerified(ctx context.Context, userID string, email string) error
+	SetPendingEmail(ctx context.Context, userID string, email string) error
+	ConfirmPendingEmail(ctx context.Context, userID string, email string) error
+	UpdateProfile(ctx context.Context, userID string, profile entity.Profile) error
+	UpdateAvatar(ctx context.Context, userID string, key string, at time.Time) error
 }
 
+type PasswordResetRepository interface {
+	ReplacePasswordReset(ctx context.Context, reset *entity.PasswordReset) error
+	FindPasswordReset(ctx context.Context, tokenHash string, now time.Time) (*entity.PasswordReset, error)
+	TakePasswordReset(ctx context.Context, tokenHash string, now time.Time) (*entity.PasswordReset, error)
+}
+
+// passwordResetTTL is how long a reset token can be used.
+const passwordResetTTL = time.Hour
+
 type AuthUseCase struct {
 	userRepo UserRepository
-	jwtSecret []byte
+	keys *KeyRing
+	notifier Notifier
+	throttle *AttemptThrottle
+	passwords *PasswordService
+	settingsRepo SettingsRepository
+	mfaIssuer string
+	verificationRepo EmailVerificationRepository
+	verifyRoles []entity.Role
+	apiTokenRepo APITokenRepository
+	roles *RoleUseCase
+	impersonationRepo ImpersonationRepository
+	resetRepo PasswordResetRepository
+	accounts accountCache
 }
 
-func NewAuthUseCase(repo UserRepository, jwtSecret []byte) *AuthUseCase {
+// NewAuthUseCase returns the auth usecase. Accounts with one of verifyRoles
+// cannot sign in until they have verified their email address.
+func NewAuthUseCase(repo UserRepository, keys *KeyRing, notifier Notifier, throttle *AttemptThrottle, passwords *PasswordService, settingsRepo SettingsRepository, mfaIssuer string, verificationRepo EmailVerificationRepository, verifyRoles []entity.Role, apiTokenRepo APITokenRepository, roles *RoleUseCase, impersonationRepo ImpersonationRepository, resetRepo PasswordResetRepository) *AuthUseCase {
 	return &AuthUseCase{
 		userRepo: repo,
-		jwtSecret: jwtSecret,
+		keys: keys,
+		notifier: notifier,
+		throttle: throttle,
+		passwords: passwords,
+		settingsRepo: settingsRepo,
+		mfaIssuer: mfaIssuer,
+		verificationRepo: verificationRepo,
+		verifyRoles: verifyRoles,
+		apiTokenRepo: apiTokenRepo,
+		roles: roles,
+		impersonationRepo: impersonationRepo,
+		resetRepo: resetRepo,
 	}
 }
 
-var resetTokens = make(map[string]string)
-
+// Register creates a student account. Any other role is given afterwards by
+// someone who may grant it, so asking for one here is refused.
 func (a *AuthUseCase) Register(ctx context.Context, email, password string, role entity.Role) error {
 	existingUser, err := a.userRepo.FindByEmail(ctx, email)
 	if err == nil && existingUser != nil {
 		return entity.ErrEmailExists
 	}
 
-	if role != entity.RoleAdmin && role != entity.RoleTeacher && role != entity.RoleStudent {
+	if role == "" {
 		role = entity.RoleStudent
 	}
+	if role != entity.RoleStudent {
+		return entity.ErrPermissionDenied
+	}
+
+	if err := a.passwords.Validate(ctx, password, email); err != nil {
+		return err
+	}
 
-	hashedPwd, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPwd, err := a.passwords.Hash(password)
 	if err != nil {
 		return err
 	}
 
 	user := &entity.User{
+		ID:			primitive.NewObjectID(),
 		Email:		email,
-		Password:	string(hashedPwd),
+		Password:	hashedPwd,
 		Role: 		role,
 		CreatedAt: 	time.Now(),
 	}
 
-	return a.userRepo.Create(ctx, user)
+	if err := a.userRepo.Create(ctx, user); err != nil {
+		return err
+	}
+
+	// The account exists at this point. If the mail cannot be queued, the
+	// user can ask for it to be sent again.
+	if err := a.sendEmailVerification(ctx, user, email); err != nil {
+		log.Printf("auth: failed to send email verification to user %s: %v", user.ID.Hex(), err)
+	}
+	return nil
 }
 
-func (a *AuthUseCase) Login(ctx context.Context, email, password string) (string, error) {
+// Login checks the credentials. The result holds a session token, or a
+// challenge to be completed with CompleteMFALogin when the account has a
+// second factor. Failed attempts are counted per account and per client
+// address, and once there are too many the attempt is refused with a
+// *entity.ThrottledError before the password is even checked.
+func (a *AuthUseCase) Login(ctx context.Context, email, password string) (*entity.LoginResult, error) {
+	if err := a.throttle.Check(ctx, "login", email); err != nil {
+		return nil, err
+	}
+
 	user, err := a.userRepo.FindByEmail(ctx, email)
 	if err != nil {
-		return "", entity.ErrUserNotFound
+		a.passwords.CompareDummy(password)
+		if err := a.throttle.Fail(ctx, "login", email); err != nil {
+			return nil, err
+		}
+		return nil, entity.ErrUserNotFound
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return "", entity.ErrInvalidPassword
+		if err := a.throttle.Fail(ctx, "login", email); err != nil {
+			return nil, err
+		}
+		return nil, entity.ErrInvalidPassword
+	}
+
+	if err := a.throttle.Succeed(ctx, "login", email); err != nil {
+		return nil, err
+	}
+
+	if !user.EmailVerified && a.requiresVerifiedEmail(user.Role) {
+		return nil, entity.ErrEmailNotVerified
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	// The plain password is only at hand here, so hashes made at an older,
+	// lower cost are upgraded as their owners sign in.
+	if a.passwords.NeedsRehash(user.Password) {
+		if hashed, err := a.passwords.Hash(password); err == nil {
+			if err := a.userRepo.UpdatePassword(ctx, user.ID.Hex(), hashed); err != nil {
+				log.Printf("auth: failed to rehash password of user %s: %v", user.ID.Hex(), err)
+			}
+		}
+	}
+
+	return a.completeFirstFactor(ctx, user)
+}
+
+func (a *AuthUseCase) issueSessionToken(user *entity.User) (string, error) {
+	return a.keys.Sign(tokenSession, jwt.MapClaims{
 		"userId": user.ID.Hex(),
 		"email": user.Email,
 		"role": string(user.Role),
 		"exp": time.Now().Add(time.Hour * 72).Unix(),
 	})
-
-	tokenStr, err := token.SignedString(a.jwtSecret)
-
-	return tokenStr, err
 }
 
-func (a *AuthUseCase) ParseToken(tokenStr string) (string, string, error) {
-	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error){
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, entity.ErrInvalidToken
-		}
-		return a.jwtSecret, nil
-	}) 
+// ParseToken returns the user of a session token and the role stored for
+// them. The account has to exist and not be archived. For the token of an
+// impersonation session it also returns the session, which has to be still
+// active, as does the account of the one who started it.
+func (a *AuthUseCase) ParseToken(ctx context.Context, tokenStr string) (string, string, *entity.ImpersonationSession, error) {
+	token, kind, err := a.keys.Parse(tokenStr, jwt.MapClaims{}, tokenSession, tokenImpersonation)
 
 	if err != nil || !token.Valid {
-		return "", "", entity.ErrInvalidToken
+		return "", "", nil, entity.ErrInvalidToken
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return "", "", entity.ErrInvalidToken
+		return "", "", nil, entity.ErrInvalidToken
 	}
 
 	userID, ok := claims["userId"].(string)
 	if !ok {
-		return "", "", entity.ErrInvalidToken
+		return "", "", nil, entity.ErrInvalidToken
 	}
 
-	role, ok := claims["role"].(string)
-	if !ok {
-		role = string(entity.RoleStudent)
+	// The role claim is the one the user had at sign-in. The stored role
+	// counts, so that a changed role applies without signing in again.
+	role, err := a.activeRole(ctx, userID)
+	if err != nil {
+		return "", "", nil, err
 	}
 
-	return userID, role, nil
+	var session *entity.ImpersonationSession
+	if kind == tokenImpersonation {
+		sid, ok := claims["sid"].(string)
+		if !ok {
+			return "", "", nil, entity.ErrInvalidToken
+		}
+		session, err = a.parseImpersonation(ctx, sid, userID)
+		if err != nil {
+			return "", "", nil, err
+		}
+		if _, err := a.activeRole(ctx, session.ActorID.Hex()); err != nil {
+			return "", "", nil, err
+		}
+	}
+
+	return userID, string(role), session, nil
 }
 
-func (a *AuthUseCase) RequestPasswordReset(ctx context.Context, email string) (string, error) {
+// RequestPasswordReset emails a reset token to the account, if there is one.
+// An unknown email is not an error, so callers cannot tell whether an account
+// exists. Every request counts towards the throttle, which keeps the path
+// from being used to flood an inbox.
+func (a *AuthUseCase) RequestPasswordReset(ctx context.Context, email string) error {
+	if err := a.throttle.Check(ctx, "password_reset", email); err != nil {
+		return err
+	}
+	if err := a.throttle.Fail(ctx, "password_reset", email); err != nil {
+		return err
+	}
+
+	// Service accounts have no password to reset.
 	user, err := a.userRepo.FindByEmail(ctx, email)
+	if err != nil || user.ServiceAccount {
+		return nil
+	}
+
+	// From here on failures are only logged: an error would tell the
+	// caller that the address is registered.
+	tokenStr, err := randomURLToken(32)
+	if err == nil {
+		err = a.resetRepo.ReplacePasswordReset(ctx, &entity.PasswordReset{
+			TokenHash: hashToken(tokenStr),
+			UserID:    user.ID,
+			Email:     user.Email,
+			ExpiresAt: time.Now().Add(passwordResetTTL),
+		})
+	}
 	if err != nil {
-		return "", entity.ErrUserNotFound
+		log.Printf("auth: failed to issue reset token for user %s: %v", user.ID.Hex(), err)
+		return nil
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"email": user.Email,
-		"exp": time.Now().Add(time.Hour * 1).Unix(),
+	err = a.notifier.Notify(ctx, user, entity.NotificationPasswordReset, map[string]interface{}{
+		"Email": user.Email,
+		"Token": tokenStr,
 	})
-
-	tokenStr, err := token.SignedString(a.jwtSecret)
 	if err != nil {
-		return "", err
+		log.Printf("auth: failed to queue reset mail for user %s: %v", user.ID.Hex(), err)
 	}
-
-	resetTokens[tokenStr] = user.Email
-
-	return tokenStr, nil
+	return nil
 }
 
-func (a *AuthUseCase) ResetPassword(ctx context.Context, tokenStr, newPassword string) error {
-	email, ok := resetTokens[tokenStr]
-	if !ok {
-		return entity.ErrInvalidToken
+// ResetPassword sets a new password with a reset token and returns the user
+// whose password it reset. The token works once. It is only used up when
+// the password is accepted, so a rejected password can be retried.
+func (a *AuthUseCase) ResetPassword(ctx context.Context, tokenStr, newPassword string) (*entity.User, error) {
+	reset, err := a.resetRepo.FindPasswordReset(ctx, hashToken(tokenStr), time.Now())
+	if err != nil {
+		return nil, err
 	}
 
-	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error){
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, entity.ErrInvalidToken
-		}
-		return a.jwtSecret, nil
-	})
-
-	if err != nil || !token.Valid {
-		return entity.ErrInvalidToken
+	// The token was sent to an address the account may no longer have.
+	user, err := a.userRepo.FindByID(ctx, reset.UserID.Hex())
+	if err != nil || user.Email != reset.Email {
+		return nil, entity.ErrInvalidToken
 	}
 
-	hashedPwd, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	hashedPwd, err := a.newPasswordHash(ctx, user, user.Email, newPassword)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	user, err := a.userRepo.FindByEmail(ctx, email)
-	if err != nil {
-		return entity.ErrUserNotFound
+	if _, err := a.resetRepo.TakePasswordReset(ctx, reset.TokenHash, time.Now()); err != nil {
+		return nil, err
 	}
-
-	err = a.userRepo.UpdatePassword(ctx, user.ID.Hex(), string(hashedPwd))
-	if err == nil {
-		delete(resetTokens, tokenStr)
+	if err := a.userRepo.ReplacePassword(ctx, user.ID.Hex(), hashedPwd, a.passwords.HistorySize()); err != nil {
+		return nil, err
 	}
-
-	return err
+	return user, nil
 }
 
 func (a *AuthUseCase) GetProfile(ctx context.Context, userID string) (*entity.User, error) {
 	return a.userRepo.FindByID(ctx, userID)
 }
 
-func (a *AuthUseCase) UpdateProfile(ctx context.Context, userID, newEmail, newPassword string) error {
+// UpdateProfile changes the password right away. A new email address is
+// only recorded as pending and a verification token is sent to it; it
+// replaces the current address once confirmed with VerifyEmail. The result
+// reports whether such a confirmation is outstanding.
+func (a *AuthUseCase) UpdateProfile(ctx context.Context, userID, newEmail, newPassword string) (bool, error) {
 	user, err := a.userRepo.FindByID(ctx, userID)
 	if err != nil {
-		 return err
+		 return false, err
 	}
 
-	if newEmail != "" && newEmail != user.Email {
-		err := a.userRepo.UpdateEmail(ctx, userID, newEmail)
+	// The new password is checked before anything is changed, so that a
+	// refused password does not leave the email half updated.
+	var hashedPwd string
+	if newPassword != "" {
+		hashedPwd, err = a.newPasswordHash(ctx, user, user.Email, newPassword)
 		if err != nil {
-			return err
+			return false, err
 		}
 	}
 
-	if newPassword != "" {
-		hashedPwd, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
-		if err != nil {
-			return err
+	emailPending := false
+	if newEmail != "" && newEmail != user.Email {
+		if _, err := a.userRepo.FindByEmail(ctx, newEmail); err == nil {
+			return false, entity.ErrEmailExists
+		}
+		if err := a.userRepo.SetPendingEmail(ctx, userID, newEmail); err != nil {
+			return false, err
+		}
+		if err := a.sendEmailVerification(ctx, user, newEmail); err != nil {
+			return false, err
 		}
-		err = a.userRepo.UpdatePassword(ctx, userID, string(hashedPwd))
+		emailPending = true
+	}
+
+	if hashedPwd != "" {
+		err = a.userRepo.ReplacePassword(ctx, userID, hashedPwd, a.passwords.HistorySize())
 		if err != nil {
-			return err
+			return emailPending, err
 		}
 
 	}
 
-	return nil
+	return emailPending, nil
+}
+
+// newPasswordHash checks a password that is to replace the current one of
+// user and hashes it. Besides the policy and the breach list, the password
+// may not be the current one or one of those kept in the history.
+func (a *AuthUseCase) newPasswordHash(ctx context.Context, user *entity.User, email, password string) (string, error) {
+	if err := a.passwords.Validate(ctx, password, email); err != nil {
+		return "", err
+	}
+	if err := a.passwords.CheckReuse(user, password); err != nil {
+		return "", err
+	}
+	return a.passwords.Hash(password)
 }
 
+// DeleteUser moves the account to the trash, which also stops it from
+// signing in and ends its sessions. Memberships and records that refer to
+// the user are kept until it is purged.
 func (a *AuthUseCase) DeleteUser(ctx context.Context, userID string) error {
-	return a.userRepo.Delete(ctx, userID)
+	if err := a.userRepo.Delete(ctx, userID); err != nil {
+		return err
+	}
+	a.accounts.forget(userID)
+	return nil
 }
 
-func (a *AuthUseCase) ListAllUsers(ctx context.Context) ([]*entity.User, error) {
-	return a.userRepo.ListAll(ctx)
+// ListUsers returns a page of users, filterable by role and email.
+func (a *AuthUseCase) ListUsers(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.User], error) {
+	return a.userRepo.ListUsers(ctx, opts)
 }
 
 // func (a *AuthUseCase) UpdateUserRole(ctx context.Context, userID string, role entity.Role) error {
@@ -226,6 +398,35 @@ func (a *AuthUseCase) ListAllUsers(ctx context.Context) ([]*entity.User, error)
 // 	return a.userRepo.UpdateEmail(ctx, userID, user.Email)
 // }
 
+// UpdateUserRole gives a user another role. The signed-in user has to be
+// allowed to grant both the old and the new role.
 func (a *AuthUseCase) UpdateUserRole(ctx context.Context, userID string, role entity.Role) error {
-	return a.userRepo.UpdateRole(ctx, userID, role)
+	if err := a.roles.CheckRole(ctx, role); err != nil {
+		return err
+	}
+	user, err := a.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := a.checkGrant(ctx, user.Role, role); err != nil {
+		return err
+	}
+	if err := a.userRepo.UpdateRole(ctx, userID, role); err != nil {
+		return err
+	}
+	a.accounts.forget(userID)
+	return nil
+}
+
+func (a *AuthUseCase) checkGrant(ctx context.Context, roles ...entity.Role) error {
+	for _, r := range roles {
+		ok, err := a.roles.CanGrant(ctx, r)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return entity.ErrPermissionDenied
+		}
+	}
+	return nil
 }
\ No newline at end of file
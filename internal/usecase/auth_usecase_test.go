@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type memResets struct {
+	resets map[string]*entity.PasswordReset
+}
+
+func (m *memResets) ReplacePasswordReset(ctx context.Context, reset *entity.PasswordReset) error {
+	for hash, r := range m.resets {
+		if r.UserID == reset.UserID {
+			delete(m.resets, hash)
+		}
+	}
+	m.resets[reset.TokenHash] = reset
+	return nil
+}
+
+func (m *memResets) FindPasswordReset(ctx context.Context, tokenHash string, now time.Time) (*entity.PasswordReset, error) {
+	r, ok := m.resets[tokenHash]
+	if !ok || !r.ExpiresAt.After(now) {
+		return nil, entity.ErrInvalidToken
+	}
+	return r, nil
+}
+
+func (m *memResets) TakePasswordReset(ctx context.Context, tokenHash string, now time.Time) (*entity.PasswordReset, error) {
+	r, err := m.FindPasswordReset(ctx, tokenHash, now)
+	if err != nil {
+		return nil, err
+	}
+	delete(m.resets, tokenHash)
+	return r, nil
+}
+
+func TestResetPassword(t *testing.T) {
+	const token = "reset-token"
+	tests := []struct {
+		name      string
+		expiresIn time.Duration
+		email     string
+		passwords []string
+		wantErrs  []error
+	}{
+		{"resets once", time.Hour, "", []string{"a new passphrase", "another passphrase"}, []error{nil, entity.ErrInvalidToken}},
+		{"rejected password can be retried", time.Hour, "", []string{"short", "a new passphrase"}, []error{entity.ErrWeakPassword, nil}},
+		{"expired", -time.Minute, "", []string{"a new passphrase"}, []error{entity.ErrInvalidToken}},
+		{"address changed since", time.Hour, "old@example.com", []string{"a new passphrase"}, []error{entity.ErrInvalidToken}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := testUser(entity.RoleStudent)
+			email := tt.email
+			if email == "" {
+				email = user.Email
+			}
+			resets := &memResets{resets: map[string]*entity.PasswordReset{
+				hashToken(token): {TokenHash: hashToken(token), UserID: user.ID, Email: email, ExpiresAt: time.Now().Add(tt.expiresIn)},
+			}}
+			passwords := NewPasswordService(entity.PasswordPolicy{MinLength: 10}, nil, bcrypt.MinCost, 0)
+			a := NewAuthUseCase(newMemUsers(user), nil, nil, nil, passwords, nil, "", nil, nil, nil, nil, nil, resets)
+
+			for i, password := range tt.passwords {
+				_, err := a.ResetPassword(context.Background(), token, password)
+				if !errors.Is(err, tt.wantErrs[i]) {
+					t.Fatalf("ResetPassword(%q) error = %v, want %v", password, err, tt.wantErrs[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CalendarUseCase struct {
+	userRepo       UserRepository
+	courseRepo     CourseRepository
+	classRepo      ClassRepository
+	assignmentRepo AssignmentRepository
+	assessmentRepo AssessmentRepository
+	progressRepo   ProgressRepository
+	lessonRepo     LessonRepository
+	roles          *RoleUseCase
+}
+
+func NewCalendarUseCase(
+	userRepo UserRepository,
+	courseRepo CourseRepository,
+	classRepo ClassRepository,
+	assignmentRepo AssignmentRepository,
+	assessmentRepo AssessmentRepository,
+	progressRepo ProgressRepository,
+	lessonRepo LessonRepository,
+	roles *RoleUseCase,
+) *CalendarUseCase {
+	return &CalendarUseCase{
+		userRepo:       userRepo,
+		courseRepo:     courseRepo,
+		classRepo:      classRepo,
+		assignmentRepo: assignmentRepo,
+		assessmentRepo: assessmentRepo,
+		progressRepo:   progressRepo,
+		lessonRepo:     lessonRepo,
+		roles:          roles,
+	}
+}
+
+// HasFeed reports whether the user has a calendar feed. Only a hash of its
+// token is stored, so the feed URL is shown only by RegenerateToken.
+func (c *CalendarUseCase) HasFeed(ctx context.Context, userID string) (bool, error) {
+	user, err := c.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return user.CalendarTokenHash != "", nil
+}
+
+// RegenerateToken creates the calendar token, or replaces it and so revokes
+// any previously shared feed URL. Only its hash is stored.
+func (c *CalendarUseCase) RegenerateToken(ctx context.Context, userID string) (string, error) {
+	token, err := randomToken(20)
+	if err != nil {
+		return "", err
+	}
+
+	if err := c.userRepo.UpdateCalendarToken(ctx, userID, hashToken(token)); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Events resolves a feed token to its owner and lists the deadlines and
+// assessments of the courses the owner studies or teaches. Students get
+// them through the release rules: hidden items are left out and locked
+// ones come without their description.
+func (c *CalendarUseCase) Events(ctx context.Context, token string) ([]*entity.CalendarEvent, error) {
+	if token == "" {
+		return nil, entity.ErrInvalidToken
+	}
+
+	user, err := c.userRepo.FindByCalendarToken(ctx, hashToken(token))
+	if err != nil {
+		if err == entity.ErrUserNotFound {
+			return nil, entity.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	courses, learner, err := c.coursesFor(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*entity.CalendarEvent
+	for _, course := range courses {
+		// Teachers see every item, so their gate lets everything through.
+		gate := func(entity.Gated) bool { return false }
+		if learner {
+			eval, err := newReleaseEvaluator(ctx, c.progressRepo, c.lessonRepo, c.assessmentRepo, user.ID, course.ID)
+			if err != nil {
+				return nil, err
+			}
+			gate = eval.gate
+		}
+
+		assignments, err := c.assignmentRepo.ListAssignmentsByCourse(ctx, course.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range assignments {
+			if gate(a) {
+				continue
+			}
+			events = append(events, &entity.CalendarEvent{
+				UID:         "assignment-" + a.ID.Hex() + "@e-learning",
+				Summary:     course.Name + ": " + a.Title,
+				Description: a.Description,
+				Start:       a.DueDate,
+				Updated:     latest(a.CreatedAt, a.UpdatedAt),
+			})
+		}
+
+		assessments, err := c.assessmentRepo.ListAssessmentsByCourse(ctx, course.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range assessments {
+			if gate(a) {
+				continue
+			}
+			events = append(events, &entity.CalendarEvent{
+				UID:         "assessment-" + a.ID.Hex() + "@e-learning",
+				Summary:     course.Name + ": " + a.Title,
+				Description: a.Description,
+				Start:       a.Date,
+				Updated:     latest(a.CreatedAt, a.UpdatedAt),
+			})
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+
+	return events, nil
+}
+
+// coursesFor returns the courses the user teaches or else studies, and
+// whether the user studies them.
+func (c *CalendarUseCase) coursesFor(ctx context.Context, user *entity.User) ([]*entity.Course, bool, error) {
+	teaches, err := c.roles.HasPermission(ctx, user.Role, entity.PermTeachingView)
+	if err != nil {
+		return nil, false, err
+	}
+	learns, err := c.roles.HasPermission(ctx, user.Role, entity.PermLearn)
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch {
+	case teaches:
+		courses, err := c.courseRepo.ListCoursesByTeacher(ctx, user.ID)
+		return courses, false, err
+	case learns:
+		classes, err := c.classRepo.ListClassesByStudent(ctx, user.ID)
+		if err != nil {
+			return nil, false, err
+		}
+
+		seen := make(map[primitive.ObjectID]struct{})
+		var courses []*entity.Course
+		for _, cl := range classes {
+			if _, ok := seen[cl.CourseID]; ok || cl.CourseID.IsZero() {
+				continue
+			}
+			seen[cl.CourseID] = struct{}{}
+
+			course, err := c.courseRepo.GetCourse(ctx, cl.CourseID.Hex())
+			if err == entity.ErrCourseNotFound {
+				continue
+			}
+			if err != nil {
+				return nil, false, err
+			}
+			courses = append(courses, course)
+		}
+		return courses, true, nil
+	default:
+		return []*entity.Course{}, false, nil
+	}
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,134 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type feedUsers struct {
+	*memUsers
+	tokens map[string]*entity.User // by hash
+}
+
+func (m *feedUsers) FindByCalendarToken(ctx context.Context, tokenHash string) (*entity.User, error) {
+	if u, ok := m.tokens[tokenHash]; ok {
+		return u, nil
+	}
+	return nil, entity.ErrUserNotFound
+}
+
+type feedCourse struct {
+	CourseRepository
+	ClassRepository
+	AssignmentRepository
+	AssessmentRepository
+	ProgressRepository
+	course      *entity.Course
+	teacher     primitive.ObjectID
+	assignments []*entity.Assignment
+	assessments []*entity.Assessment
+}
+
+func (f *feedCourse) GetCourse(ctx context.Context, id string) (*entity.Course, error) {
+	return f.course, nil
+}
+
+func (f *feedCourse) ListCoursesByTeacher(ctx context.Context, teacherID primitive.ObjectID) ([]*entity.Course, error) {
+	return []*entity.Course{f.course}, nil
+}
+
+func (f *feedCourse) ListClassesByStudent(ctx context.Context, studentID primitive.ObjectID) ([]*entity.Class, error) {
+	return []*entity.Class{{CourseID: f.course.ID, StudentIDs: []primitive.ObjectID{studentID}}}, nil
+}
+
+// The lists hand out copies, as the repositories decode fresh documents.
+func (f *feedCourse) ListAssignmentsByCourse(ctx context.Context, courseID primitive.ObjectID) ([]*entity.Assignment, error) {
+	out := make([]*entity.Assignment, len(f.assignments))
+	for i, a := range f.assignments {
+		copy := *a
+		out[i] = &copy
+	}
+	return out, nil
+}
+
+func (f *feedCourse) ListAssessmentsByCourse(ctx context.Context, courseID primitive.ObjectID) ([]*entity.Assessment, error) {
+	out := make([]*entity.Assessment, len(f.assessments))
+	for i, a := range f.assessments {
+		copy := *a
+		out[i] = &copy
+	}
+	return out, nil
+}
+
+func (f *feedCourse) GetAssessment(ctx context.Context, id string) (*entity.Assessment, error) {
+	return nil, entity.ErrAssessmentNotFound
+}
+
+func (f *feedCourse) ListProgressByStudentAndCourse(ctx context.Context, studentID, courseID primitive.ObjectID) ([]*entity.Progress, error) {
+	return nil, nil
+}
+
+func TestCalendarFeedFollowsReleaseRules(t *testing.T) {
+	future := time.Now().Add(24 * time.Hour)
+	until := func(hide bool) *entity.ReleaseRule {
+		return &entity.ReleaseRule{HideUntilMet: hide, Conditions: []entity.ReleaseCondition{{Type: entity.ConditionAfterDate, Date: &future}}}
+	}
+	f := &feedCourse{
+		course: &entity.Course{ID: primitive.NewObjectID(), Name: "Biology"},
+		assignments: []*entity.Assignment{
+			{ID: primitive.NewObjectID(), Title: "Open", Description: "open details"},
+			{ID: primitive.NewObjectID(), Title: "Locked", Description: "locked details", Release: until(false)},
+			{ID: primitive.NewObjectID(), Title: "Hidden", Description: "hidden details", Release: until(true)},
+		},
+		assessments: []*entity.Assessment{
+			{ID: primitive.NewObjectID(), Title: "Hidden quiz", Description: "quiz details", Release: until(true)},
+		},
+	}
+	student := testUser(entity.RoleStudent)
+	teacher := testUser(entity.RoleTeacher)
+	users := &feedUsers{memUsers: newMemUsers(student, teacher), tokens: map[string]*entity.User{
+		hashToken("student-token"): student,
+		hashToken("teacher-token"): teacher,
+	}}
+	calendar := NewCalendarUseCase(users, f, f, f, f, f, memLessons{}, NewRoleUseCase(seededRoles(), &memRoles{}))
+
+	tests := []struct {
+		token string
+		want  map[string]string // summary to description
+	}{
+		{"student-token", map[string]string{
+			"Biology: Open":   "open details",
+			"Biology: Locked": "",
+		}},
+		{"teacher-token", map[string]string{
+			"Biology: Open":        "open details",
+			"Biology: Locked":      "locked details",
+			"Biology: Hidden":      "hidden details",
+			"Biology: Hidden quiz": "quiz details",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			events, err := calendar.Events(context.Background(), tt.token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]string, len(events))
+			for _, e := range events {
+				got[e.Summary] = e.Description
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+			for summary, description := range tt.want {
+				if d, ok := got[summary]; !ok || d != description {
+					t.Errorf("event %q = %q (listed: %v), want %q", summary, d, ok, description)
+				}
+			}
+		})
+	}
+}
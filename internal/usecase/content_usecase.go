@@ -0,0 +1,489 @@
+package usecase
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ModuleRepository interface {
+	CreateModule(ctx context.Context, m *entity.Module) error
+	GetModule(ctx context.Context, id string) (*entity.Module, error)
+	UpdateModule(ctx context.Context, m *entity.Module) error
+	DeleteModule(ctx context.Context, id string) error
+	ListModulesByCourse(ctx context.Context, courseID primitive.ObjectID) ([]*entity.Module, error)
+}
+
+type LessonRepository interface {
+	CreateLesson(ctx context.Context, l *entity.Lesson) error
+	GetLesson(ctx context.Context, id string) (*entity.Lesson, error)
+	UpdateLesson(ctx context.Context, l *entity.Lesson) error
+	DeleteLesson(ctx context.Context, id string) error
+	ListLessonsByModule(ctx context.Context, moduleID primitive.ObjectID) ([]*entity.Lesson, error)
+	ListLessonsByCourse(ctx context.Context, courseID primitive.ObjectID) ([]*entity.Lesson, error)
+	AddAttachment(ctx context.Context, lessonID primitive.ObjectID, a entity.Attachment) error
+	RemoveAttachment(ctx context.Context, lessonID, attachmentID primitive.ObjectID) error
+	UpdateLessonRelease(ctx context.Context, lessonID primitive.ObjectID, rule *entity.ReleaseRule) error
+}
+
+// BlobStore stores uploaded files such as lesson attachments.
+type BlobStore interface {
+	Put(ctx context.Context, key, contentType string, r io.Reader) (int64, error)
+	Get(ctx context.Context, key string) (io.ReadCloser, string, error)
+	Delete(ctx context.Context, key string) error
+}
+
+type ContentUseCase struct {
+	moduleRepo     ModuleRepository
+	lessonRepo     LessonRepository
+	courseRepo     CourseRepository
+	classRepo      ClassRepository
+	progressRepo   ProgressRepository
+	assessmentRepo AssessmentRepository
+	blobs          BlobStore
+}
+
+func NewContentUseCase(
+	moduleRepo ModuleRepository,
+	lessonRepo LessonRepository,
+	courseRepo CourseRepository,
+	classRepo ClassRepository,
+	progressRepo ProgressRepository,
+	assessmentRepo AssessmentRepository,
+	blobs BlobStore,
+) *ContentUseCase {
+	return &ContentUseCase{
+		moduleRepo:     moduleRepo,
+		lessonRepo:     lessonRepo,
+		courseRepo:     courseRepo,
+		classRepo:      classRepo,
+		progressRepo:   progressRepo,
+		assessmentRepo: assessmentRepo,
+		blobs:          blobs,
+	}
+}
+
+// --- Teacher ---
+func (c *ContentUseCase) ListModulesForTeacher(ctx context.Context, teacherID, courseID string) ([]*entity.ModuleContent, error) {
+	course, err := c.teacherCourse(ctx, teacherID, courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.courseContent(ctx, course.ID, func(*entity.Lesson) bool { return true })
+}
+
+func (c *ContentUseCase) CreateModule(ctx context.Context, teacherID string, m *entity.Module) error {
+	if _, err := c.teacherCourse(ctx, teacherID, m.CourseID.Hex()); err != nil {
+		return err
+	}
+	if strings.TrimSpace(m.Title) == "" {
+		return entity.ErrInvalidLesson
+	}
+
+	m.CreatedAt = time.Now().UTC()
+	return c.moduleRepo.CreateModule(ctx, m)
+}
+
+func (c *ContentUseCase) UpdateModule(ctx context.Context, teacherID string, m *entity.Module) error {
+	existing, err := c.teacherModule(ctx, teacherID, m.ID.Hex())
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(m.Title) == "" {
+		return entity.ErrInvalidLesson
+	}
+
+	existing.Title = m.Title
+	existing.Position = m.Position
+	existing.UpdatedAt = time.Now().UTC()
+	return c.moduleRepo.UpdateModule(ctx, existing)
+}
+
+// DeleteModule removes the module together with its lessons and their attachments.
+func (c *ContentUseCase) DeleteModule(ctx context.Context, teacherID, moduleID string) error {
+	module, err := c.teacherModule(ctx, teacherID, moduleID)
+	if err != nil {
+		return err
+	}
+
+	lessons, err := c.lessonRepo.ListLessonsByModule(ctx, module.ID)
+	if err != nil {
+		return err
+	}
+	for _, l := range lessons {
+		if err := c.deleteLesson(ctx, l); err != nil {
+			return err
+		}
+	}
+
+	return c.moduleRepo.DeleteModule(ctx, moduleID)
+}
+
+func (c *ContentUseCase) CreateLesson(ctx context.Context, teacherID string, l *entity.Lesson) error {
+	module, err := c.teacherModule(ctx, teacherID, l.ModuleID.Hex())
+	if err != nil {
+		return err
+	}
+	if err := validateLesson(l); err != nil {
+		return err
+	}
+
+	l.CourseID = module.CourseID
+	l.Attachments = []entity.Attachment{}
+	l.CreatedAt = time.Now().UTC()
+	return c.lessonRepo.CreateLesson(ctx, l)
+}
+
+func (c *ContentUseCase) GetLessonForTeacher(ctx context.Context, teacherID, lessonID string) (*entity.Lesson, error) {
+	return c.teacherLesson(ctx, teacherID, lessonID)
+}
+
+func (c *ContentUseCase) UpdateLesson(ctx context.Context, teacherID string, l *entity.Lesson) error {
+	existing, err := c.teacherLesson(ctx, teacherID, l.ID.Hex())
+	if err != nil {
+		return err
+	}
+	if err := validateLesson(l); err != nil {
+		return err
+	}
+
+	// Lessons may move between modules, but only within the same course.
+	if !l.ModuleID.IsZero() && l.ModuleID != existing.ModuleID {
+		module, err := c.moduleRepo.GetModule(ctx, l.ModuleID.Hex())
+		if err != nil {
+			return err
+		}
+		if module.CourseID != existing.CourseID {
+			return entity.ErrInvalidLesson
+		}
+		existing.ModuleID = module.ID
+	}
+
+	existing.Title = l.Title
+	existing.Body = l.Body
+	existing.Links = l.Links
+	existing.Position = l.Position
+	existing.Status = l.Status
+	existing.ReleaseAt = l.ReleaseAt
+	existing.UpdatedAt = time.Now().UTC()
+	return c.lessonRepo.UpdateLesson(ctx, existing)
+}
+
+func (c *ContentUseCase) DeleteLesson(ctx context.Context, teacherID, lessonID string) error {
+	lesson, err := c.teacherLesson(ctx, teacherID, lessonID)
+	if err != nil {
+		return err
+	}
+
+	return c.deleteLesson(ctx, lesson)
+}
+
+func (c *ContentUseCase) AddAttachment(ctx context.Context, teacherID, lessonID, name, contentType string, r io.Reader) (*entity.Attachment, error) {
+	lesson, err := c.teacherLesson(ctx, teacherID, lessonID)
+	if err != nil {
+		return nil, err
+	}
+
+	att := entity.Attachment{
+		ID:          primitive.NewObjectID(),
+		Name:        name,
+		ContentType: contentType,
+		UploadedAt:  time.Now().UTC(),
+	}
+	att.Key = "lessons/" + lesson.ID.Hex() + "/" + att.ID.Hex()
+
+	size, err := c.blobs.Put(ctx, att.Key, contentType, r)
+	if err != nil {
+		return nil, err
+	}
+	att.Size = size
+
+	if err := c.lessonRepo.AddAttachment(ctx, lesson.ID, att); err != nil {
+		c.blobs.Delete(ctx, att.Key)
+		return nil, err
+	}
+
+	return &att, nil
+}
+
+func (c *ContentUseCase) RemoveAttachment(ctx context.Context, teacherID, lessonID, attachmentID string) error {
+	lesson, err := c.teacherLesson(ctx, teacherID, lessonID)
+	if err != nil {
+		return err
+	}
+
+	att, err := findAttachment(lesson, attachmentID)
+	if err != nil {
+		return err
+	}
+
+	if err := c.lessonRepo.RemoveAttachment(ctx, lesson.ID, att.ID); err != nil {
+		return err
+	}
+
+	return c.blobs.Delete(ctx, att.Key)
+}
+
+func (c *ContentUseCase) OpenAttachmentForTeacher(ctx context.Context, teacherID, lessonID, attachmentID string) (*entity.Attachment, io.ReadCloser, error) {
+	lesson, err := c.teacherLesson(ctx, teacherID, lessonID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return c.openAttachment(ctx, lesson, attachmentID)
+}
+
+// --- Student ---
+func (c *ContentUseCase) ListModulesForStudent(ctx context.Context, studentID, courseID string) ([]*entity.ModuleContent, error) {
+	course, err := c.courseRepo.GetCourse(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.requireEnrollment(ctx, studentID, course.ID); err != nil {
+		return nil, err
+	}
+
+	eval, err := c.releaseEvaluator(ctx, studentID, course.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Locked lessons are listed with their lock reasons but without content;
+	// lessons whose rule asks to hide them are left out until unlocked.
+	now := time.Now()
+	return c.courseContent(ctx, course.ID, func(l *entity.Lesson) bool {
+		if !l.IsReleased(now) {
+			return false
+		}
+		return !eval.gate(l)
+	})
+}
+
+// GetLessonForStudent returns a released lesson and records that the student viewed it.
+func (c *ContentUseCase) GetLessonForStudent(ctx context.Context, studentID, lessonID string) (*entity.Lesson, error) {
+	lesson, err := c.studentLesson(ctx, studentID, lessonID)
+	if err != nil {
+		return nil, err
+	}
+
+	// studentLesson already checked enrollment, so the ID is well formed.
+	sid, _ := primitive.ObjectIDFromHex(studentID)
+	err = c.progressRepo.SaveProgressIfAbsent(ctx, &entity.Progress{
+		StudentID: sid,
+		CourseID:  lesson.CourseID,
+		ItemType:  entity.ProgressLesson,
+		ItemID:    lesson.ID,
+		Status:    entity.ProgressViewed,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return lesson, nil
+}
+
+func (c *ContentUseCase) OpenAttachmentForStudent(ctx context.Context, studentID, lessonID, attachmentID string) (*entity.Attachment, io.ReadCloser, error) {
+	lesson, err := c.studentLesson(ctx, studentID, lessonID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return c.openAttachment(ctx, lesson, attachmentID)
+}
+
+// --- helpers ---
+func (c *ContentUseCase) courseContent(ctx context.Context, courseID primitive.ObjectID, include func(*entity.Lesson) bool) ([]*entity.ModuleContent, error) {
+	modules, err := c.moduleRepo.ListModulesByCourse(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	lessons, err := c.lessonRepo.ListLessonsByCourse(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	byModule := make(map[primitive.ObjectID][]*entity.Lesson)
+	for _, l := range lessons {
+		if include(l) {
+			byModule[l.ModuleID] = append(byModule[l.ModuleID], l)
+		}
+	}
+
+	content := make([]*entity.ModuleContent, 0, len(modules))
+	for _, m := range modules {
+		ls := byModule[m.ID]
+		if ls == nil {
+			ls = []*entity.Lesson{}
+		}
+		content = append(content, &entity.ModuleContent{Module: m, Lessons: ls})
+	}
+
+	return content, nil
+}
+
+func (c *ContentUseCase) teacherCourse(ctx context.Context, teacherID, courseID string) (*entity.Course, error) {
+	course, err := c.courseRepo.GetCourse(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	if !isAssignedTeacher(course, teacherID) {
+		return nil, entity.ErrUnauthorized
+	}
+	return course, nil
+}
+
+func (c *ContentUseCase) teacherModule(ctx context.Context, teacherID, moduleID string) (*entity.Module, error) {
+	module, err := c.moduleRepo.GetModule(ctx, moduleID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.teacherCourse(ctx, teacherID, module.CourseID.Hex()); err != nil {
+		return nil, err
+	}
+	return module, nil
+}
+
+func (c *ContentUseCase) teacherLesson(ctx context.Context, teacherID, lessonID string) (*entity.Lesson, error) {
+	lesson, err := c.lessonRepo.GetLesson(ctx, lessonID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.teacherCourse(ctx, teacherID, lesson.CourseID.Hex()); err != nil {
+		return nil, err
+	}
+	return lesson, nil
+}
+
+func (c *ContentUseCase) studentLesson(ctx context.Context, studentID, lessonID string) (*entity.Lesson, error) {
+	lesson, err := c.lessonRepo.GetLesson(ctx, lessonID)
+	if err != nil {
+		return nil, err
+	}
+	if !lesson.IsReleased(time.Now()) {
+		return nil, entity.ErrLessonNotFound
+	}
+	if err := c.requireEnrollment(ctx, studentID, lesson.CourseID); err != nil {
+		return nil, err
+	}
+
+	eval, err := c.releaseEvaluator(ctx, studentID, lesson.CourseID)
+	if err != nil {
+		return nil, err
+	}
+	if availability, _ := eval.check(lesson.Release); availability.Locked {
+		return nil, entity.ErrItemLocked
+	}
+	return lesson, nil
+}
+
+func (c *ContentUseCase) releaseEvaluator(ctx context.Context, studentID string, courseID primitive.ObjectID) (*releaseEvaluator, error) {
+	sid, err := primitive.ObjectIDFromHex(studentID)
+	if err != nil {
+		return nil, entity.ErrUserNotFound
+	}
+	return newReleaseEvaluator(ctx, c.progressRepo, c.lessonRepo, c.assessmentRepo, sid, courseID)
+}
+
+func (c *ContentUseCase) requireEnrollment(ctx context.Context, studentID string, courseID primitive.ObjectID) error {
+	enrolled, err := isEnrolled(ctx, c.classRepo, studentID, courseID)
+	if err != nil {
+		return err
+	}
+	if !enrolled {
+		return entity.ErrUnauthorized
+	}
+	return nil
+}
+
+func (c *ContentUseCase) deleteLesson(ctx context.Context, l *entity.Lesson) error {
+	for _, att := range l.Attachments {
+		if err := c.blobs.Delete(ctx, att.Key); err != nil {
+			return err
+		}
+	}
+	return c.lessonRepo.DeleteLesson(ctx, l.ID.Hex())
+}
+
+func (c *ContentUseCase) openAttachment(ctx context.Context, lesson *entity.Lesson, attachmentID string) (*entity.Attachment, io.ReadCloser, error) {
+	att, err := findAttachment(lesson, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rc, _, err := c.blobs.Get(ctx, att.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return att, rc, nil
+}
+
+func findAttachment(lesson *entity.Lesson, attachmentID string) (*entity.Attachment, error) {
+	for i := range lesson.Attachments {
+		if lesson.Attachments[i].ID.Hex() == attachmentID {
+			return &lesson.Attachments[i], nil
+		}
+	}
+	return nil, entity.ErrAttachmentNotFound
+}
+
+func validateLesson(l *entity.Lesson) error {
+	if strings.TrimSpace(l.Title) == "" {
+		return entity.ErrInvalidLesson
+	}
+
+	switch l.Status {
+	case "":
+		l.Status = entity.LessonDraft
+	case entity.LessonDraft, entity.LessonPublished:
+	default:
+		return entity.ErrInvalidLesson
+	}
+
+	if l.Links == nil {
+		l.Links = []entity.LessonLink{}
+	}
+	for _, link := range l.Links {
+		u, err := url.Parse(link.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return entity.ErrInvalidLesson
+		}
+	}
+
+	return nil
+}
+
+func isAssignedTeacher(course *entity.Course, teacherID string) bool {
+	for _, id := range course.AssignedTeacher {
+		if id.Hex() == teacherID {
+			return true
+		}
+	}
+	return false
+}
+
+func isEnrolled(ctx context.Context, classRepo ClassRepository, studentID string, courseID primitive.ObjectID) (bool, error) {
+	oid, err := primitive.ObjectIDFromHex(studentID)
+	if err != nil {
+		return false, entity.ErrUserNotFound
+	}
+
+	classes, err := classRepo.ListClassesByStudent(ctx, oid)
+	if err != nil {
+		return false, err
+	}
+	for _, cl := range classes {
+		if cl.CourseID == courseID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,196 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The fakes keep their records in memory. They implement the methods the
+// tests use; the embedded interfaces panic on any other.
+
+type memUsers struct {
+	UserRepository
+	users map[string]*entity.User
+}
+
+func newMemUsers(users ...*entity.User) *memUsers {
+	m := &memUsers{users: map[string]*entity.User{}}
+	for _, u := range users {
+		m.users[u.ID.Hex()] = u
+	}
+	return m
+}
+
+func (m *memUsers) FindByID(ctx context.Context, id string) (*entity.User, error) {
+	u, ok := m.users[id]
+	if !ok {
+		return nil, entity.ErrUserNotFound
+	}
+	copy := *u
+	return &copy, nil
+}
+
+func (m *memUsers) FindByEmail(ctx context.Context, email string) (*entity.User, error) {
+	for _, u := range m.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, entity.ErrUserNotFound
+}
+
+func (m *memUsers) UpdateRole(ctx context.Context, id string, role entity.Role) error {
+	u, ok := m.users[id]
+	if !ok {
+		return entity.ErrUserNotFound
+	}
+	u.Role = role
+	return nil
+}
+
+func (m *memUsers) ReplacePassword(ctx context.Context, id string, hashed string, keep int) error {
+	u, ok := m.users[id]
+	if !ok {
+		return entity.ErrUserNotFound
+	}
+	u.Password = hashed
+	return nil
+}
+
+func (m *memUsers) Delete(ctx context.Context, id string) error {
+	if _, ok := m.users[id]; !ok {
+		return entity.ErrUserNotFound
+	}
+	delete(m.users, id)
+	return nil
+}
+
+type memRoles struct {
+	roles []*entity.RoleDefinition
+}
+
+func (m *memRoles) ListRoles(ctx context.Context) ([]*entity.RoleDefinition, error) {
+	return m.roles, nil
+}
+
+func (m *memRoles) GetRole(ctx context.Context, name entity.Role) (*entity.RoleDefinition, error) {
+	for _, r := range m.roles {
+		if r.Name == name {
+			return r, nil
+		}
+	}
+	return nil, entity.ErrRoleNotFound
+}
+
+func (m *memRoles) CreateRole(ctx context.Context, role *entity.RoleDefinition) error {
+	m.roles = append(m.roles, role)
+	return nil
+}
+
+func (m *memRoles) UpdateRole(ctx context.Context, name entity.Role, description string, permissions []entity.Permission, at time.Time) error {
+	r, err := m.GetRole(ctx, name)
+	if err != nil {
+		return err
+	}
+	r.Description, r.Permissions, r.UpdatedAt = description, permissions, at
+	return nil
+}
+
+func (m *memRoles) DeleteRole(ctx context.Context, name entity.Role) error {
+	return nil
+}
+
+func (m *memRoles) CountByRole(ctx context.Context, role entity.Role) (int64, error) {
+	return 0, nil
+}
+
+// seededRoles are the built-in roles as the migrations leave them.
+func seededRoles() *memRoles {
+	return &memRoles{roles: []*entity.RoleDefinition{
+		{Name: entity.RoleAdmin, Builtin: true, Permissions: []entity.Permission{
+			entity.PermUsersRead, entity.PermUsersManage, entity.PermRolesManage, entity.PermSettingsManage, entity.PermAuditRead,
+			entity.PermCoursesRead, entity.PermCoursesWrite, entity.PermCoursesDelete, entity.PermTermsWrite, entity.PermTermsDelete,
+			entity.PermClassesRead, entity.PermClassesWrite, entity.PermEnrollmentWrite, entity.PermClassesDelete,
+			entity.PermAnnouncementsWrite, entity.PermAnnouncementsDelete, entity.PermTrashManage, entity.PermImpersonate,
+		}},
+		{Name: entity.RoleTeacher, Builtin: true, Permissions: []entity.Permission{
+			entity.PermTeachingView, entity.PermContentWrite, entity.PermContentDelete, entity.PermAssignmentsWrite,
+			entity.PermGradesRead, entity.PermGradesWrite, entity.PermAttendanceRead, entity.PermAttendanceWrite, entity.PermAttendanceDelete,
+			entity.PermMessagesWrite,
+		}},
+		{Name: entity.RoleStudent, Builtin: true, Permissions: []entity.Permission{entity.PermLearn}},
+		{Name: entity.RoleGuardian, Builtin: true, Permissions: []entity.Permission{entity.PermLinkedStudents}},
+		{Name: "registrar", Permissions: []entity.Permission{
+			entity.PermUsersRead, entity.PermCoursesRead, entity.PermClassesRead, entity.PermEnrollmentWrite,
+		}},
+	}}
+}
+
+type memSigningKeys struct {
+	mu   sync.Mutex
+	keys []*entity.SigningKey
+}
+
+func (m *memSigningKeys) ListSigningKeys(ctx context.Context, now time.Time) ([]*entity.SigningKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*entity.SigningKey(nil), m.keys...), nil
+}
+
+func (m *memSigningKeys) InsertSigningKey(ctx context.Context, key *entity.SigningKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys = append(m.keys, key)
+	return nil
+}
+
+func (m *memSigningKeys) ExpireSigningKeys(ctx context.Context, activeBefore, expiresAt time.Time) error {
+	return nil
+}
+
+func (m *memSigningKeys) DeleteSigningKey(ctx context.Context, kid string) error {
+	return nil
+}
+
+type memImpersonations struct {
+	sessions map[primitive.ObjectID]*entity.ImpersonationSession
+}
+
+func (m *memImpersonations) CreateImpersonation(ctx context.Context, s *entity.ImpersonationSession) error {
+	m.sessions[s.ID] = s
+	return nil
+}
+
+func (m *memImpersonations) GetImpersonation(ctx context.Context, id string) (*entity.ImpersonationSession, error) {
+	oid, _ := primitive.ObjectIDFromHex(id)
+	s, ok := m.sessions[oid]
+	if !ok {
+		return nil, entity.ErrImpersonationNotFound
+	}
+	copy := *s
+	return &copy, nil
+}
+
+func (m *memImpersonations) EndImpersonation(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	m.sessions[id].EndedAt = &at
+	return nil
+}
+
+func (m *memImpersonations) RecordImpersonatedWrite(ctx context.Context, id primitive.ObjectID, w entity.ImpersonatedWrite) error {
+	m.sessions[id].Writes = append(m.sessions[id].Writes, w)
+	return nil
+}
+
+func (m *memImpersonations) ListImpersonations(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.ImpersonationSession], error) {
+	return nil, nil
+}
+
+// actorContext is the context of a request signed in with role.
+func actorContext(userID primitive.ObjectID, role entity.Role) context.Context {
+	ctx := context.WithValue(context.Background(), "userID", userID.Hex())
+	return context.WithValue(ctx, "userRole", role)
+}
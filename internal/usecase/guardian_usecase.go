@@ -0,0 +1,321 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type GuardianLinkRepository interface {
+	CreateGuardianLink(ctx context.Context, link *entity.GuardianLink) error
+	GetGuardianLink(ctx context.Context, id string) (*entity.GuardianLink, error)
+	FindGuardianInvitation(ctx context.Context, tokenHash string, now time.Time) (*entity.GuardianLink, error)
+	ActivateGuardianLink(ctx context.Context, id, guardianID primitive.ObjectID, at time.Time) error
+	ListGuardianLinksByStudent(ctx context.Context, studentID primitive.ObjectID) ([]*entity.GuardianLink, error)
+	ListGuardianLinksByGuardian(ctx context.Context, guardianID primitive.ObjectID) ([]*entity.GuardianLink, error)
+	CountPendingGuardianInvitations(ctx context.Context, studentID primitive.ObjectID, now time.Time) (int64, error)
+	IsGuardianOf(ctx context.Context, guardianID, studentID primitive.ObjectID) (bool, error)
+	DeleteGuardianLink(ctx context.Context, id primitive.ObjectID) error
+}
+
+const (
+	// guardianInvitationTTL is how long an invitation can be accepted.
+	guardianInvitationTTL = 7 * 24 * time.Hour
+	// maxPendingInvitations caps the invitations a student has open at a
+	// time, so that the invitation mail cannot be used to flood an inbox.
+	maxPendingInvitations = 5
+)
+
+// GuardianUseCase links guardians to students and gives guardians a
+// read-only view of their linked students. The view reuses the student's
+// own queries, so a guardian sees what the student sees.
+type GuardianUseCase struct {
+	linkRepo GuardianLinkRepository
+	userRepo UserRepository
+	roles    *RoleUseCase
+	notifier Notifier
+	students *StudentUseCase
+	progress *ProgressUseCase
+}
+
+func NewGuardianUseCase(
+	linkRepo GuardianLinkRepository,
+	userRepo UserRepository,
+	roles *RoleUseCase,
+	notifier Notifier,
+	students *StudentUseCase,
+	progress *ProgressUseCase,
+) *GuardianUseCase {
+	return &GuardianUseCase{
+		linkRepo: linkRepo,
+		userRepo: userRepo,
+		roles:    roles,
+		notifier: notifier,
+		students: students,
+		progress: progress,
+	}
+}
+
+// LinkGuardian links a guardian to a student at once, for admins.
+func (g *GuardianUseCase) LinkGuardian(ctx context.Context, adminID, guardianID, studentID string) (*entity.GuardianLink, error) {
+	guardian, err := g.guardianUser(ctx, guardianID)
+	if err != nil {
+		return nil, err
+	}
+	student, err := g.studentUser(ctx, studentID)
+	if err != nil {
+		return nil, err
+	}
+	createdBy, err := primitive.ObjectIDFromHex(adminID)
+	if err != nil {
+		return nil, entity.ErrUserNotFound
+	}
+
+	now := time.Now()
+	link := &entity.GuardianLink{
+		StudentID:   student.ID,
+		GuardianID:  &guardian.ID,
+		Email:       guardian.Email,
+		Status:      entity.GuardianLinkActive,
+		CreatedBy:   createdBy,
+		CreatedAt:   now,
+		ConfirmedAt: &now,
+	}
+	if err := g.linkRepo.CreateGuardianLink(ctx, link); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// InviteGuardian mails an invitation to email on behalf of a student. The
+// link becomes active when a guardian account with that address accepts.
+func (g *GuardianUseCase) InviteGuardian(ctx context.Context, studentID, email string) (*entity.GuardianLink, error) {
+	email = strings.TrimSpace(email)
+	student, err := g.studentUser(ctx, studentID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	n, err := g.linkRepo.CountPendingGuardianInvitations(ctx, student.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if n >= maxPendingInvitations {
+		return nil, entity.ErrTooManyInvitations
+	}
+
+	token, err := randomURLToken(32)
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := now.Add(guardianInvitationTTL)
+	link := &entity.GuardianLink{
+		StudentID: student.ID,
+		Email:     email,
+		Status:    entity.GuardianLinkPending,
+		TokenHash: hashToken(token),
+		CreatedBy: student.ID,
+		CreatedAt: now,
+		ExpiresAt: &expiresAt,
+	}
+	if err := g.linkRepo.CreateGuardianLink(ctx, link); err != nil {
+		return nil, err
+	}
+
+	err = g.notifier.Notify(ctx, &entity.User{Email: email}, entity.NotificationGuardianInvite, map[string]interface{}{
+		"Student": student.Name(),
+		"Token":   token,
+		"Days":    int(guardianInvitationTTL.Hours() / 24),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// AcceptGuardianInvitation activates the invitation with the given token
+// for the signed-in guardian, whose address has to be the invited one.
+func (g *GuardianUseCase) AcceptGuardianInvitation(ctx context.Context, guardianID, token string) (*entity.GuardianLink, error) {
+	link, err := g.linkRepo.FindGuardianInvitation(ctx, hashToken(token), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	guardian, err := g.guardianUser(ctx, guardianID)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(guardian.Email, link.Email) {
+		return nil, entity.ErrInvitationMismatch
+	}
+
+	now := time.Now()
+	if err := g.linkRepo.ActivateGuardianLink(ctx, link.ID, guardian.ID, now); err != nil {
+		return nil, err
+	}
+	link.GuardianID, link.Status, link.ConfirmedAt, link.ExpiresAt = &guardian.ID, entity.GuardianLinkActive, &now, nil
+	return link, nil
+}
+
+// ListStudentGuardians returns the links and pending invitations of a
+// student.
+func (g *GuardianUseCase) ListStudentGuardians(ctx context.Context, studentID string) ([]*entity.GuardianLink, error) {
+	oid, err := primitive.ObjectIDFromHex(studentID)
+	if err != nil {
+		return nil, entity.ErrUserNotFound
+	}
+	return g.linkRepo.ListGuardianLinksByStudent(ctx, oid)
+}
+
+// ListGuardianStudents returns the students linked to a guardian. Students
+// whose account has since been deleted are left out.
+func (g *GuardianUseCase) ListGuardianStudents(ctx context.Context, guardianID string) ([]*entity.LinkedStudent, error) {
+	oid, err := primitive.ObjectIDFromHex(guardianID)
+	if err != nil {
+		return nil, entity.ErrUserNotFound
+	}
+	links, err := g.linkRepo.ListGuardianLinksByGuardian(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+
+	students := []*entity.LinkedStudent{}
+	for _, link := range links {
+		user, err := g.userRepo.FindByID(ctx, link.StudentID.Hex())
+		if err == entity.ErrUserNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		linkedAt := link.CreatedAt
+		if link.ConfirmedAt != nil {
+			linkedAt = *link.ConfirmedAt
+		}
+		students = append(students, &entity.LinkedStudent{ID: user.ID, Name: user.Name(), Email: user.Email, LinkedAt: linkedAt})
+	}
+	return students, nil
+}
+
+// RemoveGuardianLink removes a link or invitation, for admins.
+func (g *GuardianUseCase) RemoveGuardianLink(ctx context.Context, linkID string) (*entity.GuardianLink, error) {
+	link, err := g.linkRepo.GetGuardianLink(ctx, linkID)
+	if err != nil {
+		return nil, err
+	}
+	return link, g.linkRepo.DeleteGuardianLink(ctx, link.ID)
+}
+
+// CancelGuardianInvitation withdraws an invitation of the student that has
+// not been accepted yet. Active links can only be removed by an admin.
+func (g *GuardianUseCase) CancelGuardianInvitation(ctx context.Context, studentID, linkID string) error {
+	link, err := g.linkRepo.GetGuardianLink(ctx, linkID)
+	if err != nil {
+		return err
+	}
+	if link.StudentID.Hex() != studentID || link.Status != entity.GuardianLinkPending {
+		return entity.ErrGuardianLinkNotFound
+	}
+	return g.linkRepo.DeleteGuardianLink(ctx, link.ID)
+}
+
+// --- Guardian view ---
+
+func (g *GuardianUseCase) StudentCourses(ctx context.Context, guardianID, studentID string) ([]*entity.Course, error) {
+	if err := g.checkLinked(ctx, guardianID, studentID); err != nil {
+		return nil, err
+	}
+	return g.students.GetEnrolledCourses(ctx, studentID)
+}
+
+func (g *GuardianUseCase) StudentAssignments(ctx context.Context, guardianID, studentID string, opts entity.ListOptions) (*entity.Page[*entity.Assignment], error) {
+	if err := g.checkLinked(ctx, guardianID, studentID); err != nil {
+		return nil, err
+	}
+	return g.students.ListAssignmentsForStudent(ctx, studentID, opts)
+}
+
+func (g *GuardianUseCase) StudentAssessments(ctx context.Context, guardianID, studentID string, opts entity.ListOptions) (*entity.Page[*entity.Assessment], error) {
+	if err := g.checkLinked(ctx, guardianID, studentID); err != nil {
+		return nil, err
+	}
+	return g.students.ListAssessmentsForStudent(ctx, studentID, opts)
+}
+
+func (g *GuardianUseCase) StudentSubmissions(ctx context.Context, guardianID, studentID string) ([]*entity.Submission, error) {
+	if err := g.checkLinked(ctx, guardianID, studentID); err != nil {
+		return nil, err
+	}
+	return g.students.ListSubmissionsForStudent(ctx, studentID)
+}
+
+func (g *GuardianUseCase) StudentProgress(ctx context.Context, guardianID, studentID, courseID string) (*entity.CourseProgress, error) {
+	if err := g.checkLinked(ctx, guardianID, studentID); err != nil {
+		return nil, err
+	}
+	return g.progress.GetStudentProgress(ctx, studentID, courseID)
+}
+
+func (g *GuardianUseCase) StudentAnnouncements(ctx context.Context, guardianID, studentID string, opts entity.ListOptions) (*entity.Page[*entity.Announcement], error) {
+	if err := g.checkLinked(ctx, guardianID, studentID); err != nil {
+		return nil, err
+	}
+	return g.students.ListAnnouncementsForStudent(ctx, studentID, opts)
+}
+
+// checkLinked returns entity.ErrGuardianLinkNotFound unless the guardian
+// has an active link to the student, so that other students cannot be told
+// apart from unknown ones.
+func (g *GuardianUseCase) checkLinked(ctx context.Context, guardianID, studentID string) error {
+	gid, err := primitive.ObjectIDFromHex(guardianID)
+	if err != nil {
+		return entity.ErrGuardianLinkNotFound
+	}
+	sid, err := primitive.ObjectIDFromHex(studentID)
+	if err != nil {
+		return entity.ErrGuardianLinkNotFound
+	}
+	ok, err := g.linkRepo.IsGuardianOf(ctx, gid, sid)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return entity.ErrGuardianLinkNotFound
+	}
+	return nil
+}
+
+// guardianUser returns the user if their role lets them follow students.
+func (g *GuardianUseCase) guardianUser(ctx context.Context, id string) (*entity.User, error) {
+	user, err := g.userRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := g.roles.HasPermission(ctx, user.Role, entity.PermLinkedStudents)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, entity.ErrNotGuardian
+	}
+	return user, nil
+}
+
+// studentUser returns the user if their role takes part in classes.
+func (g *GuardianUseCase) studentUser(ctx context.Context, id string) (*entity.User, error) {
+	user, err := g.userRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := g.roles.HasPermission(ctx, user.Role, entity.PermLearn)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, entity.ErrNotStudent
+	}
+	return user, nil
+}
@@ -0,0 +1,103 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Transactor runs fn in one database transaction. Repository calls made with
+// the context passed to fn take part in it.
+type Transactor interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// DependencyRepository counts and removes the records that refer to an item
+// about to be deleted.
+type DependencyRepository interface {
+	CourseDependents(ctx context.Context, courseID primitive.ObjectID) (entity.Dependents, error)
+	RemoveCourseDependents(ctx context.Context, courseID primitive.ObjectID) ([]string, error)
+	ClassDependents(ctx context.Context, classID primitive.ObjectID) (entity.Dependents, error)
+	RemoveClassDependents(ctx context.Context, classID primitive.ObjectID) error
+	UserDependents(ctx context.Context, userID primitive.ObjectID) (entity.Dependents, error)
+	RemoveUserDependents(ctx context.Context, userID primitive.ObjectID) ([]string, error)
+	AssignmentDependents(ctx context.Context, assignmentID primitive.ObjectID) (entity.Dependents, error)
+	RemoveAssignmentDependents(ctx context.Context, assignmentID primitive.ObjectID) error
+	AssessmentDependents(ctx context.Context, assessmentID primitive.ObjectID) (entity.Dependents, error)
+	RemoveAssessmentDependents(ctx context.Context, assessmentID primitive.ObjectID) error
+	TermDependents(ctx context.Context, termID primitive.ObjectID) (entity.Dependents, error)
+	RemoveTermDependents(ctx context.Context, termID primitive.ObjectID) error
+}
+
+// deleteChecked deletes an item and what refers to it in one transaction.
+// Without cascade it refuses with a DependentsError as soon as count finds
+// any dependent; with it, remove takes care of them before the item goes.
+func deleteChecked(
+	ctx context.Context,
+	tx Transactor,
+	cascade bool,
+	count func(ctx context.Context) (entity.Dependents, error),
+	remove func(ctx context.Context) error,
+	del func(ctx context.Context) error,
+) error {
+	return tx.WithTransaction(ctx, func(ctx context.Context) error {
+		deps, err := count(ctx)
+		if err != nil {
+			return err
+		}
+		if len(deps) > 0 && !cascade {
+			return &entity.DependentsError{Dependents: deps}
+		}
+		if err := remove(ctx); err != nil {
+			return err
+		}
+		return del(ctx)
+	})
+}
+
+// checkCourse reports a ReferenceError when the course does not exist.
+func checkCourse(ctx context.Context, courseRepo CourseRepository, field string, courseID primitive.ObjectID) (*entity.Course, error) {
+	course, err := courseRepo.GetCourse(ctx, courseID.Hex())
+	if errors.Is(err, entity.ErrCourseNotFound) {
+		return nil, &entity.ReferenceError{Field: field, ID: courseID}
+	}
+	return course, err
+}
+
+// checkClass reports a ReferenceError when the class does not exist.
+func checkClass(ctx context.Context, classRepo ClassRepository, field string, classID primitive.ObjectID) error {
+	_, err := classRepo.GetClass(ctx, classID.Hex())
+	if errors.Is(err, entity.ErrClassNotFound) {
+		return &entity.ReferenceError{Field: field, ID: classID}
+	}
+	return err
+}
+
+// checkUsers reports a ReferenceError for the first ID that is not a user
+// whose role has the given permission.
+func checkUsers(ctx context.Context, userRepo UserRepository, roles *RoleUseCase, field string, ids []primitive.ObjectID, perm entity.Permission) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	users, err := userRepo.FindUsersByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	found := make(map[primitive.ObjectID]bool, len(users))
+	for _, u := range users {
+		ok, err := roles.HasPermission(ctx, u.Role, perm)
+		if err != nil {
+			return err
+		}
+		found[u.ID] = ok
+	}
+	for _, id := range ids {
+		if !found[id] {
+			return &entity.ReferenceError{Field: field, ID: id}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,380 @@
+package usecase
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"log"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/srgjo27/e-learning/internal/entity"
+)
+
+type SigningKeyRepository interface {
+	ListSigningKeys(ctx context.Context, now time.Time) ([]*entity.SigningKey, error)
+	InsertSigningKey(ctx context.Context, key *entity.SigningKey) error
+	ExpireSigningKeys(ctx context.Context, activeBefore, expiresAt time.Time) error
+	DeleteSigningKey(ctx context.Context, kid string) error
+}
+
+// The ring is kept in memory and read again from the database after
+// keyRingRefresh, or sooner when a token names a key it does not know,
+// which is how instances learn about keys another one created.
+const (
+	keyRingRefresh      = time.Minute
+	keyRingMissCooldown = 10 * time.Second
+	keyRingLoadTimeout  = 5 * time.Second
+)
+
+type ringKey struct {
+	meta    *entity.SigningKey
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// KeyRing signs tokens with the current key of a rotating set of asymmetric
+// keys and verifies them against every key that has not expired, picking
+// the key by the kid header.
+type KeyRing struct {
+	repo   SigningKeyRepository
+	policy entity.KeyRotationPolicy
+
+	mu       sync.Mutex
+	keys     []*ringKey // by ActiveFrom, oldest first
+	loadedAt time.Time
+	missedAt time.Time
+}
+
+func NewKeyRing(repo SigningKeyRepository, policy entity.KeyRotationPolicy) (*KeyRing, error) {
+	if _, err := signingMethod(policy.Algorithm); err != nil {
+		return nil, err
+	}
+	return &KeyRing{repo: repo, policy: policy}, nil
+}
+
+func signingMethod(alg string) (jwt.SigningMethod, error) {
+	switch alg {
+	case jwt.SigningMethodEdDSA.Alg():
+		return jwt.SigningMethodEdDSA, nil
+	case jwt.SigningMethodRS256.Alg():
+		return jwt.SigningMethodRS256, nil
+	}
+	return nil, entity.ErrUnsupportedAlgorithm
+}
+
+// tokenKind tells apart the tokens the ring signs. Each kind has its own
+// typ header and audience, and Parse only accepts the kinds it is asked
+// for, so that one kind of token cannot be passed off as another.
+type tokenKind string
+
+const (
+	tokenSession       tokenKind = "session"
+	tokenImpersonation tokenKind = "impersonation"
+	tokenMFAChallenge  tokenKind = "mfa-challenge"
+)
+
+func (t tokenKind) typ() string {
+	return t.audience() + "+jwt"
+}
+
+func (t tokenKind) audience() string {
+	return "e-learning:" + string(t)
+}
+
+// Sign signs claims as a token of the given kind with the current key and
+// names the key in the kid header.
+func (k *KeyRing) Sign(kind tokenKind, claims jwt.MapClaims) (string, error) {
+	keys := k.snapshot(false)
+	key := currentKey(keys, time.Now())
+	if key == nil {
+		return "", entity.ErrSigningKeyNotFound
+	}
+	claims["aud"] = kind.audience()
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.meta.ID
+	token.Header["typ"] = kind.typ()
+	return token.SignedString(key.private)
+}
+
+// Parse verifies tokenStr against the key its kid header names, checks that
+// it is a token of one of kinds and fills claims. It returns the kind.
+func (k *KeyRing) Parse(tokenStr string, claims jwt.MapClaims, kinds ...tokenKind) (*jwt.Token, tokenKind, error) {
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key := k.find(kid)
+		if key == nil {
+			return nil, entity.ErrSigningKeyNotFound
+		}
+		if t.Method.Alg() != key.method.Alg() {
+			return nil, entity.ErrInvalidToken
+		}
+		return key.public, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodRS256.Alg()}))
+	if err != nil {
+		return nil, "", err
+	}
+
+	typ, _ := token.Header["typ"].(string)
+	aud, err := claims.GetAudience()
+	if err != nil {
+		return nil, "", entity.ErrInvalidToken
+	}
+	for _, kind := range kinds {
+		if typ == kind.typ() && len(aud) == 1 && aud[0] == kind.audience() {
+			return token, kind, nil
+		}
+	}
+	return nil, "", entity.ErrInvalidToken
+}
+
+func (k *KeyRing) find(kid string) *ringKey {
+	now := time.Now()
+	lookup := func(keys []*ringKey) *ringKey {
+		for _, key := range keys {
+			if key.meta.ID == kid && (key.meta.ExpiresAt.IsZero() || now.Before(key.meta.ExpiresAt)) {
+				return key
+			}
+		}
+		return nil
+	}
+	if key := lookup(k.snapshot(false)); key != nil {
+		return key
+	}
+	return lookup(k.snapshot(true))
+}
+
+// JWKS returns the public keys of the ring, including keys that do not sign
+// yet, so that verifiers know them before the first token they sign.
+func (k *KeyRing) JWKS() []entity.JWK {
+	keys := k.snapshot(false)
+	set := make([]entity.JWK, 0, len(keys))
+	for _, key := range keys {
+		jwk := entity.JWK{Kid: key.meta.ID, Use: "sig", Alg: key.meta.Algorithm}
+		switch pub := key.public.(type) {
+		case ed25519.PublicKey:
+			jwk.Kty, jwk.Crv, jwk.X = "OKP", "Ed25519", base64.RawURLEncoding.EncodeToString(pub)
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		}
+		set = append(set, jwk)
+	}
+	return set
+}
+
+// ListKeys returns the keys of the ring, oldest first.
+func (k *KeyRing) ListKeys(ctx context.Context) ([]*entity.SigningKey, error) {
+	if err := k.load(ctx); err != nil {
+		return nil, err
+	}
+	keys := k.snapshot(false)
+	list := make([]*entity.SigningKey, len(keys))
+	for i, key := range keys {
+		list[i] = key.meta
+	}
+	return list, nil
+}
+
+// Maintain creates the first key of an empty ring and rotates the ring
+// when the newest key is due to be replaced or uses an algorithm other than
+// the configured one. The new key is published PublishAhead before it takes
+// over. It is meant to run periodically under a lock.
+func (k *KeyRing) Maintain(ctx context.Context, now time.Time) error {
+	if err := k.load(ctx); err != nil {
+		return err
+	}
+	keys := k.snapshot(false)
+	if len(keys) == 0 {
+		_, err := k.rotate(ctx, now, now)
+		return err
+	}
+
+	newest := keys[len(keys)-1].meta
+	due := newest.ActiveFrom.Add(k.policy.RotateEvery - k.policy.PublishAhead)
+	if now.Before(due) && newest.Algorithm == k.policy.Algorithm {
+		return nil
+	}
+	_, err := k.rotate(ctx, now, now.Add(k.policy.PublishAhead))
+	return err
+}
+
+// Rotate creates a new key. It takes over after PublishAhead, or at once if
+// immediate is set, as when the current key is suspected to be compromised;
+// verifiers that cached the key set may then reject new tokens until they
+// fetch it again.
+func (k *KeyRing) Rotate(ctx context.Context, immediate bool) (*entity.SigningKey, error) {
+	now := time.Now()
+	activeFrom := now.Add(k.policy.PublishAhead)
+	if immediate {
+		activeFrom = now
+	}
+	return k.rotate(ctx, now, activeFrom)
+}
+
+func (k *KeyRing) rotate(ctx context.Context, now, activeFrom time.Time) (*entity.SigningKey, error) {
+	key, err := newSigningKey(k.policy.Algorithm, now, activeFrom)
+	if err != nil {
+		return nil, err
+	}
+	if err := k.repo.InsertSigningKey(ctx, key); err != nil {
+		return nil, err
+	}
+	// Keys it replaces still verify the tokens they signed until those
+	// have run out.
+	if err := k.repo.ExpireSigningKeys(ctx, activeFrom, activeFrom.Add(k.policy.VerifyFor)); err != nil {
+		return nil, err
+	}
+	log.Printf("Created signing key %s (%s), signing from %s", key.ID, key.Algorithm, activeFrom.Format(time.RFC3339))
+	return key, k.load(ctx)
+}
+
+// RevokeKey removes a key, so that every token it signed is rejected. The
+// key that signs at the moment cannot be revoked; rotate at once first.
+func (k *KeyRing) RevokeKey(ctx context.Context, kid string) error {
+	if err := k.load(ctx); err != nil {
+		return err
+	}
+	if current := currentKey(k.snapshot(false), time.Now()); current != nil && current.meta.ID == kid {
+		return entity.ErrSigningKeyInUse
+	}
+	if err := k.repo.DeleteSigningKey(ctx, kid); err != nil {
+		return err
+	}
+	return k.load(ctx)
+}
+
+// snapshot returns the keys in memory, reading them again first if they are
+// stale, or if miss is set and the last read for a miss is long enough ago.
+// A failed read keeps the keys there are.
+func (k *KeyRing) snapshot(miss bool) []*ringKey {
+	k.mu.Lock()
+	now := time.Now()
+	stale := now.Sub(k.loadedAt) > keyRingRefresh
+	if miss && now.Sub(k.missedAt) > keyRingMissCooldown {
+		k.missedAt = now
+		stale = true
+	}
+	keys := k.keys
+	k.mu.Unlock()
+
+	if stale {
+		ctx, cancel := context.WithTimeout(context.Background(), keyRingLoadTimeout)
+		defer cancel()
+		if err := k.load(ctx); err != nil {
+			log.Printf("Error loading signing keys: %v", err)
+			return keys
+		}
+		k.mu.Lock()
+		keys = k.keys
+		k.mu.Unlock()
+	}
+	return keys
+}
+
+func (k *KeyRing) load(ctx context.Context) error {
+	now := time.Now()
+	stored, err := k.repo.ListSigningKeys(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]*ringKey, 0, len(stored))
+	for _, s := range stored {
+		key, err := parseSigningKey(s)
+		if err != nil {
+			log.Printf("Skipping signing key %s: %v", s.ID, err)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].meta.ActiveFrom.Before(keys[j].meta.ActiveFrom)
+	})
+
+	k.mu.Lock()
+	k.keys, k.loadedAt = keys, now
+	k.mu.Unlock()
+	return nil
+}
+
+// currentKey returns the key that signs at now: the last one to have become
+// active, or the first one if none has yet.
+func currentKey(keys []*ringKey, now time.Time) *ringKey {
+	var current *ringKey
+	for _, key := range keys {
+		if !key.meta.ActiveFrom.After(now) {
+			current = key
+		}
+	}
+	if current == nil && len(keys) > 0 {
+		current = keys[0]
+	}
+	return current
+}
+
+func newSigningKey(alg string, now, activeFrom time.Time) (*entity.SigningKey, error) {
+	var private crypto.Signer
+	var err error
+	switch alg {
+	case jwt.SigningMethodEdDSA.Alg():
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	case jwt.SigningMethodRS256.Alg():
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	default:
+		return nil, entity.ErrUnsupportedAlgorithm
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		return nil, err
+	}
+	kid, err := randomURLToken(12)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.SigningKey{
+		ID:         kid,
+		Algorithm:  alg,
+		PrivateKey: privateDER,
+		PublicKey:  publicDER,
+		CreatedAt:  now,
+		ActiveFrom: activeFrom,
+	}, nil
+}
+
+func parseSigningKey(s *entity.SigningKey) (*ringKey, error) {
+	method, err := signingMethod(s.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	private, err := x509.ParsePKCS8PrivateKey(s.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, entity.ErrUnsupportedAlgorithm
+	}
+	public, err := x509.ParsePKIXPublicKey(s.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &ringKey{meta: s, method: method, private: signer, public: public}, nil
+}
@@ -0,0 +1,75 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/srgjo27/e-learning/internal/entity"
+)
+
+func newTestKeyRing(t *testing.T) *KeyRing {
+	t.Helper()
+	keys, err := NewKeyRing(&memSigningKeys{}, entity.KeyRotationPolicy{
+		Algorithm: jwt.SigningMethodEdDSA.Alg(), RotateEvery: time.Hour, VerifyFor: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.Maintain(context.Background(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func TestKeyRingTokenKinds(t *testing.T) {
+	keys := newTestKeyRing(t)
+	kinds := []tokenKind{tokenSession, tokenImpersonation, tokenMFAChallenge}
+
+	for _, signed := range kinds {
+		token, err := keys.Sign(signed, jwt.MapClaims{
+			"userId": "u1",
+			"exp":    time.Now().Add(time.Minute).Unix(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, wanted := range kinds {
+			_, kind, err := keys.Parse(token, jwt.MapClaims{}, wanted)
+			if signed == wanted && (err != nil || kind != signed) {
+				t.Errorf("%s token parsed as %s: got %q, %v", signed, wanted, kind, err)
+			}
+			if signed != wanted && err == nil {
+				t.Errorf("%s token accepted as %s", signed, wanted)
+			}
+		}
+	}
+}
+
+func TestParseTokenRejectsOtherKinds(t *testing.T) {
+	student := testUser(entity.RoleStudent)
+	a := newImpersonationAuth(t, student)
+
+	session, err := a.issueSessionToken(student)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _, _, err := a.ParseToken(context.Background(), session); err != nil || id != student.ID.Hex() {
+		t.Fatalf("session token: got %q, %v", id, err)
+	}
+
+	// A challenge token with the claims of a session must not pass for one.
+	forged, err := a.keys.Sign(tokenMFAChallenge, jwt.MapClaims{
+		"userId":  student.ID.Hex(),
+		"role":    string(entity.RoleStudent),
+		"mfaUser": student.ID.Hex(),
+		"exp":     time.Now().Add(time.Minute).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := a.ParseToken(context.Background(), forged); err != entity.ErrInvalidToken {
+		t.Fatalf("challenge token as session: got %v, want ErrInvalidToken", err)
+	}
+}
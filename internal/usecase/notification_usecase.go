@@ -0,0 +1,85 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Notifier queues emails in the outbox. The writes use ctx, so callers that
+// queue a notification about a change run both in one transaction, and the
+// change is never saved without its notification.
+type Notifier interface {
+	// Notify queues an email for a user.
+	Notify(ctx context.Context, user *entity.User, kind entity.NotificationKind, data map[string]interface{}) error
+	// Broadcast queues a job that mails every user later on.
+	Broadcast(ctx context.Context, kind entity.NotificationKind, data map[string]interface{}) error
+}
+
+type NotificationUseCase struct {
+	userRepo UserRepository
+}
+
+func NewNotificationUseCase(userRepo UserRepository) *NotificationUseCase {
+	return &NotificationUseCase{
+		userRepo: userRepo,
+	}
+}
+
+func (n *NotificationUseCase) GetPreferences(ctx context.Context, userID string) (*entity.NotificationPreferences, error) {
+	user, err := n.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user.NotificationPreferences, nil
+}
+
+func (n *NotificationUseCase) UpdatePreferences(ctx context.Context, userID string, prefs entity.NotificationPreferences) error {
+	for _, kind := range prefs.Disabled {
+		if !entity.IsValidNotificationKind(kind) {
+			return entity.ErrNotificationKindInvalid
+		}
+	}
+	if prefs.Disabled == nil {
+		prefs.Disabled = []entity.NotificationKind{}
+	}
+
+	return n.userRepo.UpdateNotificationPreferences(ctx, userID, prefs)
+}
+
+// notifyUsers sends the same notification to every user, stopping at the first
+// outbox write that fails.
+func notifyUsers(ctx context.Context, notifier Notifier, users []*entity.User, kind entity.NotificationKind, data map[string]interface{}) error {
+	for _, u := range users {
+		if err := notifier.Notify(ctx, u, kind, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// studentsInCourse returns the users enrolled in any class of the course.
+func studentsInCourse(ctx context.Context, classRepo ClassRepository, userRepo UserRepository, courseID primitive.ObjectID) ([]*entity.User, error) {
+	classes, err := classRepo.ListClassesByCourse(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[primitive.ObjectID]struct{})
+	var ids []primitive.ObjectID
+	for _, cl := range classes {
+		for _, id := range cl.StudentIDs {
+			if _, ok := seen[id]; !ok {
+				seen[id] = struct{}{}
+				ids = append(ids, id)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return []*entity.User{}, nil
+	}
+
+	return userRepo.FindUsersByIDs(ctx, ids)
+}
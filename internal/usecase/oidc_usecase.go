@@ -0,0 +1,242 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type OIDCProvider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, challenge string) (string, error)
+	Exchange(ctx context.Context, code, verifier, nonce string) (*entity.OIDCIdentity, error)
+}
+
+type OIDCFlowRepository interface {
+	SaveOIDCFlow(ctx context.Context, flow *entity.OIDCFlow) error
+	TakeOIDCFlow(ctx context.Context, state string, now time.Time) (*entity.OIDCFlow, error)
+}
+
+// OIDCFlowTTL is how long a user has to sign in at the identity provider.
+const OIDCFlowTTL = 10 * time.Minute
+
+// OIDCUseCase signs users in through an OpenID Connect identity provider,
+// creating their account on first sign-in. Password login is not affected.
+type OIDCUseCase struct {
+	provider   OIDCProvider
+	flowRepo   OIDCFlowRepository
+	userRepo   UserRepository
+	auth       *AuthUseCase
+	groupRoles map[string]entity.Role
+}
+
+// NewOIDCUseCase returns the single sign-on usecase. provider is nil when
+// no identity provider is configured. groupRoles maps identity provider
+// groups to roles.
+func NewOIDCUseCase(provider OIDCProvider, flowRepo OIDCFlowRepository, userRepo UserRepository, auth *AuthUseCase, groupRoles map[string]entity.Role) *OIDCUseCase {
+	return &OIDCUseCase{
+		provider:   provider,
+		flowRepo:   flowRepo,
+		userRepo:   userRepo,
+		auth:       auth,
+		groupRoles: groupRoles,
+	}
+}
+
+// BeginLogin starts a login and returns the identity provider URL to send
+// the browser to, and a secret for the browser to keep until the callback.
+// The state, nonce and PKCE verifier are kept until the callback.
+func (o *OIDCUseCase) BeginLogin(ctx context.Context) (string, string, error) {
+	if o.provider == nil {
+		return "", "", entity.ErrOIDCNotConfigured
+	}
+
+	var values [4]string
+	for i := range values {
+		v, err := randomURLToken(32)
+		if err != nil {
+			return "", "", err
+		}
+		values[i] = v
+	}
+	flow := &entity.OIDCFlow{
+		State:        values[0],
+		Nonce:        values[1],
+		CodeVerifier: values[2],
+		BrowserHash:  hashToken(values[3]),
+		ExpiresAt:    time.Now().Add(OIDCFlowTTL),
+	}
+	if err := o.flowRepo.SaveOIDCFlow(ctx, flow); err != nil {
+		return "", "", err
+	}
+
+	challenge := sha256.Sum256([]byte(flow.CodeVerifier))
+	redirect, err := o.provider.AuthCodeURL(ctx, flow.State, flow.Nonce, base64.RawURLEncoding.EncodeToString(challenge[:]))
+	if err != nil {
+		return "", "", err
+	}
+	return redirect, values[3], nil
+}
+
+// CompleteLogin handles the callback of a login. browserSecret is what
+// BeginLogin gave the browser; a callback from another browser, as in a
+// login CSRF, is entity.ErrOIDCInvalidState. Like a password login, the
+// result is a session token or, when the account has a second factor, a
+// challenge.
+func (o *OIDCUseCase) CompleteLogin(ctx context.Context, state, code, browserSecret string) (*entity.LoginResult, error) {
+	if o.provider == nil {
+		return nil, entity.ErrOIDCNotConfigured
+	}
+
+	flow, err := o.flowRepo.TakeOIDCFlow(ctx, state, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if browserSecret == "" || subtle.ConstantTimeCompare([]byte(hashToken(browserSecret)), []byte(flow.BrowserHash)) != 1 {
+		return nil, entity.ErrOIDCInvalidState
+	}
+	id, err := o.provider.Exchange(ctx, code, flow.CodeVerifier, flow.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := o.provision(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return o.auth.completeFirstFactor(ctx, user)
+}
+
+// AllowLink lets the next single sign-on with the user's email link the
+// account to the identity provider account, or takes that back. Only
+// someone who may grant the user's role may allow it.
+func (o *OIDCUseCase) AllowLink(ctx context.Context, userID string, allowed bool) error {
+	user, err := o.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	ok, err := o.auth.roles.CanGrant(ctx, user.Role)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return entity.ErrPermissionDenied
+	}
+	return o.userRepo.AllowOIDCLink(ctx, userID, allowed)
+}
+
+// provision finds the account of an identity. An account already linked to
+// it is used first. Otherwise, when the identity has a verified email, an
+// account with that email is linked from then on if an admin allowed it
+// (see AllowLink) and refused if not, and without one a new account is
+// created. Either way the address counts as verified.
+//
+// When the identity's groups map to a role, the account is given that role.
+// When they no longer do, an account holding a role the groups hand out
+// falls back to the student role; roles given outside the group mapping are
+// left alone.
+func (o *OIDCUseCase) provision(ctx context.Context, id *entity.OIDCIdentity) (*entity.User, error) {
+	subject := id.Issuer + "|" + id.Subject
+	role, mapped, err := o.roleFor(ctx, id.Groups)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := o.userRepo.FindByOIDCSubject(ctx, subject)
+	if errors.Is(err, entity.ErrUserNotFound) {
+		if id.Email == "" || !id.EmailVerified {
+			return nil, entity.ErrOIDCNoEmail
+		}
+
+		user, err = o.userRepo.FindByEmail(ctx, id.Email)
+		if errors.Is(err, entity.ErrUserNotFound) {
+			if !mapped {
+				role = entity.RoleStudent
+			}
+			user = &entity.User{
+				ID:            primitive.NewObjectID(),
+				Email:         id.Email,
+				EmailVerified: true,
+				Role:          role,
+				OIDCSubject:   subject,
+				CreatedAt:     time.Now(),
+			}
+			return user, o.userRepo.Create(ctx, user)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !user.OIDCLinkAllowed {
+			return nil, entity.ErrOIDCLinkRequired
+		}
+		if err := o.userRepo.LinkOIDCSubject(ctx, user.ID.Hex(), subject); err != nil {
+			return nil, err
+		}
+		// The identity provider vouches for the address.
+		if !user.EmailVerified {
+			if err := o.userRepo.MarkEmailVerified(ctx, user.ID.Hex(), user.Email); err != nil {
+				return nil, err
+			}
+			user.EmailVerified = true
+		}
+		user.OIDCLinkAllowed = false
+	} else if err != nil {
+		return nil, err
+	}
+
+	if !mapped && o.grantsRole(user.Role) {
+		role, mapped = entity.RoleStudent, true
+	}
+	if mapped && user.Role != role {
+		if err := o.userRepo.UpdateRole(ctx, user.ID.Hex(), role); err != nil {
+			return nil, err
+		}
+		user.Role = role
+	}
+	return user, nil
+}
+
+// roleFor returns the most privileged role any of the groups maps to,
+// taken to be the one with the most permissions.
+func (o *OIDCUseCase) roleFor(ctx context.Context, groups []string) (entity.Role, bool, error) {
+	var best entity.Role
+	bestRank := -1
+	for _, g := range groups {
+		r, ok := o.groupRoles[g]
+		if !ok {
+			continue
+		}
+		perms, err := o.auth.roles.Permissions(ctx, r)
+		if err != nil {
+			return "", false, err
+		}
+		if len(perms) > bestRank {
+			best, bestRank = r, len(perms)
+		}
+	}
+	return best, best != "", nil
+}
+
+// grantsRole reports whether some group maps to role.
+func (o *OIDCUseCase) grantsRole(role entity.Role) bool {
+	for _, r := range o.groupRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func randomURLToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,102 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BreachedPasswordRanges looks up hashes of compromised passwords by range.
+// Range returns the upper case hex suffixes of the SHA-1 hashes starting with
+// a five digit prefix.
+type BreachedPasswordRanges interface {
+	Range(ctx context.Context, prefix string) ([]string, error)
+}
+
+// PasswordService checks new passwords against the policy, the breached
+// password list and the account's previous passwords, and hashes them at
+// the configured bcrypt cost.
+type PasswordService struct {
+	policy      entity.PasswordPolicy
+	breached    BreachedPasswordRanges
+	cost        int
+	historySize int
+	dummyHash   []byte
+}
+
+// NewPasswordService returns a service that hashes at cost and refuses the
+// last historySize passwords of an account. breached may be nil, in which
+// case no breach check is made.
+func NewPasswordService(policy entity.PasswordPolicy, breached BreachedPasswordRanges, cost, historySize int) *PasswordService {
+	dummyHash, _ := bcrypt.GenerateFromPassword([]byte("not a real password"), cost)
+	return &PasswordService{
+		policy:      policy,
+		breached:    breached,
+		cost:        cost,
+		historySize: historySize,
+		dummyHash:   dummyHash,
+	}
+}
+
+// Validate returns an error wrapping entity.ErrWeakPassword or
+// entity.ErrPasswordBreached if password may not be used for email.
+func (p *PasswordService) Validate(ctx context.Context, password, email string) error {
+	if err := p.policy.Check(password, email); err != nil {
+		return err
+	}
+	if p.breached == nil {
+		return nil
+	}
+
+	// Only the first five digits of the hash are used for the lookup.
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	suffixes, err := p.breached.Range(ctx, hash[:5])
+	if err != nil {
+		return err
+	}
+	for _, s := range suffixes {
+		if s == hash[5:] {
+			return entity.ErrPasswordBreached
+		}
+	}
+	return nil
+}
+
+// CheckReuse returns entity.ErrPasswordReused if password matches the
+// current hash of user or one of the hashes kept in its history.
+func (p *PasswordService) CheckReuse(user *entity.User, password string) error {
+	for _, hash := range append([]string{user.Password}, user.PasswordHistory...) {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return entity.ErrPasswordReused
+		}
+	}
+	return nil
+}
+
+func (p *PasswordService) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), p.cost)
+	return string(hashed), err
+}
+
+// CompareDummy spends as long as checking a real password, so that a failed
+// sign-in for an unknown email takes as long as one for a known account.
+func (p *PasswordService) CompareDummy(password string) {
+	bcrypt.CompareHashAndPassword(p.dummyHash, []byte(password))
+}
+
+// NeedsRehash reports whether hash was made at a lower cost than the one
+// configured now.
+func (p *PasswordService) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err == nil && cost < p.cost
+}
+
+// HistorySize is how many previous hashes are kept per account.
+func (p *PasswordService) HistorySize() int {
+	return p.historySize
+}
@@ -0,0 +1,130 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ProfileUseCase keeps the names, avatar, locale and time zone of users.
+// Avatars are kept in the blob store next to lesson attachments.
+type ProfileUseCase struct {
+	userRepo UserRepository
+	blobs    BlobStore
+}
+
+func NewProfileUseCase(userRepo UserRepository, blobs BlobStore) *ProfileUseCase {
+	return &ProfileUseCase{
+		userRepo: userRepo,
+		blobs:    blobs,
+	}
+}
+
+// UpdateProfile applies a user's changes to their own profile.
+func (p *ProfileUseCase) UpdateProfile(ctx context.Context, userID string, update entity.ProfileUpdate) (*entity.User, error) {
+	return p.UpdateUserProfile(ctx, userID, update, nil)
+}
+
+// UpdateUserProfile applies changes to a user's profile. Only admins change
+// student numbers, so studentNumber is nil unless an admin made the change.
+func (p *ProfileUseCase) UpdateUserProfile(ctx context.Context, userID string, update entity.ProfileUpdate, studentNumber *string) (*entity.User, error) {
+	user, err := p.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := user.Profile
+	if err := update.Apply(&profile); err != nil {
+		return nil, err
+	}
+	if studentNumber != nil {
+		if err := entity.ValidateStudentNumber(*studentNumber); err != nil {
+			return nil, err
+		}
+		profile.StudentNumber = *studentNumber
+	}
+
+	if err := p.userRepo.UpdateProfile(ctx, userID, profile); err != nil {
+		return nil, err
+	}
+	user.Profile = profile
+	return user, nil
+}
+
+// SetAvatar stores the image read from r as the user's avatar and removes
+// the one it replaces. The image type is detected from its content rather
+// than taken from the client.
+func (p *ProfileUseCase) SetAvatar(ctx context.Context, userID string, r io.Reader) (*entity.User, error) {
+	user, err := p.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, entity.ErrInvalidAvatar
+	}
+	head = head[:n]
+	contentType := http.DetectContentType(head)
+	if !entity.IsAvatarType(contentType) {
+		return nil, entity.ErrInvalidAvatar
+	}
+
+	key := "avatars/" + user.ID.Hex() + "/" + primitive.NewObjectID().Hex()
+	body := io.LimitReader(io.MultiReader(bytes.NewReader(head), r), entity.MaxAvatarSize+1)
+	size, err := p.blobs.Put(ctx, key, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	if size > entity.MaxAvatarSize {
+		p.blobs.Delete(ctx, key)
+		return nil, entity.ErrInvalidAvatar
+	}
+
+	now := time.Now().UTC()
+	if err := p.userRepo.UpdateAvatar(ctx, userID, key, now); err != nil {
+		p.blobs.Delete(ctx, key)
+		return nil, err
+	}
+	if user.AvatarKey != "" {
+		p.blobs.Delete(ctx, user.AvatarKey)
+	}
+
+	user.AvatarKey, user.AvatarUpdatedAt = key, &now
+	return user, nil
+}
+
+func (p *ProfileUseCase) RemoveAvatar(ctx context.Context, userID string) error {
+	user, err := p.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.AvatarKey == "" {
+		return entity.ErrAvatarNotFound
+	}
+
+	if err := p.userRepo.UpdateAvatar(ctx, userID, "", time.Time{}); err != nil {
+		return err
+	}
+	p.blobs.Delete(ctx, user.AvatarKey)
+	return nil
+}
+
+// OpenAvatar returns the avatar image of a user and its content type. The
+// caller closes the reader.
+func (p *ProfileUseCase) OpenAvatar(ctx context.Context, userID string) (io.ReadCloser, string, error) {
+	user, err := p.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if user.AvatarKey == "" {
+		return nil, "", entity.ErrAvatarNotFound
+	}
+	return p.blobs.Get(ctx, user.AvatarKey)
+}
@@ -0,0 +1,403 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ProgressRepository interface {
+	SaveProgress(ctx context.Context, p *entity.Progress) error
+	SaveProgressIfAbsent(ctx context.Context, p *entity.Progress) error
+	ListProgressByStudentAndCourse(ctx context.Context, studentID, courseID primitive.ObjectID) ([]*entity.Progress, error)
+	ListProgress(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.Progress], error)
+}
+
+type ProgressUseCase struct {
+	progressRepo   ProgressRepository
+	courseRepo     CourseRepository
+	classRepo      ClassRepository
+	userRepo       UserRepository
+	lessonRepo     LessonRepository
+	assignmentRepo AssignmentRepository
+	assessmentRepo AssessmentRepository
+}
+
+func NewProgressUseCase(
+	progressRepo ProgressRepository,
+	courseRepo CourseRepository,
+	classRepo ClassRepository,
+	userRepo UserRepository,
+	lessonRepo LessonRepository,
+	assignmentRepo AssignmentRepository,
+	assessmentRepo AssessmentRepository,
+) *ProgressUseCase {
+	return &ProgressUseCase{
+		progressRepo:   progressRepo,
+		courseRepo:     courseRepo,
+		classRepo:      classRepo,
+		userRepo:       userRepo,
+		lessonRepo:     lessonRepo,
+		assignmentRepo: assignmentRepo,
+		assessmentRepo: assessmentRepo,
+	}
+}
+
+// --- Recording ---
+
+func (p *ProgressUseCase) CompleteLesson(ctx context.Context, studentID, lessonID string) error {
+	sid, err := primitive.ObjectIDFromHex(studentID)
+	if err != nil {
+		return entity.ErrUserNotFound
+	}
+
+	lesson, err := p.lessonRepo.GetLesson(ctx, lessonID)
+	if err != nil {
+		return err
+	}
+	if !lesson.IsReleased(time.Now()) {
+		return entity.ErrLessonNotFound
+	}
+	if err := p.requireEnrollment(ctx, studentID, lesson.CourseID); err != nil {
+		return err
+	}
+
+	eval, err := newReleaseEvaluator(ctx, p.progressRepo, p.lessonRepo, p.assessmentRepo, sid, lesson.CourseID)
+	if err != nil {
+		return err
+	}
+	if availability, _ := eval.check(lesson.Release); availability.Locked {
+		return entity.ErrItemLocked
+	}
+
+	return p.progressRepo.SaveProgress(ctx, &entity.Progress{
+		StudentID: sid,
+		CourseID:  lesson.CourseID,
+		ItemType:  entity.ProgressLesson,
+		ItemID:    lesson.ID,
+		Status:    entity.ProgressCompleted,
+		UpdatedAt: time.Now(),
+	})
+}
+
+// RecordAssessmentResult stores a student's score, as a percentage, for an
+// assessment in one of the teacher's courses.
+func (p *ProgressUseCase) RecordAssessmentResult(ctx context.Context, teacherID, assessmentID, studentID string, score float64) error {
+	if score < 0 || score > 100 {
+		return entity.ErrInvalidScore
+	}
+
+	sid, err := primitive.ObjectIDFromHex(studentID)
+	if err != nil {
+		return entity.ErrUserNotFound
+	}
+
+	assessment, err := p.assessmentRepo.GetAssessment(ctx, assessmentID)
+	if err != nil {
+		return err
+	}
+	course, err := p.courseRepo.GetCourse(ctx, assessment.CourseID.Hex())
+	if err != nil {
+		return err
+	}
+	if !isAssignedTeacher(course, teacherID) {
+		return entity.ErrUnauthorized
+	}
+	if err := p.requireEnrollment(ctx, studentID, course.ID); err != nil {
+		return err
+	}
+
+	return p.progressRepo.SaveProgress(ctx, &entity.Progress{
+		StudentID: sid,
+		CourseID:  course.ID,
+		ItemType:  entity.ProgressAssessment,
+		ItemID:    assessment.ID,
+		Status:    entity.ProgressTaken,
+		Score:     &score,
+		UpdatedAt: time.Now(),
+	})
+}
+
+// --- Criteria ---
+func (p *ProgressUseCase) UpdateCompletionCriteria(ctx context.Context, teacherID, courseID string, criteria entity.CompletionCriteria) error {
+	if err := criteria.Validate(); err != nil {
+		return err
+	}
+
+	course, err := p.courseRepo.GetCourse(ctx, courseID)
+	if err != nil {
+		return err
+	}
+	if !isAssignedTeacher(course, teacherID) {
+		return entity.ErrUnauthorized
+	}
+
+	return p.courseRepo.UpdateCompletionCriteria(ctx, course.ID, criteria)
+}
+
+// --- Reporting ---
+
+// GetStudentProgress returns the student's own progress in a course with a
+// per-item breakdown. Items the release rules hide from the student are
+// left out and do not count.
+func (p *ProgressUseCase) GetStudentProgress(ctx context.Context, studentID, courseID string) (*entity.CourseProgress, error) {
+	sid, err := primitive.ObjectIDFromHex(studentID)
+	if err != nil {
+		return nil, entity.ErrUserNotFound
+	}
+
+	course, err := p.courseRepo.GetCourse(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.requireEnrollment(ctx, studentID, course.ID); err != nil {
+		return nil, err
+	}
+
+	items, err := p.courseItems(ctx, course)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := p.progressRepo.ListProgressByStudentAndCourse(ctx, sid, course.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.evaluateProgress(ctx, course, sid, items, records, true), nil
+}
+
+// GetClassProgress builds the completion dashboard for every student of a
+// class the teacher teaches. As in GetStudentProgress, the items hidden
+// from a student do not count towards their percent.
+func (p *ProgressUseCase) GetClassProgress(ctx context.Context, teacherID, classID string) (*entity.ClassProgress, error) {
+	class, err := p.classRepo.GetClass(ctx, classID)
+	if err != nil {
+		return nil, err
+	}
+
+	course, err := p.courseRepo.GetCourse(ctx, class.CourseID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if !teachesClass(class, teacherID) && !isAssignedTeacher(course, teacherID) {
+		return nil, entity.ErrUnauthorized
+	}
+
+	items, err := p.courseItems(ctx, course)
+	if err != nil {
+		return nil, err
+	}
+
+	byStudent, err := p.classRecords(ctx, course, class, items)
+	if err != nil {
+		return nil, err
+	}
+
+	emails := make(map[primitive.ObjectID]string)
+	if len(class.StudentIDs) > 0 {
+		users, err := p.userRepo.FindUsersByIDs(ctx, class.StudentIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			emails[u.ID] = u.Email
+		}
+	}
+
+	result := &entity.ClassProgress{
+		ClassID:  class.ID,
+		CourseID: course.ID,
+		Students: make([]*entity.CourseProgress, 0, len(class.StudentIDs)),
+	}
+	var sum float64
+	for _, sid := range class.StudentIDs {
+		cp, ok := byStudent[sid]
+		if !ok {
+			cp = p.evaluateProgress(ctx, course, sid, items, nil, false)
+		}
+		cp.Email = emails[sid]
+		result.Students = append(result.Students, cp)
+		sum += cp.Percent
+	}
+	if len(result.Students) > 0 {
+		result.AveragePercent = sum / float64(len(result.Students))
+	}
+
+	return result, nil
+}
+
+// classRecords evaluates the progress of the students of a class who have
+// any records in the course. Records are read page by page in student
+// order, so only those of one student are held at a time.
+func (p *ProgressUseCase) classRecords(ctx context.Context, course *entity.Course, class *entity.Class, items []courseItem) (map[primitive.ObjectID]*entity.CourseProgress, error) {
+	result := make(map[primitive.ObjectID]*entity.CourseProgress)
+	if len(class.StudentIDs) == 0 {
+		return result, nil
+	}
+
+	ids := make([]string, len(class.StudentIDs))
+	for i, id := range class.StudentIDs {
+		ids[i] = id.Hex()
+	}
+	opts := entity.ListOptions{Limit: entity.MaxPageLimit, Sort: "student_id"}.
+		WithFilter("course_id", course.ID.Hex()).
+		WithFilter("student_id", strings.Join(ids, ","))
+
+	var current []*entity.Progress
+	flush := func() {
+		if len(current) > 0 {
+			sid := current[0].StudentID
+			result[sid] = p.evaluateProgress(ctx, course, sid, items, current, false)
+			current = nil
+		}
+	}
+	for {
+		page, err := p.progressRepo.ListProgress(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, rec := range page.Items {
+			if len(current) > 0 && current[0].StudentID != rec.StudentID {
+				flush()
+			}
+			current = append(current, rec)
+		}
+		if page.NextCursor == "" {
+			flush()
+			return result, nil
+		}
+		opts.Cursor = page.NextCursor
+	}
+}
+
+// courseItem is an item that counts towards completion, with the release
+// rule that may hide it from a student.
+type courseItem struct {
+	entity.ItemProgress
+	release *entity.ReleaseRule
+}
+
+// courseItems lists the items that count towards completion under the course's criteria.
+func (p *ProgressUseCase) courseItems(ctx context.Context, course *entity.Course) ([]courseItem, error) {
+	criteria := course.Criteria()
+	var items []courseItem
+
+	if criteria.Lessons != entity.LessonRequirementNone {
+		lessons, err := p.lessonRepo.ListLessonsByCourse(ctx, course.ID)
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		for _, l := range lessons {
+			if l.IsReleased(now) {
+				items = append(items, courseItem{entity.ItemProgress{ItemType: entity.ProgressLesson, ItemID: l.ID, Title: l.Title}, l.Release})
+			}
+		}
+	}
+
+	if criteria.Assignments {
+		assignments, err := p.assignmentRepo.ListAssignmentsByCourse(ctx, course.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range assignments {
+			items = append(items, courseItem{entity.ItemProgress{ItemType: entity.ProgressAssignment, ItemID: a.ID, Title: a.Title}, a.Release})
+		}
+	}
+
+	if criteria.Assessments {
+		assessments, err := p.assessmentRepo.ListAssessmentsByCourse(ctx, course.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range assessments {
+			items = append(items, courseItem{entity.ItemProgress{ItemType: entity.ProgressAssessment, ItemID: a.ID, Title: a.Title}, a.Release})
+		}
+	}
+
+	return items, nil
+}
+
+func (p *ProgressUseCase) requireEnrollment(ctx context.Context, studentID string, courseID primitive.ObjectID) error {
+	enrolled, err := isEnrolled(ctx, p.classRepo, studentID, courseID)
+	if err != nil {
+		return err
+	}
+	if !enrolled {
+		return entity.ErrUnauthorized
+	}
+	return nil
+}
+
+// evaluateProgress scores a student's records against the course items.
+// records must be all of the student's records in the course, as the
+// release rules are checked against them.
+func (p *ProgressUseCase) evaluateProgress(ctx context.Context, course *entity.Course, studentID primitive.ObjectID, items []courseItem, records []*entity.Progress, withItems bool) *entity.CourseProgress {
+	criteria := course.Criteria()
+	eval := releaseEvaluatorFor(ctx, p.lessonRepo, p.assessmentRepo, records)
+
+	type key struct {
+		t  entity.ProgressItemType
+		id primitive.ObjectID
+	}
+	byItem := make(map[key]*entity.Progress, len(records))
+	for _, r := range records {
+		byItem[key{r.ItemType, r.ItemID}] = r
+	}
+
+	cp := &entity.CourseProgress{
+		StudentID: studentID,
+		CourseID:  course.ID,
+	}
+	for _, ci := range items {
+		if eval.hides(ci.release) {
+			continue
+		}
+		item := ci.ItemProgress
+		cp.Total++
+		if rec, ok := byItem[key{item.ItemType, item.ItemID}]; ok {
+			item.Status = rec.Status
+			item.Score = rec.Score
+			item.Done = isDone(criteria, rec)
+		}
+		if item.Done {
+			cp.Completed++
+		}
+		if withItems {
+			cp.Items = append(cp.Items, item)
+		}
+	}
+	if cp.Total > 0 {
+		cp.Percent = float64(cp.Completed) * 100 / float64(cp.Total)
+	}
+
+	return cp
+}
+
+func isDone(criteria entity.CompletionCriteria, rec *entity.Progress) bool {
+	switch rec.ItemType {
+	case entity.ProgressLesson:
+		if criteria.Lessons == entity.LessonRequirementViewed {
+			return rec.Status == entity.ProgressViewed || rec.Status == entity.ProgressCompleted
+		}
+		return rec.Status == entity.ProgressCompleted
+	case entity.ProgressAssignment:
+		return rec.Status == entity.ProgressSubmitted
+	case entity.ProgressAssessment:
+		return rec.Status == entity.ProgressTaken && rec.Score != nil && *rec.Score >= criteria.PassingScore
+	}
+	return false
+}
+
+func teachesClass(class *entity.Class, teacherID string) bool {
+	for _, id := range class.TeacherIDs {
+		if id.Hex() == teacherID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,260 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReleaseUseCase lets teachers gate lessons, assignments and assessments
+// behind release conditions.
+type ReleaseUseCase struct {
+	courseRepo     CourseRepository
+	lessonRepo     LessonRepository
+	assignmentRepo AssignmentRepository
+	assessmentRepo AssessmentRepository
+}
+
+func NewReleaseUseCase(
+	courseRepo CourseRepository,
+	lessonRepo LessonRepository,
+	assignmentRepo AssignmentRepository,
+	assessmentRepo AssessmentRepository,
+) *ReleaseUseCase {
+	return &ReleaseUseCase{
+		courseRepo:     courseRepo,
+		lessonRepo:     lessonRepo,
+		assignmentRepo: assignmentRepo,
+		assessmentRepo: assessmentRepo,
+	}
+}
+
+// SetLessonRelease replaces the lesson's release rule. A nil or empty rule
+// makes the lesson unconditionally available again.
+func (r *ReleaseUseCase) SetLessonRelease(ctx context.Context, teacherID, lessonID string, rule *entity.ReleaseRule) error {
+	lesson, err := r.lessonRepo.GetLesson(ctx, lessonID)
+	if err != nil {
+		return err
+	}
+	rule, err = r.prepare(ctx, teacherID, lesson.CourseID, lesson.ID, rule)
+	if err != nil {
+		return err
+	}
+
+	return r.lessonRepo.UpdateLessonRelease(ctx, lesson.ID, rule)
+}
+
+func (r *ReleaseUseCase) SetAssignmentRelease(ctx context.Context, teacherID, assignmentID string, rule *entity.ReleaseRule) error {
+	assignment, err := r.assignmentRepo.GetAssignment(ctx, assignmentID)
+	if err != nil {
+		return err
+	}
+	rule, err = r.prepare(ctx, teacherID, assignment.CourseID, assignment.ID, rule)
+	if err != nil {
+		return err
+	}
+
+	return r.assignmentRepo.UpdateAssignmentRelease(ctx, assignment.ID, rule)
+}
+
+func (r *ReleaseUseCase) SetAssessmentRelease(ctx context.Context, teacherID, assessmentID string, rule *entity.ReleaseRule) error {
+	assessment, err := r.assessmentRepo.GetAssessment(ctx, assessmentID)
+	if err != nil {
+		return err
+	}
+	rule, err = r.prepare(ctx, teacherID, assessment.CourseID, assessment.ID, rule)
+	if err != nil {
+		return err
+	}
+
+	return r.assessmentRepo.UpdateAssessmentRelease(ctx, assessment.ID, rule)
+}
+
+// prepare checks ownership and validates that every condition refers to an
+// item of the same course other than the gated item itself.
+func (r *ReleaseUseCase) prepare(ctx context.Context, teacherID string, courseID, itemID primitive.ObjectID, rule *entity.ReleaseRule) (*entity.ReleaseRule, error) {
+	course, err := r.courseRepo.GetCourse(ctx, courseID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if !isAssignedTeacher(course, teacherID) {
+		return nil, entity.ErrUnauthorized
+	}
+
+	if rule == nil || len(rule.Conditions) == 0 {
+		return nil, nil
+	}
+
+	for _, c := range rule.Conditions {
+		switch c.Type {
+		case entity.ConditionLessonCompleted:
+			if c.LessonID == itemID {
+				return nil, entity.ErrInvalidReleaseRule
+			}
+			lesson, err := r.lessonRepo.GetLesson(ctx, c.LessonID.Hex())
+			if err != nil || lesson.CourseID != courseID {
+				return nil, entity.ErrInvalidReleaseRule
+			}
+		case entity.ConditionAssessmentScore:
+			if c.AssessmentID == itemID || c.MinScore < 0 || c.MinScore > 100 {
+				return nil, entity.ErrInvalidReleaseRule
+			}
+			assessment, err := r.assessmentRepo.GetAssessment(ctx, c.AssessmentID.Hex())
+			if err != nil || assessment.CourseID != courseID {
+				return nil, entity.ErrInvalidReleaseRule
+			}
+		case entity.ConditionAfterDate:
+			if c.Date == nil || c.Date.IsZero() {
+				return nil, entity.ErrInvalidReleaseRule
+			}
+		default:
+			return nil, entity.ErrInvalidReleaseRule
+		}
+	}
+
+	return rule, nil
+}
+
+// releaseEvaluator checks release rules for one student in one course. It
+// loads the student's progress once and caches the titles it mentions in
+// lock reasons.
+type releaseEvaluator struct {
+	ctx            context.Context
+	now            time.Time
+	lessonRepo     LessonRepository
+	assessmentRepo AssessmentRepository
+	progress       map[primitive.ObjectID]*entity.Progress
+	titles         map[primitive.ObjectID]string
+}
+
+func newReleaseEvaluator(
+	ctx context.Context,
+	progressRepo ProgressRepository,
+	lessonRepo LessonRepository,
+	assessmentRepo AssessmentRepository,
+	studentID, courseID primitive.ObjectID,
+) (*releaseEvaluator, error) {
+	records, err := progressRepo.ListProgressByStudentAndCourse(ctx, studentID, courseID)
+	if err != nil {
+		return nil, err
+	}
+	return releaseEvaluatorFor(ctx, lessonRepo, assessmentRepo, records), nil
+}
+
+// releaseEvaluatorFor evaluates rules against progress records the caller
+// already holds: all of one student's records in the course.
+func releaseEvaluatorFor(ctx context.Context, lessonRepo LessonRepository, assessmentRepo AssessmentRepository, records []*entity.Progress) *releaseEvaluator {
+	progress := make(map[primitive.ObjectID]*entity.Progress, len(records))
+	for _, rec := range records {
+		progress[rec.ItemID] = rec
+	}
+
+	return &releaseEvaluator{
+		ctx:            ctx,
+		now:            time.Now(),
+		lessonRepo:     lessonRepo,
+		assessmentRepo: assessmentRepo,
+		progress:       progress,
+		titles:         make(map[primitive.ObjectID]string),
+	}
+}
+
+// check returns the unmet conditions of a rule as human readable reasons,
+// and whether the item should be hidden rather than shown as locked.
+func (e *releaseEvaluator) check(rule *entity.ReleaseRule) (entity.Availability, bool) {
+	if rule == nil {
+		return entity.Availability{}, false
+	}
+
+	var reasons []string
+	for _, c := range rule.Conditions {
+		if e.met(c) {
+			continue
+		}
+		switch c.Type {
+		case entity.ConditionLessonCompleted:
+			reasons = append(reasons, fmt.Sprintf("Complete the lesson %q first", e.lessonTitle(c.LessonID)))
+		case entity.ConditionAssessmentScore:
+			reasons = append(reasons, fmt.Sprintf("Score at least %g%% on %q", c.MinScore, e.assessmentTitle(c.AssessmentID)))
+		case entity.ConditionAfterDate:
+			reasons = append(reasons, "Available after "+c.Date.UTC().Format(time.RFC3339))
+		}
+	}
+
+	if len(reasons) == 0 {
+		return entity.Availability{}, false
+	}
+	return entity.Availability{Locked: true, LockReasons: reasons}, rule.HideUntilMet
+}
+
+// locked reports whether rule keeps its item from the student, whether it
+// is shown as locked or hidden. Unlike check it looks up no titles, so it
+// is cheap to ask for many students.
+func (e *releaseEvaluator) locked(rule *entity.ReleaseRule) bool {
+	if rule == nil {
+		return false
+	}
+	for _, c := range rule.Conditions {
+		if !e.met(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// hides reports whether rule hides its item from the student.
+func (e *releaseEvaluator) hides(rule *entity.ReleaseRule) bool {
+	return rule != nil && rule.HideUntilMet && e.locked(rule)
+}
+
+func (e *releaseEvaluator) met(c entity.ReleaseCondition) bool {
+	switch c.Type {
+	case entity.ConditionLessonCompleted:
+		rec, ok := e.progress[c.LessonID]
+		return ok && rec.Status == entity.ProgressCompleted
+	case entity.ConditionAssessmentScore:
+		rec, ok := e.progress[c.AssessmentID]
+		return ok && rec.Score != nil && *rec.Score >= c.MinScore
+	case entity.ConditionAfterDate:
+		return c.Date == nil || !e.now.Before(*c.Date)
+	}
+	return true
+}
+
+// gate applies the release rule of item: it reports whether the item is
+// hidden from the student, and locks it when it is shown but its
+// conditions are unmet.
+func (e *releaseEvaluator) gate(item entity.Gated) bool {
+	availability, hidden := e.check(item.ReleaseRule())
+	if availability.Locked && !hidden {
+		item.Lock(availability)
+	}
+	return hidden
+}
+
+func (e *releaseEvaluator) lessonTitle(id primitive.ObjectID) string {
+	if t, ok := e.titles[id]; ok {
+		return t
+	}
+	title := "another lesson"
+	if l, err := e.lessonRepo.GetLesson(e.ctx, id.Hex()); err == nil {
+		title = l.Title
+	}
+	e.titles[id] = title
+	return title
+}
+
+func (e *releaseEvaluator) assessmentTitle(id primitive.ObjectID) string {
+	if t, ok := e.titles[id]; ok {
+		return t
+	}
+	title := "another assessment"
+	if a, err := e.assessmentRepo.GetAssessment(e.ctx, id.Hex()); err == nil {
+		title = a.Title
+	}
+	e.titles[id] = title
+	return title
+}
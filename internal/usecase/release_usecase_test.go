@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memLessons struct{ LessonRepository }
+
+func (memLessons) GetLesson(ctx context.Context, id string) (*entity.Lesson, error) {
+	return nil, entity.ErrLessonNotFound
+}
+
+type memAssessments struct{ AssessmentRepository }
+
+func (memAssessments) GetAssessment(ctx context.Context, id string) (*entity.Assessment, error) {
+	return nil, entity.ErrAssessmentNotFound
+}
+
+func TestReleaseGate(t *testing.T) {
+	lesson, quiz := primitive.NewObjectID(), primitive.NewObjectID()
+	score := func(s float64) *float64 { return &s }
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	records := []*entity.Progress{
+		{ItemID: lesson, Status: entity.ProgressCompleted},
+		{ItemID: quiz, Status: entity.ProgressTaken, Score: score(70)},
+	}
+
+	tests := []struct {
+		name       string
+		rule       *entity.ReleaseRule
+		wantHidden bool
+		wantLocked bool
+	}{
+		{"no rule", nil, false, false},
+		{"lesson completed", &entity.ReleaseRule{Conditions: []entity.ReleaseCondition{{Type: entity.ConditionLessonCompleted, LessonID: lesson}}}, false, false},
+		{"other lesson", &entity.ReleaseRule{Conditions: []entity.ReleaseCondition{{Type: entity.ConditionLessonCompleted, LessonID: primitive.NewObjectID()}}}, false, true},
+		{"score reached", &entity.ReleaseRule{Conditions: []entity.ReleaseCondition{{Type: entity.ConditionAssessmentScore, AssessmentID: quiz, MinScore: 70}}}, false, false},
+		{"score missed", &entity.ReleaseRule{Conditions: []entity.ReleaseCondition{{Type: entity.ConditionAssessmentScore, AssessmentID: quiz, MinScore: 80}}}, false, true},
+		{"date passed", &entity.ReleaseRule{Conditions: []entity.ReleaseCondition{{Type: entity.ConditionAfterDate, Date: &past}}}, false, false},
+		{"date ahead", &entity.ReleaseRule{Conditions: []entity.ReleaseCondition{{Type: entity.ConditionAfterDate, Date: &future}}}, false, true},
+		{"date ahead, hidden", &entity.ReleaseRule{HideUntilMet: true, Conditions: []entity.ReleaseCondition{{Type: entity.ConditionAfterDate, Date: &future}}}, true, true},
+		{"met, hidden until then", &entity.ReleaseRule{HideUntilMet: true, Conditions: []entity.ReleaseCondition{{Type: entity.ConditionAfterDate, Date: &past}}}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eval := releaseEvaluatorFor(context.Background(), memLessons{}, memAssessments{}, records)
+			if got := eval.locked(tt.rule); got != tt.wantLocked {
+				t.Errorf("locked() = %v, want %v", got, tt.wantLocked)
+			}
+			if got := eval.hides(tt.rule); got != tt.wantHidden {
+				t.Errorf("hides() = %v, want %v", got, tt.wantHidden)
+			}
+
+			a := &entity.Assignment{Title: "Essay", Description: "Write 500 words", Release: tt.rule}
+			if hidden := eval.gate(a); hidden != tt.wantHidden {
+				t.Fatalf("gate() = %v, want %v", hidden, tt.wantHidden)
+			}
+			if tt.wantHidden {
+				return
+			}
+			if a.Locked != tt.wantLocked || (a.Description == "") != tt.wantLocked || a.Title != "Essay" {
+				t.Errorf("gated assignment = %+v, want locked: %v, description withheld: %v", a, tt.wantLocked, tt.wantLocked)
+			}
+			if tt.wantLocked && len(a.LockReasons) == 0 {
+				t.Error("locked without reasons")
+			}
+		})
+	}
+}
+
+func TestProgressLeavesOutHiddenItems(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	hidden := &entity.ReleaseRule{HideUntilMet: true, Conditions: []entity.ReleaseCondition{{Type: entity.ConditionAfterDate, Date: &future}}}
+	locked := &entity.ReleaseRule{Conditions: []entity.ReleaseCondition{{Type: entity.ConditionAfterDate, Date: &future}}}
+	done, open, secret, later := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+
+	items := []courseItem{
+		{entity.ItemProgress{ItemType: entity.ProgressLesson, ItemID: done, Title: "Intro"}, nil},
+		{entity.ItemProgress{ItemType: entity.ProgressLesson, ItemID: open, Title: "Basics"}, nil},
+		{entity.ItemProgress{ItemType: entity.ProgressLesson, ItemID: secret, Title: "Final exam answers"}, hidden},
+		{entity.ItemProgress{ItemType: entity.ProgressLesson, ItemID: later, Title: "Advanced"}, locked},
+	}
+	records := []*entity.Progress{{ItemType: entity.ProgressLesson, ItemID: done, Status: entity.ProgressCompleted}}
+
+	p := &ProgressUseCase{}
+	cp := p.evaluateProgress(context.Background(), &entity.Course{}, primitive.NewObjectID(), items, records, true)
+	if cp.Total != 3 || cp.Completed != 1 {
+		t.Errorf("progress = %d of %d, want 1 of 3", cp.Completed, cp.Total)
+	}
+	for _, item := range cp.Items {
+		if item.ItemID == secret {
+			t.Errorf("hidden item %q listed", item.Title)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ReminderRepository interface {
+	ClaimReminder(ctx context.Context, rec *entity.ReminderSend) (bool, error)
+	ReleaseReminder(ctx context.Context, id string) error
+}
+
+type ReminderUseCase struct {
+	assignmentRepo AssignmentRepository
+	assessmentRepo AssessmentRepository
+	submitRepo     SubmissionRepository
+	courseRepo     CourseRepository
+	classRepo      ClassRepository
+	userRepo       UserRepository
+	reminderRepo   ReminderRepository
+	progressRepo   ProgressRepository
+	lessonRepo     LessonRepository
+	notifier       Notifier
+	offsets        []time.Duration
+}
+
+// NewReminderUseCase builds the due-date reminder logic. offsets are the lead
+// times before a deadline at which students are reminded, e.g. 48h and 2h.
+func NewReminderUseCase(
+	assignmentRepo AssignmentRepository,
+	assessmentRepo AssessmentRepository,
+	submitRepo SubmissionRepository,
+	courseRepo CourseRepository,
+	classRepo ClassRepository,
+	userRepo UserRepository,
+	reminderRepo ReminderRepository,
+	progressRepo ProgressRepository,
+	lessonRepo LessonRepository,
+	notifier Notifier,
+	offsets []time.Duration,
+) *ReminderUseCase {
+	sorted := append([]time.Duration{}, offsets...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return &ReminderUseCase{
+		assignmentRepo: assignmentRepo,
+		assessmentRepo: assessmentRepo,
+		submitRepo:     submitRepo,
+		courseRepo:     courseRepo,
+		classRepo:      classRepo,
+		userRepo:       userRepo,
+		reminderRepo:   reminderRepo,
+		progressRepo:   progressRepo,
+		lessonRepo:     lessonRepo,
+		notifier:       notifier,
+		offsets:        sorted,
+	}
+}
+
+// SendDueReminders reminds enrolled students of assignments and assessments
+// whose deadline falls within the largest offset from now. Each item gets at
+// most one reminder per offset and student; only the tightest offset that
+// applies is used, so an item created close to its deadline does not trigger
+// every reminder at once. Students the item's release rule still keeps it
+// from, locked or hidden, are not reminded.
+func (r *ReminderUseCase) SendDueReminders(ctx context.Context, now time.Time) error {
+	if len(r.offsets) == 0 {
+		return nil
+	}
+	horizon := now.Add(r.offsets[len(r.offsets)-1])
+
+	assignments, err := r.assignmentRepo.ListAssignmentsDueBetween(ctx, now, horizon)
+	if err != nil {
+		return err
+	}
+	for _, a := range assignments {
+		err := r.remind(ctx, entity.ReminderAssignment, a.ID, a.CourseID, a.Title, a.Release, a.DueDate, now, func(student *entity.User) (bool, error) {
+			subs, err := r.submitRepo.ListSubmissionsByAssignmentAndStudent(ctx, a.ID, student.ID)
+			return len(subs) > 0, err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	assessments, err := r.assessmentRepo.ListAssessmentsBetween(ctx, now, horizon)
+	if err != nil {
+		return err
+	}
+	for _, a := range assessments {
+		err := r.remind(ctx, entity.ReminderAssessment, a.ID, a.CourseID, a.Title, a.Release, a.Date, now, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *ReminderUseCase) remind(
+	ctx context.Context,
+	itemType entity.ReminderItemType,
+	itemID, courseID primitive.ObjectID,
+	title string,
+	release *entity.ReleaseRule,
+	due, now time.Time,
+	done func(student *entity.User) (bool, error),
+) error {
+	offset, ok := r.offsetFor(due.Sub(now))
+	if !ok {
+		return nil
+	}
+
+	course, err := r.courseRepo.GetCourse(ctx, courseID.Hex())
+	if errors.Is(err, entity.ErrCourseNotFound) {
+		// The course is in the trash; its students are not reminded.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	students, err := studentsInCourse(ctx, r.classRepo, r.userRepo, courseID)
+	if err != nil {
+		return err
+	}
+
+	for _, student := range students {
+		if release != nil {
+			eval, err := newReleaseEvaluator(ctx, r.progressRepo, r.lessonRepo, r.assessmentRepo, student.ID, courseID)
+			if err != nil {
+				return err
+			}
+			if eval.locked(release) {
+				continue
+			}
+		}
+		if done != nil {
+			finished, err := done(student)
+			if err != nil {
+				return err
+			}
+			if finished {
+				continue
+			}
+		}
+
+		rec := entity.NewReminderSend(itemType, itemID, student.ID, offset)
+		claimed, err := r.reminderRepo.ClaimReminder(ctx, rec)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		err = r.notifier.Notify(ctx, student, entity.NotificationDueReminder, map[string]interface{}{
+			"ItemType":    string(itemType),
+			"Title":       title,
+			"CourseName":  course.Name,
+			"DueDate":     due,
+			"HoursLeft":   int(math.Round(offset.Hours())),
+			"MinutesLeft": int(math.Round(offset.Minutes())),
+		})
+		if err != nil {
+			// Let the next run retry this reminder. If the claim cannot be
+			// released, this reminder is not sent at all.
+			if relErr := r.reminderRepo.ReleaseReminder(ctx, rec.ID); relErr != nil {
+				return errors.Join(err, fmt.Errorf("release reminder %s: %w", rec.ID, relErr))
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *ReminderUseCase) offsetFor(left time.Duration) (time.Duration, bool) {
+	for _, o := range r.offsets {
+		if left <= o {
+			return o, true
+		}
+	}
+	return 0, false
+}
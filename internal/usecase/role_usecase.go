@@ -0,0 +1,248 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+)
+
+type RoleRepository interface {
+	ListRoles(ctx context.Context) ([]*entity.RoleDefinition, error)
+	GetRole(ctx context.Context, name entity.Role) (*entity.RoleDefinition, error)
+	CreateRole(ctx context.Context, role *entity.RoleDefinition) error
+	UpdateRole(ctx context.Context, name entity.Role, description string, permissions []entity.Permission, at time.Time) error
+	DeleteRole(ctx context.Context, name entity.Role) error
+}
+
+type RoleUserRepository interface {
+	CountByRole(ctx context.Context, role entity.Role) (int64, error)
+}
+
+// Every permission check reads the roles from memory. They are read again
+// after roleCacheTTL, so a change made on another instance applies there
+// within that time; changes made here apply at once.
+const roleCacheTTL = 30 * time.Second
+
+// RoleUseCase manages the role definitions and answers which permissions a
+// role has.
+type RoleUseCase struct {
+	roleRepo RoleRepository
+	userRepo RoleUserRepository
+
+	mu       sync.Mutex
+	roles    map[entity.Role]*entity.RoleDefinition
+	loadedAt time.Time
+}
+
+func NewRoleUseCase(roleRepo RoleRepository, userRepo RoleUserRepository) *RoleUseCase {
+	return &RoleUseCase{roleRepo: roleRepo, userRepo: userRepo}
+}
+
+func (u *RoleUseCase) definitions(ctx context.Context) (map[entity.Role]*entity.RoleDefinition, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.roles != nil && time.Since(u.loadedAt) < roleCacheTTL {
+		return u.roles, nil
+	}
+
+	list, err := u.roleRepo.ListRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	roles := make(map[entity.Role]*entity.RoleDefinition, len(list))
+	for _, r := range list {
+		roles[r.Name] = r
+	}
+	u.roles, u.loadedAt = roles, time.Now()
+	return roles, nil
+}
+
+func (u *RoleUseCase) invalidate() {
+	u.mu.Lock()
+	u.roles = nil
+	u.mu.Unlock()
+}
+
+// HasPermission reports whether role grants perm. Unknown roles grant
+// nothing.
+func (u *RoleUseCase) HasPermission(ctx context.Context, role entity.Role, perm entity.Permission) (bool, error) {
+	roles, err := u.definitions(ctx)
+	if err != nil {
+		return false, err
+	}
+	def, ok := roles[role]
+	return ok && def.Has(perm), nil
+}
+
+// ActorCan reports whether the signed-in user of the request has perm.
+func (u *RoleUseCase) ActorCan(ctx context.Context, perm entity.Permission) (bool, error) {
+	role, _ := ctx.Value("userRole").(entity.Role)
+	return u.HasPermission(ctx, role, perm)
+}
+
+// Permissions returns the permissions of role, or none for an unknown role.
+func (u *RoleUseCase) Permissions(ctx context.Context, role entity.Role) ([]entity.Permission, error) {
+	roles, err := u.definitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if def, ok := roles[role]; ok {
+		return def.Permissions, nil
+	}
+	return []entity.Permission{}, nil
+}
+
+// CheckRole returns entity.ErrRoleNotFound unless role is defined.
+func (u *RoleUseCase) CheckRole(ctx context.Context, role entity.Role) error {
+	roles, err := u.definitions(ctx)
+	if err != nil {
+		return err
+	}
+	if _, ok := roles[role]; !ok {
+		return entity.ErrRoleNotFound
+	}
+	return nil
+}
+
+// CanGrant reports whether the signed-in user may give role to someone.
+// See canHandOut for the rule.
+func (u *RoleUseCase) CanGrant(ctx context.Context, role entity.Role) (bool, error) {
+	perms, err := u.Permissions(ctx, role)
+	if err != nil {
+		return false, err
+	}
+	return u.canHandOut(ctx, perms)
+}
+
+// canHandOut reports whether the signed-in user may hand out perms, in a
+// role they grant or define. Users who manage users or roles may hand out
+// any permission but the privileged ones, so that admins can make someone
+// a teacher or a student without teaching or studying themselves. The
+// privileged permissions, which decide who may do what, can only be
+// handed out by users who hold them, so that nobody can hand out more
+// power than they have.
+func (u *RoleUseCase) canHandOut(ctx context.Context, perms []entity.Permission) (bool, error) {
+	manager, err := u.actorManages(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range perms {
+		if manager && !entity.IsPrivilegedPermission(p) {
+			continue
+		}
+		ok, err := u.ActorCan(ctx, p)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (u *RoleUseCase) actorManages(ctx context.Context) (bool, error) {
+	for _, p := range []entity.Permission{entity.PermUsersManage, entity.PermRolesManage} {
+		ok, err := u.ActorCan(ctx, p)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+func (u *RoleUseCase) ListRoles(ctx context.Context) ([]*entity.RoleDefinition, error) {
+	return u.roleRepo.ListRoles(ctx)
+}
+
+func (u *RoleUseCase) GetRole(ctx context.Context, name entity.Role) (*entity.RoleDefinition, error) {
+	return u.roleRepo.GetRole(ctx, name)
+}
+
+// CreateRole defines a new role. As with granting a role, the signed-in
+// user has to be allowed to hand out every permission the role gets.
+func (u *RoleUseCase) CreateRole(ctx context.Context, role *entity.RoleDefinition) error {
+	role.Description = strings.TrimSpace(role.Description)
+	if !entity.IsValidRoleName(role.Name) {
+		return entity.ErrInvalidRole
+	}
+	if err := u.checkPermissions(ctx, role.Permissions); err != nil {
+		return err
+	}
+	if role.Permissions == nil {
+		role.Permissions = []entity.Permission{}
+	}
+
+	now := time.Now()
+	role.Builtin = false
+	role.CreatedAt, role.UpdatedAt = now, now
+	if err := u.roleRepo.CreateRole(ctx, role); err != nil {
+		return err
+	}
+	u.invalidate()
+	return nil
+}
+
+// UpdateRole replaces the description and permissions of a role. Users
+// with the role get the new permissions with their next request served by
+// this instance, and within roleCacheTTL on the others. The admin role keeps
+// roles.manage, so that roles can always be managed.
+func (u *RoleUseCase) UpdateRole(ctx context.Context, name entity.Role, description string, permissions []entity.Permission) error {
+	if err := u.checkPermissions(ctx, permissions); err != nil {
+		return err
+	}
+	if permissions == nil {
+		permissions = []entity.Permission{}
+	}
+	if name == entity.RoleAdmin && !(&entity.RoleDefinition{Permissions: permissions}).Has(entity.PermRolesManage) {
+		return entity.ErrRoleLockout
+	}
+	if err := u.roleRepo.UpdateRole(ctx, name, strings.TrimSpace(description), permissions, time.Now()); err != nil {
+		return err
+	}
+	u.invalidate()
+	return nil
+}
+
+// DeleteRole deletes a role that is neither built in nor assigned to any
+// user.
+func (u *RoleUseCase) DeleteRole(ctx context.Context, name entity.Role) error {
+	role, err := u.roleRepo.GetRole(ctx, name)
+	if err != nil {
+		return err
+	}
+	if role.Builtin {
+		return entity.ErrBuiltinRole
+	}
+	n, err := u.userRepo.CountByRole(ctx, name)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return entity.ErrRoleInUse
+	}
+
+	if err := u.roleRepo.DeleteRole(ctx, name); err != nil {
+		return err
+	}
+	u.invalidate()
+	return nil
+}
+
+// checkPermissions accepts known permissions that the signed-in user may
+// hand out.
+func (u *RoleUseCase) checkPermissions(ctx context.Context, perms []entity.Permission) error {
+	for _, p := range perms {
+		if !entity.IsValidPermission(p) {
+			return entity.ErrInvalidRole
+		}
+	}
+	ok, err := u.canHandOut(ctx, perms)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return entity.ErrPermissionDenied
+	}
+	return nil
+}
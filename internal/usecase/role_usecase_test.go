@@ -0,0 +1,78 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testRoles are the seeded roles plus a help desk role that manages users
+// without holding any privileged permission, and a role a registrar holds
+// all permissions of.
+func testRoles() *RoleUseCase {
+	roles := seededRoles()
+	roles.roles = append(roles.roles,
+		&entity.RoleDefinition{Name: "helpdesk", Permissions: []entity.Permission{entity.PermUsersRead, entity.PermUsersManage}},
+		&entity.RoleDefinition{Name: "enroller", Permissions: []entity.Permission{entity.PermClassesRead, entity.PermEnrollmentWrite}},
+	)
+	return NewRoleUseCase(roles, roles)
+}
+
+func TestCanGrant(t *testing.T) {
+	tests := []struct {
+		actor entity.Role
+		role  entity.Role
+		want  bool
+	}{
+		{entity.RoleAdmin, entity.RoleAdmin, true},
+		{entity.RoleAdmin, entity.RoleTeacher, true},
+		{"helpdesk", entity.RoleTeacher, true},
+		{"helpdesk", entity.RoleStudent, true},
+		{"helpdesk", entity.RoleAdmin, false},
+		{"registrar", "enroller", true},
+		{"registrar", entity.RoleStudent, false},
+		{entity.RoleTeacher, entity.RoleStudent, false},
+		{entity.RoleStudent, entity.RoleStudent, true},
+		{entity.RoleStudent, entity.RoleGuardian, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.actor)+" grants "+string(tt.role), func(t *testing.T) {
+			roles := testRoles()
+			got, err := roles.CanGrant(actorContext(primitive.NewObjectID(), tt.actor), tt.role)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("CanGrant() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPermissions(t *testing.T) {
+	tests := []struct {
+		name  string
+		actor entity.Role
+		perms []entity.Permission
+		want  error
+	}{
+		{"admin hands out privileged", entity.RoleAdmin, []entity.Permission{entity.PermRolesManage, entity.PermImpersonate}, nil},
+		{"manager hands out what it lacks", "helpdesk", []entity.Permission{entity.PermGradesWrite, entity.PermLearn}, nil},
+		{"manager hands out privileged", "helpdesk", []entity.Permission{entity.PermUsersRead, entity.PermImpersonate}, entity.ErrPermissionDenied},
+		{"non-manager hands out held", "registrar", []entity.Permission{entity.PermEnrollmentWrite}, nil},
+		{"non-manager hands out unheld", "registrar", []entity.Permission{entity.PermEnrollmentWrite, entity.PermCoursesWrite}, entity.ErrPermissionDenied},
+		{"unknown permission", entity.RoleAdmin, []entity.Permission{"courses.everything"}, entity.ErrInvalidRole},
+		{"no permissions", entity.RoleStudent, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roles := testRoles()
+			err := roles.checkPermissions(actorContext(primitive.NewObjectID(), tt.actor), tt.perms)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("checkPermissions() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
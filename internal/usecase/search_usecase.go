@@ -0,0 +1,345 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultSearchLimit = 10
+	maxSearchLimit     = 50
+	snippetLength      = 160
+)
+
+type SearchRepository interface {
+	SearchCourses(ctx context.Context, q string, courseIDs []primitive.ObjectID, limit int) ([]entity.Scored[*entity.Course], error)
+	SearchLessons(ctx context.Context, q string, courseIDs []primitive.ObjectID, publishedOnly bool, limit int) ([]entity.Scored[*entity.Lesson], error)
+	SearchAssignments(ctx context.Context, q string, courseIDs []primitive.ObjectID, limit int) ([]entity.Scored[*entity.Assignment], error)
+	SearchAnnouncements(ctx context.Context, q string, restricted bool, courseIDs, classIDs []primitive.ObjectID, limit int) ([]entity.Scored[*entity.Announcement], error)
+	SearchUsers(ctx context.Context, q string, userIDs []primitive.ObjectID, limit int) ([]entity.Scored[*entity.User], error)
+}
+
+type SearchUseCase struct {
+	searchRepo     SearchRepository
+	courseRepo     CourseRepository
+	classRepo      ClassRepository
+	progressRepo   ProgressRepository
+	lessonRepo     LessonRepository
+	assessmentRepo AssessmentRepository
+	roles          *RoleUseCase
+}
+
+func NewSearchUseCase(
+	searchRepo SearchRepository,
+	courseRepo CourseRepository,
+	classRepo ClassRepository,
+	progressRepo ProgressRepository,
+	lessonRepo LessonRepository,
+	assessmentRepo AssessmentRepository,
+	roles *RoleUseCase,
+) *SearchUseCase {
+	return &SearchUseCase{
+		searchRepo:     searchRepo,
+		courseRepo:     courseRepo,
+		classRepo:      classRepo,
+		progressRepo:   progressRepo,
+		lessonRepo:     lessonRepo,
+		assessmentRepo: assessmentRepo,
+		roles:          roles,
+	}
+}
+
+// searchScope is what a caller may see. Users who may read every course are
+// unrestricted; everyone else only sees the courses, classes and users
+// listed here.
+type searchScope struct {
+	restricted bool
+	student    bool
+	courseIDs  []primitive.ObjectID
+	classIDs   []primitive.ObjectID
+	userIDs    []primitive.ObjectID
+}
+
+// Search runs q against every requested type the caller may search and
+// returns the hits grouped by type. An empty types list searches them all.
+func (s *SearchUseCase) Search(ctx context.Context, userID string, role entity.Role, q string, types []entity.SearchType, limit int) (*entity.SearchResults, error) {
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return nil, entity.ErrInvalidSearch
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+	if len(types) == 0 {
+		types = entity.SearchTypes
+	}
+
+	uid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+	scope, err := s.scopeFor(ctx, uid, role)
+	if err != nil {
+		return nil, err
+	}
+
+	results := &entity.SearchResults{Query: q, Results: make(map[entity.SearchType][]entity.SearchHit)}
+	for _, t := range types {
+		var hits []entity.SearchHit
+		switch t {
+		case entity.SearchCourses:
+			hits, err = s.searchCourses(ctx, q, scope, limit)
+		case entity.SearchLessons:
+			hits, err = s.searchLessons(ctx, uid, q, scope, limit)
+		case entity.SearchAssignments:
+			hits, err = s.searchAssignments(ctx, uid, q, scope, limit)
+		case entity.SearchAnnouncements:
+			hits, err = s.searchAnnouncements(ctx, q, scope, limit)
+		case entity.SearchUsers:
+			hits, err = s.searchUsers(ctx, q, scope, limit)
+		default:
+			return nil, entity.ErrInvalidSearch
+		}
+		if err != nil {
+			return nil, err
+		}
+		results.Results[t] = hits
+	}
+
+	return results, nil
+}
+
+func (s *SearchUseCase) scopeFor(ctx context.Context, userID primitive.ObjectID, role entity.Role) (*searchScope, error) {
+	all, err := s.roles.HasPermission(ctx, role, entity.PermCoursesRead)
+	if err != nil {
+		return nil, err
+	}
+	teaches, err := s.roles.HasPermission(ctx, role, entity.PermTeachingView)
+	if err != nil {
+		return nil, err
+	}
+	learns, err := s.roles.HasPermission(ctx, role, entity.PermLearn)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case all:
+		return &searchScope{}, nil
+
+	case teaches:
+		scope := &searchScope{restricted: true}
+		courses, err := s.courseRepo.ListCoursesByTeacher(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range courses {
+			scope.courseIDs = appendUnique(scope.courseIDs, c.ID)
+		}
+		classes, err := s.classRepo.ListClassesByTeacher(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, cl := range classes {
+			scope.classIDs = appendUnique(scope.classIDs, cl.ID)
+			if !cl.CourseID.IsZero() {
+				scope.courseIDs = appendUnique(scope.courseIDs, cl.CourseID)
+			}
+			for _, id := range cl.StudentIDs {
+				scope.userIDs = appendUnique(scope.userIDs, id)
+			}
+		}
+		return scope, nil
+
+	case learns:
+		scope := &searchScope{restricted: true, student: true}
+		classes, err := s.classRepo.ListClassesByStudent(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, cl := range classes {
+			scope.classIDs = appendUnique(scope.classIDs, cl.ID)
+			if !cl.CourseID.IsZero() {
+				scope.courseIDs = appendUnique(scope.courseIDs, cl.CourseID)
+			}
+		}
+		return scope, nil
+	}
+
+	return nil, entity.ErrUnauthorized
+}
+
+func (s *SearchUseCase) searchCourses(ctx context.Context, q string, scope *searchScope, limit int) ([]entity.SearchHit, error) {
+	if scope.restricted && len(scope.courseIDs) == 0 {
+		return []entity.SearchHit{}, nil
+	}
+	found, err := s.searchRepo.SearchCourses(ctx, q, scope.courseIDs, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]entity.SearchHit, 0, len(found))
+	for _, f := range found {
+		hits = append(hits, entity.SearchHit{
+			ID:       f.Item.ID,
+			Title:    f.Item.Name,
+			Snippet:  snippet(f.Item.Description),
+			CourseID: f.Item.ID,
+			Score:    f.Score,
+		})
+	}
+	return hits, nil
+}
+
+// searchLessons only returns released lessons to students, and drops those
+// whose release conditions hide them.
+func (s *SearchUseCase) searchLessons(ctx context.Context, studentID primitive.ObjectID, q string, scope *searchScope, limit int) ([]entity.SearchHit, error) {
+	if scope.restricted && len(scope.courseIDs) == 0 {
+		return []entity.SearchHit{}, nil
+	}
+	found, err := s.searchRepo.SearchLessons(ctx, q, scope.courseIDs, scope.student, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	evals := make(map[primitive.ObjectID]*releaseEvaluator)
+	hits := make([]entity.SearchHit, 0, len(found))
+	for _, f := range found {
+		if scope.student {
+			eval, err := s.releaseEvaluator(ctx, evals, studentID, f.Item.CourseID)
+			if err != nil {
+				return nil, err
+			}
+			if eval.gate(f.Item) {
+				continue
+			}
+		}
+		hits = append(hits, entity.SearchHit{
+			ID:       f.Item.ID,
+			Title:    f.Item.Title,
+			Snippet:  snippet(f.Item.Body),
+			CourseID: f.Item.CourseID,
+			Score:    f.Score,
+		})
+	}
+	return hits, nil
+}
+
+// searchAssignments, like searchLessons, drops assignments hidden from the
+// student and leaves out the description of locked ones.
+func (s *SearchUseCase) searchAssignments(ctx context.Context, studentID primitive.ObjectID, q string, scope *searchScope, limit int) ([]entity.SearchHit, error) {
+	if scope.restricted && len(scope.courseIDs) == 0 {
+		return []entity.SearchHit{}, nil
+	}
+	found, err := s.searchRepo.SearchAssignments(ctx, q, scope.courseIDs, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	evals := make(map[primitive.ObjectID]*releaseEvaluator)
+	hits := make([]entity.SearchHit, 0, len(found))
+	for _, f := range found {
+		if scope.student {
+			eval, err := s.releaseEvaluator(ctx, evals, studentID, f.Item.CourseID)
+			if err != nil {
+				return nil, err
+			}
+			if eval.gate(f.Item) {
+				continue
+			}
+		}
+		hits = append(hits, entity.SearchHit{
+			ID:       f.Item.ID,
+			Title:    f.Item.Title,
+			Snippet:  snippet(f.Item.Description),
+			CourseID: f.Item.CourseID,
+			Score:    f.Score,
+		})
+	}
+	return hits, nil
+}
+
+func (s *SearchUseCase) searchAnnouncements(ctx context.Context, q string, scope *searchScope, limit int) ([]entity.SearchHit, error) {
+	found, err := s.searchRepo.SearchAnnouncements(ctx, q, scope.restricted, scope.courseIDs, scope.classIDs, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]entity.SearchHit, 0, len(found))
+	for _, f := range found {
+		hit := entity.SearchHit{
+			ID:      f.Item.ID,
+			Title:   f.Item.Title,
+			Snippet: snippet(f.Item.Content),
+			Score:   f.Score,
+		}
+		if f.Item.TargetAudience == entity.AudienceCourse {
+			hit.CourseID = f.Item.TargetID
+		}
+		hits = append(hits, hit)
+	}
+	return hits, nil
+}
+
+// searchUsers lets admins find anyone and teachers find the students of
+// their classes. Students cannot search users.
+func (s *SearchUseCase) searchUsers(ctx context.Context, q string, scope *searchScope, limit int) ([]entity.SearchHit, error) {
+	if scope.student || (scope.restricted && len(scope.userIDs) == 0) {
+		return []entity.SearchHit{}, nil
+	}
+	found, err := s.searchRepo.SearchUsers(ctx, q, scope.userIDs, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]entity.SearchHit, 0, len(found))
+	for _, f := range found {
+		hits = append(hits, entity.SearchHit{
+			ID:      f.Item.ID,
+			Title:   f.Item.Email,
+			Snippet: string(f.Item.Role),
+			Score:   f.Score,
+		})
+	}
+	return hits, nil
+}
+
+func (s *SearchUseCase) releaseEvaluator(ctx context.Context, cache map[primitive.ObjectID]*releaseEvaluator, studentID, courseID primitive.ObjectID) (*releaseEvaluator, error) {
+	if eval, ok := cache[courseID]; ok {
+		return eval, nil
+	}
+	eval, err := newReleaseEvaluator(ctx, s.progressRepo, s.lessonRepo, s.assessmentRepo, studentID, courseID)
+	if err != nil {
+		return nil, err
+	}
+	cache[courseID] = eval
+	return eval, nil
+}
+
+func appendUnique(ids []primitive.ObjectID, id primitive.ObjectID) []primitive.ObjectID {
+	if containsID(ids, id) {
+		return ids
+	}
+	return append(ids, id)
+}
+
+// snippet shortens text to a preview, cutting at a word boundary.
+func snippet(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= snippetLength {
+		return text
+	}
+	runes := []rune(text)[:snippetLength]
+	cut := string(runes)
+	if i := strings.LastIndex(cut, " "); i > snippetLength/2 {
+		cut = cut[:i]
+	}
+	return cut + "…"
+}
@@ -2,6 +2,8 @@ package usecase
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"github.com/srgjo27/e-learning/internal/entity"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -21,6 +23,9 @@ type StudentUseCase struct {
 	messageRepo 	MessageRepository
 	submitRepo 		SubmissionRepository
 	userRepo 		UserRepository
+	progressRepo 	ProgressRepository
+	lessonRepo 		LessonRepository
+	announcementRepo AnnouncementRepository
 }
 
 func NewStudentUseCase(
@@ -31,6 +36,9 @@ func NewStudentUseCase(
 	messageRepo MessageRepository,
 	submitRepo SubmissionRepository,
 	userRepo UserRepository,
+	progressRepo ProgressRepository,
+	lessonRepo LessonRepository,
+	announcementRepo AnnouncementRepository,
 ) *StudentUseCase {
 	return &StudentUseCase{
 		courseRepo: courseRepo,
@@ -40,6 +48,9 @@ func NewStudentUseCase(
 		messageRepo: messageRepo,
 		submitRepo: submitRepo,
 		userRepo: userRepo,
+		progressRepo: progressRepo,
+		lessonRepo: lessonRepo,
+		announcementRepo: announcementRepo,
 	}
 }
 
@@ -82,15 +93,160 @@ func (s *StudentUseCase) GetEnrolledClasses(ctx context.Context, studentID strin
 	return s.classRepo.ListClassesByStudent(ctx, oid)
 }
 
-func (s *StudentUseCase) ListAssignmentsForStudent(ctx context.Context, studentID string) ([]*entity.Assignment, error) {
-	// TODO:
-	// For simplicity, we will return all assignments for now
-	// In a real implementation, we would filter by the student's enrolled courses
-	return s.assignmentRepo.ListAssignmentsByCourse(ctx, primitive.NewObjectID())
+// ListAssignmentsForStudent returns a page of the assignments of the
+// student's enrolled courses, optionally narrowed with a course_id filter.
+// Items gated by release conditions the student has not met are either
+// locked, with the reasons and without their description, or left out, so
+// a page may hold fewer items than the limit.
+func (s *StudentUseCase) ListAssignmentsForStudent(ctx context.Context, studentID string, opts entity.ListOptions) (*entity.Page[*entity.Assignment], error) {
+	oid, err := primitive.ObjectIDFromHex(studentID)
+	if err != nil {
+		return nil, err
+	}
+
+	courses, err := s.enrolledCourseFilter(ctx, oid, opts.Filters["course_id"])
+	if err != nil {
+		return nil, err
+	}
+	if courses == "" {
+		return &entity.Page[*entity.Assignment]{Items: []*entity.Assignment{}}, nil
+	}
+
+	page, err := s.assignmentRepo.ListAssignments(ctx, opts.WithFilter("course_id", courses))
+	if err != nil {
+		return nil, err
+	}
+
+	evals := make(map[primitive.ObjectID]*releaseEvaluator)
+	visible := make([]*entity.Assignment, 0, len(page.Items))
+	for _, a := range page.Items {
+		eval, err := s.releaseEvaluator(ctx, evals, oid, a.CourseID)
+		if err != nil {
+			return nil, err
+		}
+		if eval.gate(a) {
+			continue
+		}
+		visible = append(visible, a)
+	}
+	page.Items = visible
+
+	return page, nil
 }
 
-func (s *StudentUseCase) ListAssessmentsForStudent(ctx context.Context, studentID string) ([]*entity.Assessment, error) {
-	return s.assessmentRepo.ListAssessments(ctx)
+func (s *StudentUseCase) ListAssessmentsForStudent(ctx context.Context, studentID string, opts entity.ListOptions) (*entity.Page[*entity.Assessment], error) {
+	oid, err := primitive.ObjectIDFromHex(studentID)
+	if err != nil {
+		return nil, err
+	}
+
+	courses, err := s.enrolledCourseFilter(ctx, oid, opts.Filters["course_id"])
+	if err != nil {
+		return nil, err
+	}
+	if courses == "" {
+		return &entity.Page[*entity.Assessment]{Items: []*entity.Assessment{}}, nil
+	}
+
+	page, err := s.assessmentRepo.ListAssessments(ctx, opts.WithFilter("course_id", courses))
+	if err != nil {
+		return nil, err
+	}
+
+	evals := make(map[primitive.ObjectID]*releaseEvaluator)
+	visible := make([]*entity.Assessment, 0, len(page.Items))
+	for _, a := range page.Items {
+		eval, err := s.releaseEvaluator(ctx, evals, oid, a.CourseID)
+		if err != nil {
+			return nil, err
+		}
+		if eval.gate(a) {
+			continue
+		}
+		visible = append(visible, a)
+	}
+	page.Items = visible
+
+	return page, nil
+}
+
+// enrolledCourseFilter returns the student's course IDs as a course_id filter
+// value, restricted to the requested ones when given. It is empty when
+// nothing is left to list.
+func (s *StudentUseCase) enrolledCourseFilter(ctx context.Context, studentID primitive.ObjectID, requested string) (string, error) {
+	classes, err := s.classRepo.ListClassesByStudent(ctx, studentID)
+	if err != nil {
+		return "", err
+	}
+
+	wanted := make(map[string]bool)
+	for _, id := range strings.Split(requested, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			wanted[id] = true
+		}
+	}
+
+	seen := make(map[primitive.ObjectID]bool)
+	var ids []string
+	for _, cl := range classes {
+		if cl.CourseID.IsZero() || seen[cl.CourseID] {
+			continue
+		}
+		seen[cl.CourseID] = true
+		if len(wanted) > 0 && !wanted[cl.CourseID.Hex()] {
+			continue
+		}
+		ids = append(ids, cl.CourseID.Hex())
+	}
+
+	return strings.Join(ids, ","), nil
+}
+
+func (s *StudentUseCase) releaseEvaluator(ctx context.Context, cache map[primitive.ObjectID]*releaseEvaluator, studentID, courseID primitive.ObjectID) (*releaseEvaluator, error) {
+	if eval, ok := cache[courseID]; ok {
+		return eval, nil
+	}
+	eval, err := newReleaseEvaluator(ctx, s.progressRepo, s.lessonRepo, s.assessmentRepo, studentID, courseID)
+	if err != nil {
+		return nil, err
+	}
+	cache[courseID] = eval
+	return eval, nil
+}
+
+// ListAnnouncementsForStudent returns a page of the announcements addressed
+// to everyone or to one of the student's classes or their courses. Archived
+// announcements are left out.
+func (s *StudentUseCase) ListAnnouncementsForStudent(ctx context.Context, studentID string, opts entity.ListOptions) (*entity.Page[*entity.Announcement], error) {
+	oid, err := primitive.ObjectIDFromHex(studentID)
+	if err != nil {
+		return nil, err
+	}
+
+	classes, err := s.classRepo.ListClassesByStudent(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+	var classIDs, courseIDs []primitive.ObjectID
+	for _, cl := range classes {
+		classIDs = append(classIDs, cl.ID)
+		if !cl.CourseID.IsZero() {
+			courseIDs = append(courseIDs, cl.CourseID)
+		}
+	}
+
+	return s.announcementRepo.ListAnnouncementsForAudience(ctx, courseIDs, classIDs, opts.WithFilter("archived", "false"))
+}
+
+// ListSubmissionsForStudent returns the student's submissions with their
+// grades and feedback.
+func (s *StudentUseCase) ListSubmissionsForStudent(ctx context.Context, studentID string) ([]*entity.Submission, error) {
+	oid, err := primitive.ObjectIDFromHex(studentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.submitRepo.ListSubmissionsByStudent(ctx, oid)
 }
 
 func (s *StudentUseCase) ListMessagesForStudent(ctx context.Context, studentID string) ([]*entity.Message, error) {
@@ -103,14 +259,55 @@ func (s *StudentUseCase) ListMessagesForStudent(ctx context.Context, studentID s
 }
 
 func (s *StudentUseCase) SubmitAssignment(ctx context.Context, submission *entity.Submission) error {
+	assignment, err := s.assignmentRepo.GetAssignment(ctx, submission.AssignmentID.Hex())
+	if err != nil {
+		return err
+	}
+
+	enrolled, err := isEnrolled(ctx, s.classRepo, submission.StudentID.Hex(), assignment.CourseID)
+	if err != nil {
+		return err
+	}
+	if !enrolled {
+		return entity.ErrUnauthorized
+	}
+
+	eval, err := newReleaseEvaluator(ctx, s.progressRepo, s.lessonRepo, s.assessmentRepo, submission.StudentID, assignment.CourseID)
+	if err != nil {
+		return err
+	}
+	if availability, _ := eval.check(assignment.Release); availability.Locked {
+		return entity.ErrItemLocked
+	}
+
 	submission.SubmittedAt = submission.SubmittedAt.UTC()
-	return s.submitRepo.CreateSubmission(ctx, submission)
+	if err := s.submitRepo.CreateSubmission(ctx, submission); err != nil {
+		return err
+	}
+
+	return s.progressRepo.SaveProgress(ctx, &entity.Progress{
+		StudentID: submission.StudentID,
+		CourseID:  assignment.CourseID,
+		ItemType:  entity.ProgressAssignment,
+		ItemID:    assignment.ID,
+		Status:    entity.ProgressSubmitted,
+		UpdatedAt: time.Now(),
+	})
 }
 
 func (s *StudentUseCase) UpdateSubmission(ctx context.Context, submission *entity.Submission) error {
 	return s.submitRepo.UpdateSubmission(ctx, submission)
 }
 
-func (s *StudentUseCase) GetSubmission(ctx context.Context, submissionID string) (*entity.Submission, error) {
-	return s.submitRepo.GetSubmission(ctx, submissionID)
+func (s *StudentUseCase) GetSubmission(ctx context.Context, studentID, submissionID string) (*entity.Submission, error) {
+	sub, err := s.submitRepo.GetSubmission(ctx, submissionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if sub.StudentID.Hex() != studentID {
+		return nil, entity.ErrSubmissionNotFound
+	}
+
+	return sub, nil
 }
\ No newline at end of file
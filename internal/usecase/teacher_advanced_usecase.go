@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"context"
+	"time"
 
 	"github.com/srgjo27/e-learning/internal/entity"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -12,7 +13,10 @@ type AssignmentRepository interface {
 	GetAssignment(ctx context.Context, id string) (*entity.Assignment, error)
 	UpdateAssignment(ctx context.Context, a *entity.Assignment) error
 	DeleteAssignment(ctx context.Context, id string) error
+	ListAssignments(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.Assignment], error)
 	ListAssignmentsByCourse(ctx context.Context, courseID primitive.ObjectID) ([]*entity.Assignment, error)
+	ListAssignmentsDueBetween(ctx context.Context, from, to time.Time) ([]*entity.Assignment, error)
+	UpdateAssignmentRelease(ctx context.Context, assignmentID primitive.ObjectID, rule *entity.ReleaseRule) error
 }
 
 type AssessmentRepository interface {
@@ -20,8 +24,10 @@ type AssessmentRepository interface {
 	GetAssessment(ctx context.Context, id string) (*entity.Assessment, error)
 	UpdateAssessment(ctx context.Context, a *entity.Assessment) error
 	DeleteAssessment(ctx context.Context, id string) error
-	ListAssessments(ctx context.Context) ([]*entity.Assessment, error)
+	ListAssessments(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.Assessment], error)
 	ListAssessmentsByCourse(ctx context.Context, courseID primitive.ObjectID) ([]*entity.Assessment, error)
+	ListAssessmentsBetween(ctx context.Context, from, to time.Time) ([]*entity.Assessment, error)
+	UpdateAssessmentRelease(ctx context.Context, assessmentID primitive.ObjectID, rule *entity.ReleaseRule) error
 }
 
 type MessageRepository interface {
@@ -29,7 +35,7 @@ type MessageRepository interface {
 	GetMessage(ctx context.Context, id string) (*entity.Message, error)
 	UpdateMessage(ctx context.Context, m *entity.Message) error
 	DeleteMessage(ctx context.Context, id string) error
-	ListMessagesBySender(ctx context.Context, senderID primitive.ObjectID) ([]*entity.Message, error)
+	ListMessages(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.Message], error)
 	ListMessagesForReceiver(ctx context.Context, receiverID primitive.ObjectID) ([]*entity.Message, error)
 }
 
@@ -37,24 +43,74 @@ type TeacherAdvancedUseCase struct {
 	assignmentRepo AssignmentRepository
 	assessmentRepo AssessmentRepository
 	messageRepo    MessageRepository
+	submitRepo     SubmissionRepository
+	courseRepo     CourseRepository
+	classRepo      ClassRepository
+	userRepo       UserRepository
+	notifier       Notifier
+	deps           DependencyRepository
+	tx             Transactor
 }
 
 func NewTeacherAdvancedUseCase(
 	ar AssignmentRepository,
 	asr AssessmentRepository,
-	mr MessageRepository) *TeacherAdvancedUseCase {
+	mr MessageRepository,
+	sr SubmissionRepository,
+	cr CourseRepository,
+	clr ClassRepository,
+	ur UserRepository,
+	notifier Notifier,
+	deps DependencyRepository,
+	tx Transactor) *TeacherAdvancedUseCase {
 
 	return &TeacherAdvancedUseCase{
 		assignmentRepo: ar,
 		assessmentRepo: asr,
 		messageRepo:    mr,
+		submitRepo:     sr,
+		courseRepo:     cr,
+		classRepo:      clr,
+		userRepo:       ur,
+		notifier:       notifier,
+		deps:           deps,
+		tx:             tx,
 	}
 }
 
 // --- Assignment ---
 func (t *TeacherAdvancedUseCase) CreateAssignment(ctx context.Context, a *entity.Assignment) error {
+	course, err := checkCourse(ctx, t.courseRepo, "course_id", a.CourseID)
+	if err != nil {
+		return err
+	}
+
+	// Release rules are validated and set through ReleaseUseCase once the
+	// assignment exists, so a new assignment is available to every student.
+	a.Release = nil
 	a.CreatedAt = a.CreatedAt.UTC()
-	return t.assignmentRepo.CreateAssignment(ctx, a)
+	return t.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := t.assignmentRepo.CreateAssignment(ctx, a); err != nil {
+			return err
+		}
+		return t.notifyNewAssignment(ctx, a, course)
+	})
+}
+
+// notifyNewAssignment mails the students of the course about a new
+// assignment. It has no release rule yet, so none of them is locked out.
+func (t *TeacherAdvancedUseCase) notifyNewAssignment(ctx context.Context, a *entity.Assignment, course *entity.Course) error {
+	students, err := studentsInCourse(ctx, t.classRepo, t.userRepo, a.CourseID)
+	if err != nil {
+		return err
+	}
+
+	return notifyUsers(ctx, t.notifier, students, entity.NotificationNewAssignment, map[string]interface{}{
+		"Title":       a.Title,
+		"Description": a.Description,
+		"CourseName":  course.Name,
+		"DueDate":     a.DueDate,
+	})
 }
 
 func (t *TeacherAdvancedUseCase) GetAssignment(ctx context.Context, id string) (*entity.Assignment, error) {
@@ -62,23 +118,72 @@ func (t *TeacherAdvancedUseCase) GetAssignment(ctx context.Context, id string) (
 }
 
 func (t *TeacherAdvancedUseCase) UpdateAssignment(ctx context.Context, a *entity.Assignment) error {
+	if _, err := checkCourse(ctx, t.courseRepo, "course_id", a.CourseID); err != nil {
+		return err
+	}
 	return t.assignmentRepo.UpdateAssignment(ctx, a)
 }
 
+// DeleteAssignment moves the assignment to the trash. Its submissions are
+// kept until it is purged.
 func (t *TeacherAdvancedUseCase) DeleteAssignment(ctx context.Context, id string) error {
 	return t.assignmentRepo.DeleteAssignment(ctx, id)
 }
 
-func (t *TeacherAdvancedUseCase) ListAssignmentsByCourse(ctx context.Context, courseID string) ([]*entity.Assignment, error) {
-	oid, err := primitive.ObjectIDFromHex(courseID)
-	if err != nil {
+func (t *TeacherAdvancedUseCase) ListAssignmentsByCourse(ctx context.Context, courseID string, opts entity.ListOptions) (*entity.Page[*entity.Assignment], error) {
+	if _, err := primitive.ObjectIDFromHex(courseID); err != nil {
 		return nil, err
 	}
-	return t.assignmentRepo.ListAssignmentsByCourse(ctx, oid)
+	return t.assignmentRepo.ListAssignments(ctx, opts.WithFilter("course_id", courseID))
+}
+
+// --- Submission ---
+func (t *TeacherAdvancedUseCase) GradeSubmission(ctx context.Context, submissionID string, grade float64, feedback *string) error {
+	sub, err := t.submitRepo.GetSubmission(ctx, submissionID)
+	if err != nil {
+		return err
+	}
+
+	sub.Grade = &grade
+	sub.Feedback = feedback
+	return t.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := t.submitRepo.UpdateSubmission(ctx, sub); err != nil {
+			return err
+		}
+		return t.notifyGrade(ctx, sub)
+	})
+}
+
+func (t *TeacherAdvancedUseCase) notifyGrade(ctx context.Context, sub *entity.Submission) error {
+	assignment, err := t.assignmentRepo.GetAssignment(ctx, sub.AssignmentID.Hex())
+	if err != nil {
+		return err
+	}
+
+	student, err := t.userRepo.FindByID(ctx, sub.StudentID.Hex())
+	if err != nil {
+		return err
+	}
+
+	data := map[string]interface{}{
+		"AssignmentTitle": assignment.Title,
+		"Grade":           *sub.Grade,
+		"Feedback":        "",
+	}
+	if sub.Feedback != nil {
+		data["Feedback"] = *sub.Feedback
+	}
+
+	return t.notifier.Notify(ctx, student, entity.NotificationGrade, data)
 }
 
 // --- Assessment ---
 func (t *TeacherAdvancedUseCase) CreateAssessment(ctx context.Context, a *entity.Assessment) error {
+	if _, err := checkCourse(ctx, t.courseRepo, "course_id", a.CourseID); err != nil {
+		return err
+	}
+	// As with assignments, release rules are set through ReleaseUseCase.
+	a.Release = nil
 	a.CreatedAt = a.CreatedAt.UTC()
 	return t.assessmentRepo.CreateAssessment(ctx, a)
 }
@@ -88,23 +193,45 @@ func (t *TeacherAdvancedUseCase) GetAssessment(ctx context.Context, id string) (
 }
 
 func (t *TeacherAdvancedUseCase) UpdateAssessment(ctx context.Context, a *entity.Assessment) error {
+	if _, err := checkCourse(ctx, t.courseRepo, "course_id", a.CourseID); err != nil {
+		return err
+	}
 	return t.assessmentRepo.UpdateAssessment(ctx, a)
 }
 
-func (t *TeacherAdvancedUseCase) DeleteAssessment(ctx context.Context, id string) error {
-	return t.assessmentRepo.DeleteAssessment(ctx, id)
+// DeleteAssessment deletes the assessment. Recorded results and release
+// conditions that depend on it block the delete unless cascade is set.
+func (t *TeacherAdvancedUseCase) DeleteAssessment(ctx context.Context, id string, cascade bool) error {
+	assessment, err := t.assessmentRepo.GetAssessment(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return deleteChecked(ctx, t.tx, cascade,
+		func(ctx context.Context) (entity.Dependents, error) {
+			return t.deps.AssessmentDependents(ctx, assessment.ID)
+		},
+		func(ctx context.Context) error {
+			return t.deps.RemoveAssessmentDependents(ctx, assessment.ID)
+		},
+		func(ctx context.Context) error {
+			return t.assessmentRepo.DeleteAssessment(ctx, id)
+		},
+	)
 }
 
-func (t *TeacherAdvancedUseCase) ListAssessmentsByCourse(ctx context.Context, courseID string) ([]*entity.Assessment, error) {
-	oid, err := primitive.ObjectIDFromHex(courseID)
-	if err != nil {
+func (t *TeacherAdvancedUseCase) ListAssessmentsByCourse(ctx context.Context, courseID string, opts entity.ListOptions) (*entity.Page[*entity.Assessment], error) {
+	if _, err := primitive.ObjectIDFromHex(courseID); err != nil {
 		return nil, err
 	}
-	return t.assessmentRepo.ListAssessmentsByCourse(ctx, oid)
+	return t.assessmentRepo.ListAssessments(ctx, opts.WithFilter("course_id", courseID))
 }
 
 // --- Message ---
 func (t *TeacherAdvancedUseCase) CreateMessage(ctx context.Context, m *entity.Message) error {
+	if err := t.checkReceivers(ctx, m.ReceiverIDs); err != nil {
+		return err
+	}
 	m.CreatedAt = m.CreatedAt.UTC()
 	return t.messageRepo.CreateMessage(ctx, m)
 }
@@ -114,18 +241,45 @@ func (t *TeacherAdvancedUseCase) GetMessage(ctx context.Context, id string) (*en
 }
 
 func (t *TeacherAdvancedUseCase) UpdateMessage(ctx context.Context, m *entity.Message) error {
+	if err := t.checkReceivers(ctx, m.ReceiverIDs); err != nil {
+		return err
+	}
 	return t.messageRepo.UpdateMessage(ctx, m)
 }
 
+// checkReceivers makes sure every recipient is a user or a class.
+func (t *TeacherAdvancedUseCase) checkReceivers(ctx context.Context, ids []primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	users, err := t.userRepo.FindUsersByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	found := make(map[primitive.ObjectID]bool, len(users))
+	for _, u := range users {
+		found[u.ID] = true
+	}
+	for _, id := range ids {
+		if found[id] {
+			continue
+		}
+		if err := checkClass(ctx, t.classRepo, "receiver_ids", id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (t *TeacherAdvancedUseCase) DeleteMessage(ctx context.Context, id string) error {
 	return t.messageRepo.DeleteMessage(ctx, id)
 }
 
-func (t *TeacherAdvancedUseCase) ListMessagesBySender(ctx context.Context, senderID string) ([]*entity.Message, error) {
-	oid, err := primitive.ObjectIDFromHex(senderID)
-	if err != nil {
+func (t *TeacherAdvancedUseCase) ListMessagesBySender(ctx context.Context, senderID string, opts entity.ListOptions) (*entity.Page[*entity.Message], error) {
+	if _, err := primitive.ObjectIDFromHex(senderID); err != nil {
 		return nil, err
 	}
-	
-	return t.messageRepo.ListMessagesBySender(ctx, oid)
+
+	return t.messageRepo.ListMessages(ctx, opts.WithFilter("sender_id", senderID))
 }
\ No newline at end of file
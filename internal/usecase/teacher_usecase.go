@@ -11,32 +11,44 @@ type TeacherUseCase struct {
 	courseRepo CourseRepository
 	classRepo  ClassRepository
 	userRepo   UserRepository
+	termRepo   TermRepository
 }
 
-func NewTeacherUseCase(courseRepo CourseRepository, classRepo ClassRepository, userRepo UserRepository) *TeacherUseCase {
+func NewTeacherUseCase(courseRepo CourseRepository, classRepo ClassRepository, userRepo UserRepository, termRepo TermRepository) *TeacherUseCase {
 	return &TeacherUseCase{
 		courseRepo: courseRepo,
 		classRepo:  classRepo,
 		userRepo:   userRepo,
+		termRepo:   termRepo,
 	}
 }
 
-func (t *TeacherUseCase) GetAssignedCourses(ctx context.Context, teacherID string) ([]*entity.Course, error) {
-	oid, err := primitive.ObjectIDFromHex(teacherID)
+// GetAssignedCourses returns a page of the teacher's courses, limited to the
+// current term unless a "term" filter is given.
+func (t *TeacherUseCase) GetAssignedCourses(ctx context.Context, teacherID string, opts entity.ListOptions) (*entity.Page[*entity.Course], error) {
+	if _, err := primitive.ObjectIDFromHex(teacherID); err != nil {
+		return nil, err
+	}
+
+	opts, err := applyTermFilter(ctx, t.termRepo, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	return t.courseRepo.ListCoursesByTeacher(ctx, oid)
+	return t.courseRepo.ListCourses(ctx, opts.WithFilter("teacher_id", teacherID))
 }
 
-func (t *TeacherUseCase) GetAssignedClasses(ctx context.Context, teacherID string) ([]*entity.Class, error) {
-	oid, err := primitive.ObjectIDFromHex(teacherID)
+func (t *TeacherUseCase) GetAssignedClasses(ctx context.Context, teacherID string, opts entity.ListOptions) (*entity.Page[*entity.Class], error) {
+	if _, err := primitive.ObjectIDFromHex(teacherID); err != nil {
+		return nil, err
+	}
+
+	opts, err := applyTermFilter(ctx, t.termRepo, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	return t.classRepo.ListClassesByTeacher(ctx, oid)
+	return t.classRepo.ListClasses(ctx, opts.WithFilter("teacher_id", teacherID))
 }
 
 func (t *TeacherUseCase) GetStudentsInClass(ctx context.Context, classID string) ([]*entity.User, error) {
@@ -0,0 +1,359 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AllTerms disables the default current-term filter of course and class listings.
+const AllTerms = "all"
+
+type TermRepository interface {
+	CreateTerm(ctx context.Context, t *entity.Term) error
+	GetTerm(ctx context.Context, id string) (*entity.Term, error)
+	UpdateTerm(ctx context.Context, t *entity.Term) error
+	DeleteTerm(ctx context.Context, id string) error
+	ListTerms(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.Term], error)
+	FindTermAt(ctx context.Context, at time.Time) (*entity.Term, error)
+}
+
+type TermUseCase struct {
+	termRepo       TermRepository
+	courseRepo     CourseRepository
+	moduleRepo     ModuleRepository
+	lessonRepo     LessonRepository
+	assignmentRepo AssignmentRepository
+	assessmentRepo AssessmentRepository
+	blobs          BlobStore
+	deps           DependencyRepository
+	tx             Transactor
+}
+
+func NewTermUseCase(
+	termRepo TermRepository,
+	courseRepo CourseRepository,
+	moduleRepo ModuleRepository,
+	lessonRepo LessonRepository,
+	assignmentRepo AssignmentRepository,
+	assessmentRepo AssessmentRepository,
+	blobs BlobStore,
+	deps DependencyRepository,
+	tx Transactor,
+) *TermUseCase {
+	return &TermUseCase{
+		termRepo:       termRepo,
+		courseRepo:     courseRepo,
+		moduleRepo:     moduleRepo,
+		lessonRepo:     lessonRepo,
+		assignmentRepo: assignmentRepo,
+		assessmentRepo: assessmentRepo,
+		blobs:          blobs,
+		deps:           deps,
+		tx:             tx,
+	}
+}
+
+// --- Term ---
+func (t *TermUseCase) CreateTerm(ctx context.Context, term *entity.Term) error {
+	if err := term.Validate(); err != nil {
+		return err
+	}
+	term.CreatedAt = time.Now()
+	return t.termRepo.CreateTerm(ctx, term)
+}
+
+func (t *TermUseCase) GetTerm(ctx context.Context, id string) (*entity.Term, error) {
+	return t.termRepo.GetTerm(ctx, id)
+}
+
+func (t *TermUseCase) UpdateTerm(ctx context.Context, term *entity.Term) error {
+	if err := term.Validate(); err != nil {
+		return err
+	}
+	return t.termRepo.UpdateTerm(ctx, term)
+}
+
+// DeleteTerm deletes the term. Courses and classes in it block the delete
+// unless cascade is set, in which case they are left without a term.
+func (t *TermUseCase) DeleteTerm(ctx context.Context, id string, cascade bool) error {
+	term, err := t.termRepo.GetTerm(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return deleteChecked(ctx, t.tx, cascade,
+		func(ctx context.Context) (entity.Dependents, error) {
+			return t.deps.TermDependents(ctx, term.ID)
+		},
+		func(ctx context.Context) error {
+			return t.deps.RemoveTermDependents(ctx, term.ID)
+		},
+		func(ctx context.Context) error {
+			return t.termRepo.DeleteTerm(ctx, id)
+		},
+	)
+}
+
+func (t *TermUseCase) ListTerms(ctx context.Context, opts entity.ListOptions) (*entity.Page[*entity.Term], error) {
+	return t.termRepo.ListTerms(ctx, opts)
+}
+
+func (t *TermUseCase) CurrentTerm(ctx context.Context) (*entity.Term, error) {
+	return t.termRepo.FindTermAt(ctx, time.Now())
+}
+
+// --- Rollover ---
+
+// Rollover offers a course again in another term. The new course gets copies
+// of the modules, lessons (with their attachments), assignments and
+// assessments, with every date moved by the distance between the two term
+// starts. Classes, submissions and progress are not copied.
+func (t *TermUseCase) Rollover(ctx context.Context, courseID, termID string) (*entity.Course, error) {
+	source, err := t.courseRepo.GetCourse(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	if source.TermID.IsZero() {
+		return nil, entity.ErrCourseNotInTerm
+	}
+
+	fromTerm, err := t.termRepo.GetTerm(ctx, source.TermID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	toTerm, err := t.termRepo.GetTerm(ctx, termID)
+	if err != nil {
+		return nil, err
+	}
+	if fromTerm.ID == toTerm.ID {
+		return nil, entity.ErrInvalidTerm
+	}
+	shift := toTerm.StartDate.Sub(fromTerm.StartDate)
+	now := time.Now()
+
+	course := &entity.Course{
+		ID:                 primitive.NewObjectID(),
+		Name:               source.Name,
+		Description:        source.Description,
+		AssignedTeacher:    source.AssignedTeacher,
+		CreatedAt:          now,
+		TermID:             toTerm.ID,
+		ClonedFrom:         source.ID,
+		CompletionCriteria: source.CompletionCriteria,
+	}
+	if err := t.courseRepo.CreateCourse(ctx, course); err != nil {
+		return nil, err
+	}
+
+	modules, err := t.moduleRepo.ListModulesByCourse(ctx, source.ID)
+	if err != nil {
+		return nil, err
+	}
+	moduleIDs := make(map[primitive.ObjectID]primitive.ObjectID, len(modules))
+	for _, m := range modules {
+		clone := *m
+		clone.ID = primitive.NewObjectID()
+		clone.CourseID = course.ID
+		clone.CreatedAt = now
+		clone.UpdatedAt = now
+		if err := t.moduleRepo.CreateModule(ctx, &clone); err != nil {
+			return nil, err
+		}
+		moduleIDs[m.ID] = clone.ID
+	}
+
+	// Release rules refer to other items of the course, so they are copied
+	// once every item exists under its new ID.
+	ids := make(map[primitive.ObjectID]primitive.ObjectID)
+	var releases []func() error
+
+	lessons, err := t.lessonRepo.ListLessonsByCourse(ctx, source.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range lessons {
+		clone := *l
+		clone.ID = primitive.NewObjectID()
+		clone.ModuleID = moduleIDs[l.ModuleID]
+		clone.CourseID = course.ID
+		clone.ReleaseAt = shiftTime(l.ReleaseAt, shift)
+		clone.Release = nil
+		clone.CreatedAt = now
+		clone.UpdatedAt = now
+		clone.Attachments, err = t.copyAttachments(ctx, clone.ID, l.Attachments)
+		if err != nil {
+			return nil, err
+		}
+		if err := t.lessonRepo.CreateLesson(ctx, &clone); err != nil {
+			return nil, err
+		}
+		ids[l.ID] = clone.ID
+
+		if l.Release != nil {
+			rule, id := l.Release, clone.ID
+			releases = append(releases, func() error {
+				return t.lessonRepo.UpdateLessonRelease(ctx, id, remapRelease(rule, ids, shift))
+			})
+		}
+	}
+
+	assessments, err := t.assessmentRepo.ListAssessmentsByCourse(ctx, source.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range assessments {
+		clone := *a
+		clone.ID = primitive.NewObjectID()
+		clone.CourseID = course.ID
+		clone.Date = a.Date.Add(shift)
+		clone.Release = nil
+		clone.CreatedAt = now
+		clone.UpdatedAt = now
+		if err := t.assessmentRepo.CreateAssessment(ctx, &clone); err != nil {
+			return nil, err
+		}
+		ids[a.ID] = clone.ID
+
+		if a.Release != nil {
+			rule, id := a.Release, clone.ID
+			releases = append(releases, func() error {
+				return t.assessmentRepo.UpdateAssessmentRelease(ctx, id, remapRelease(rule, ids, shift))
+			})
+		}
+	}
+
+	assignments, err := t.assignmentRepo.ListAssignmentsByCourse(ctx, source.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range assignments {
+		clone := *a
+		clone.ID = primitive.NewObjectID()
+		clone.CourseID = course.ID
+		clone.DueDate = a.DueDate.Add(shift)
+		clone.Release = nil
+		clone.CreatedAt = now
+		clone.UpdatedAt = now
+		if err := t.assignmentRepo.CreateAssignment(ctx, &clone); err != nil {
+			return nil, err
+		}
+
+		if a.Release != nil {
+			rule, id := a.Release, clone.ID
+			releases = append(releases, func() error {
+				return t.assignmentRepo.UpdateAssignmentRelease(ctx, id, remapRelease(rule, ids, shift))
+			})
+		}
+	}
+
+	for _, apply := range releases {
+		if err := apply(); err != nil {
+			return nil, err
+		}
+	}
+
+	return course, nil
+}
+
+// copyAttachments duplicates the stored files so the two offerings can
+// manage their attachments independently. Files missing from the store are
+// dropped.
+func (t *TermUseCase) copyAttachments(ctx context.Context, lessonID primitive.ObjectID, atts []entity.Attachment) ([]entity.Attachment, error) {
+	copies := make([]entity.Attachment, 0, len(atts))
+	for _, att := range atts {
+		rc, _, err := t.blobs.Get(ctx, att.Key)
+		if errors.Is(err, entity.ErrBlobNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		clone := att
+		clone.ID = primitive.NewObjectID()
+		clone.Key = "lessons/" + lessonID.Hex() + "/" + clone.ID.Hex()
+		_, err = t.blobs.Put(ctx, clone.Key, att.ContentType, rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		copies = append(copies, clone)
+	}
+	return copies, nil
+}
+
+// remapRelease rewrites a release rule for the cloned course. Conditions on
+// items that were not copied are dropped.
+func remapRelease(rule *entity.ReleaseRule, ids map[primitive.ObjectID]primitive.ObjectID, shift time.Duration) *entity.ReleaseRule {
+	out := &entity.ReleaseRule{HideUntilMet: rule.HideUntilMet}
+	for _, c := range rule.Conditions {
+		switch c.Type {
+		case entity.ConditionLessonCompleted:
+			id, ok := ids[c.LessonID]
+			if !ok {
+				continue
+			}
+			c.LessonID = id
+		case entity.ConditionAssessmentScore:
+			id, ok := ids[c.AssessmentID]
+			if !ok {
+				continue
+			}
+			c.AssessmentID = id
+		case entity.ConditionAfterDate:
+			c.Date = shiftTime(c.Date, shift)
+		}
+		out.Conditions = append(out.Conditions, c)
+	}
+	if len(out.Conditions) == 0 {
+		return nil
+	}
+	return out
+}
+
+func shiftTime(t *time.Time, d time.Duration) *time.Time {
+	if t == nil {
+		return nil
+	}
+	shifted := t.Add(d)
+	return &shifted
+}
+
+// applyTermFilter resolves the "term" filter of a course or class listing
+// into a term_id filter. Without it the listing is limited to the current
+// term and to what has no term, or left unfiltered when no term is running;
+// AllTerms disables it.
+func applyTermFilter(ctx context.Context, termRepo TermRepository, opts entity.ListOptions) (entity.ListOptions, error) {
+	termID := opts.Filters["term"]
+	filters := make(map[string]string, len(opts.Filters))
+	for k, v := range opts.Filters {
+		if k != "term" {
+			filters[k] = v
+		}
+	}
+	opts.Filters = filters
+
+	switch termID {
+	case AllTerms:
+		return opts, nil
+	case "":
+		term, err := termRepo.FindTermAt(ctx, time.Now())
+		if errors.Is(err, entity.ErrTermNotFound) {
+			return opts, nil
+		}
+		if err != nil {
+			return opts, err
+		}
+		return opts.WithFilter("term_id_or_unset", term.ID.Hex()), nil
+	default:
+		term, err := termRepo.GetTerm(ctx, termID)
+		if err != nil {
+			return opts, err
+		}
+		return opts.WithFilter("term_id", term.ID.Hex()), nil
+	}
+}
@@ -0,0 +1,98 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+)
+
+type AttemptRepository interface {
+	GetAttempts(ctx context.Context, key string) (*entity.LoginAttempts, error)
+	RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration) (int, error)
+	Block(ctx context.Context, key string, until time.Time) error
+	ResetAttempts(ctx context.Context, key string) error
+}
+
+// AttemptThrottle slows down and eventually locks out repeated failed
+// attempts, counted both per account and per client address. A zero
+// address policy counts per account only. The counters are stored by the
+// repository so that every instance sees the same state.
+type AttemptThrottle struct {
+	attemptRepo AttemptRepository
+	account     entity.ThrottlePolicy
+	ip          entity.ThrottlePolicy
+}
+
+func NewAttemptThrottle(attemptRepo AttemptRepository, account, ip entity.ThrottlePolicy) *AttemptThrottle {
+	return &AttemptThrottle{
+		attemptRepo: attemptRepo,
+		account:     account,
+		ip:          ip,
+	}
+}
+
+type throttleKey struct {
+	key    string
+	policy entity.ThrottlePolicy
+}
+
+// keys returns the counters for an attempt on email under scope, such as
+// "login". The address counter is left out when the client address is not
+// known.
+func (t *AttemptThrottle) keys(ctx context.Context, scope, email string) []throttleKey {
+	keys := []throttleKey{{
+		key:    scope + ":account:" + strings.ToLower(strings.TrimSpace(email)),
+		policy: t.account,
+	}}
+	if ip, _ := ctx.Value("clientIP").(string); ip != "" && t.ip != (entity.ThrottlePolicy{}) {
+		keys = append(keys, throttleKey{key: scope + ":ip:" + ip, policy: t.ip})
+	}
+	return keys
+}
+
+// Check returns a *entity.ThrottledError while attempts on email are
+// refused, either for the account or for the client address.
+func (t *AttemptThrottle) Check(ctx context.Context, scope, email string) error {
+	now := time.Now()
+	var wait time.Duration
+	for _, k := range t.keys(ctx, scope, email) {
+		a, err := t.attemptRepo.GetAttempts(ctx, k.key)
+		if err != nil {
+			return err
+		}
+		if a != nil && a.BlockedUntil.After(now) && a.BlockedUntil.Sub(now) > wait {
+			wait = a.BlockedUntil.Sub(now)
+		}
+	}
+	if wait > 0 {
+		return &entity.ThrottledError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// Fail counts a failed attempt on email and blocks further attempts for as
+// long as the policy asks.
+func (t *AttemptThrottle) Fail(ctx context.Context, scope, email string) error {
+	now := time.Now()
+	for _, k := range t.keys(ctx, scope, email) {
+		failures, err := t.attemptRepo.RecordFailure(ctx, k.key, now, k.policy.Window)
+		if err != nil {
+			return err
+		}
+		if delay := k.policy.Delay(failures); delay > 0 {
+			if err := t.attemptRepo.Block(ctx, k.key, now.Add(delay)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Succeed clears the account counter after a successful attempt. The address
+// counter is left alone so that one valid account cannot be used to reset
+// the count for guesses against others.
+func (t *AttemptThrottle) Succeed(ctx context.Context, scope, email string) error {
+	return t.attemptRepo.ResetAttempts(ctx, t.keys(ctx, scope, email)[0].key)
+}
@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 test vectors,
+// "12345678901234567890", base32 encoded.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// The RFC lists eight digit codes; these are their last six.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := totpCode(rfc6238Secret, tt.unix/totpPeriod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("totpCode() at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := now.Unix() / totpPeriod
+	code := func(step int64) string {
+		c, err := totpCode(rfc6238Secret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", rfc6238Secret, code(step), step, true},
+		{"previous step", rfc6238Secret, code(step - 1), step - 1, true},
+		{"next step", rfc6238Secret, code(step + 1), step + 1, true},
+		{"two steps old", rfc6238Secret, code(step - 2), 0, false},
+		{"two steps ahead", rfc6238Secret, code(step + 2), 0, false},
+		{"spaces as typed", rfc6238Secret, code(step)[:3] + " " + code(step)[3:], step, true},
+		{"lower case secret", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", code(step), step, true},
+		{"too short", rfc6238Secret, code(step)[:5], 0, false},
+		{"too long", rfc6238Secret, code(step) + "0", 0, false},
+		{"bad secret", "not base32!", code(step), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := matchTOTP(tt.secret, tt.code, now)
+			if ok != tt.wantOK || got != tt.wantStep {
+				t.Errorf("matchTOTP() = %d, %v, want %d, %v", got, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
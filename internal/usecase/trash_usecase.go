@@ -0,0 +1,173 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TrashRepository interface {
+	ListTrash(ctx context.Context, t entity.TrashType, opts entity.ListOptions) (*entity.Page[*entity.TrashItem], error)
+	GetTrashItem(ctx context.Context, t entity.TrashType, id primitive.ObjectID) (*entity.TrashItem, error)
+	ListDeletedBefore(ctx context.Context, t entity.TrashType, cutoff time.Time) ([]primitive.ObjectID, error)
+	Restore(ctx context.Context, t entity.TrashType, id primitive.ObjectID) error
+	Purge(ctx context.Context, t entity.TrashType, id primitive.ObjectID) error
+	SetArchived(ctx context.Context, t entity.TrashType, id primitive.ObjectID, at *time.Time) error
+}
+
+// TrashUseCase manages soft-deleted items. Deleting a course, class, user,
+// assignment or announcement only moves it to the trash, from where it can
+// be restored until the retention period runs out and it is purged.
+type TrashUseCase struct {
+	trashRepo TrashRepository
+	deps      DependencyRepository
+	tx        Transactor
+	blobs     BlobStore
+	retention time.Duration
+}
+
+func NewTrashUseCase(trashRepo TrashRepository, deps DependencyRepository, tx Transactor, blobs BlobStore, retention time.Duration) *TrashUseCase {
+	return &TrashUseCase{
+		trashRepo: trashRepo,
+		deps:      deps,
+		tx:        tx,
+		blobs:     blobs,
+		retention: retention,
+	}
+}
+
+// ListTrash returns a page of trashed items of one type, most recently
+// deleted first.
+func (t *TrashUseCase) ListTrash(ctx context.Context, typ entity.TrashType, opts entity.ListOptions) (*entity.Page[*entity.TrashItem], error) {
+	page, err := t.trashRepo.ListTrash(ctx, typ, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range page.Items {
+		item.PurgeAt = item.DeletedAt.Add(t.retention)
+	}
+	return page, nil
+}
+
+func (t *TrashUseCase) Restore(ctx context.Context, typ entity.TrashType, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return entity.ErrTrashItemNotFound
+	}
+	return t.trashRepo.Restore(ctx, typ, oid)
+}
+
+// Archive marks an item as archived. Archived items stay readable and can
+// be left out of listings with the "archived" filter.
+func (t *TrashUseCase) Archive(ctx context.Context, typ entity.TrashType, id string) error {
+	now := time.Now()
+	return t.setArchived(ctx, typ, id, &now)
+}
+
+func (t *TrashUseCase) Unarchive(ctx context.Context, typ entity.TrashType, id string) error {
+	return t.setArchived(ctx, typ, id, nil)
+}
+
+func (t *TrashUseCase) setArchived(ctx context.Context, typ entity.TrashType, id string, at *time.Time) error {
+	if !typ.Valid() {
+		return entity.ErrInvalidTrashType
+	}
+	// An invalid ID matches nothing and is reported as not found.
+	oid, _ := primitive.ObjectIDFromHex(id)
+	return t.trashRepo.SetArchived(ctx, typ, oid, at)
+}
+
+// Purge removes a trashed item for good. Records that still refer to it
+// block the purge unless cascade is set, in which case they go as well.
+func (t *TrashUseCase) Purge(ctx context.Context, typ entity.TrashType, id string, cascade bool) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return entity.ErrTrashItemNotFound
+	}
+	if _, err := t.trashRepo.GetTrashItem(ctx, typ, oid); err != nil {
+		return err
+	}
+
+	var keys []string
+	count, remove := t.dependents(typ, oid, &keys)
+	err = deleteChecked(ctx, t.tx, cascade, count, remove, func(ctx context.Context) error {
+		return t.trashRepo.Purge(ctx, typ, oid)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Files are not part of the transaction, so they go once it has committed.
+	for _, key := range keys {
+		if err := t.blobs.Delete(ctx, key); err != nil && !errors.Is(err, entity.ErrBlobNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// dependents returns the functions that count and remove what refers to a
+// trashed item. Blob keys of removed lesson attachments and avatars are
+// stored in keys.
+func (t *TrashUseCase) dependents(typ entity.TrashType, id primitive.ObjectID, keys *[]string) (
+	func(ctx context.Context) (entity.Dependents, error),
+	func(ctx context.Context) error,
+) {
+	switch typ {
+	case entity.TrashCourses:
+		return func(ctx context.Context) (entity.Dependents, error) {
+				return t.deps.CourseDependents(ctx, id)
+			}, func(ctx context.Context) (err error) {
+				*keys, err = t.deps.RemoveCourseDependents(ctx, id)
+				return err
+			}
+	case entity.TrashClasses:
+		return func(ctx context.Context) (entity.Dependents, error) {
+				return t.deps.ClassDependents(ctx, id)
+			}, func(ctx context.Context) error {
+				return t.deps.RemoveClassDependents(ctx, id)
+			}
+	case entity.TrashUsers:
+		return func(ctx context.Context) (entity.Dependents, error) {
+				return t.deps.UserDependents(ctx, id)
+			}, func(ctx context.Context) (err error) {
+				*keys, err = t.deps.RemoveUserDependents(ctx, id)
+				return err
+			}
+	case entity.TrashAssignments:
+		return func(ctx context.Context) (entity.Dependents, error) {
+				return t.deps.AssignmentDependents(ctx, id)
+			}, func(ctx context.Context) error {
+				return t.deps.RemoveAssignmentDependents(ctx, id)
+			}
+	default:
+		return func(ctx context.Context) (entity.Dependents, error) {
+				return nil, nil
+			}, func(ctx context.Context) error {
+				return nil
+			}
+	}
+}
+
+// PurgeExpired purges, with cascade, every item that has been in the trash
+// for longer than the retention period. It is run by the scheduler.
+func (t *TrashUseCase) PurgeExpired(ctx context.Context, now time.Time) error {
+	cutoff := now.Add(-t.retention)
+	for _, typ := range entity.TrashTypes {
+		ids, err := t.trashRepo.ListDeletedBefore(ctx, typ, cutoff)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			// An earlier cascade may already have removed the item.
+			err := t.Purge(ctx, typ, id.Hex(), true)
+			if err != nil && !errors.Is(err, entity.ErrTrashItemNotFound) {
+				return err
+			}
+		}
+	}
+	return nil
+}
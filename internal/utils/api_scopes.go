@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"net/http"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+)
+
+// routeScopes lists the routes that accept API tokens, by method and path
+// template, with the scope a token needs for each. Routes that are not
+// listed, such as the profile, API token and security settings routes, only
+// accept session tokens.
+var routeScopes = map[string]entity.APIScope{
+	"GET /v1/terms/current": entity.ScopeCoursesRead,
+
+	"GET /v1/admin/users":              entity.ScopeUsersRead,
+	"PUT /v1/admin/users/{id}/role":    entity.ScopeUsersWrite,
+	"PUT /v1/admin/users/{id}/profile": entity.ScopeUsersWrite,
+	"GET /v1/admin/courses":            entity.ScopeCoursesRead,
+	"POST /v1/admin/courses":           entity.ScopeCoursesWrite,
+	"GET /v1/admin/courses/{id}":       entity.ScopeCoursesRead,
+	"PUT /v1/admin/courses/{id}":       entity.ScopeCoursesWrite,
+	"GET /v1/admin/terms":              entity.ScopeCoursesRead,
+	"GET /v1/admin/terms/{id}":         entity.ScopeCoursesRead,
+	"GET /v1/admin/classes":            entity.ScopeEnrollmentsRead,
+	"POST /v1/admin/classes":           entity.ScopeEnrollmentsWrite,
+	"GET /v1/admin/classes/{id}":       entity.ScopeEnrollmentsRead,
+	"PUT /v1/admin/classes/{id}":       entity.ScopeEnrollmentsWrite,
+
+	"GET /v1/teacher/courses":                              entity.ScopeCoursesRead,
+	"GET /v1/teacher/classes":                              entity.ScopeEnrollmentsRead,
+	"GET /v1/teacher/classes/{id}/students":                entity.ScopeEnrollmentsRead,
+	"GET /v1/teacher/assignments":                          entity.ScopeGradesRead,
+	"GET /v1/teacher/assessments":                          entity.ScopeGradesRead,
+	"PUT /v1/teacher/submissions/{id}/grade":               entity.ScopeGradesWrite,
+	"GET /v1/teacher/courses/{id}/modules":                 entity.ScopeCoursesRead,
+	"GET /v1/teacher/lessons/{id}":                         entity.ScopeCoursesRead,
+	"GET /v1/teacher/classes/{id}/progress":                entity.ScopeGradesRead,
+	"PUT /v1/teacher/assessments/{id}/results/{studentId}": entity.ScopeGradesWrite,
+	"GET /v1/teacher/classes/{id}/sessions":                entity.ScopeAttendanceRead,
+	"POST /v1/teacher/classes/{id}/sessions":               entity.ScopeAttendanceWrite,
+	"GET /v1/teacher/classes/{id}/attendance":              entity.ScopeAttendanceRead,
+	"GET /v1/teacher/sessions/{id}/attendance":             entity.ScopeAttendanceRead,
+	"PUT /v1/teacher/sessions/{id}/attendance":             entity.ScopeAttendanceWrite,
+
+	"GET /v1/student/courses/{id}/modules":    entity.ScopeCoursesRead,
+	"GET /v1/student/lessons/{id}":            entity.ScopeCoursesRead,
+	"GET /v1/student/courses/{id}/progress":   entity.ScopeGradesRead,
+	"GET /v1/student/assignments":             entity.ScopeGradesRead,
+	"GET /v1/student/assessments":             entity.ScopeGradesRead,
+	"GET /v1/student/submissions/{id}":        entity.ScopeGradesRead,
+	"GET /v1/student/classes/{id}/attendance": entity.ScopeAttendanceRead,
+}
+
+// requiredScope returns the scope an API token needs for the matched route,
+// and false if the route does not accept API tokens.
+func requiredScope(r *http.Request) (entity.APIScope, bool) {
+	tmpl := routeTemplate(r)
+	if tmpl == "" {
+		return "", false
+	}
+	scope, ok := routeScopes[r.Method+" "+tmpl]
+	return scope, ok
+}
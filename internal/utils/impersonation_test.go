@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/srgjo27/e-learning/internal/entity"
+	"github.com/srgjo27/e-learning/internal/usecase"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// writeLog records the writes made in a session. Its embedded interface
+// panics on any other method.
+type writeLog struct {
+	usecase.ImpersonationRepository
+	writes []entity.ImpersonatedWrite
+}
+
+func (l *writeLog) RecordImpersonatedWrite(ctx context.Context, id primitive.ObjectID, w entity.ImpersonatedWrite) error {
+	l.writes = append(l.writes, w)
+	return nil
+}
+
+func impersonatedRouter(session *entity.ImpersonationSession, log *writeLog) *mux.Router {
+	auth := usecase.NewAuthUseCase(nil, nil, nil, nil, nil, nil, "", nil, nil, nil, nil, log, nil)
+	ok := func(status int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(status) }
+	}
+
+	r := mux.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			serveImpersonated(auth, session, next, w, r)
+		})
+	})
+	r.HandleFunc("/v1/courses", ok(http.StatusOK)).Methods(http.MethodGet)
+	r.HandleFunc("/v1/courses", ok(http.StatusCreated)).Methods(http.MethodPost)
+	r.HandleFunc("/v1/courses/{id}", ok(http.StatusNoContent)).Methods(http.MethodDelete)
+	r.HandleFunc("/v1/impersonation/end", ok(http.StatusOK)).Methods(http.MethodPost)
+	r.HandleFunc("/v1/profile", ok(http.StatusOK)).Methods(http.MethodGet, http.MethodPut)
+	r.HandleFunc("/v1/profile/permissions", ok(http.StatusOK)).Methods(http.MethodGet)
+	r.HandleFunc("/v1/profile/calendar", ok(http.StatusOK)).Methods(http.MethodGet)
+	r.HandleFunc("/v1/profile/calendar/regenerate", ok(http.StatusOK)).Methods(http.MethodPost)
+	return r
+}
+
+func TestServeImpersonated(t *testing.T) {
+	tests := []struct {
+		name        string
+		allowWrites bool
+		method      string
+		path        string
+		wantStatus  int
+		wantLogged  bool
+	}{
+		{"read", false, http.MethodGet, "/v1/courses", http.StatusOK, false},
+		{"own profile read", false, http.MethodGet, "/v1/profile", http.StatusOK, false},
+		{"permissions read", false, http.MethodGet, "/v1/profile/permissions", http.StatusOK, false},
+		{"credential read", false, http.MethodGet, "/v1/profile/calendar", http.StatusForbidden, false},
+		{"credential read with writes", true, http.MethodGet, "/v1/profile/calendar", http.StatusForbidden, false},
+		{"write in read-only session", false, http.MethodPost, "/v1/courses", http.StatusForbidden, false},
+		{"delete in read-only session", false, http.MethodDelete, "/v1/courses/42", http.StatusForbidden, false},
+		{"end read-only session", false, http.MethodPost, "/v1/impersonation/end", http.StatusOK, false},
+		{"write with writes", true, http.MethodPost, "/v1/courses", http.StatusCreated, true},
+		{"delete with writes", true, http.MethodDelete, "/v1/courses/42", http.StatusNoContent, true},
+		{"end session with writes", true, http.MethodPost, "/v1/impersonation/end", http.StatusOK, false},
+		{"profile write with writes", true, http.MethodPut, "/v1/profile", http.StatusForbidden, false},
+		{"credential write with writes", true, http.MethodPost, "/v1/profile/calendar/regenerate", http.StatusForbidden, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &entity.ImpersonationSession{ID: primitive.NewObjectID(), AllowWrites: tt.allowWrites}
+			log := &writeLog{}
+			rec := httptest.NewRecorder()
+			impersonatedRouter(session, log).ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("%s %s answered %d, want %d", tt.method, tt.path, rec.Code, tt.wantStatus)
+			}
+			if logged := len(log.writes) > 0; logged != tt.wantLogged {
+				t.Fatalf("%s %s recorded %v, want a record: %v", tt.method, tt.path, log.writes, tt.wantLogged)
+			}
+			if tt.wantLogged {
+				w := log.writes[0]
+				if w.Method != tt.method || w.Path != tt.path || w.Status != tt.wantStatus {
+					t.Errorf("recorded %+v, want %s %s %d", w, tt.method, tt.path, tt.wantStatus)
+				}
+			}
+		})
+	}
+}
@@ -24,7 +24,12 @@ func JWTMiddleware(authUseCase *usecase.AuthUseCase, next http.Handler) http.Han
 		}
 
 		token := parts[1]
-		userID, roleStr, err := authUseCase.ParseToken(token)
+		if strings.HasPrefix(token, entity.APITokenPrefix) {
+			serveAPIToken(authUseCase, token, next, w, r)
+			return
+		}
+
+		userID, roleStr, session, err := authUseCase.ParseToken(r.Context(), token)
 		if err != nil {
 			http.Error(w, "Invalid token", http.StatusUnauthorized)
 			return
@@ -32,6 +37,34 @@ func JWTMiddleware(authUseCase *usecase.AuthUseCase, next http.Handler) http.Han
 
 		ctx := context.WithValue(r.Context(), "userID", userID)
 		ctx = context.WithValue(ctx, "userRole", entity.Role(roleStr))
+		if session != nil {
+			serveImpersonated(authUseCase, session, next, w, r.WithContext(ctx))
+			return
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
+}
+
+// serveAPIToken authenticates a request made with a personal API token. The
+// route has to accept API tokens and the token has to carry its scope.
+func serveAPIToken(authUseCase *usecase.AuthUseCase, secret string, next http.Handler, w http.ResponseWriter, r *http.Request) {
+	token, role, err := authUseCase.ParseAPIToken(r.Context(), secret)
+	if err != nil {
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	scope, ok := requiredScope(r)
+	if !ok {
+		http.Error(w, "Forbidden - not available to API tokens", http.StatusForbidden)
+		return
+	}
+	if !token.HasScope(scope) {
+		http.Error(w, "Forbidden - token lacks scope "+string(scope), http.StatusForbidden)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), "userID", token.UserID.Hex())
+	ctx = context.WithValue(ctx, "userRole", role)
+	next.ServeHTTP(w, r.WithContext(ctx))
 }
\ No newline at end of file
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"net/http"
+
+	"github.com/srgjo27/e-learning/internal/entity"
+	"github.com/srgjo27/e-learning/internal/usecase"
+)
+
+// RequirePermission lets a request through if the role of the signed-in
+// user grants perm. It runs after JWTMiddleware.
+func RequirePermission(roles *usecase.RoleUseCase, perm entity.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ok, err := roles.ActorCan(r.Context(), perm)
+			if err != nil {
+				http.Error(w, "Failed to check permissions", http.StatusInternalServerError)
+				return
+			}
+			if !ok {
+				http.Error(w, "Forbidden - insufficient permissions", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
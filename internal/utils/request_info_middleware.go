@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+// RequestInfoMiddleware stores the client address and user agent in the
+// request context as "clientIP" and "userAgent", for the audit log.
+func RequestInfoMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+
+		ctx := context.WithValue(r.Context(), "clientIP", ip)
+		ctx = context.WithValue(ctx, "userAgent", r.UserAgent())
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}